@@ -0,0 +1,341 @@
+package main
+
+import (
+	"archive/tar"
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// An archive is a tar file, compressed by its suffix (.tar.zst, .tar.gz, or
+// plain .tar), holding the archived records as history.jsonl and, when the
+// rollout files went with them, each file under sessions/ by its path
+// relative to the sessions directory.
+const (
+	archiveHistoryEntry = "history.jsonl"
+	archiveSessionsDir  = "sessions/"
+)
+
+func runArchive(args []string) error {
+	fs := flag.NewFlagSet("archive", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+
+	inputPath := fs.String("in", defaultOutputFile(), "History JSONL path")
+	before := fs.String("before", "", "Archive records older than this date (YYYY-MM-DD) or RFC3339 time")
+	outPath := fs.String("out", "", "Archive path: .tar.zst, .tar.gz, or .tar")
+	withSources := fs.Bool("with-sources", false, "Also move rollout files whose records are all archived")
+	sessionsDir := fs.String("sessions-dir", defaultSessionsDir(), "Codex sessions directory, for relative source paths")
+	dryRun := fs.Bool("dry-run", false, "Count what would be archived without writing or removing anything")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if strings.TrimSpace(*before) == "" {
+		return errors.New("--before is required")
+	}
+	if strings.TrimSpace(*outPath) == "" && !*dryRun {
+		return errors.New("--out is required")
+	}
+	cutoff, err := parseDateOrTime(*before, "--before")
+	if err != nil {
+		return err
+	}
+
+	records, sources, err := selectArchiveRecords(*inputPath, cutoff, *sessionsDir, *withSources)
+	if err != nil {
+		return err
+	}
+	if *dryRun {
+		fmt.Printf("would archive %d records and %d source files\n", len(records), len(sources))
+		return nil
+	}
+	if len(records) == 0 {
+		fmt.Println("nothing to archive")
+		return nil
+	}
+
+	if err := writeArchive(*outPath, records, sources, *sessionsDir); err != nil {
+		return err
+	}
+	archived := make(idSet, len(records))
+	for _, record := range records {
+		archived.add(record.ID)
+	}
+	removed, err := removeRecords(*inputPath, func(record Record) bool { return archived.has(record.ID) })
+	if err != nil {
+		return err
+	}
+	for _, source := range sources {
+		if err := os.Remove(source); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+		}
+	}
+	fmt.Printf("archived=%d sources=%d out=%s\n", removed, len(sources), *outPath)
+	return nil
+}
+
+// parseDateOrTime reads a YYYY-MM-DD date (midnight UTC) or an RFC3339
+// time.
+func parseDateOrTime(raw, flagName string) (time.Time, error) {
+	if day, err := time.Parse(time.DateOnly, strings.TrimSpace(raw)); err == nil {
+		return day, nil
+	}
+	t, err := parseBoundTime(raw, flagName)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s value %q: expected YYYY-MM-DD or RFC3339", flagName, raw)
+	}
+	return t, nil
+}
+
+// selectArchiveRecords returns the records older than cutoff, oldest first.
+// With withSources it also returns the rollout files every one of whose
+// records is archived, and that still exist.
+func selectArchiveRecords(historyPath string, cutoff time.Time, sessionsDir string, withSources bool) ([]Record, []string, error) {
+	var records []Record
+	keep := make(map[string]bool)
+	err := forEachRecord(historyPath, func(record Record) error {
+		source := resolveSourcePath(sessionsDir, record.SourceFile)
+		if t, ok := parseRecordTime(record.Timestamp); ok && t.Before(cutoff) {
+			records = append(records, record)
+			if _, seen := keep[source]; !seen {
+				keep[source] = false
+			}
+		} else {
+			keep[source] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	sortRecordsChronological(records)
+
+	var sources []string
+	if withSources {
+		for _, record := range records {
+			// A hashed source names no file to move; it stays wherever
+			// it is.
+			if isHashedPath(record.SourceFile) {
+				continue
+			}
+			source := resolveSourcePath(sessionsDir, record.SourceFile)
+			if source == "" || keep[source] {
+				continue
+			}
+			keep[source] = true // list each file once
+			if info, err := os.Stat(source); err == nil && info.Mode().IsRegular() {
+				sources = append(sources, source)
+			}
+		}
+	}
+	return records, sources, nil
+}
+
+// archiveSourceName is the name a rollout file is stored under.
+func archiveSourceName(sessionsDir, source string) string {
+	rel := relativeSourcePath(sessionsDir, source)
+	if filepath.IsAbs(rel) {
+		rel = filepath.Base(rel)
+	}
+	return archiveSessionsDir + filepath.ToSlash(rel)
+}
+
+// writeArchive writes records and sources into a new archive at out,
+// through a temp file so a failed run leaves no partial archive.
+func writeArchive(out string, records []Record, sources []string, sessionsDir string) error {
+	if _, err := os.Stat(out); err == nil {
+		return fmt.Errorf("%s already exists", out)
+	}
+	tmpPath := out + ".tmp"
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpPath)
+	defer file.Close()
+
+	var sink io.Writer = file
+	var compressor io.WriteCloser
+	if compression := compressionFor(out); compression != "" {
+		compressor, err = compressWriter(file, compression)
+		if err != nil {
+			return err
+		}
+		sink = compressor
+	}
+
+	tw := tar.NewWriter(sink)
+	var history strings.Builder
+	encoder := json.NewEncoder(&history)
+	encoder.SetEscapeHTML(false)
+	for _, record := range records {
+		if err := encoder.Encode(record); err != nil {
+			return err
+		}
+	}
+	now := time.Now()
+	if err := writeTarFile(tw, archiveHistoryEntry, []byte(history.String()), now); err != nil {
+		return err
+	}
+	for _, source := range sources {
+		data, err := os.ReadFile(source)
+		if err != nil {
+			return err
+		}
+		modTime := now
+		if info, err := os.Stat(source); err == nil {
+			modTime = info.ModTime()
+		}
+		if err := writeTarFile(tw, archiveSourceName(sessionsDir, source), data, modTime); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if compressor != nil {
+		if err := compressor.Close(); err != nil {
+			return err
+		}
+	}
+	if err := file.Sync(); err != nil {
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, out)
+}
+
+func writeTarFile(tw *tar.Writer, name string, data []byte, modTime time.Time) error {
+	header := &tar.Header{Name: name, Mode: 0o644, Size: int64(len(data)), ModTime: modTime, Typeflag: tar.TypeReg}
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err := tw.Write(data)
+	return err
+}
+
+func runRestore(args []string) error {
+	fs := flag.NewFlagSet("restore", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+
+	inputPath := fs.String("in", defaultOutputFile(), "History JSONL path to merge into")
+	withSources := fs.Bool("with-sources", false, "Also put archived rollout files back into --sessions-dir")
+	sessionsDir := fs.String("sessions-dir", defaultSessionsDir(), "Codex sessions directory")
+
+	// The archive may come before or after the flags.
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return errors.New("missing archive path")
+	}
+	archivePath := fs.Arg(0)
+	if err := fs.Parse(fs.Args()[1:]); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected argument %q", fs.Arg(0))
+	}
+
+	restored, sources, err := restoreArchive(archivePath, *inputPath, *sessionsDir, *withSources)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("restored=%d sources=%d in=%s\n", restored, sources, *inputPath)
+	return nil
+}
+
+// restoreArchive merges an archive's records into the history, skipping IDs
+// it already has, and with withSources writes back rollout files that do
+// not exist. It returns how many records and files it restored.
+func restoreArchive(archivePath, historyPath, sessionsDir string, withSources bool) (int, int, error) {
+	reader, err := openHistory(archivePath)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer reader.Close()
+
+	var records []Record
+	sources := 0
+	tr := tar.NewReader(reader)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return 0, 0, fmt.Errorf("%s: %w", archivePath, err)
+		}
+		name := path.Clean(header.Name)
+		switch {
+		case name == archiveHistoryEntry:
+			scanner := bufio.NewScanner(tr)
+			scanner.Buffer(make([]byte, 64*1024), scannerMaxTokenSize)
+			for scanner.Scan() {
+				var record Record
+				if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+					return 0, 0, fmt.Errorf("%s: %s: %w", archivePath, archiveHistoryEntry, err)
+				}
+				records = append(records, record)
+			}
+			if err := scanner.Err(); err != nil {
+				return 0, 0, err
+			}
+		case withSources && strings.HasPrefix(name, archiveSessionsDir) && header.Typeflag == tar.TypeReg:
+			rel := strings.TrimPrefix(name, archiveSessionsDir)
+			if rel == "" || strings.HasPrefix(rel, "../") || path.IsAbs(rel) {
+				return 0, 0, fmt.Errorf("%s: unsafe entry %q", archivePath, header.Name)
+			}
+			target := filepath.Join(sessionsDir, filepath.FromSlash(rel))
+			if _, err := os.Stat(target); err == nil {
+				continue
+			}
+			data, err := io.ReadAll(tr)
+			if err != nil {
+				return 0, 0, err
+			}
+			if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+				return 0, 0, err
+			}
+			if err := writeFileAtomic(target, data); err != nil {
+				return 0, 0, err
+			}
+			os.Chtimes(target, header.ModTime, header.ModTime)
+			sources++
+		}
+	}
+
+	unlock, err := lockHistory(historyPath)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer unlock()
+	existing, err := loadIDIndex(historyPath, false)
+	if err != nil {
+		return 0, 0, err
+	}
+	var missing []Record
+	restored := make(idSet)
+	for _, record := range records {
+		if existing.has(record.ID) {
+			continue
+		}
+		existing.add(record.ID)
+		restored.add(record.ID)
+		missing = append(missing, record)
+	}
+	if err := appendRecords(historyPath, missing, true); err != nil {
+		return 0, 0, err
+	}
+	return len(missing), sources, forgetRemovedIDs(historyPath, restored)
+}
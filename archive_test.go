@@ -0,0 +1,83 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestArchiveAndRestore(t *testing.T) {
+	dir := t.TempDir()
+	sessionsDir := filepath.Join(dir, "sessions")
+	oldSource := filepath.Join(sessionsDir, "2025", "12", "old.jsonl")
+	mixedSource := filepath.Join(sessionsDir, "2025", "12", "mixed.jsonl")
+	for _, source := range []string{oldSource, mixedSource} {
+		if err := os.MkdirAll(filepath.Dir(source), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(source, []byte("{}\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	history := filepath.Join(dir, "history.jsonl")
+	records := []Record{
+		{ID: "a1", SessionID: "old", Timestamp: "2025-12-01T10:00:00Z", Role: "user", Text: "old", SourceFile: oldSource},
+		{ID: "b1", SessionID: "mixed", Timestamp: "2025-12-31T23:00:00Z", Role: "user", Text: "late", SourceFile: mixedSource},
+		{ID: "b2", SessionID: "mixed", Timestamp: "2026-01-01T01:00:00Z", Role: "assistant", Text: "new year", SourceFile: mixedSource},
+	}
+	if err := appendRecords(history, records, false); err != nil {
+		t.Fatal(err)
+	}
+
+	cutoff, err := parseDateOrTime("2026-01-01", "--before")
+	if err != nil || !cutoff.Equal(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("got %v, %v", cutoff, err)
+	}
+	archived, sources, err := selectArchiveRecords(history, cutoff, sessionsDir, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// mixed.jsonl still has a record after the cutoff, so it stays.
+	if len(archived) != 2 || len(sources) != 1 || sources[0] != oldSource {
+		t.Fatalf("unexpected selection: %+v %v", archived, sources)
+	}
+
+	out := filepath.Join(dir, "old.tar.gz")
+	if err := writeArchive(out, archived, sources, sessionsDir); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeArchive(out, archived, sources, sessionsDir); err == nil {
+		t.Fatal("expected an error for an existing archive")
+	}
+	if _, err := removeRecords(history, func(record Record) bool { return record.ID != "b2" }); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(oldSource); err != nil {
+		t.Fatal(err)
+	}
+
+	restored, files, err := restoreArchive(out, history, sessionsDir, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored != 2 || files != 1 {
+		t.Fatalf("restored %d records and %d files", restored, files)
+	}
+	if _, err := os.Stat(oldSource); err != nil {
+		t.Fatalf("source not restored: %v", err)
+	}
+	all, err := loadRecords(history)
+	if err != nil || len(all) != 3 {
+		t.Fatalf("got %d records, %v", len(all), err)
+	}
+	removed, err := loadRemovedIDs(history)
+	if err != nil || len(removed) != 0 {
+		t.Fatalf("expected restored IDs to leave the removed set, got %d, %v", len(removed), err)
+	}
+
+	// Restoring again adds nothing.
+	if restored, files, err := restoreArchive(out, history, sessionsDir, true); err != nil || restored != 0 || files != 0 {
+		t.Fatalf("second restore: %d %d %v", restored, files, err)
+	}
+}
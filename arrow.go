@@ -0,0 +1,414 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// This file writes the Arrow IPC streaming format: a schema message, one
+// record batch message per arrowBatchSize records, and an end-of-stream
+// marker. Message metadata is FlatBuffers, built by the small front-to-back
+// encoder below; bodies are uncompressed.
+
+// arrowBatchSize is how many records go into one record batch. Batches are
+// written as soon as they fill, so memory use is bounded by one batch.
+const arrowBatchSize = 4096
+
+// Arrow enum values, from Schema.fbs and Message.fbs.
+const (
+	arrowMetadataV5      = 4
+	arrowHeaderSchema    = 1
+	arrowHeaderBatch     = 3
+	arrowTypeInt         = 2
+	arrowTypeUtf8        = 5
+	arrowTypeTimestamp   = 10
+	arrowUnitMicrosecond = 2
+)
+
+// arrowStreamWriter encodes records as an Arrow IPC stream.
+type arrowStreamWriter struct {
+	w       io.Writer
+	pending []Record
+	started bool
+}
+
+func newArrowStreamWriter(w io.Writer) *arrowStreamWriter {
+	return &arrowStreamWriter{w: w}
+}
+
+func (a *arrowStreamWriter) Write(record Record) error {
+	a.pending = append(a.pending, record)
+	if len(a.pending) < arrowBatchSize {
+		return nil
+	}
+	return a.flush()
+}
+
+// Close writes any buffered records and the end-of-stream marker.
+func (a *arrowStreamWriter) Close() error {
+	if err := a.flush(); err != nil {
+		return err
+	}
+	_, err := a.w.Write([]byte{0xFF, 0xFF, 0xFF, 0xFF, 0, 0, 0, 0})
+	return err
+}
+
+func (a *arrowStreamWriter) writeSchema() error {
+	if a.started {
+		return nil
+	}
+	a.started = true
+
+	fields := make([]*fbTable, len(recordColumns))
+	for i, column := range recordColumns {
+		field := &fbTable{}
+		field.set(0, fbString(column.name))
+		field.set(1, fbBool(column.optional))
+		switch {
+		case column.timestamp:
+			unit := &fbTable{}
+			unit.set(0, fbScalar{size: 2, value: arrowUnitMicrosecond})
+			unit.set(1, fbString("UTC"))
+			field.set(2, fbScalar{size: 1, value: arrowTypeTimestamp})
+			field.set(3, unit)
+		case column.int != nil:
+			integer := &fbTable{}
+			integer.set(0, fbScalar{size: 4, value: 64})
+			integer.set(1, fbBool(true))
+			field.set(2, fbScalar{size: 1, value: arrowTypeInt})
+			field.set(3, integer)
+		default:
+			field.set(2, fbScalar{size: 1, value: arrowTypeUtf8})
+			field.set(3, &fbTable{})
+		}
+		// Readers reject a field without a children vector, even an empty one.
+		field.set(5, fbTables(nil))
+		fields[i] = field
+	}
+	schema := &fbTable{}
+	schema.set(1, fbTables(fields))
+	return a.writeMessage(arrowHeaderSchema, schema, nil)
+}
+
+// flush writes the pending records as one record batch. Each column
+// contributes a validity bitmap (omitted when it has no nulls) and either
+// int32 offsets plus UTF-8 data or int64 values.
+func (a *arrowStreamWriter) flush() error {
+	if err := a.writeSchema(); err != nil {
+		return err
+	}
+	if len(a.pending) == 0 {
+		return nil
+	}
+	records := a.pending
+	a.pending = a.pending[:0]
+
+	var body []byte
+	var nodes, buffers []byte
+	addBuffer := func(data []byte) {
+		buffers = binary.LittleEndian.AppendUint64(buffers, uint64(len(body)))
+		buffers = binary.LittleEndian.AppendUint64(buffers, uint64(len(data)))
+		body = append(body, data...)
+		for len(body)%8 != 0 {
+			body = append(body, 0)
+		}
+	}
+
+	for _, column := range recordColumns {
+		validity := make([]byte, (len(records)+7)/8)
+		nulls := 0
+		var offsets, values []byte
+		if column.str != nil {
+			offsets = binary.LittleEndian.AppendUint32(offsets, 0)
+		}
+		for i, record := range records {
+			present := true
+			if column.str != nil {
+				var value string
+				value, present = column.str(record)
+				if present || !column.optional {
+					values = append(values, value...)
+					present = true
+				}
+				offsets = binary.LittleEndian.AppendUint32(offsets, uint32(len(values)))
+			} else {
+				var value int64
+				value, present = column.int(record)
+				if !present && !column.optional {
+					present = true
+				}
+				if !present {
+					value = 0
+				}
+				values = binary.LittleEndian.AppendUint64(values, uint64(value))
+			}
+			if present {
+				validity[i/8] |= 1 << (i % 8)
+			} else {
+				nulls++
+			}
+		}
+
+		nodes = binary.LittleEndian.AppendUint64(nodes, uint64(len(records)))
+		nodes = binary.LittleEndian.AppendUint64(nodes, uint64(nulls))
+		if nulls == 0 {
+			addBuffer(nil)
+		} else {
+			addBuffer(validity)
+		}
+		if column.str != nil {
+			addBuffer(offsets)
+		}
+		addBuffer(values)
+	}
+
+	batch := &fbTable{}
+	batch.set(0, fbScalar{size: 8, value: uint64(len(records))})
+	batch.set(1, fbStructs{elemSize: 16, data: nodes})
+	batch.set(2, fbStructs{elemSize: 16, data: buffers})
+	return a.writeMessage(arrowHeaderBatch, batch, body)
+}
+
+// writeMessage writes one encapsulated message: the continuation marker,
+// the metadata length, the Message flatbuffer padded to 8 bytes, and the
+// body.
+func (a *arrowStreamWriter) writeMessage(headerType uint64, header *fbTable, body []byte) error {
+	message := &fbTable{}
+	message.set(0, fbScalar{size: 2, value: arrowMetadataV5})
+	message.set(1, fbScalar{size: 1, value: headerType})
+	message.set(2, header)
+	message.set(3, fbScalar{size: 8, value: uint64(len(body))})
+
+	metadata := buildFlatBuffer(message)
+	for len(metadata)%8 != 0 {
+		metadata = append(metadata, 0)
+	}
+	prefix := binary.LittleEndian.AppendUint32([]byte{0xFF, 0xFF, 0xFF, 0xFF}, uint32(len(metadata)))
+	for _, chunk := range [][]byte{prefix, metadata, body} {
+		if _, err := a.w.Write(chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// renderArrow encodes records as an Arrow IPC stream.
+func renderArrow(records []Record) ([]byte, error) {
+	var out bytes.Buffer
+	writer := newArrowStreamWriter(&out)
+	for _, record := range records {
+		if err := writer.Write(record); err != nil {
+			return nil, err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// streamArrowExport writes the matching records of the history at inPath to
+// outPath (stdout when empty) as they are read, one batch at a time.
+func streamArrowExport(inPath, outPath string, filter RecordFilter, opts ExportOptions) (int, error) {
+	var out io.Writer = os.Stdout
+	if strings.TrimSpace(outPath) != "" {
+		if err := os.MkdirAll(filepath.Dir(outPath), 0o755); err != nil {
+			return 0, err
+		}
+		file, err := os.Create(outPath)
+		if err != nil {
+			return 0, err
+		}
+		defer file.Close()
+		out = file
+	}
+	buffered := bufio.NewWriter(out)
+	var sink io.Writer = buffered
+	var compressed io.WriteCloser
+	if opts.Compression != "" {
+		var err error
+		if compressed, err = compressWriter(buffered, opts.Compression); err != nil {
+			return 0, err
+		}
+		sink = compressed
+	}
+	writer := newArrowStreamWriter(sink)
+
+	match := newRecordMatcher(filter)
+	count := 0
+	err := forEachRecord(inPath, func(record Record) error {
+		if !match(record) {
+			return nil
+		}
+		if opts.NoSources {
+			record = stripRecordSources(record)
+		}
+		count++
+		return writer.Write(record)
+	})
+	if err != nil {
+		return 0, err
+	}
+	if err := writer.Close(); err != nil {
+		return 0, err
+	}
+	if compressed != nil {
+		if err := compressed.Close(); err != nil {
+			return 0, err
+		}
+	}
+	return count, buffered.Flush()
+}
+
+// FlatBuffers values. A table's fields are indexed by field ID; unions take
+// two IDs, the type (a 1-byte scalar) and the value (a table).
+type (
+	fbTable struct {
+		fields []any
+	}
+	fbScalar struct {
+		size  int
+		value uint64
+	}
+	fbString string
+	fbTables []*fbTable
+	// fbStructs is a vector of fixed-size structs, already encoded.
+	fbStructs struct {
+		elemSize int
+		data     []byte
+	}
+)
+
+func fbBool(value bool) fbScalar {
+	if value {
+		return fbScalar{size: 1, value: 1}
+	}
+	return fbScalar{size: 1, value: 0}
+}
+
+func (t *fbTable) set(id int, value any) {
+	for len(t.fields) <= id {
+		t.fields = append(t.fields, nil)
+	}
+	t.fields[id] = value
+}
+
+// buildFlatBuffer encodes root front to back: every object is written
+// before the objects it references, so all offsets point forward.
+func buildFlatBuffer(root *fbTable) []byte {
+	b := &fbBuilder{buf: make([]byte, 4)}
+	pos := b.object(root)
+	binary.LittleEndian.PutUint32(b.buf[0:], uint32(pos))
+	return b.buf
+}
+
+type fbBuilder struct {
+	buf []byte
+}
+
+func (b *fbBuilder) align(n int) {
+	for len(b.buf)%n != 0 {
+		b.buf = append(b.buf, 0)
+	}
+}
+
+// object writes value and returns the position uoffsets should point at.
+func (b *fbBuilder) object(value any) int {
+	switch v := value.(type) {
+	case *fbTable:
+		return b.table(v)
+	case fbString:
+		b.align(4)
+		pos := len(b.buf)
+		b.buf = binary.LittleEndian.AppendUint32(b.buf, uint32(len(v)))
+		b.buf = append(b.buf, v...)
+		b.buf = append(b.buf, 0)
+		return pos
+	case fbTables:
+		b.align(4)
+		pos := len(b.buf)
+		b.buf = binary.LittleEndian.AppendUint32(b.buf, uint32(len(v)))
+		slots := len(b.buf)
+		b.buf = append(b.buf, make([]byte, 4*len(v))...)
+		for i, table := range v {
+			b.patch(slots+4*i, b.table(table))
+		}
+		return pos
+	case fbStructs:
+		// The elements, not the length, must be 8-byte aligned.
+		for (len(b.buf)+4)%8 != 0 {
+			b.buf = append(b.buf, 0)
+		}
+		pos := len(b.buf)
+		b.buf = binary.LittleEndian.AppendUint32(b.buf, uint32(len(v.data)/v.elemSize))
+		b.buf = append(b.buf, v.data...)
+		return pos
+	}
+	panic("flatbuffers: unsupported value")
+}
+
+// patch stores at slot the uoffset to target.
+func (b *fbBuilder) patch(slot, target int) {
+	binary.LittleEndian.PutUint32(b.buf[slot:], uint32(target-slot))
+}
+
+// table writes the vtable, then the table (fields laid out largest first so
+// each is naturally aligned), then the objects its fields reference.
+func (b *fbBuilder) table(t *fbTable) int {
+	offsets := make([]int, len(t.fields))
+	size := 4
+	for _, width := range []int{8, 4, 2, 1} {
+		for id, field := range t.fields {
+			if field == nil || fbFieldSize(field) != width {
+				continue
+			}
+			for size%width != 0 {
+				size++
+			}
+			offsets[id] = size
+			size += width
+		}
+	}
+
+	b.align(2)
+	vtable := len(b.buf)
+	b.buf = binary.LittleEndian.AppendUint16(b.buf, uint16(4+2*len(t.fields)))
+	b.buf = binary.LittleEndian.AppendUint16(b.buf, uint16(size))
+	for _, offset := range offsets {
+		b.buf = binary.LittleEndian.AppendUint16(b.buf, uint16(offset))
+	}
+
+	b.align(8)
+	start := len(b.buf)
+	b.buf = append(b.buf, make([]byte, size)...)
+	binary.LittleEndian.PutUint32(b.buf[start:], uint32(int32(start-vtable)))
+	for id, field := range t.fields {
+		if scalar, ok := field.(fbScalar); ok {
+			for i := 0; i < scalar.size; i++ {
+				b.buf[start+offsets[id]+i] = byte(scalar.value >> (8 * i))
+			}
+		}
+	}
+	for id, field := range t.fields {
+		if field == nil {
+			continue
+		}
+		if _, ok := field.(fbScalar); ok {
+			continue
+		}
+		b.patch(start+offsets[id], b.object(field))
+	}
+	return start
+}
+
+func fbFieldSize(field any) int {
+	if scalar, ok := field.(fbScalar); ok {
+		return scalar.size
+	}
+	return 4
+}
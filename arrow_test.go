@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRenderArrowFraming(t *testing.T) {
+	records := []Record{
+		{ID: "id1", SessionID: "s1", Timestamp: "2026-02-17T10:00:00Z", Role: "user", Text: "hello", SourceLine: 3},
+		{ID: "id2", SessionID: "s1", Timestamp: "bad", Role: "assistant", Text: "hi"},
+	}
+	content, err := renderExport("arrow", records, ExportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(content, []byte{0xFF, 0xFF, 0xFF, 0xFF}) || !bytes.HasSuffix(content, []byte{0xFF, 0xFF, 0xFF, 0xFF, 0, 0, 0, 0}) {
+		t.Fatal("missing continuation or end-of-stream marker")
+	}
+	schemaLen := binary.LittleEndian.Uint32(content[4:])
+	if schemaLen%8 != 0 {
+		t.Fatalf("schema metadata length %d is not 8-byte aligned", schemaLen)
+	}
+	schema := content[8 : 8+schemaLen]
+	for _, name := range []string{"session_id", "timestamp", "UTC", "source_line", "raw"} {
+		if !bytes.Contains(schema, []byte(name)) {
+			t.Fatalf("schema is missing %q", name)
+		}
+	}
+	if !bytes.Contains(content[8+schemaLen:], []byte("hellohi")) {
+		t.Fatal("text column data not found in record batch")
+	}
+}
+
+// TestRenderArrowGolden pins the output to a file an independent reader
+// has decoded, as TestRenderParquetGolden does.
+func TestRenderArrowGolden(t *testing.T) {
+	content, err := renderExport("arrow", goldenExportRecords(), ExportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden, err := os.ReadFile(filepath.Join("testdata", "export.arrow"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(content, golden) {
+		t.Fatal("Arrow output differs from testdata/export.arrow")
+	}
+}
+
+func TestStreamArrowExportFilters(t *testing.T) {
+	dir := t.TempDir()
+	history := filepath.Join(dir, "history.jsonl")
+	var records []Record
+	for i := 0; i < arrowBatchSize+10; i++ {
+		role := "user"
+		if i%2 == 1 {
+			role = "assistant"
+		}
+		records = append(records, Record{ID: fmt.Sprintf("id%d", i), SessionID: "s", Timestamp: "2026-02-17T10:00:00Z", Role: role, Text: "x"})
+	}
+	if err := appendRecords(history, records, false); err != nil {
+		t.Fatal(err)
+	}
+
+	out := filepath.Join(dir, "out", "user.arrow")
+	count, err := streamArrowExport(history, out, RecordFilter{Role: "user"}, ExportOptions{NoSources: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != (arrowBatchSize+10)/2 {
+		t.Fatalf("expected %d records, got %d", (arrowBatchSize+10)/2, count)
+	}
+	content, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasSuffix(content, []byte{0xFF, 0xFF, 0xFF, 0xFF, 0, 0, 0, 0}) {
+		t.Fatal("missing end-of-stream marker")
+	}
+}
+
+func TestOrderExportArrowKeepsFileOrder(t *testing.T) {
+	fileOrder := func() []Record {
+		return []Record{
+			{ID: "late", Timestamp: "2026-02-17T11:00:00Z"},
+			{ID: "early", Timestamp: "2026-02-17T09:00:00Z"},
+			{ID: "middle", Timestamp: "2026-02-17T10:00:00Z"},
+		}
+	}
+	ids := func(records []Record) string {
+		var out []string
+		for _, record := range records {
+			out = append(out, record.ID)
+		}
+		return fmt.Sprint(out)
+	}
+	for _, tc := range []struct {
+		format string
+		desc   bool
+		limit  int
+		want   string
+	}{
+		{"arrow", false, 0, "[late early middle]"},
+		{"arrow", true, 0, "[middle early late]"},
+		{"arrow", false, 2, "[early middle]"},
+		{"arrow", true, 1, "[middle]"},
+		{"jsonl", false, 0, "[early middle late]"},
+		{"jsonl", true, 2, "[late middle]"},
+	} {
+		if got := ids(orderExport(fileOrder(), tc.format, tc.desc, tc.limit)); got != tc.want {
+			t.Errorf("orderExport(%s, desc=%v, limit=%d) = %s, want %s", tc.format, tc.desc, tc.limit, got, tc.want)
+		}
+	}
+}
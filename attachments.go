@@ -0,0 +1,131 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Attachment points at an image that was attached to a message. Inline
+// (base64) images are identified by their hash and, when an assets
+// directory is configured, saved there; file references keep their path.
+type Attachment struct {
+	Type   string `json:"type"`
+	MIME   string `json:"mime,omitempty"`
+	Path   string `json:"path,omitempty"`
+	SHA256 string `json:"sha256,omitempty"`
+	Bytes  int    `json:"bytes,omitempty"`
+}
+
+var imageExtensions = map[string]string{
+	"image/png":  ".png",
+	"image/jpeg": ".jpg",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+// errInvalidImage marks an inline image that cannot be decoded; such
+// entries are skipped rather than failing the whole session file.
+var errInvalidImage = errors.New("invalid inline image")
+
+// imageAttachments converts a message's image list, skipping undecodable
+// entries. Only failures to save an asset are returned.
+func imageAttachments(refs []string, assetsDir string) ([]Attachment, error) {
+	var out []Attachment
+	for _, ref := range refs {
+		attachment, err := imageAttachment(ref, assetsDir)
+		if errors.Is(err, errInvalidImage) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, attachment)
+	}
+	return out, nil
+}
+
+// imageAttachment builds an attachment from one entry of a message's image
+// list: either a data: URL or a reference to a file or remote URL.
+func imageAttachment(ref, assetsDir string) (Attachment, error) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return Attachment{}, errInvalidImage
+	}
+	if !strings.HasPrefix(ref, "data:") {
+		return Attachment{Type: "image", Path: ref}, nil
+	}
+
+	header, payload, ok := strings.Cut(strings.TrimPrefix(ref, "data:"), ",")
+	if !ok || !strings.HasSuffix(header, ";base64") {
+		return Attachment{}, errInvalidImage
+	}
+	data, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		return Attachment{}, errInvalidImage
+	}
+
+	sum := sha256.Sum256(data)
+	attachment := Attachment{
+		Type:   "image",
+		MIME:   strings.TrimSuffix(header, ";base64"),
+		SHA256: hex.EncodeToString(sum[:]),
+		Bytes:  len(data),
+	}
+	if assetsDir != "" {
+		path, err := saveAsset(assetsDir, attachment, data)
+		if err != nil {
+			return Attachment{}, err
+		}
+		attachment.Path = path
+	}
+	return attachment, nil
+}
+
+// saveAsset stores data under its hash, so the same image attached twice is
+// written once. Files are created via a unique temp name because session
+// files are parsed concurrently.
+func saveAsset(dir string, attachment Attachment, data []byte) (string, error) {
+	ext := imageExtensions[attachment.MIME]
+	if ext == "" {
+		ext = ".bin"
+	}
+	path := filepath.Join(dir, attachment.SHA256+ext)
+	if _, err := os.Stat(path); err == nil {
+		return path, nil
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	tmp, err := os.CreateTemp(dir, ".asset-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// attachmentPlaceholder stands in for the text of a message that consists
+// of attachments only.
+func attachmentPlaceholder(attachments []Attachment) string {
+	if len(attachments) == 1 {
+		return "[1 image]"
+	}
+	return fmt.Sprintf("[%d images]", len(attachments))
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExtractImageAttachments(t *testing.T) {
+	// A 1x1 transparent PNG.
+	png := "iVBORw0KGgoAAAANSUhEUgAAAAEAAAABCAQAAAC1HAwCAAAAC0lEQVR42mNkYAAAAAYAAjCB0C8AAAAASUVORK5CYII="
+	path := writeSessionFile(t,
+		`{"timestamp":"2026-02-17T12:00:01Z","type":"event_msg","payload":{"type":"user_message","message":"what is wrong here?","images":["data:image/png;base64,`+png+`","/tmp/screenshot.png","data:image/png;base64,!!!"]}}`,
+		`{"timestamp":"2026-02-17T12:00:02Z","type":"event_msg","payload":{"type":"user_message","message":"","images":["data:image/png;base64,`+png+`"]}}`,
+	)
+	assets := filepath.Join(t.TempDir(), "assets")
+
+	records, err := extractRecords(path, ExtractOptions{AssetsDir: assets})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("expected 2 records, got %#v", records)
+	}
+	first := records[0].Attachments
+	if len(first) != 2 || first[0].MIME != "image/png" || first[0].SHA256 == "" || first[1].Path != "/tmp/screenshot.png" {
+		t.Fatalf("unexpected attachments: %#v", first)
+	}
+	saved, err := os.ReadFile(first[0].Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(saved) != first[0].Bytes || !bytes.HasPrefix(saved, []byte("\x89PNG")) {
+		t.Fatalf("unexpected saved asset (%d bytes)", len(saved))
+	}
+
+	if records[1].Text != "[1 image]" || records[1].Attachments[0].Path != first[0].Path {
+		t.Fatalf("image-only message not recorded: %#v", records[1])
+	}
+	entries, _ := os.ReadDir(assets)
+	if len(entries) != 1 {
+		t.Fatalf("expected one deduplicated asset, got %d", len(entries))
+	}
+}
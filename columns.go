@@ -0,0 +1,47 @@
+package main
+
+import "encoding/json"
+
+// recordColumn describes one column of the columnar exports and how to read
+// it from a record. A column is either a string column (str) or an int64
+// column (int); value functions report false for a null.
+type recordColumn struct {
+	name      string
+	optional  bool
+	timestamp bool
+	str       func(Record) (string, bool)
+	int       func(Record) (int64, bool)
+}
+
+// recordColumns mirrors Record. Nested fields are stored as JSON text so
+// the schema stays flat. Timestamps are microseconds since the epoch, UTC.
+var recordColumns = []recordColumn{
+	{name: "id", str: func(r Record) (string, bool) { return r.ID, true }},
+	{name: "session_id", str: func(r Record) (string, bool) { return r.SessionID, true }},
+	{name: "timestamp", optional: true, timestamp: true, int: func(r Record) (int64, bool) {
+		t, ok := parseRecordTime(r.Timestamp)
+		if !ok {
+			return 0, false
+		}
+		return t.UnixMicro(), true
+	}},
+	{name: "role", str: func(r Record) (string, bool) { return r.Role, true }},
+	{name: "text", str: func(r Record) (string, bool) { return r.Text, true }},
+	{name: "source_file", optional: true, str: func(r Record) (string, bool) { return r.SourceFile, r.SourceFile != "" }},
+	{name: "source_line", optional: true, int: func(r Record) (int64, bool) { return int64(r.SourceLine), r.SourceLine != 0 }},
+	{name: "meta", optional: true, str: func(r Record) (string, bool) { return jsonColumn(r.Meta, len(r.Meta) > 0) }},
+	{name: "attachments", optional: true, str: func(r Record) (string, bool) { return jsonColumn(r.Attachments, len(r.Attachments) > 0) }},
+	{name: "tool", optional: true, str: func(r Record) (string, bool) { return jsonColumn(r.Tool, r.Tool != nil) }},
+	{name: "raw", optional: true, str: func(r Record) (string, bool) { return string(r.Raw), len(r.Raw) > 0 }},
+}
+
+func jsonColumn(value any, present bool) (string, bool) {
+	if !present {
+		return "", false
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", false
+	}
+	return string(data), true
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+type CompactResult struct {
+	Lines      int `json:"lines"`
+	Kept       int `json:"kept"`
+	Duplicates int `json:"duplicates"`
+	Incomplete int `json:"incomplete"`
+	Blank      int `json:"blank"`
+	// Malformed lines are not records, so compact cannot judge them; they are
+	// carried to the end of the file untouched.
+	Malformed int `json:"malformed"`
+}
+
+func (r CompactResult) removed() int {
+	return r.Duplicates + r.Incomplete + r.Blank
+}
+
+// recordComplete reports whether record has every field the other commands
+// rely on; text may legitimately be empty.
+func recordComplete(record Record) bool {
+	for _, value := range []string{record.ID, record.SessionID, record.Timestamp, record.Role} {
+		if strings.TrimSpace(value) == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// compactHistory dedupes by ID (first occurrence wins), drops incomplete
+// records and blank lines, and sorts the rest chronologically. Without
+// dryRun the history is replaced atomically under the history lock.
+func compactHistory(path string, dryRun bool) (CompactResult, error) {
+	if err := checkWritableHistory(path); err != nil {
+		return CompactResult{}, err
+	}
+	unlock, err := lockHistory(path)
+	if err != nil {
+		return CompactResult{}, err
+	}
+	defer unlock()
+
+	file, err := os.Open(path)
+	if err != nil {
+		return CompactResult{}, err
+	}
+	defer file.Close()
+
+	var result CompactResult
+	var records []Record
+	var malformed [][]byte
+	seen := make(idSet)
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), scannerMaxTokenSize)
+	for scanner.Scan() {
+		result.Lines++
+		line := scanner.Bytes()
+		if len(bytes.TrimSpace(line)) == 0 {
+			result.Blank++
+			continue
+		}
+		var record Record
+		if err := json.Unmarshal(line, &record); err != nil {
+			result.Malformed++
+			malformed = append(malformed, append([]byte(nil), line...))
+			continue
+		}
+		if !recordComplete(record) {
+			result.Incomplete++
+			continue
+		}
+		if seen.has(record.ID) {
+			result.Duplicates++
+			continue
+		}
+		seen.add(record.ID)
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return CompactResult{}, err
+	}
+	result.Kept = len(records)
+
+	if dryRun {
+		return result, nil
+	}
+
+	sortRecordsChronological(records)
+	err = replaceHistoryFile(path, func(writer *bufio.Writer) error {
+		encoder := json.NewEncoder(writer)
+		encoder.SetEscapeHTML(false)
+		for _, record := range records {
+			if err := encoder.Encode(record); err != nil {
+				return err
+			}
+		}
+		for _, line := range malformed {
+			if _, err := writer.Write(append(line, '\n')); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return CompactResult{}, err
+	}
+	return result, nil
+}
+
+func runCompact(args []string) error {
+	fs := flag.NewFlagSet("compact", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+
+	inputPath := fs.String("in", defaultOutputFile(), "History JSONL path to compact in place")
+	dryRun := fs.Bool("dry-run", false, "Report what would be removed without writing")
+	jsonOut := fs.Bool("json", false, "Print as JSON")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	result, err := compactHistory(*inputPath, *dryRun)
+	if err != nil {
+		return err
+	}
+
+	if *jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(result); err != nil {
+			return err
+		}
+	} else {
+		action := "removed"
+		if *dryRun {
+			action = "would_remove"
+		}
+		fmt.Printf("lines=%d kept=%d %s=%d duplicates=%d incomplete=%d blank=%d\n",
+			result.Lines, result.Kept, action, result.removed(), result.Duplicates, result.Incomplete, result.Blank)
+	}
+	if result.Malformed > 0 {
+		fmt.Fprintf(os.Stderr, "warning: %d unparseable lines kept at the end of %s; run repair to quarantine them\n", result.Malformed, *inputPath)
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCompactHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	content := strings.Join([]string{
+		`{"id":"b","session_id":"s","timestamp":"2026-02-17T10:00:00Z","role":"user","text":"later"}`,
+		`{"id":"a","session_id":"s","timestamp":"2026-02-17T09:00:00Z","role":"user","text":"earlier"}`,
+		`{"id":"b","session_id":"s","timestamp":"2026-02-17T10:00:00Z","role":"user","text":"later"}`,
+		`{"id":"c","session_id":"","timestamp":"2026-02-17T11:00:00Z","role":"user","text":"no session"}`,
+		``,
+		`{"id":"d","session_id":"s","timestamp":"2026-02-17T1`,
+	}, "\n") + "\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	preview, err := compactHistory(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != content {
+		t.Fatal("dry run modified the history")
+	}
+
+	result, err := compactHistory(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := CompactResult{Lines: 6, Kept: 2, Duplicates: 1, Incomplete: 1, Blank: 1, Malformed: 1}
+	if result != want || preview != want {
+		t.Fatalf("got %+v (preview %+v), want %+v", result, preview, want)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 3 || !strings.Contains(lines[0], `"id":"a"`) || !strings.Contains(lines[1], `"id":"b"`) || !strings.HasPrefix(lines[2], `{"id":"d"`) {
+		t.Fatalf("unexpected compacted history:\n%s", data)
+	}
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Compression for archived histories and exports. gzip is built in; zstd
+// goes through the zstd command-line tool, like SQLite goes through
+// sqlite3.
+const (
+	compressionGzip = "gzip"
+	compressionZstd = "zstd"
+)
+
+var compressionSuffixes = map[string]string{
+	compressionGzip: ".gz",
+	compressionZstd: ".zst",
+}
+
+// compressionFor infers the compression of a file from its name.
+func compressionFor(path string) string {
+	switch {
+	case strings.HasSuffix(path, ".gz"):
+		return compressionGzip
+	case strings.HasSuffix(path, ".zst"):
+		return compressionZstd
+	}
+	return ""
+}
+
+// checkWritableHistory rejects a compressed history as the target of a
+// write. Readers decompress .gz and .zst transparently, but sync, import,
+// and the commands that rewrite the history only write plain JSONL, which
+// would corrupt a compressed file.
+func checkWritableHistory(path string) error {
+	if compression := compressionFor(path); compression != "" {
+		return fmt.Errorf("%s is %s-compressed and can only be read; decompress it (%s -d) to write to it", path, compression, compression)
+	}
+	return nil
+}
+
+// openHistory opens a history file for reading, decompressing .gz and .zst
+// files transparently.
+func openHistory(path string) (io.ReadCloser, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	switch compressionFor(path) {
+	case compressionGzip:
+		reader, err := gzip.NewReader(file)
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		return readCloser{Reader: reader, close: func() error {
+			reader.Close()
+			return file.Close()
+		}}, nil
+	case compressionZstd:
+		cmd := exec.Command("zstd", "-dcq")
+		cmd.Stdin = file
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		stdout, err := cmd.StdoutPipe()
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+		if err := cmd.Start(); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("zstd: %w", err)
+		}
+		return readCloser{Reader: stdout, close: func() error {
+			// Readers may stop early; drain so zstd can exit.
+			io.Copy(io.Discard, stdout)
+			err := cmd.Wait()
+			file.Close()
+			if err != nil {
+				return fmt.Errorf("zstd %s: %w: %s", path, err, strings.TrimSpace(stderr.String()))
+			}
+			return nil
+		}}, nil
+	}
+	return file, nil
+}
+
+type readCloser struct {
+	io.Reader
+	close func() error
+}
+
+func (r readCloser) Close() error {
+	return r.close()
+}
+
+// compressWriter returns a writer that compresses into w. Close flushes the
+// compressed stream but does not close w.
+func compressWriter(w io.Writer, compression string) (io.WriteCloser, error) {
+	switch compression {
+	case compressionGzip:
+		return gzip.NewWriter(w), nil
+	case compressionZstd:
+		cmd := exec.Command("zstd", "-cq")
+		cmd.Stdout = w
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		stdin, err := cmd.StdinPipe()
+		if err != nil {
+			return nil, err
+		}
+		if err := cmd.Start(); err != nil {
+			return nil, fmt.Errorf("zstd: %w", err)
+		}
+		return writeCloser{Writer: stdin, close: func() error {
+			stdin.Close()
+			if err := cmd.Wait(); err != nil {
+				return fmt.Errorf("zstd: %w: %s", err, strings.TrimSpace(stderr.String()))
+			}
+			return nil
+		}}, nil
+	}
+	return nil, fmt.Errorf("unknown compression %q", compression)
+}
+
+type writeCloser struct {
+	io.Writer
+	close func() error
+}
+
+func (w writeCloser) Close() error {
+	return w.close()
+}
+
+func compressBytes(content []byte, compression string) ([]byte, error) {
+	var out bytes.Buffer
+	writer, err := compressWriter(&out, compression)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := writer.Write(content); err != nil {
+		writer.Close()
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// exportCompression reads --gzip/--zstd.
+func exportCompression(gzipFlag, zstdFlag bool) (string, error) {
+	switch {
+	case gzipFlag && zstdFlag:
+		return "", errors.New("--gzip and --zstd cannot be combined")
+	case gzipFlag:
+		return compressionGzip, nil
+	case zstdFlag:
+		return compressionZstd, nil
+	}
+	return "", nil
+}
+
+// withCompressionSuffix adds the compression's suffix to path unless it is
+// already there.
+func withCompressionSuffix(path, compression string) string {
+	suffix := compressionSuffixes[compression]
+	if path == "" || strings.HasSuffix(path, suffix) {
+		return path
+	}
+	return path + suffix
+}
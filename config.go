@@ -0,0 +1,213 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Config is the optional JSON config file. Every field is optional; unset
+// fields fall back to the built-in defaults, and explicit flags always win.
+type Config struct {
+	SessionsDir string      `json:"sessions_dir,omitempty"`
+	Output      string      `json:"output,omitempty"`
+	Watch       WatchConfig `json:"watch,omitempty"`
+	// Embeddings configures search --semantic.
+	Embeddings EmbeddingsConfig `json:"embeddings,omitempty"`
+	// Searches are saved filter sets for --saved.
+	Searches map[string]SavedSearch `json:"searches,omitempty"`
+	// Prices are per-model token prices for stats --costs.
+	Prices map[string]ModelPrice `json:"prices,omitempty"`
+	// Retention bounds the history watch keeps.
+	Retention RetentionConfig `json:"retention,omitempty"`
+}
+
+type WatchConfig struct {
+	Interval string `json:"interval,omitempty"`
+	// MaxInterval and Debounce are the defaults of watch's --max-interval
+	// and --debounce.
+	MaxInterval     string `json:"max_interval,omitempty"`
+	Debounce        string `json:"debounce,omitempty"`
+	Heartbeat       string `json:"heartbeat,omitempty"`
+	RelativeSources bool   `json:"relative_sources,omitempty"`
+	// From and Include are the defaults of watch's --from and --include.
+	From    string `json:"from,omitempty"`
+	Include string `json:"include,omitempty"`
+}
+
+// appConfig is loaded once in main before any command runs.
+var appConfig Config
+
+func defaultConfigPath() string {
+	if env := strings.TrimSpace(os.Getenv("CODEX_HISTORY_CONFIG")); env != "" {
+		return env
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return filepath.Join(codexHome(), "codex-history.json")
+	}
+	return filepath.Join(dir, "codex-history", "config.json")
+}
+
+func loadConfig(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return Config{}, nil
+		}
+		return Config{}, err
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return Config{}, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+	if cfg.Watch.Interval != "" {
+		if _, err := time.ParseDuration(cfg.Watch.Interval); err != nil {
+			return Config{}, fmt.Errorf("invalid config %s: watch.interval: %w", path, err)
+		}
+	}
+	for name, raw := range map[string]string{"watch.max_interval": cfg.Watch.MaxInterval, "watch.debounce": cfg.Watch.Debounce} {
+		if raw == "" {
+			continue
+		}
+		d, err := time.ParseDuration(raw)
+		if err == nil && d < 0 {
+			err = errors.New("must be >= 0")
+		}
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid config %s: %s: %w", path, name, err)
+		}
+	}
+	if _, err := parseBoundTime(cfg.Watch.From, "watch.from"); err != nil {
+		return Config{}, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+	if _, err := parseIncludeList(cfg.Watch.Include); err != nil {
+		return Config{}, fmt.Errorf("invalid config %s: watch.include: %w", path, err)
+	}
+	if _, err := parseRetentionPolicy(cfg.Retention.MaxAge, cfg.Retention.MaxSize, cfg.Retention.Every); err != nil {
+		return Config{}, fmt.Errorf("invalid config %s: retention: %w", path, err)
+	}
+	for model, price := range cfg.Prices {
+		if price.Input < 0 || price.CachedInput < 0 || price.Output < 0 {
+			return Config{}, fmt.Errorf("invalid config %s: prices.%s: prices must be >= 0", path, model)
+		}
+	}
+	return cfg, nil
+}
+
+func saveConfig(path string, cfg Config) error {
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, append(data, '\n'))
+}
+
+func defaultWatchInterval() time.Duration {
+	if interval, err := time.ParseDuration(appConfig.Watch.Interval); err == nil && interval > 0 {
+		return interval
+	}
+	return 5 * time.Second
+}
+
+// defaultWatchMaxInterval is how far watch backs off while nothing changes.
+func defaultWatchMaxInterval() time.Duration {
+	if interval, err := time.ParseDuration(appConfig.Watch.MaxInterval); err == nil && interval >= 0 {
+		return interval
+	}
+	return time.Minute
+}
+
+func defaultWatchDebounce() time.Duration {
+	if debounce, err := time.ParseDuration(appConfig.Watch.Debounce); err == nil && debounce >= 0 {
+		return debounce
+	}
+	return time.Second
+}
+
+// SavedSearch is a named set of filter flags from the config's "searches"
+// section, applied with --saved NAME.
+type SavedSearch struct {
+	Session       string `json:"session,omitempty"`
+	Role          string `json:"role,omitempty"`
+	From          string `json:"from,omitempty"`
+	To            string `json:"to,omitempty"`
+	Contains      string `json:"contains,omitempty"`
+	Match         string `json:"match,omitempty"`
+	MatchRole     string `json:"match_role,omitempty"`
+	Model         string `json:"model,omitempty"`
+	Project       string `json:"project,omitempty"`
+	CaseSensitive bool   `json:"case_sensitive,omitempty"`
+	Word          bool   `json:"word,omitempty"`
+}
+
+func (s SavedSearch) flags() map[string]string {
+	values := map[string]string{
+		"session":    s.Session,
+		"role":       s.Role,
+		"from":       s.From,
+		"to":         s.To,
+		"contains":   s.Contains,
+		"match":      s.Match,
+		"match-role": s.MatchRole,
+		"model":      s.Model,
+		"project":    s.Project,
+	}
+	if s.CaseSensitive {
+		values["case-sensitive"] = "true"
+	}
+	if s.Word {
+		values["word"] = "true"
+	}
+	return values
+}
+
+// applySavedSearch sets the flags of the named saved search on fs. Flags
+// given on the command line win over the saved values.
+func applySavedSearch(fs *flag.FlagSet, name string) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil
+	}
+	saved, ok := appConfig.Searches[name]
+	if !ok {
+		names := make([]string, 0, len(appConfig.Searches))
+		for known := range appConfig.Searches {
+			names = append(names, known)
+		}
+		sort.Strings(names)
+		if len(names) == 0 {
+			return fmt.Errorf("unknown saved search %q (the config file defines none)", name)
+		}
+		return fmt.Errorf("unknown saved search %q (defined: %s)", name, strings.Join(names, ", "))
+	}
+
+	explicit := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+	values := saved.flags()
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value := values[key]
+		if value == "" || explicit[key] {
+			continue
+		}
+		if fs.Lookup(key) == nil {
+			return fmt.Errorf("saved search %q sets %s, which %s does not support", name, key, fs.Name())
+		}
+		if err := fs.Set(key, value); err != nil {
+			return fmt.Errorf("saved search %q: --%s: %w", name, key, err)
+		}
+	}
+	return nil
+}
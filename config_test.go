@@ -0,0 +1,47 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestApplySavedSearch(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	data := `{"searches": {"bugs": {"contains": "panic", "role": "assistant", "word": true}, "models": {"model": "o3"}}}`
+	if err := os.WriteFile(configPath, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := loadConfig(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := appConfig
+	appConfig = cfg
+	defer func() { appConfig = previous }()
+
+	fs := flag.NewFlagSet("show", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	contains := fs.String("contains", "", "")
+	role := fs.String("role", "", "")
+	word := fs.Bool("word", false, "")
+	if err := fs.Parse([]string{"--role", "user"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := applySavedSearch(fs, "bugs"); err != nil {
+		t.Fatal(err)
+	}
+	if *contains != "panic" || *role != "user" || !*word {
+		t.Fatalf("unexpected flags: contains=%q role=%q word=%v", *contains, *role, *word)
+	}
+
+	if err := applySavedSearch(fs, "models"); err == nil || !strings.Contains(err.Error(), "does not support") {
+		t.Fatalf("expected an unsupported flag error, got %v", err)
+	}
+	if err := applySavedSearch(fs, "nope"); err == nil || !strings.Contains(err.Error(), "bugs, models") {
+		t.Fatalf("expected the defined names in the error, got %v", err)
+	}
+}
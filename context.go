@@ -0,0 +1,106 @@
+package main
+
+import (
+	"errors"
+	"flag"
+)
+
+// contextLine is a record printed with context: either a hit or one of the
+// records around it.
+type contextLine struct {
+	Record Record
+	Hit    bool
+}
+
+// contextFlags registers -C, -B, and -A on fs and returns a function that
+// resolves them to the number of records before and after each hit.
+func contextFlags(fs *flag.FlagSet) func() (before, after int, err error) {
+	around := fs.Int("C", 0, "Also print N records before and after each match in the same session")
+	beforeFlag := fs.Int("B", 0, "Also print N records before each match in the same session")
+	afterFlag := fs.Int("A", 0, "Also print N records after each match in the same session")
+	return func() (int, int, error) {
+		if *around < 0 || *beforeFlag < 0 || *afterFlag < 0 {
+			return 0, 0, errors.New("-C, -B, and -A must be >= 0")
+		}
+		before, after := *around, *around
+		if *beforeFlag > 0 {
+			before = *beforeFlag
+		}
+		if *afterFlag > 0 {
+			after = *afterFlag
+		}
+		return before, after, nil
+	}
+}
+
+// loadHitSessions reads every record of the sessions that hits belong to,
+// in chronological order, so context can be taken from records that the
+// filters themselves excluded.
+func loadHitSessions(path string, hits []Record) (map[string][]Record, error) {
+	sessions := make(map[string][]Record)
+	for _, hit := range hits {
+		sessions[hit.SessionID] = nil
+	}
+	err := forEachRecord(path, func(record Record) error {
+		if records, ok := sessions[record.SessionID]; ok {
+			sessions[record.SessionID] = append(records, record)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, records := range sessions {
+		sortRecordsChronological(records)
+	}
+	return sessions, nil
+}
+
+// withContext expands each hit to the records up to before ahead of it and
+// after behind it in its session. Hits are taken in order; a hit whose
+// range touches the previous block of the same session extends that block,
+// and records already printed for a session are never repeated.
+func withContext(hits []Record, sessions map[string][]Record, before, after int) [][]contextLine {
+	positions := make(map[string]int)
+	for _, records := range sessions {
+		for i, record := range records {
+			positions[record.ID] = i
+		}
+	}
+	isHit := make(map[string]bool, len(hits))
+	for _, hit := range hits {
+		isHit[hit.ID] = true
+	}
+
+	var blocks [][]contextLine
+	printedUpTo := make(map[string]int)
+	lastSession := ""
+	for _, hit := range hits {
+		records := sessions[hit.SessionID]
+		pos, ok := positions[hit.ID]
+		if !ok {
+			continue
+		}
+		start, end := max(pos-before, 0), min(pos+after, len(records)-1)
+		if next, ok := printedUpTo[hit.SessionID]; ok {
+			if end < next {
+				continue
+			}
+			if start <= next && lastSession == hit.SessionID && len(blocks) > 0 {
+				// Continue the previous block.
+				start = next
+			} else {
+				start = max(start, next)
+				blocks = append(blocks, nil)
+			}
+		} else {
+			blocks = append(blocks, nil)
+		}
+		for i := start; i <= end; i++ {
+			blocks[len(blocks)-1] = append(blocks[len(blocks)-1], contextLine{Record: records[i], Hit: isHit[records[i].ID]})
+		}
+		printedUpTo[hit.SessionID] = end + 1
+		lastSession = hit.SessionID
+	}
+	return blocks
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestWithContext(t *testing.T) {
+	var s1, s2 []Record
+	for i := 0; i < 8; i++ {
+		s1 = append(s1, Record{ID: fmt.Sprintf("a%d", i), SessionID: "s1", Timestamp: fmt.Sprintf("2026-02-17T10:0%d:00Z", i)})
+	}
+	for i := 0; i < 3; i++ {
+		s2 = append(s2, Record{ID: fmt.Sprintf("b%d", i), SessionID: "s2", Timestamp: fmt.Sprintf("2026-02-17T10:0%d:30Z", i)})
+	}
+	sessions := map[string][]Record{"s1": s1, "s2": s2}
+
+	render := func(blocks [][]contextLine) string {
+		var parts []string
+		for _, block := range blocks {
+			var ids []string
+			for _, line := range block {
+				id := line.Record.ID
+				if line.Hit {
+					id = strings.ToUpper(id)
+				}
+				ids = append(ids, id)
+			}
+			parts = append(parts, strings.Join(ids, " "))
+		}
+		return strings.Join(parts, " | ")
+	}
+
+	// Overlapping ranges in one session merge into one block.
+	got := render(withContext([]Record{s1[1], s1[3], s1[7]}, sessions, 1, 1))
+	if got != "a0 A1 a2 A3 a4 | a6 A7" {
+		t.Fatalf("unexpected blocks: %s", got)
+	}
+
+	// Interleaved sessions never repeat a record.
+	got = render(withContext([]Record{s1[2], s2[0], s1[3]}, sessions, 1, 0))
+	if got != "a1 A2 | B0 | A3" {
+		t.Fatalf("unexpected interleaved blocks: %s", got)
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ModelPrice is what one model costs, in US dollars per million tokens.
+// CachedInput defaults to Input when zero.
+type ModelPrice struct {
+	Input       float64 `json:"input"`
+	CachedInput float64 `json:"cached_input,omitempty"`
+	Output      float64 `json:"output"`
+}
+
+// cost prices usage. Cached input tokens are part of input tokens, and
+// reasoning tokens part of output tokens, as Codex reports them.
+func (p ModelPrice) cost(usage TokenUsage) float64 {
+	cached := p.CachedInput
+	if cached == 0 {
+		cached = p.Input
+	}
+	uncached := max(usage.InputTokens-usage.CachedInputTokens, 0)
+	return (float64(uncached)*p.Input + float64(usage.CachedInputTokens)*cached + float64(usage.OutputTokens)*p.Output) / 1e6
+}
+
+// CostLine is the estimated spend of one model, session, or period.
+type CostLine struct {
+	Key      string  `json:"key"`
+	Sessions int     `json:"sessions"`
+	Tokens   int64   `json:"total_tokens"`
+	Cost     float64 `json:"cost_usd"`
+}
+
+// CostReport is stats --costs output. UnpricedModels lists models with
+// token usage but no entry in the price table; their sessions are left out
+// of the lines and the total.
+type CostReport struct {
+	By             string     `json:"by"`
+	Lines          []CostLine `json:"lines"`
+	Total          float64    `json:"total_usd"`
+	UnpricedModels []string   `json:"unpriced_models,omitempty"`
+}
+
+// computeCosts estimates spend for the sessions in lastSeen, which maps
+// each matched session to its latest matched timestamp. by is model,
+// session, day, week, or month; a session's cost falls in the period of
+// its last activity, since usage is only known per session.
+func computeCosts(lastSeen map[string]string, info map[string]SessionInfo, prices map[string]ModelPrice, by string, loc *time.Location) (CostReport, error) {
+	if len(prices) == 0 {
+		return CostReport{}, errors.New("stats --costs needs a \"prices\" table in the config file")
+	}
+	switch by {
+	case "model", "session", "day", "week", "month":
+	default:
+		return CostReport{}, fmt.Errorf("unsupported --by %q for --costs (use model, session, day, week, or month)", by)
+	}
+
+	report := CostReport{By: by}
+	lines := make(map[string]*CostLine)
+	unpriced := make(map[string]struct{})
+	for sessionID, last := range lastSeen {
+		session, ok := info[sessionID]
+		if !ok || session.Usage == nil {
+			continue
+		}
+		model := strings.TrimSpace(session.Model)
+		if model == "" {
+			model = unknownModel
+		}
+		price, ok := prices[model]
+		if !ok {
+			unpriced[model] = struct{}{}
+			continue
+		}
+
+		key := model
+		switch by {
+		case "session":
+			key = sessionID
+		case "day", "week", "month":
+			t, ok := parseRecordTime(last)
+			if !ok {
+				continue
+			}
+			key = periodKey(t.In(loc), by)
+		}
+		line, ok := lines[key]
+		if !ok {
+			line = &CostLine{Key: key}
+			lines[key] = line
+		}
+		cost := price.cost(*session.Usage)
+		line.Sessions++
+		line.Tokens += session.Usage.TotalTokens
+		line.Cost += cost
+		report.Total += cost
+	}
+
+	for _, line := range lines {
+		report.Lines = append(report.Lines, *line)
+	}
+	sort.Slice(report.Lines, func(i, j int) bool {
+		a, b := report.Lines[i], report.Lines[j]
+		if by == "model" || by == "session" {
+			if a.Cost != b.Cost {
+				return a.Cost > b.Cost
+			}
+		}
+		return a.Key < b.Key
+	})
+	for model := range unpriced {
+		report.UnpricedModels = append(report.UnpricedModels, model)
+	}
+	sort.Strings(report.UnpricedModels)
+	return report, nil
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestComputeCosts(t *testing.T) {
+	prices := map[string]ModelPrice{
+		"gpt-5-codex": {Input: 1.25, CachedInput: 0.125, Output: 10},
+		"o4-mini":     {Input: 1, Output: 4},
+	}
+	info := map[string]SessionInfo{
+		"s1": {Model: "gpt-5-codex", Usage: &TokenUsage{InputTokens: 1_000_000, CachedInputTokens: 600_000, OutputTokens: 100_000, TotalTokens: 1_100_000}},
+		"s2": {Model: "gpt-5-codex", Usage: &TokenUsage{InputTokens: 200_000, OutputTokens: 10_000, TotalTokens: 210_000}},
+		"s3": {Model: "o4-mini", Usage: &TokenUsage{InputTokens: 500_000, CachedInputTokens: 500_000, TotalTokens: 500_000}},
+		"s4": {Model: "mystery", Usage: &TokenUsage{TotalTokens: 5}},
+	}
+	lastSeen := map[string]string{
+		"s1": "2026-02-17T10:00:00Z",
+		"s2": "2026-02-18T10:00:00Z",
+		"s3": "2026-02-17T23:00:00Z",
+		"s4": "2026-02-17T10:00:00Z",
+	}
+	near := func(a, b float64) bool { return math.Abs(a-b) < 1e-9 }
+
+	// s1: 0.4M*1.25 + 0.6M*0.125 + 0.1M*10 = 0.5 + 0.075 + 1 = 1.575
+	// s2: 0.2M*1.25 + 0.01M*10 = 0.25 + 0.1 = 0.35
+	// s3: cached input falls back to the input price: 0.5
+	report, err := computeCosts(lastSeen, info, prices, "model", time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Lines) != 2 || report.Lines[0].Key != "gpt-5-codex" || report.Lines[0].Sessions != 2 || !near(report.Lines[0].Cost, 1.925) || !near(report.Lines[1].Cost, 0.5) {
+		t.Fatalf("unexpected model lines: %+v", report.Lines)
+	}
+	if !near(report.Total, 2.425) || len(report.UnpricedModels) != 1 || report.UnpricedModels[0] != "mystery" {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+
+	days, err := computeCosts(lastSeen, info, prices, "day", time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(days.Lines) != 2 || days.Lines[0].Key != "2026-02-17" || !near(days.Lines[0].Cost, 2.075) || days.Lines[1].Key != "2026-02-18" {
+		t.Fatalf("unexpected day lines: %+v", days.Lines)
+	}
+
+	if _, err := computeCosts(lastSeen, info, nil, "model", time.UTC); err == nil {
+		t.Fatal("expected an error without prices")
+	}
+	if _, err := computeCosts(lastSeen, info, prices, "hour", time.UTC); err == nil {
+		t.Fatal("expected an error for an unsupported grouping")
+	}
+}
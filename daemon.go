@@ -0,0 +1,184 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// daemonEnv marks the background copy of watch that --daemon starts, so it
+// runs the loop instead of starting another copy.
+const daemonEnv = "CODEX_HISTORY_DAEMON"
+
+// daemonStopTimeout bounds how long watch --stop waits for the watcher to
+// finish its cycle and exit.
+const daemonStopTimeout = 10 * time.Second
+
+// daemonStartGrace is how long watch --daemon waits to see the background
+// watcher survive its first cycle.
+const daemonStartGrace = time.Second
+
+func defaultPIDFile() string {
+	return strings.TrimSuffix(defaultOutputFile(), ".jsonl") + ".watch.pid"
+}
+
+func defaultDaemonLogFile() string {
+	return strings.TrimSuffix(defaultOutputFile(), ".jsonl") + ".watch.log"
+}
+
+// isDaemonChild reports whether this process is the background watcher.
+func isDaemonChild() bool {
+	return os.Getenv(daemonEnv) != ""
+}
+
+// readPIDFile returns the PID in path, or 0 when there is no PID file.
+func readPIDFile(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || pid <= 0 {
+		return 0, fmt.Errorf("%s: invalid PID %q", path, strings.TrimSpace(string(data)))
+	}
+	return pid, nil
+}
+
+func writePIDFile(path string, pid int) error {
+	return writeFileAtomic(path, []byte(strconv.Itoa(pid)+"\n"))
+}
+
+// runningPID returns the PID of the watcher recorded in path while that
+// process is alive, and 0 otherwise. A PID file left behind by a watcher
+// that was killed is ignored.
+func runningPID(path string) (int, error) {
+	pid, err := readPIDFile(path)
+	if err != nil || pid == 0 {
+		return 0, err
+	}
+	if !processAlive(pid) {
+		return 0, nil
+	}
+	return pid, nil
+}
+
+// removePIDFile removes path if it still names pid, so a watcher exiting
+// late does not remove the PID file of the one that replaced it.
+func removePIDFile(path string, pid int) {
+	if recorded, err := readPIDFile(path); err == nil && recorded == pid {
+		os.Remove(path)
+	}
+}
+
+// startDaemon runs this watch command again in the background, detached
+// from the terminal, logging to logFile.
+func startDaemon(pidFile, logFile string) error {
+	if pid, err := runningPID(pidFile); err != nil {
+		return err
+	} else if pid != 0 {
+		return fmt.Errorf("watch is already running (pid %d)", pid)
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(logFile), 0o755); err != nil {
+		return err
+	}
+	log, err := os.OpenFile(logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	defer log.Close()
+
+	// main took the global --codex-home out of os.Args; pass it on.
+	args := os.Args[1:]
+	if codexHomeOverride != "" {
+		args = append([]string{"--codex-home", codexHomeOverride}, args...)
+	}
+	cmd := exec.Command(exe, args...)
+	cmd.Env = append(os.Environ(), daemonEnv+"=1")
+	// The watcher writes its entries to logFile itself. Stderr is kept
+	// for output that bypasses the log, such as a crash.
+	cmd.Stderr = log
+	cmd.SysProcAttr = daemonSysProcAttr()
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	pid := cmd.Process.Pid
+	if err := writePIDFile(pidFile, pid); err != nil {
+		terminateProcess(pid)
+		return err
+	}
+	// Most mistakes (a busy --metrics-addr, an unwritable history) stop the
+	// watcher at once; catch those rather than report a start.
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+	select {
+	case err := <-exited:
+		removePIDFile(pidFile, pid)
+		return fmt.Errorf("watch exited at startup (%v); see %s", err, logFile)
+	case <-time.After(daemonStartGrace):
+	}
+	fmt.Printf("watch started pid=%d pid_file=%s log=%s\n", pid, pidFile, logFile)
+	return nil
+}
+
+// printDaemonStatus prints the background watcher's PID and, when the
+// heartbeat is its own, its last cycle. It fails when no watcher runs.
+func printDaemonStatus(pidFile, heartbeatFile string) error {
+	pid, err := runningPID(pidFile)
+	if err != nil {
+		return err
+	}
+	if pid == 0 {
+		return fmt.Errorf("watch is not running (no live process in %s)", pidFile)
+	}
+	line := fmt.Sprintf("running pid=%d pid_file=%s", pid, pidFile)
+	var lastError string
+	if heartbeat, err := loadHeartbeat(heartbeatFile); heartbeatFile != "" && err == nil && heartbeat.PID == pid {
+		line += " started_at=" + heartbeat.StartedAt + " last_cycle=" + heartbeat.LastCycle
+		if heartbeat.LastSuccess != "" {
+			line += " last_success=" + heartbeat.LastSuccess
+		}
+		lastError = heartbeat.LastError
+	}
+	fmt.Println(line)
+	if lastError != "" {
+		fmt.Printf("last_error: %s\n", lastError)
+	}
+	return nil
+}
+
+// stopDaemon asks the background watcher to stop and waits for it to exit.
+func stopDaemon(pidFile string) error {
+	pid, err := runningPID(pidFile)
+	if err != nil {
+		return err
+	}
+	if pid == 0 {
+		os.Remove(pidFile)
+		return fmt.Errorf("watch is not running (no live process in %s)", pidFile)
+	}
+	if err := terminateProcess(pid); err != nil {
+		return err
+	}
+	deadline := time.Now().Add(daemonStopTimeout)
+	for processAlive(pid) {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("watch (pid %d) did not stop within %s", pid, daemonStopTimeout)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	removePIDFile(pidFile, pid)
+	fmt.Printf("stopped pid=%d\n", pid)
+	return nil
+}
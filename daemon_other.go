@@ -0,0 +1,33 @@
+//go:build !unix
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+func daemonSysProcAttr() *syscall.SysProcAttr {
+	return nil
+}
+
+// processAlive relies on FindProcess, which fails for a finished process on
+// Windows and always succeeds elsewhere.
+func processAlive(pid int) bool {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	process.Release()
+	return true
+}
+
+// terminateProcess kills the process outright: there is no SIGTERM to let
+// watch finish its cycle.
+func terminateProcess(pid int) error {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	return process.Kill()
+}
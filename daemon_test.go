@@ -0,0 +1,67 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestPIDFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "watch.pid")
+	if pid, err := runningPID(path); err != nil || pid != 0 {
+		t.Fatalf("missing PID file: got %d, %v", pid, err)
+	}
+
+	if err := writePIDFile(path, os.Getpid()); err != nil {
+		t.Fatal(err)
+	}
+	if pid, err := runningPID(path); err != nil || pid != os.Getpid() {
+		t.Fatalf("live PID: got %d, %v", pid, err)
+	}
+	removePIDFile(path, os.Getpid()+1)
+	if _, err := os.Stat(path); err != nil {
+		t.Fatal("another process's PID file should be kept")
+	}
+	removePIDFile(path, os.Getpid())
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatal("own PID file should be removed")
+	}
+
+	if err := os.WriteFile(path, []byte("nope\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runningPID(path); err == nil {
+		t.Fatal("an invalid PID file should be an error")
+	}
+}
+
+func TestStopDaemon(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs sleep and SIGTERM")
+	}
+	cmd := exec.Command("sleep", "60")
+	if err := cmd.Start(); err != nil {
+		t.Skip(err)
+	}
+	// Reap the child as soon as it exits, as init does for a real daemon.
+	go cmd.Wait()
+
+	path := filepath.Join(t.TempDir(), "watch.pid")
+	if err := writePIDFile(path, cmd.Process.Pid); err != nil {
+		t.Fatal(err)
+	}
+	if err := stopDaemon(path); err != nil {
+		t.Fatal(err)
+	}
+	if processAlive(cmd.Process.Pid) {
+		t.Fatal("process still alive after stop")
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatal("PID file should be removed after stop")
+	}
+	if err := stopDaemon(path); err == nil {
+		t.Fatal("stopping with nothing running should fail")
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+func runDelete(args []string) error {
+	fs := flag.NewFlagSet("delete", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+
+	inputPath := fs.String("in", defaultOutputFile(), "History JSONL path")
+	sessionID := fs.String("session", "", "Delete records of this session")
+	role := fs.String("role", "", "Only delete records of this role")
+	from := fs.String("from", "", "Only delete records at/after this RFC3339 timestamp")
+	to := fs.String("to", "", "Only delete records at/before this RFC3339 timestamp")
+	contains := fs.String("contains", "", "Only delete records whose text contains this (case-insensitive)")
+	caseSensitive := fs.Bool("case-sensitive", false, "Match --contains with exact case")
+	word := fs.Bool("word", false, "Match --contains only as a whole word")
+	matchExpr := fs.String("match", "", "Only delete records whose text matches this regular expression")
+	model := fs.String("model", "", "Only delete records written with this model")
+	project := fs.String("project", "", "Only delete records from this working directory or substring")
+	dryRun := fs.Bool("dry-run", false, "List the sessions and record counts that would be deleted")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	fromTime, err := parseBoundTime(*from, "--from")
+	if err != nil {
+		return err
+	}
+	toTime, err := parseBoundTime(*to, "--to")
+	if err != nil {
+		return err
+	}
+	if err := validateTimeRange(fromTime, toTime); err != nil {
+		return err
+	}
+	pattern, err := compileMatch(*matchExpr, "")
+	if err != nil {
+		return err
+	}
+	filter := RecordFilter{
+		SessionID:     strings.TrimSpace(*sessionID),
+		Role:          strings.TrimSpace(*role),
+		From:          fromTime,
+		To:            toTime,
+		Contains:      strings.TrimSpace(*contains),
+		Match:         pattern,
+		Model:         strings.TrimSpace(*model),
+		Project:       strings.TrimSpace(*project),
+		CaseSensitive: *caseSensitive,
+		Word:          *word,
+	}
+	// Filters other than --role and the time range narrow down what to
+	// delete; without one, a typo could empty the history.
+	if filter.SessionID == "" && filter.Contains == "" && filter.Match == nil && filter.Model == "" && filter.Project == "" && fromTime.IsZero() && toTime.IsZero() {
+		return errors.New("delete needs a filter: --session, --from/--to, --contains, --match, --model, or --project")
+	}
+	match := newRecordMatcher(filter)
+
+	if *dryRun {
+		counts, total, err := countSessionMatches(*inputPath, match)
+		if err != nil {
+			return err
+		}
+		for _, count := range counts {
+			fmt.Printf("%s records=%d\n", count.SessionID, count.Records)
+		}
+		fmt.Printf("would delete %d records from %d sessions\n", total, len(counts))
+		return nil
+	}
+
+	touched := make(map[string]struct{})
+	removed, err := removeRecords(*inputPath, func(record Record) bool {
+		if match(record) {
+			touched[record.SessionID] = struct{}{}
+			return true
+		}
+		return false
+	})
+	if err != nil {
+		return err
+	}
+	emptied, err := dropEmptySessionInfo(*inputPath, touched)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("deleted %d records from %d sessions (%d removed entirely) in %s\n", removed, len(touched), emptied, *inputPath)
+	if removed > 0 {
+		noteDerivedCopies(*inputPath, "deleted")
+	}
+	return nil
+}
+
+// countSessionMatches counts the records match selects per session, in
+// the order sessions first appear, and in total.
+func countSessionMatches(path string, match func(Record) bool) ([]sessionMatchCount, int, error) {
+	var counts []sessionMatchCount
+	index := make(map[string]int)
+	total := 0
+	err := forEachRecord(path, func(record Record) error {
+		if !match(record) {
+			return nil
+		}
+		i, ok := index[record.SessionID]
+		if !ok {
+			i = len(counts)
+			index[record.SessionID] = i
+			counts = append(counts, sessionMatchCount{SessionID: record.SessionID})
+		}
+		counts[i].Records++
+		total++
+		return nil
+	})
+	return counts, total, err
+}
+
+// dropEmptySessionInfo removes the sidecar entries of the given sessions
+// that no longer have any records, returning how many it removed.
+func dropEmptySessionInfo(historyPath string, sessions map[string]struct{}) (int, error) {
+	if len(sessions) == 0 {
+		return 0, nil
+	}
+	remaining := make(map[string]struct{})
+	err := forEachRecord(historyPath, func(record Record) error {
+		if _, ok := sessions[record.SessionID]; ok {
+			remaining[record.SessionID] = struct{}{}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	emptied := 0
+	for sessionID := range sessions {
+		if _, ok := remaining[sessionID]; !ok {
+			emptied++
+		}
+	}
+
+	infoPath := sessionInfoPath(historyPath)
+	info, err := loadSessionInfo(infoPath)
+	if err != nil {
+		return 0, err
+	}
+	changed := false
+	for sessionID := range sessions {
+		if _, ok := remaining[sessionID]; ok {
+			continue
+		}
+		if _, ok := info[sessionID]; ok {
+			delete(info, sessionID)
+			changed = true
+		}
+	}
+	if changed {
+		if err := saveSessionInfo(infoPath, info); err != nil {
+			return 0, err
+		}
+	}
+	return emptied, nil
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestDeleteSessionRecords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	records := []Record{
+		{ID: "1", SessionID: "s1", Timestamp: "2026-02-17T10:00:00Z", Role: "user", Text: "my key is hunter2"},
+		{ID: "2", SessionID: "s1", Timestamp: "2026-02-17T10:01:00Z", Role: "assistant", Text: "noted"},
+		{ID: "3", SessionID: "s2", Timestamp: "2026-02-17T11:00:00Z", Role: "user", Text: "hunter2 again"},
+		{ID: "4", SessionID: "s3", Timestamp: "2026-02-17T12:00:00Z", Role: "user", Text: "unrelated"},
+	}
+	if err := appendRecords(path, records, false); err != nil {
+		t.Fatal(err)
+	}
+	infoPath := sessionInfoPath(path)
+	if err := saveSessionInfo(infoPath, map[string]SessionInfo{"s1": {Model: "o4-mini"}, "s2": {Model: "o4-mini"}, "s3": {Model: "o4-mini"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	match := newRecordMatcher(RecordFilter{Contains: "HUNTER2"})
+	counts, total, err := countSessionMatches(path, match)
+	if err != nil || total != 2 || len(counts) != 2 || counts[0].SessionID != "s1" || counts[0].Records != 1 {
+		t.Fatalf("unexpected counts: %+v %d %v", counts, total, err)
+	}
+
+	touched := make(map[string]struct{})
+	removed, err := removeRecords(path, func(record Record) bool {
+		if match(record) {
+			touched[record.SessionID] = struct{}{}
+			return true
+		}
+		return false
+	})
+	if err != nil || removed != 2 {
+		t.Fatalf("removed %d, %v", removed, err)
+	}
+	emptied, err := dropEmptySessionInfo(path, touched)
+	if err != nil || emptied != 1 {
+		t.Fatalf("emptied %d, %v", emptied, err)
+	}
+	info, err := loadSessionInfo(infoPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := info["s2"]; ok || len(info) != 2 {
+		t.Fatalf("expected only s2 dropped from the sidecar, got %v", info)
+	}
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+type DemoOptions struct {
+	OutDir         string
+	Days           int
+	SessionsPerDay int
+	Start          time.Time
+	Seed           int64
+	// Corrupt adds one truncated JSON line so error paths (sources --errors,
+	// lint) have something to report; sync refuses such files.
+	Corrupt bool
+}
+
+type DemoResult struct {
+	Files int
+	Lines int
+}
+
+var demoPrompts = []struct {
+	user      string
+	assistant string
+	command   string
+	// file is edited by the turn, or "" for read-only turns.
+	file string
+}{
+	{"Add a --limit flag to the export command.", "Added `--limit` to export and covered it in `TestRenderExportJSONL`.", "go test ./...", "export.go"},
+	{"Why does sync panic on an empty sessions directory?", "listSessionFiles returned nil on ENOENT; it now returns an empty slice.", "go run . sync --dry-run", "sync.go"},
+	{"Refactor the scheduler to avoid the data race in Tick.", "Moved the counter behind a mutex and added a `-race` test.", "go test -race ./scheduler", "scheduler/tick.go"},
+	{"Write a README section for the watch command.", "Documented `watch --interval` with an example.", "cat README.md", "README.md"},
+	{"Find where the API key is read from the environment.", "It is read in `config.Load` via `os.Getenv(\"API_KEY\")`.", "grep -rn API_KEY .", ""},
+	{"Convert the CSV exporter to stream rows.", "The exporter now writes through `csv.Writer` row by row.", "go build ./...", "export_csv.go"},
+}
+
+var demoProjects = []string{"/home/demo/src/codex-history-cli", "/home/demo/src/scheduler", "/home/demo/src/webapp"}
+
+func runDemo(args []string) error {
+	fs := flag.NewFlagSet("demo", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+
+	outDir := fs.String("out", "", "Directory to create the fake sessions tree in")
+	days := fs.Int("days", 3, "Number of days of sessions")
+	perDay := fs.Int("sessions-per-day", 2, "Sessions per day")
+	start := fs.String("start", "", "RFC3339 time of the first session (default: days ago at 09:00 UTC)")
+	seed := fs.Int64("seed", 1, "Random seed; the same seed yields the same tree")
+	corrupt := fs.Bool("corrupt", false, "Also write a truncated JSON line into one session file")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if strings.TrimSpace(*outDir) == "" {
+		return errors.New("--out is required")
+	}
+	if *days <= 0 || *perDay <= 0 {
+		return errors.New("--days and --sessions-per-day must be > 0")
+	}
+
+	startTime, err := parseBoundTime(*start, "--start")
+	if err != nil {
+		return err
+	}
+	if startTime.IsZero() {
+		today := time.Now().UTC().Truncate(24 * time.Hour)
+		startTime = today.AddDate(0, 0, -*days).Add(9 * time.Hour)
+	}
+
+	result, err := generateDemo(DemoOptions{
+		OutDir:         *outDir,
+		Days:           *days,
+		SessionsPerDay: *perDay,
+		Start:          startTime,
+		Seed:           *seed,
+		Corrupt:        *corrupt,
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("wrote %d session files (%d lines) to %s\n", result.Files, result.Lines, *outDir)
+	fmt.Printf("try: codex-history sync --sessions-dir %s --out %s\n", *outDir, demoHistoryPath(*outDir))
+	return nil
+}
+
+// demoHistoryPath is the history file suggested for a demo tree. It sits
+// beside the tree, not in it: sync reads every .jsonl under --sessions-dir,
+// so a history inside would be scanned as a session file.
+func demoHistoryPath(outDir string) string {
+	return filepath.Clean(outDir) + ".history.jsonl"
+}
+
+func generateDemo(opts DemoOptions) (DemoResult, error) {
+	rng := rand.New(rand.NewSource(opts.Seed))
+	result := DemoResult{}
+
+	for day := 0; day < opts.Days; day++ {
+		for n := 0; n < opts.SessionsPerDay; n++ {
+			started := opts.Start.AddDate(0, 0, day).Add(time.Duration(n*3) * time.Hour).Add(time.Duration(rng.Intn(3600)) * time.Second)
+			// Put the broken line in the last session so earlier files stay clean.
+			corrupt := opts.Corrupt && day == opts.Days-1 && n == opts.SessionsPerDay-1
+
+			id := demoUUID(rng)
+			lines, err := demoSessionLines(rng, id, started, corrupt)
+			if err != nil {
+				return DemoResult{}, err
+			}
+
+			path := filepath.Join(opts.OutDir, started.Format("2006"), started.Format("01"), started.Format("02"),
+				fmt.Sprintf("rollout-%s-%s.jsonl", started.Format("2006-01-02T15-04-05"), id))
+
+			if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+				return DemoResult{}, err
+			}
+			if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o644); err != nil {
+				return DemoResult{}, err
+			}
+			result.Files++
+			result.Lines += len(lines)
+		}
+	}
+	return result, nil
+}
+
+func demoSessionLines(rng *rand.Rand, sessionID string, started time.Time, corrupt bool) ([]string, error) {
+	lines := make([]string, 0, 32)
+	clock := started
+	tick := func() string {
+		clock = clock.Add(time.Duration(2+rng.Intn(40)) * time.Second)
+		return clock.Format("2006-01-02T15:04:05.000Z")
+	}
+	add := func(timestamp, kind string, payload any) error {
+		raw, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		line, err := json.Marshal(envelope{Timestamp: timestamp, Type: kind, Payload: raw})
+		if err != nil {
+			return err
+		}
+		lines = append(lines, string(line))
+		return nil
+	}
+
+	project := demoProjects[rng.Intn(len(demoProjects))]
+	model := []string{"gpt-5-codex", "gpt-5", "o4-mini"}[rng.Intn(3)]
+
+	if err := add(started.Format("2006-01-02T15:04:05.000Z"), "session_meta", map[string]any{
+		"id": sessionID, "timestamp": started.Format(time.RFC3339), "cwd": project, "originator": "codex_cli_rs", "cli_version": "0.40.0",
+	}); err != nil {
+		return nil, err
+	}
+	if err := add(tick(), "response_item", demoMessageItem("user",
+		"<environment_context>\n  <cwd>"+project+"</cwd>\n</environment_context>")); err != nil {
+		return nil, err
+	}
+	if err := add(tick(), "turn_context", map[string]any{
+		"cwd": project, "approval_policy": "on-request", "sandbox_policy": map[string]any{"mode": "workspace-write"}, "model": model,
+	}); err != nil {
+		return nil, err
+	}
+
+	inputTokens, outputTokens := 0, 0
+	turns := 1 + rng.Intn(4)
+	for turn := 0; turn < turns; turn++ {
+		prompt := demoPrompts[rng.Intn(len(demoPrompts))]
+		callID := fmt.Sprintf("call_%06d", rng.Intn(1000000))
+		inputTokens += 800 + rng.Intn(4000)
+		outputTokens += 100 + rng.Intn(900)
+
+		steps := []demoStep{
+			{"response_item", demoMessageItem("user", prompt.user)},
+			{"event_msg", map[string]any{"type": "user_message", "message": prompt.user}},
+			{"event_msg", map[string]any{"type": "agent_reasoning", "text": "**Planning** Looking at the relevant code before changing it."}},
+			{"response_item", map[string]any{"type": "function_call", "name": "shell", "call_id": callID,
+				"arguments": fmt.Sprintf(`{"command":["bash","-lc",%q],"workdir":%q}`, prompt.command, project)}},
+			{"event_msg", map[string]any{"type": "exec_command_begin", "call_id": callID,
+				"command": []string{"bash", "-lc", prompt.command}, "cwd": project}},
+			{"event_msg", map[string]any{"type": "exec_command_end", "call_id": callID,
+				"stdout": "ok\n", "stderr": "", "aggregated_output": "ok\n", "exit_code": 0}},
+			{"response_item", map[string]any{"type": "function_call_output", "call_id": callID,
+				"output": `{"output":"ok\n","metadata":{"exit_code":0,"duration_seconds":0.4}}`}},
+		}
+		if prompt.file != "" {
+			patchID := fmt.Sprintf("call_%06d", rng.Intn(1000000))
+			path := project + "/" + prompt.file
+			steps = append(steps,
+				demoStep{"event_msg", map[string]any{"type": "patch_apply_begin", "call_id": patchID, "auto_approved": true,
+					"changes": map[string]any{path: map[string]any{"update": map[string]any{
+						"unified_diff": "@@ -1,2 +1,3 @@\n context\n-old line\n+new line\n+added line\n", "move_path": nil}}}}},
+				demoStep{"event_msg", map[string]any{"type": "patch_apply_end", "call_id": patchID,
+					"stdout": "Success. Updated the following files:\nM " + path + "\n", "success": true}},
+			)
+		}
+		steps = append(steps,
+			demoStep{"event_msg", map[string]any{"type": "agent_message", "message": prompt.assistant}},
+			demoStep{"response_item", demoMessageItem("assistant", prompt.assistant)},
+			demoStep{"event_msg", map[string]any{"type": "token_count", "info": map[string]any{
+				"total_token_usage": map[string]any{"input_tokens": inputTokens, "cached_input_tokens": inputTokens / 2, "output_tokens": outputTokens, "total_tokens": inputTokens + outputTokens},
+			}}},
+		)
+		for _, step := range steps {
+			if err := add(tick(), step.kind, step.payload); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// Lines that are valid JSON but that no extractor understands: sync
+	// must skip them without failing.
+	lines = append(lines,
+		`{"timestamp":"`+tick()+`","type":"event_msg","payload":{"type":"user_message","message":42}}`,
+		`{"timestamp":"`+tick()+`","type":"future_event","payload":{"anything":true}}`,
+		`{"type":"event_msg","payload":"not an object"}`,
+	)
+	if corrupt {
+		lines = append(lines, `{"timestamp":"`+tick()+`","type":"event_msg","payload":{"type":"agent_mess`)
+	}
+	return lines, nil
+}
+
+// demoMessageItem is the response_item copy Codex writes of every message.
+func demoMessageItem(role, text string) map[string]any {
+	contentType := "input_text"
+	if role == "assistant" {
+		contentType = "output_text"
+	}
+	return map[string]any{"type": "message", "role": role,
+		"content": []map[string]any{{"type": contentType, "text": text}}}
+}
+
+type demoStep struct {
+	kind    string
+	payload any
+}
+
+func demoUUID(rng *rand.Rand) string {
+	b := make([]byte, 16)
+	rng.Read(b)
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestGenerateDemoIsSyncable(t *testing.T) {
+	root := t.TempDir()
+	sessionsRoot := filepath.Join(root, "sessions")
+	start := time.Date(2026, 2, 15, 9, 0, 0, 0, time.UTC)
+
+	result, err := generateDemo(DemoOptions{OutDir: sessionsRoot, Days: 2, SessionsPerDay: 2, Start: start, Seed: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Files != 4 {
+		t.Fatalf("expected 4 demo files, got %d", result.Files)
+	}
+
+	// The suggested history must stay out of the tree it is synced from.
+	historyPath := demoHistoryPath(sessionsRoot)
+	sync, err := syncOnce(SyncOptions{SessionsDir: sessionsRoot, OutputPath: historyPath})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sync.Files != 4 || sync.Written == 0 {
+		t.Fatalf("unexpected sync result over demo data: %#v", sync)
+	}
+	if again, err := syncOnce(SyncOptions{SessionsDir: sessionsRoot, OutputPath: historyPath}); err != nil || again.Files != 4 || again.Written != 0 {
+		t.Fatalf("second sync should find the same 4 files and nothing new: %#v, %v", again, err)
+	}
+
+	records, err := loadRecords(historyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats := computeStats(records)
+	if stats.SessionCount != 4 || stats.User == 0 || stats.Assistant == 0 {
+		t.Fatalf("unexpected demo stats: %#v", stats)
+	}
+
+	corruptRoot := filepath.Join(root, "corrupt")
+	if _, err := generateDemo(DemoOptions{OutDir: corruptRoot, Days: 1, SessionsPerDay: 1, Start: start, Seed: 7, Corrupt: true}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := syncOnce(SyncOptions{SessionsDir: corruptRoot, OutputPath: filepath.Join(root, "corrupt.jsonl"), DryRun: true}); err == nil {
+		t.Fatal("expected sync to reject the corrupt demo file")
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/xml"
+	"html"
+	"sort"
+	"strings"
+	"time"
+)
+
+// atomMaxEntries caps the feed to the most recently active sessions; feed
+// readers only look at the top anyway.
+const atomMaxEntries = 50
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Author  atomAuthor  `xml:"author"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomEntry struct {
+	ID        string      `xml:"id"`
+	Title     string      `xml:"title"`
+	Published string      `xml:"published,omitempty"`
+	Updated   string      `xml:"updated"`
+	Category  *atomTerm   `xml:"category,omitempty"`
+	Summary   string      `xml:"summary,omitempty"`
+	Content   atomContent `xml:"content"`
+}
+
+type atomTerm struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomContent struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+// renderAtom writes an Atom feed with one entry per session, newest activity
+// first, titled by the session's first user message. The entry content is
+// the transcript rendered as in the html export.
+func renderAtom(records []Record) ([]byte, error) {
+	sessions := groupSessions(records)
+	lastTime := func(group []Record) time.Time {
+		t, _ := parseRecordTime(group[len(group)-1].Timestamp)
+		return t
+	}
+	sort.SliceStable(sessions, func(i, j int) bool {
+		return lastTime(sessions[i]).After(lastTime(sessions[j]))
+	})
+	if len(sessions) > atomMaxEntries {
+		sessions = sessions[:atomMaxEntries]
+	}
+
+	feed := atomFeed{
+		ID:      "urn:codex-history:feed",
+		Title:   "Codex History",
+		Updated: time.Now().UTC().Format(time.RFC3339),
+		Author:  atomAuthor{Name: "codex-history"},
+	}
+	if len(sessions) > 0 {
+		if t := lastTime(sessions[0]); !t.IsZero() {
+			feed.Updated = t.UTC().Format(time.RFC3339)
+		}
+	}
+	for _, group := range sessions {
+		entry := atomEntry{
+			ID:      "urn:codex-history:session:" + group[0].SessionID,
+			Title:   sessionTitle(group, 120),
+			Updated: feed.Updated,
+			Summary: "Session " + group[0].SessionID,
+		}
+		if entry.Title == "" {
+			entry.Title = "Session " + shortSessionID(group[0].SessionID)
+		}
+		if t, ok := parseRecordTime(group[0].Timestamp); ok {
+			entry.Published = t.UTC().Format(time.RFC3339)
+		}
+		if t := lastTime(group); !t.IsZero() {
+			entry.Updated = t.UTC().Format(time.RFC3339)
+		}
+		if project := sessionProject(group); project != "" {
+			entry.Category = &atomTerm{Term: project}
+		}
+
+		var content strings.Builder
+		for _, record := range group {
+			content.WriteString("<h4>" + html.EscapeString(record.Role+" · "+record.Timestamp) + "</h4>")
+			content.WriteString(string(messageHTML(record.Text)))
+		}
+		entry.Content = atomContent{Type: "html", Body: content.String()}
+		feed.Entries = append(feed.Entries, entry)
+	}
+
+	data, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), append(data, '\n')...), nil
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func TestRenderAtom(t *testing.T) {
+	records := []Record{
+		{ID: "1", SessionID: "old", Timestamp: "2026-02-16T10:00:00Z", Role: "user", Text: "older <session>", Meta: map[string]string{"cwd": "/src/app"}},
+		{ID: "2", SessionID: "new", Timestamp: "2026-02-17T10:00:00Z", Role: "user", Text: "newer session"},
+		{ID: "3", SessionID: "new", Timestamp: "2026-02-17T10:05:00Z", Role: "assistant", Text: "use `go test`"},
+	}
+	content, err := renderAtom(records)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var feed atomFeed
+	if err := xml.Unmarshal(content, &feed); err != nil {
+		t.Fatal(err)
+	}
+	if feed.Updated != "2026-02-17T10:05:00Z" || len(feed.Entries) != 2 {
+		t.Fatalf("unexpected feed: %+v", feed)
+	}
+	newest, oldest := feed.Entries[0], feed.Entries[1]
+	if newest.Title != "newer session" || newest.Published != "2026-02-17T10:00:00Z" || newest.Updated != "2026-02-17T10:05:00Z" {
+		t.Fatalf("unexpected first entry: %+v", newest)
+	}
+	if !strings.Contains(newest.Content.Body, "<code>go test</code>") || newest.Content.Type != "html" {
+		t.Fatalf("unexpected content: %+v", newest.Content)
+	}
+	if oldest.Title != "older <session>" || oldest.Category == nil || oldest.Category.Term != "app" {
+		t.Fatalf("unexpected second entry: %+v", oldest)
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// chatMessage is one turn in the OpenAI chat fine-tuning format.
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type chatConversation struct {
+	Messages []chatMessage `json:"messages"`
+}
+
+// shareGPTTurn is one turn in the ShareGPT format, which names the sides
+// "human" and "gpt".
+type shareGPTTurn struct {
+	From  string `json:"from"`
+	Value string `json:"value"`
+}
+
+type shareGPTConversation struct {
+	ID            string         `json:"id"`
+	Conversations []shareGPTTurn `json:"conversations"`
+}
+
+// conversationTurns groups records into per-session conversations, in the
+// order sessions first appear, with each session's turns in chronological
+// order. Only user and assistant messages are kept, and consecutive
+// messages from the same side are joined so the turns alternate, which
+// fine-tuning formats expect.
+func conversationTurns(records []Record) (sessionIDs []string, turns map[string][]chatMessage) {
+	bySession := make(map[string][]Record)
+	for _, record := range records {
+		if record.Role != "user" && record.Role != "assistant" {
+			continue
+		}
+		if _, ok := bySession[record.SessionID]; !ok {
+			sessionIDs = append(sessionIDs, record.SessionID)
+		}
+		bySession[record.SessionID] = append(bySession[record.SessionID], record)
+	}
+
+	turns = make(map[string][]chatMessage, len(sessionIDs))
+	for _, sessionID := range sessionIDs {
+		session := bySession[sessionID]
+		sortRecordsChronological(session)
+		var messages []chatMessage
+		for _, record := range session {
+			text := strings.TrimSpace(record.Text)
+			if n := len(messages); n > 0 && messages[n-1].Role == record.Role {
+				messages[n-1].Content += "\n\n" + text
+				continue
+			}
+			messages = append(messages, chatMessage{Role: record.Role, Content: text})
+		}
+		turns[sessionID] = messages
+	}
+	return sessionIDs, turns
+}
+
+// renderOpenAIChat writes one {"messages": [...]} line per session.
+func renderOpenAIChat(records []Record) ([]byte, error) {
+	sessionIDs, turns := conversationTurns(records)
+	var builder strings.Builder
+	encoder := json.NewEncoder(&builder)
+	encoder.SetEscapeHTML(false)
+	for _, sessionID := range sessionIDs {
+		if err := encoder.Encode(chatConversation{Messages: turns[sessionID]}); err != nil {
+			return nil, err
+		}
+	}
+	return []byte(builder.String()), nil
+}
+
+// renderShareGPT writes one {"id", "conversations": [...]} line per session.
+func renderShareGPT(records []Record) ([]byte, error) {
+	sessionIDs, turns := conversationTurns(records)
+	var builder strings.Builder
+	encoder := json.NewEncoder(&builder)
+	encoder.SetEscapeHTML(false)
+	for _, sessionID := range sessionIDs {
+		messages := turns[sessionID]
+		conversation := shareGPTConversation{ID: sessionID, Conversations: make([]shareGPTTurn, len(messages))}
+		for i, message := range messages {
+			from := "human"
+			if message.Role == "assistant" {
+				from = "gpt"
+			}
+			conversation.Conversations[i] = shareGPTTurn{From: from, Value: message.Content}
+		}
+		if err := encoder.Encode(conversation); err != nil {
+			return nil, err
+		}
+	}
+	return []byte(builder.String()), nil
+}
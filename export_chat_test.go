@@ -0,0 +1,36 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderExportConversations(t *testing.T) {
+	records := []Record{
+		{ID: "3", SessionID: "s1", Timestamp: "2026-02-17T10:00:02Z", Role: "assistant", Text: "Sure."},
+		{ID: "1", SessionID: "s1", Timestamp: "2026-02-17T10:00:00Z", Role: "user", Text: "fix it"},
+		{ID: "2", SessionID: "s1", Timestamp: "2026-02-17T10:00:01Z", Role: "user", Text: "please"},
+		{ID: "4", SessionID: "s1", Timestamp: "2026-02-17T10:00:03Z", Role: "tool", Text: "go test ./..."},
+		{ID: "5", SessionID: "s2", Timestamp: "2026-02-17T11:00:00Z", Role: "user", Text: "hi"},
+	}
+
+	content, err := renderExport("openai-chat", records, ExportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	want := `{"messages":[{"role":"user","content":"fix it\n\nplease"},{"role":"assistant","content":"Sure."}]}`
+	if len(lines) != 2 || lines[0] != want {
+		t.Fatalf("unexpected openai-chat export: %q", lines)
+	}
+
+	content, err = renderExport("sharegpt", records, ExportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines = strings.Split(strings.TrimSpace(string(content)), "\n")
+	want = `{"id":"s2","conversations":[{"from":"human","value":"hi"}]}`
+	if len(lines) != 2 || lines[1] != want {
+		t.Fatalf("unexpected sharegpt export: %q", lines)
+	}
+}
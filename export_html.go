@@ -0,0 +1,207 @@
+package main
+
+import (
+	"bytes"
+	"html"
+	"html/template"
+	"strings"
+	"time"
+)
+
+// groupSessions splits records by session, keeping sessions in the order
+// they first appear and each session's records in their given order.
+func groupSessions(records []Record) [][]Record {
+	index := make(map[string]int)
+	var sessions [][]Record
+	for _, record := range records {
+		i, ok := index[record.SessionID]
+		if !ok {
+			i = len(sessions)
+			index[record.SessionID] = i
+			sessions = append(sessions, nil)
+		}
+		sessions[i] = append(sessions[i], record)
+	}
+	return sessions
+}
+
+// sessionTitle is the first user message of a session on one line, cut to
+// at most maxBytes.
+func sessionTitle(records []Record, maxBytes int) string {
+	for _, record := range records {
+		if record.Role != "user" {
+			continue
+		}
+		title := strings.Join(strings.Fields(record.Text), " ")
+		if len(title) > maxBytes {
+			title = cutUTF8(title, maxBytes) + "…"
+		}
+		return title
+	}
+	return ""
+}
+
+type htmlSession struct {
+	ID       string
+	Title    string
+	First    string
+	Last     string
+	Messages []htmlMessage
+}
+
+type htmlMessage struct {
+	Role      string
+	Timestamp string
+	Body      template.HTML
+}
+
+// renderHTML writes a single self-contained page: no external styles,
+// scripts, or fonts, so it can be mailed or attached as is.
+func renderHTML(records []Record) ([]byte, error) {
+	var sessions []htmlSession
+	for _, group := range groupSessions(records) {
+		session := htmlSession{
+			ID:    group[0].SessionID,
+			Title: sessionTitle(group, 120),
+			First: group[0].Timestamp,
+			Last:  group[len(group)-1].Timestamp,
+		}
+		for _, record := range group {
+			session.Messages = append(session.Messages, htmlMessage{
+				Role:      record.Role,
+				Timestamp: record.Timestamp,
+				Body:      messageHTML(record.Text),
+			})
+		}
+		sessions = append(sessions, session)
+	}
+
+	var out bytes.Buffer
+	err := htmlExportTemplate.Execute(&out, map[string]any{
+		"Generated": time.Now().UTC().Format(time.RFC3339),
+		"Records":   len(records),
+		"Sessions":  sessions,
+		"Style":     htmlStyle,
+	})
+	return out.Bytes(), err
+}
+
+// messageHTML renders message text: fenced ``` blocks become code blocks,
+// `inline code` becomes code spans, and everything else is escaped text
+// with its line breaks kept.
+func messageHTML(text string) template.HTML {
+	var out strings.Builder
+	lines := strings.Split(text, "\n")
+	var prose []string
+	flushProse := func() {
+		if len(prose) == 0 {
+			return
+		}
+		out.WriteString(`<div class="text">`)
+		out.WriteString(inlineCodeHTML(strings.Join(prose, "\n")))
+		out.WriteString("</div>")
+		prose = prose[:0]
+	}
+	for i := 0; i < len(lines); i++ {
+		fence := strings.TrimSpace(lines[i])
+		if !strings.HasPrefix(fence, "```") {
+			prose = append(prose, lines[i])
+			continue
+		}
+		flushProse()
+		language := strings.TrimSpace(strings.TrimPrefix(fence, "```"))
+		var code []string
+		for i++; i < len(lines) && strings.TrimSpace(lines[i]) != "```"; i++ {
+			code = append(code, lines[i])
+		}
+		out.WriteString("<pre><code")
+		if language != "" {
+			out.WriteString(` class="language-` + html.EscapeString(language) + `"`)
+		}
+		out.WriteString(">")
+		out.WriteString(html.EscapeString(strings.Join(code, "\n")))
+		out.WriteString("</code></pre>")
+	}
+	flushProse()
+	return template.HTML(out.String())
+}
+
+func inlineCodeHTML(text string) string {
+	parts := strings.Split(text, "`")
+	if len(parts)%2 == 0 {
+		// An unmatched backtick: leave the text as typed.
+		return html.EscapeString(text)
+	}
+	var out strings.Builder
+	for i, part := range parts {
+		if i%2 == 1 {
+			out.WriteString("<code>" + html.EscapeString(part) + "</code>")
+		} else {
+			out.WriteString(html.EscapeString(part))
+		}
+	}
+	return out.String()
+}
+
+// htmlStyle is shared by the html export and the static site.
+const htmlStyle = template.CSS(`
+body { font: 15px/1.5 -apple-system, BlinkMacSystemFont, "Segoe UI", sans-serif; max-width: 960px; margin: 0 auto; padding: 1rem; color: #1f2328; background: #f6f8fa; }
+header { position: sticky; top: 0; background: #f6f8fa; padding: .5rem 0; }
+#search { width: 100%; padding: .5rem; font-size: 1rem; box-sizing: border-box; }
+details { background: #fff; border: 1px solid #d0d7de; border-radius: 6px; margin: .5rem 0; }
+summary { cursor: pointer; padding: .6rem .8rem; }
+summary .meta, .message .meta { color: #656d76; font-size: .85em; }
+.messages { padding: 0 .8rem .8rem; }
+.message { border-radius: 8px; padding: .5rem .75rem; margin: .5rem 0; max-width: 85%; }
+.message.user { background: #ddf4ff; margin-left: auto; }
+.message.assistant { background: #f0f0f0; }
+.message.tool, .message.patch { background: #fff8c5; }
+.message.reasoning { background: #fbefff; }
+.message.error { background: #ffebe9; }
+.message.event { background: #eaeef2; }
+.text { white-space: pre-wrap; overflow-wrap: anywhere; }
+pre { background: #1f2328; color: #f6f8fa; padding: .6rem; border-radius: 6px; overflow-x: auto; }
+code { font-family: ui-monospace, SFMono-Regular, Menlo, monospace; font-size: .9em; }
+.text code { background: rgba(175, 184, 193, .3); padding: 0 .2em; border-radius: 4px; }
+.hidden { display: none; }
+`)
+
+var htmlExportTemplate = template.Must(template.New("export").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Codex Conversation Export</title>
+<style>{{.Style}}</style>
+</head>
+<body>
+<header>
+<h1>Codex Conversation Export</h1>
+<p class="meta">Generated {{.Generated}} &middot; {{len .Sessions}} sessions &middot; {{.Records}} records</p>
+<input id="search" type="search" placeholder="Search messages">
+</header>
+{{range .Sessions}}<details class="session">
+<summary><strong>{{if .Title}}{{.Title}}{{else}}(no user message){{end}}</strong><br><span class="meta">{{.ID}} &middot; {{.First}} &ndash; {{.Last}} &middot; {{len .Messages}} messages</span></summary>
+<div class="messages">
+{{range .Messages}}<div class="message {{.Role}}"><div class="meta">{{.Role}} &middot; {{.Timestamp}}</div>{{.Body}}</div>
+{{end}}</div>
+</details>
+{{else}}<p><em>No records matched.</em></p>
+{{end}}<script>
+document.getElementById("search").addEventListener("input", function (event) {
+  var query = event.target.value.toLowerCase();
+  document.querySelectorAll("details.session").forEach(function (session) {
+    var matches = 0;
+    session.querySelectorAll(".message").forEach(function (message) {
+      var hit = !query || message.textContent.toLowerCase().indexOf(query) >= 0;
+      message.classList.toggle("hidden", !hit);
+      if (hit) matches++;
+    });
+    session.classList.toggle("hidden", matches === 0);
+    session.open = query !== "" && matches > 0;
+  });
+});
+</script>
+</body>
+</html>
+`))
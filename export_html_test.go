@@ -0,0 +1,33 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderExportHTML(t *testing.T) {
+	records := []Record{
+		{ID: "1", SessionID: "s1", Timestamp: "2026-02-17T10:00:00Z", Role: "user", Text: "Why does <main> fail?"},
+		{ID: "2", SessionID: "s1", Timestamp: "2026-02-17T10:00:05Z", Role: "assistant", Text: "Run `go vet`:\n```go\nif a < b {}\n```\ndone"},
+		{ID: "3", SessionID: "s2", Timestamp: "2026-02-17T11:00:00Z", Role: "user", Text: "next"},
+	}
+	content, err := renderExport("html", records, ExportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	page := string(content)
+	for _, want := range []string{
+		`<details class="session">`,
+		`<strong>Why does &lt;main&gt; fail?</strong>`,
+		`<div class="message assistant">`,
+		`<div class="text">Run <code>go vet</code>:</div><pre><code class="language-go">if a &lt; b {}</code></pre><div class="text">done</div>`,
+		`id="search"`,
+	} {
+		if !strings.Contains(page, want) {
+			t.Fatalf("html export is missing %q", want)
+		}
+	}
+	if strings.Count(page, `<details class="session">`) != 2 {
+		t.Fatal("expected one section per session")
+	}
+}
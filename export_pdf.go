@@ -0,0 +1,234 @@
+package main
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// The PDF export lays text out on US Letter pages using the standard Type 1
+// fonts every viewer provides, so it needs no font files or dependencies.
+// Message text is set in Courier, whose fixed width makes wrapping exact;
+// those fonts only cover Windows-1252, so other characters print as "?",
+// and renderPDF warns when that happens.
+const (
+	pdfPageWidth  = 612
+	pdfPageHeight = 792
+	pdfMargin     = 54
+	pdfFontSize   = 9
+	// pdfLineHeight is the advance of a blank line.
+	pdfLineHeight = 12
+	// Courier glyphs are 0.6 em wide.
+	pdfLineChars = (pdfPageWidth - 2*pdfMargin) * 10 / (pdfFontSize * 6)
+)
+
+// pdfLine is one line of laid-out text. Fonts: F1 Courier, F2 Helvetica-Bold.
+type pdfLine struct {
+	font string
+	size int
+	text string
+}
+
+// leading is the distance from the previous baseline down to this line's:
+// 4/3 of its font size, 12 for the 9pt body text.
+func (l pdfLine) leading() int {
+	if l.size == 0 {
+		return pdfLineHeight
+	}
+	return l.size + l.size/3
+}
+
+// renderPDF lays records out as a printable transcript, one section per
+// session.
+func renderPDF(records []Record) ([]byte, error) {
+	lines := []pdfLine{
+		{font: "F2", size: 16, text: "Codex Conversation Export"},
+		{font: "F1", size: pdfFontSize, text: fmt.Sprintf("Generated %s, %d records", time.Now().UTC().Format(time.RFC3339), len(records))},
+		{},
+	}
+	if len(records) == 0 {
+		lines = append(lines, pdfLine{font: "F1", size: pdfFontSize, text: "No records matched."})
+	}
+	for _, group := range groupSessions(records) {
+		lines = append(lines,
+			pdfLine{font: "F2", size: 12, text: "Session " + group[0].SessionID},
+			pdfLine{font: "F1", size: pdfFontSize, text: group[0].Timestamp + " - " + group[len(group)-1].Timestamp},
+			pdfLine{},
+		)
+		for _, record := range group {
+			lines = append(lines, pdfLine{font: "F2", size: pdfFontSize, text: strings.ToUpper(record.Role) + "  " + record.Timestamp})
+			for _, text := range wrapText(record.Text, pdfLineChars) {
+				lines = append(lines, pdfLine{font: "F1", size: pdfFontSize, text: text})
+			}
+			lines = append(lines, pdfLine{})
+		}
+	}
+	if chars, affected := pdfUnencodable(records); chars > 0 {
+		fmt.Fprintf(os.Stderr, "warning: %d characters in %d records are not in the PDF fonts (Windows-1252) and print as \"?\"; use --format html for other scripts\n", chars, affected)
+	}
+	return buildPDF(paginate(lines))
+}
+
+// pdfUnencodable counts the characters of records' text and session IDs
+// that pdfString cannot encode, and the records they appear in.
+func pdfUnencodable(records []Record) (chars, affected int) {
+	for _, record := range records {
+		n := 0
+		for _, r := range record.SessionID + record.Text {
+			if _, ok := pdfEncode(r); !ok && r != '\n' && r != '\t' && r != '\r' {
+				n++
+			}
+		}
+		if n > 0 {
+			chars += n
+			affected++
+		}
+	}
+	return chars, affected
+}
+
+// wrapText breaks text into lines of at most width characters, at spaces
+// where possible, keeping existing line breaks.
+func wrapText(text string, width int) []string {
+	var out []string
+	for _, paragraph := range strings.Split(strings.ReplaceAll(text, "\t", "    "), "\n") {
+		line := []rune(strings.TrimRight(paragraph, " \r"))
+		for len(line) > width {
+			cut := width
+			for i := width; i > width/2; i-- {
+				if line[i] == ' ' {
+					cut = i
+					break
+				}
+			}
+			out = append(out, string(line[:cut]))
+			line = line[cut:]
+			if len(line) > 0 && line[0] == ' ' {
+				line = line[1:]
+			}
+		}
+		out = append(out, string(line))
+	}
+	return out
+}
+
+// paginate splits lines into pages that fit between the margins. A blank
+// line is never carried to the top of a page.
+func paginate(lines []pdfLine) [][]pdfLine {
+	const usable = pdfPageHeight - 2*pdfMargin
+	var pages [][]pdfLine
+	var page []pdfLine
+	used := 0
+	for _, line := range lines {
+		if len(page) > 0 && used+line.leading() > usable {
+			pages = append(pages, page)
+			page, used = nil, 0
+		}
+		if len(page) == 0 && line.text == "" && len(pages) > 0 {
+			continue
+		}
+		page = append(page, line)
+		used += line.leading()
+	}
+	if len(page) > 0 || len(pages) == 0 {
+		pages = append(pages, page)
+	}
+	return pages
+}
+
+// buildPDF writes the document: catalog, page tree, fonts, then a page and
+// a compressed content stream per page, and the cross-reference table.
+func buildPDF(pages [][]pdfLine) ([]byte, error) {
+	var out bytes.Buffer
+	var offsets []int
+	object := func(body string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 5+2*i)
+	}
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+
+	for i, page := range pages {
+		var content bytes.Buffer
+		y := pdfPageHeight - pdfMargin
+		for _, line := range page {
+			y -= line.leading()
+			if line.text == "" {
+				continue
+			}
+			fmt.Fprintf(&content, "BT /%s %d Tf %d %d Td (%s) Tj ET\n", line.font, line.size, pdfMargin, y, pdfString(line.text))
+		}
+		fmt.Fprintf(&content, "BT /F1 8 Tf %d %d Td (Page %d of %d) Tj ET\n", pdfPageWidth-pdfMargin-60, pdfMargin/2, i+1, len(pages))
+
+		var compressed bytes.Buffer
+		zw := zlib.NewWriter(&compressed)
+		if _, err := zw.Write(content.Bytes()); err != nil {
+			return nil, err
+		}
+		if err := zw.Close(); err != nil {
+			return nil, err
+		}
+
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			pdfPageWidth, pdfPageHeight, len(offsets)+2))
+		object(fmt.Sprintf("<< /Length %d /Filter /FlateDecode >>\nstream\n%s\nendstream", compressed.Len(), compressed.Bytes()))
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	return out.Bytes(), nil
+}
+
+// winAnsiExtras maps the Windows-1252 characters outside Latin-1.
+var winAnsiExtras = map[rune]byte{
+	'€': 0x80, '‚': 0x82, 'ƒ': 0x83, '„': 0x84, '…': 0x85, '†': 0x86, '‡': 0x87,
+	'ˆ': 0x88, '‰': 0x89, 'Š': 0x8A, '‹': 0x8B, 'Œ': 0x8C, 'Ž': 0x8E,
+	'‘': 0x91, '’': 0x92, '“': 0x93, '”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97,
+	'˜': 0x98, '™': 0x99, 'š': 0x9A, '›': 0x9B, 'œ': 0x9C, 'ž': 0x9E, 'Ÿ': 0x9F,
+}
+
+// pdfEncode returns r's WinAnsiEncoding byte.
+func pdfEncode(r rune) (byte, bool) {
+	switch {
+	case r >= 0x20 && r < 0x7F, r >= 0xA0 && r <= 0xFF:
+		return byte(r), true
+	}
+	b, ok := winAnsiExtras[r]
+	return b, ok
+}
+
+// pdfString encodes text as the body of a PDF literal string in
+// WinAnsiEncoding.
+func pdfString(text string) string {
+	var out strings.Builder
+	for _, r := range text {
+		b, ok := pdfEncode(r)
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			out.WriteByte('\\')
+			out.WriteRune(r)
+		case !ok:
+			out.WriteByte('?')
+		case b < 0x7F:
+			out.WriteByte(b)
+		default:
+			fmt.Fprintf(&out, "\\%03o", b)
+		}
+	}
+	return out.String()
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRenderExportPDF(t *testing.T) {
+	records := []Record{
+		{ID: "1", SessionID: "s1", Timestamp: "2026-02-17T10:00:00Z", Role: "user", Text: "print (this)"},
+		{ID: "2", SessionID: "s1", Timestamp: "2026-02-17T10:00:05Z", Role: "assistant", Text: strings.Repeat("word ", 400)},
+	}
+	content, err := renderExport("pdf", records, ExportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(content, []byte("%PDF-1.4")) || !bytes.HasSuffix(content, []byte("%%EOF\n")) {
+		t.Fatal("missing PDF header or trailer")
+	}
+	if !bytes.Contains(content, []byte("/Count 1")) {
+		t.Fatal("expected a single page")
+	}
+}
+
+func TestWrapText(t *testing.T) {
+	got := wrapText("aaa bbb ccc\n\tdddddddddd", 8)
+	want := []string{"aaa bbb", "ccc", "    dddd", "dddddd"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("wrapText = %q, want %q", got, want)
+	}
+}
+
+func TestPDFString(t *testing.T) {
+	if got := pdfString(`a (b) \ “é” 日`); got != `a \(b\) \\ \223\351\224 ?` {
+		t.Fatalf("pdfString = %q", got)
+	}
+}
+
+func TestPaginateAdvancesByFontSize(t *testing.T) {
+	title := pdfLine{font: "F2", size: 16, text: "Title"}
+	body := pdfLine{font: "F1", size: pdfFontSize, text: "body"}
+	// The title's baseline must sit far enough above the next line's that
+	// its descenders clear the body text.
+	if title.leading() <= pdfLineHeight || body.leading() != pdfLineHeight {
+		t.Fatalf("leading: title %d, body %d", title.leading(), body.leading())
+	}
+
+	lines := []pdfLine{title}
+	for i := 0; i < 60; i++ {
+		lines = append(lines, body)
+	}
+	pages := paginate(lines)
+	height := 0
+	for _, line := range pages[0] {
+		height += line.leading()
+	}
+	if height > pdfPageHeight-2*pdfMargin {
+		t.Fatalf("first page is %dpt tall, more than the %dpt between the margins", height, pdfPageHeight-2*pdfMargin)
+	}
+	if len(pages) != 2 || len(pages[0])+len(pages[1]) != len(lines) {
+		t.Fatalf("got %d pages for %d lines", len(pages), len(lines))
+	}
+}
+
+func TestPDFUnencodable(t *testing.T) {
+	records := []Record{
+		{SessionID: "s1", Text: "café “quoted”\nnext line"},
+		{SessionID: "s1", Text: "日本語 🚀"},
+	}
+	if chars, affected := pdfUnencodable(records); chars != 4 || affected != 1 {
+		t.Fatalf("pdfUnencodable = %d chars in %d records, want 4 in 1", chars, affected)
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// exportExtensions gives the file extension for each export format.
+var exportExtensions = map[string]string{
+	"markdown":    ".md",
+	"md":          ".md",
+	"csv":         ".csv",
+	"jsonl":       ".jsonl",
+	"html":        ".html",
+	"pdf":         ".pdf",
+	"openai-chat": ".jsonl",
+	"sharegpt":    ".jsonl",
+	"parquet":     ".parquet",
+	"sqlite":      ".db",
+	"arrow":       ".arrows",
+	"atom":        ".atom",
+}
+
+// splitFileStem names a session's file by the date of its first record and
+// its short ID, so a directory listing sorts by date.
+func splitFileStem(records []Record) string {
+	date := "undated"
+	if t, ok := parseRecordTime(records[0].Timestamp); ok {
+		date = t.UTC().Format("2006-01-02")
+	}
+	return date + "-" + sessionFileName(shortSessionID(records[0].SessionID))
+}
+
+// writeSplitExport renders each session into its own file under dir and
+// returns how many files it wrote.
+func writeSplitExport(dir, format string, records []Record, opts ExportOptions) (int, error) {
+	ext, ok := exportExtensions[format]
+	if format == "template" && opts.Template != nil {
+		ext, ok = templateExtension(opts.Template), true
+	}
+	if !ok {
+		// Let renderExport report the unsupported format.
+		_, err := renderExport(format, nil, opts)
+		return 0, err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return 0, err
+	}
+
+	used := make(map[string]bool)
+	for _, session := range groupSessions(records) {
+		content, err := renderExport(format, session, opts)
+		if err != nil {
+			return 0, err
+		}
+		stem := splitFileStem(session)
+		name := stem + ext
+		for n := 2; used[name]; n++ {
+			name = fmt.Sprintf("%s-%d%s", stem, n, ext)
+		}
+		used[name] = true
+		if opts.Compression != "" {
+			if content, err = compressBytes(content, opts.Compression); err != nil {
+				return 0, err
+			}
+		}
+		if err := os.WriteFile(filepath.Join(dir, withCompressionSuffix(name, opts.Compression)), content, 0o644); err != nil {
+			return 0, err
+		}
+	}
+	return len(used), nil
+}
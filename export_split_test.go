@@ -0,0 +1,49 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestWriteSplitExport(t *testing.T) {
+	records := []Record{
+		{ID: "1", SessionID: "4f163f5f-0f9a-621d-7295-66c74d10037c", Timestamp: "2026-02-16T10:00:00Z", Role: "user", Text: "one"},
+		{ID: "2", SessionID: "0a1b2c3d-0000-0000-0000-000000000000", Timestamp: "2026-02-17T09:00:00Z", Role: "user", Text: "two"},
+		{ID: "3", SessionID: "4f163f5f-0f9a-621d-7295-66c74d10037c", Timestamp: "2026-02-17T10:00:00Z", Role: "assistant", Text: "three"},
+	}
+	dir := filepath.Join(t.TempDir(), "split")
+	files, err := writeSplitExport(dir, "jsonl", records, ExportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if files != 2 {
+		t.Fatalf("expected 2 files, got %d", files)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	sort.Strings(names)
+	if strings.Join(names, ",") != "2026-02-16-4f163f5f.jsonl,2026-02-17-0a1b2c3d.jsonl" {
+		t.Fatalf("unexpected files: %v", names)
+	}
+	content, err := os.ReadFile(filepath.Join(dir, "2026-02-16-4f163f5f.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(string(content), "\n") != 2 {
+		t.Fatalf("expected both records of the session, got %q", content)
+	}
+
+	if _, err := writeSplitExport(dir, "yaml", records, ExportOptions{}); err == nil {
+		t.Fatal("expected an error for an unsupported format")
+	}
+}
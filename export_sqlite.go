@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"codex-history-cli/internal/history"
+)
+
+const sqliteExportSchema = `
+CREATE TABLE records (
+  id TEXT PRIMARY KEY,
+  session_id TEXT NOT NULL,
+  timestamp TEXT NOT NULL,
+  role TEXT NOT NULL,
+  text TEXT NOT NULL,
+  source_file TEXT,
+  source_line INTEGER,
+  meta TEXT,
+  attachments TEXT,
+  tool TEXT,
+  raw TEXT
+);
+CREATE TABLE sessions (
+  session_id TEXT PRIMARY KEY,
+  first_timestamp TEXT,
+  last_timestamp TEXT,
+  total INTEGER NOT NULL,
+  user INTEGER NOT NULL,
+  assistant INTEGER NOT NULL,
+  other INTEGER NOT NULL,
+  errors INTEGER NOT NULL
+);
+CREATE INDEX records_session_timestamp ON records(session_id, timestamp);
+CREATE INDEX records_timestamp ON records(timestamp);
+CREATE INDEX records_role ON records(role);
+`
+
+// renderSQLite builds a SQLite database of the records and a per-session
+// summary table with the sqlite3 command-line tool, and returns the file.
+// Timestamps are stored as RFC3339 text, which SQLite's date functions
+// read directly; nested fields are JSON text, as in the parquet export.
+func renderSQLite(records []Record) ([]byte, error) {
+	dir, err := os.MkdirTemp("", "codex-history-export-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	db := filepath.Join(dir, "history.db")
+
+	var script strings.Builder
+	script.WriteString("PRAGMA journal_mode = OFF;\nBEGIN;\n")
+	script.WriteString(sqliteExportSchema)
+	for _, record := range records {
+		meta, _ := jsonColumn(record.Meta, len(record.Meta) > 0)
+		attachments, _ := jsonColumn(record.Attachments, len(record.Attachments) > 0)
+		tool, _ := jsonColumn(record.Tool, record.Tool != nil)
+		sourceLine := "NULL"
+		if record.SourceLine != 0 {
+			sourceLine = strconv.Itoa(record.SourceLine)
+		}
+		fmt.Fprintf(&script, "INSERT OR IGNORE INTO records VALUES (%s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s);\n",
+			sqlString(record.ID), sqlString(record.SessionID), sqlString(record.Timestamp), sqlString(record.Role), sqlString(record.Text),
+			sqlNullable(record.SourceFile), sourceLine, sqlNullable(meta), sqlNullable(attachments), sqlNullable(tool), sqlNullable(string(record.Raw)))
+	}
+	for _, summary := range buildSessionSummaries(records) {
+		fmt.Fprintf(&script, "INSERT INTO sessions VALUES (%s, %s, %s, %d, %d, %d, %d, %d);\n",
+			sqlString(summary.SessionID), sqlNullable(summary.FirstTimestamp), sqlNullable(summary.LastTimestamp),
+			summary.Total, summary.User, summary.Assistant, summary.Other, summary.Errors)
+	}
+	script.WriteString("COMMIT;\n")
+
+	if err := history.NewSQLiteCLI(db).Exec(script.String()); err != nil {
+		return nil, err
+	}
+	return os.ReadFile(db)
+}
+
+func sqlString(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+func sqlNullable(value string) string {
+	if value == "" {
+		return "NULL"
+	}
+	return sqlString(value)
+}
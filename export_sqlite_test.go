@@ -0,0 +1,39 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRenderExportSQLite(t *testing.T) {
+	if _, err := exec.LookPath("sqlite3"); err != nil {
+		t.Skip("sqlite3 not available")
+	}
+	records := []Record{
+		{ID: "id1", SessionID: "s1", Timestamp: "2026-02-17T10:00:00Z", Role: "user", Text: "it's here", SourceFile: "a.jsonl", SourceLine: 3},
+		{ID: "id2", SessionID: "s1", Timestamp: "2026-02-17T10:00:05Z", Role: "assistant", Text: "ok", Meta: map[string]string{"model": "gpt-5"}},
+	}
+	content, err := renderExport("sqlite", records, ExportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := filepath.Join(t.TempDir(), "history.db")
+	if err := os.WriteFile(db, content, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := exec.Command("sqlite3", db,
+		"SELECT text, source_line FROM records WHERE role = 'user';",
+		"SELECT json_extract(meta, '$.model') FROM records WHERE id = 'id2';",
+		"SELECT total, user, assistant, last_timestamp FROM sessions;").CombinedOutput()
+	if err != nil {
+		t.Fatalf("sqlite3: %v: %s", err, out)
+	}
+	want := "it's here|3\ngpt-5\n2|1|1|2026-02-17T10:00:05Z"
+	if got := strings.TrimSpace(string(out)); got != want {
+		t.Fatalf("unexpected query output:\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// templateSession is what a --per session template is executed with.
+type templateSession struct {
+	ID      string
+	Title   string
+	Project string
+	First   string
+	Last    string
+	Records []Record
+}
+
+var exportTemplateFuncs = template.FuncMap{
+	"json": func(value any) (string, error) {
+		data, err := json.Marshal(value)
+		return string(data), err
+	},
+	"upper":   strings.ToUpper,
+	"lower":   strings.ToLower,
+	"trim":    strings.TrimSpace,
+	"oneline": func(text string) string { return strings.Join(strings.Fields(text), " ") },
+	"truncate": func(maxBytes int, text string) string {
+		if len(text) <= maxBytes {
+			return text
+		}
+		return cutUTF8(text, maxBytes) + "…"
+	},
+	"short": shortSessionID,
+}
+
+// loadExportTemplate parses a --template file. The template is named after
+// the file so split exports can borrow its extension.
+func loadExportTemplate(path string) (*template.Template, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	tmpl, err := template.New(filepath.Base(path)).Funcs(exportTemplateFuncs).Parse(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("--template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// parseLineFormat parses a show --format template, which is executed once
+// per record with the same functions as export templates.
+func parseLineFormat(format string) (*template.Template, error) {
+	tmpl, err := template.New("format").Funcs(exportTemplateFuncs).Parse(format)
+	if err != nil {
+		return nil, fmt.Errorf("--format: %w", err)
+	}
+	return tmpl, nil
+}
+
+// renderTemplate executes the template once per record, or once per
+// session when perSession is set, and concatenates the results.
+func renderTemplate(tmpl *template.Template, records []Record, perSession bool) ([]byte, error) {
+	if tmpl == nil {
+		return nil, fmt.Errorf("--format template requires --template FILE")
+	}
+	var out bytes.Buffer
+	if !perSession {
+		for _, record := range records {
+			if err := tmpl.Execute(&out, record); err != nil {
+				return nil, err
+			}
+		}
+		return out.Bytes(), nil
+	}
+	for _, group := range groupSessions(records) {
+		session := templateSession{
+			ID:      group[0].SessionID,
+			Title:   sessionTitle(group, 120),
+			Project: sessionProject(group),
+			First:   group[0].Timestamp,
+			Last:    group[len(group)-1].Timestamp,
+			Records: group,
+		}
+		if err := tmpl.Execute(&out, session); err != nil {
+			return nil, err
+		}
+	}
+	return out.Bytes(), nil
+}
+
+// templateExtension guesses the output extension from the template's file
+// name, e.g. ".md" for "note.md.tmpl".
+func templateExtension(tmpl *template.Template) string {
+	name := tmpl.Name()
+	for _, suffix := range []string{".tmpl", ".tpl", ".gotmpl"} {
+		name = strings.TrimSuffix(name, suffix)
+	}
+	if ext := filepath.Ext(name); ext != "" {
+		return ext
+	}
+	return ".txt"
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRenderTemplate(t *testing.T) {
+	records := []Record{
+		{ID: "1", SessionID: "4f163f5f-0f9a-621d-7295-66c74d10037c", Timestamp: "2026-02-17T10:00:00Z", Role: "user", Text: "fix the\nbuild"},
+		{ID: "2", SessionID: "4f163f5f-0f9a-621d-7295-66c74d10037c", Timestamp: "2026-02-17T10:01:00Z", Role: "assistant", Text: "done"},
+	}
+	path := filepath.Join(t.TempDir(), "note.md.tmpl")
+	write := func(body string) {
+		if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("{{.Role | upper}}: {{.Text | oneline}}\n")
+	tmpl, err := loadExportTemplate(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := renderTemplate(tmpl, records, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "USER: fix the build\nASSISTANT: done\n" {
+		t.Fatalf("unexpected per-record output: %q", content)
+	}
+
+	write("{{short .ID}} {{.Title}} {{len .Records}} {{json .Last}}\n")
+	if tmpl, err = loadExportTemplate(path); err != nil {
+		t.Fatal(err)
+	}
+	content, err = renderTemplate(tmpl, records, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "4f163f5f fix the build 2 \"2026-02-17T10:01:00Z\"\n" {
+		t.Fatalf("unexpected per-session output: %q", content)
+	}
+	if ext := templateExtension(tmpl); ext != ".md" {
+		t.Fatalf("unexpected extension %q", ext)
+	}
+
+	write("{{.Missing")
+	if _, err := loadExportTemplate(path); err == nil {
+		t.Fatal("expected a parse error")
+	}
+}
+
+func TestParseLineFormat(t *testing.T) {
+	tmpl, err := parseLineFormat("{{.Timestamp}}\t{{short .SessionID}}\t{{.Role | upper}}\t{{oneline .Text}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out strings.Builder
+	record := Record{SessionID: "4f163f5f-0f9a", Timestamp: "2026-02-17T10:00:00Z", Role: "user", Text: "fix the\nbuild"}
+	if err := tmpl.Execute(&out, record); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "2026-02-17T10:00:00Z\t4f163f5f\tUSER\tfix the build" {
+		t.Fatalf("unexpected output: %q", out.String())
+	}
+	if _, err := parseLineFormat("{{.Text"); err == nil {
+		t.Fatal("expected a parse error")
+	}
+}
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Optional record kinds that sync can extract in addition to user and
+// assistant messages.
+const (
+	includeTools     = "tools"
+	includeReasoning = "reasoning"
+	includePatches   = "patches"
+)
+
+var knownIncludes = []string{includeTools, includeReasoning, includePatches}
+
+// includeSet is the parsed value of --include.
+type includeSet map[string]bool
+
+func parseIncludeList(value string) (includeSet, error) {
+	set := make(includeSet)
+	for _, part := range strings.Split(value, ",") {
+		name := strings.ToLower(strings.TrimSpace(part))
+		if name == "" {
+			continue
+		}
+		known := false
+		for _, candidate := range knownIncludes {
+			if name == candidate {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown --include value %q (want %s)", name, strings.Join(knownIncludes, ", "))
+		}
+		set[name] = true
+	}
+	return set, nil
+}
+
+// responseItemPayload covers the response_item shapes sync understands.
+type responseItemPayload struct {
+	Type      string          `json:"type"`
+	Name      string          `json:"name"`
+	Arguments string          `json:"arguments"`
+	Input     string          `json:"input"`
+	Output    json.RawMessage `json:"output"`
+	CallID    string          `json:"call_id"`
+	// Role and Content are set on "message" items.
+	Role    string            `json:"role"`
+	Content []responseContent `json:"content"`
+	Action  *localShellAction `json:"action"`
+}
+
+type responseContent struct {
+	Type     string `json:"type"`
+	Text     string `json:"text"`
+	ImageURL string `json:"image_url"`
+}
+
+// injectedContextPrefixes mark user-role messages that Codex writes itself
+// (environment and instruction preambles) rather than the user typing them.
+var injectedContextPrefixes = []string{
+	"<environment_context>",
+	"<user_instructions>",
+	"# AGENTS.md instructions",
+}
+
+// responseMessageContent joins the text parts of a user or assistant message
+// item and collects its images. ok is false for other roles and for context
+// Codex injected on the user's behalf.
+func responseMessageContent(item responseItemPayload) (text string, images []string, ok bool) {
+	if item.Role != "user" && item.Role != "assistant" {
+		return "", nil, false
+	}
+	parts := make([]string, 0, len(item.Content))
+	for _, content := range item.Content {
+		switch content.Type {
+		case "input_text", "output_text", "text":
+			parts = append(parts, content.Text)
+		case "input_image":
+			images = append(images, content.ImageURL)
+		}
+	}
+	text = strings.Join(parts, "\n")
+	if item.Role == "user" {
+		trimmed := strings.TrimSpace(text)
+		for _, prefix := range injectedContextPrefixes {
+			if strings.HasPrefix(trimmed, prefix) {
+				return "", nil, false
+			}
+		}
+	}
+	return text, images, true
+}
+
+type localShellAction struct {
+	Command []string `json:"command"`
+}
+
+// shellArguments is the arguments object of a "shell" function call.
+type shellArguments struct {
+	Command []string `json:"command"`
+	Workdir string   `json:"workdir"`
+}
+
+// commandString renders an argv the way a user would have typed it. The
+// common ["bash", "-lc", script] wrapper is reduced to the script itself.
+func commandString(argv []string) string {
+	if len(argv) == 3 && (argv[1] == "-lc" || argv[1] == "-c") && strings.HasSuffix(argv[0], "sh") {
+		return argv[2]
+	}
+	parts := make([]string, len(argv))
+	for i, arg := range argv {
+		if arg == "" || strings.ContainsAny(arg, " \t\n'\"\\$`") {
+			parts[i] = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+		} else {
+			parts[i] = arg
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// toolCallText returns the text for a tool record built from a
+// response_item, or "" when the item is not a tool invocation.
+func toolCallText(item responseItemPayload) string {
+	if item.Name == "apply_patch" {
+		return ""
+	}
+	switch item.Type {
+	case "local_shell_call":
+		if item.Action != nil {
+			return commandString(item.Action.Command)
+		}
+	case "function_call":
+		var args shellArguments
+		if err := json.Unmarshal([]byte(item.Arguments), &args); err == nil && len(args.Command) > 0 {
+			return commandString(args.Command)
+		}
+		if item.Name != "" {
+			return strings.TrimSpace(item.Name + " " + item.Arguments)
+		}
+	}
+	return ""
+}
+
+// toolOutputLimit caps the tool output kept on a record; command output can
+// be megabytes and the history is for finding what ran, not replaying it.
+const toolOutputLimit = 4096
+
+// ToolCall is the structured form of a tool invocation: which tool, with
+// which arguments, and (when logged) what it returned.
+type ToolCall struct {
+	Name      string          `json:"name"`
+	CallID    string          `json:"call_id,omitempty"`
+	Arguments json.RawMessage `json:"arguments,omitempty"`
+	Output    string          `json:"output,omitempty"`
+	ExitCode  *int            `json:"exit_code,omitempty"`
+}
+
+func execToolCall(ev eventPayload) *ToolCall {
+	args, err := json.Marshal(shellArguments{Command: ev.Command, Workdir: ev.Cwd})
+	if err != nil {
+		return &ToolCall{Name: "shell", CallID: ev.CallID}
+	}
+	return &ToolCall{Name: "shell", CallID: ev.CallID, Arguments: args}
+}
+
+func responseToolCall(item responseItemPayload) *ToolCall {
+	call := &ToolCall{Name: item.Name, CallID: item.CallID}
+	switch item.Type {
+	case "local_shell_call":
+		call.Name = "local_shell"
+		if item.Action != nil {
+			if args, err := json.Marshal(shellArguments{Command: item.Action.Command}); err == nil {
+				call.Arguments = args
+			}
+		}
+	case "function_call":
+		call.Arguments = rawArguments(item.Arguments)
+	}
+	return call
+}
+
+// rawArguments keeps function-call arguments as JSON when they are valid JSON
+// and as a JSON string otherwise.
+func rawArguments(arguments string) json.RawMessage {
+	if strings.TrimSpace(arguments) == "" {
+		return nil
+	}
+	if json.Valid([]byte(arguments)) {
+		return json.RawMessage(arguments)
+	}
+	quoted, err := json.Marshal(arguments)
+	if err != nil {
+		return nil
+	}
+	return quoted
+}
+
+// parseToolOutput reads a function_call_output payload. The output is a
+// string that, for shell calls, is itself JSON carrying the text and the
+// exit code.
+func parseToolOutput(raw json.RawMessage) (string, *int) {
+	var text string
+	if err := json.Unmarshal(raw, &text); err != nil {
+		// Some releases log {"content": "...", "success": bool} objects.
+		var obj struct {
+			Content string `json:"content"`
+		}
+		if err := json.Unmarshal(raw, &obj); err != nil {
+			return "", nil
+		}
+		text = obj.Content
+	}
+
+	var shell struct {
+		Output   *string `json:"output"`
+		Metadata struct {
+			ExitCode *int `json:"exit_code"`
+		} `json:"metadata"`
+	}
+	if err := json.Unmarshal([]byte(text), &shell); err == nil && shell.Output != nil {
+		return *shell.Output, shell.Metadata.ExitCode
+	}
+	return text, nil
+}
+
+func firstOutput(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+func truncateToolOutput(output string) string {
+	if len(output) <= toolOutputLimit {
+		return output
+	}
+	return cutUTF8(output, toolOutputLimit) + fmt.Sprintf("\n[truncated: %d bytes total]", len(output))
+}
+
+// eventSet selects events for passthrough by envelope type ("turn_context"),
+// payload type ("token_count"), or both ("event_msg/token_count").
+type eventSet struct {
+	all   bool
+	names map[string]bool
+}
+
+// parseEventList returns nil when passthrough is off.
+func parseEventList(value string, all bool) *eventSet {
+	set := &eventSet{all: all, names: make(map[string]bool)}
+	for _, part := range strings.Split(value, ",") {
+		if name := strings.TrimSpace(part); name != "" {
+			set.names[name] = true
+		}
+	}
+	if !set.all && len(set.names) == 0 {
+		return nil
+	}
+	return set
+}
+
+func (s *eventSet) matches(envelopeType, payloadType string) bool {
+	if s == nil {
+		return false
+	}
+	return s.all || s.names[envelopeType] || (payloadType != "" && (s.names[payloadType] || s.names[envelopeType+"/"+payloadType]))
+}
+
+// eventLabel names a passthrough record: the envelope type, qualified by
+// the payload type when there is one.
+func eventLabel(envelopeType, payloadType string) string {
+	if envelopeType == "" {
+		envelopeType = "unknown"
+	}
+	if payloadType == "" {
+		return envelopeType
+	}
+	return envelopeType + "/" + payloadType
+}
+
+// envelopePayloadType returns payload.type when the payload is an object
+// with a string type, and "" otherwise.
+func envelopePayloadType(payload json.RawMessage) string {
+	var typed struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(payload, &typed); err != nil {
+		return ""
+	}
+	return typed.Type
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeSessionFile(t *testing.T, lines ...string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "rollout-2026-02-17T12-00-00-11111111-2222-3333-4444-555555555555.jsonl")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestExtractToolRecords(t *testing.T) {
+	path := writeSessionFile(t,
+		`{"timestamp":"2026-02-17T12:00:01Z","type":"event_msg","payload":{"type":"user_message","message":"run the tests"}}`,
+		`{"timestamp":"2026-02-17T12:00:02Z","type":"response_item","payload":{"type":"function_call","name":"shell","call_id":"c1","arguments":"{\"command\":[\"bash\",\"-lc\",\"go test ./...\"]}"}}`,
+		`{"timestamp":"2026-02-17T12:00:02Z","type":"event_msg","payload":{"type":"exec_command_begin","call_id":"c1","command":["bash","-lc","go test ./..."]}}`,
+		`{"timestamp":"2026-02-17T12:00:03Z","type":"event_msg","payload":{"type":"exec_command_begin","call_id":"c2","command":["git","commit","-m","fix it"]}}`,
+	)
+
+	records, err := extractRecords(path, ExtractOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 {
+		t.Fatalf("tool records must be opt-in, got %#v", records)
+	}
+
+	records, err = extractRecords(path, ExtractOptions{Include: includeSet{includeTools: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("expected user + 2 deduped tool records, got %#v", records)
+	}
+	if records[1].Role != "tool" || records[1].Text != "go test ./..." || records[1].Meta["call_id"] != "c1" {
+		t.Fatalf("unexpected tool record: %#v", records[1])
+	}
+	if records[2].Text != "git commit -m 'fix it'" {
+		t.Fatalf("unexpected command text: %q", records[2].Text)
+	}
+
+	if _, err := parseIncludeList("tools,bogus"); err == nil {
+		t.Fatal("expected unknown include to fail")
+	}
+}
+
+func TestExtractReasoningRecords(t *testing.T) {
+	path := writeSessionFile(t,
+		`{"timestamp":"2026-02-17T12:00:01Z","type":"event_msg","payload":{"type":"user_message","message":"why?"}}`,
+		`{"timestamp":"2026-02-17T12:00:02Z","type":"event_msg","payload":{"type":"agent_reasoning","text":"**Planning** check the parser"}}`,
+		`{"timestamp":"2026-02-17T12:00:03Z","type":"event_msg","payload":{"type":"agent_message","message":"because"}}`,
+	)
+
+	include, err := parseIncludeList("reasoning")
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, err := extractRecords(path, ExtractOptions{Include: include})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 || records[1].Role != "reasoning" || records[1].Text != "**Planning** check the parser" {
+		t.Fatalf("unexpected records: %#v", records)
+	}
+}
+
+func TestExtractErrorRecords(t *testing.T) {
+	path := writeSessionFile(t,
+		`{"timestamp":"2026-02-17T12:00:01Z","type":"event_msg","payload":{"type":"user_message","message":"go"}}`,
+		`{"timestamp":"2026-02-17T12:00:02Z","type":"event_msg","payload":{"type":"stream_error","message":"stream disconnected before completion"}}`,
+		`{"timestamp":"2026-02-17T12:00:03Z","type":"event_msg","payload":{"type":"error","message":"exceeded retry limit"}}`,
+		`{"timestamp":"2026-02-17T12:00:04Z","type":"event_msg","payload":{"type":"turn_aborted","reason":"interrupted"}}`,
+	)
+
+	records, err := extractRecords(path, ExtractOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 4 || records[1].Role != "error" || records[1].Meta["event"] != "stream_error" || records[3].Text != "turn aborted: interrupted" {
+		t.Fatalf("unexpected records: %#v", records)
+	}
+
+	stats := computeStats(records)
+	if stats.Errors != 3 || stats.User != 1 {
+		t.Fatalf("unexpected stats: %#v", stats)
+	}
+	summaries := buildSessionSummaries(records)
+	if len(summaries) != 1 || summaries[0].Errors != 3 {
+		t.Fatalf("unexpected summaries: %#v", summaries)
+	}
+}
+
+func TestExtractToolCallDetails(t *testing.T) {
+	path := writeSessionFile(t,
+		`{"timestamp":"2026-02-17T12:00:01Z","type":"response_item","payload":{"type":"function_call","name":"shell","call_id":"c1","arguments":"{\"command\":[\"bash\",\"-lc\",\"ls\"],\"workdir\":\"/repo\"}"}}`,
+		`{"timestamp":"2026-02-17T12:00:02Z","type":"response_item","payload":{"type":"function_call_output","call_id":"c1","output":"{\"output\":\"main.go\\n\",\"metadata\":{\"exit_code\":0}}"}}`,
+		`{"timestamp":"2026-02-17T12:00:03Z","type":"event_msg","payload":{"type":"exec_command_begin","call_id":"c2","command":["false"],"cwd":"/repo"}}`,
+		`{"timestamp":"2026-02-17T12:00:04Z","type":"event_msg","payload":{"type":"exec_command_end","call_id":"c2","aggregated_output":"`+strings.Repeat("x", toolOutputLimit+10)+`","exit_code":1}}`,
+		`{"timestamp":"2026-02-17T12:00:05Z","type":"response_item","payload":{"type":"function_call","name":"update_plan","call_id":"c3","arguments":"{\"plan\":[]}"}}`,
+	)
+
+	records, err := extractRecords(path, ExtractOptions{Include: includeSet{includeTools: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("expected 3 tool records, got %#v", records)
+	}
+
+	shell := records[0].Tool
+	if shell == nil || shell.Name != "shell" || string(shell.Arguments) != `{"command":["bash","-lc","ls"],"workdir":"/repo"}` ||
+		shell.Output != "main.go\n" || shell.ExitCode == nil || *shell.ExitCode != 0 {
+		t.Fatalf("unexpected shell call: %#v", shell)
+	}
+
+	failed := records[1].Tool
+	if failed == nil || failed.ExitCode == nil || *failed.ExitCode != 1 || !strings.Contains(failed.Output, "[truncated:") {
+		t.Fatalf("unexpected exec call: %#v", failed)
+	}
+
+	plan := records[2].Tool
+	if plan == nil || plan.Name != "update_plan" || string(plan.Arguments) != `{"plan":[]}` || plan.Output != "" {
+		t.Fatalf("unexpected function call: %#v", plan)
+	}
+}
+
+func TestExtractPassthroughEvents(t *testing.T) {
+	path := writeSessionFile(t,
+		`{"timestamp":"2026-02-17T12:00:01Z","type":"event_msg","payload":{"type":"user_message","message":"hi"}}`,
+		`{"timestamp":"2026-02-17T12:00:02Z","type":"future_event","payload":{"anything": true}}`,
+		`{"timestamp":"2026-02-17T12:00:03Z","type":"event_msg","payload":{"type":"plan_update","plan":[]}}`,
+		`{"type":"event_msg","payload":"not an object"}`,
+	)
+
+	records, err := extractRecords(path, ExtractOptions{Events: parseEventList("future_event,event_msg/plan_update", false)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 || records[1].Role != "event" || records[1].Text != "future_event" || string(records[1].Raw) != `{"anything":true}` {
+		t.Fatalf("unexpected records: %#v", records)
+	}
+	if records[2].Text != "event_msg/plan_update" || records[1].ID == records[2].ID {
+		t.Fatalf("unexpected plan record: %#v", records[2])
+	}
+	if records[1].ID != recordContentID(records[1]) {
+		t.Fatal("passthrough ID must cover the raw payload")
+	}
+
+	// --all-events takes every line that produced no other record, and
+	// dates undated lines with the previous timestamp; user messages are
+	// not duplicated.
+	records, err = extractRecords(path, ExtractOptions{Events: parseEventList("", true)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 4 || records[0].Role != "user" || records[3].Text != "event_msg" || records[3].Timestamp != records[2].Timestamp {
+		t.Fatalf("unexpected --all-events records: %#v", records)
+	}
+
+	if parseEventList(" ", false) != nil {
+		t.Fatal("expected passthrough to be off without types")
+	}
+}
+
+func TestExtractResponseItemMessages(t *testing.T) {
+	responseOnly := writeSessionFile(t,
+		`{"timestamp":"2026-02-17T12:00:00Z","type":"response_item","payload":{"type":"message","role":"user","content":[{"type":"input_text","text":"<environment_context>\n<cwd>/repo</cwd>\n</environment_context>"}]}}`,
+		`{"timestamp":"2026-02-17T12:00:01Z","type":"response_item","payload":{"type":"message","role":"developer","content":[{"type":"input_text","text":"system rules"}]}}`,
+		`{"timestamp":"2026-02-17T12:00:02Z","type":"response_item","payload":{"type":"message","role":"user","content":[{"type":"input_text","text":"fix the build"},{"type":"input_image","image_url":"/tmp/error.png"}]}}`,
+		`{"timestamp":"2026-02-17T12:00:03Z","type":"response_item","payload":{"type":"reasoning","summary":[],"content":null}}`,
+		`{"timestamp":"2026-02-17T12:00:04Z","type":"response_item","payload":{"type":"message","role":"assistant","content":[{"type":"output_text","text":"Fixed."}]}}`,
+	)
+	records, err := extractRecords(responseOnly, ExtractOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || records[0].Role != "user" || records[0].Text != "fix the build" || len(records[0].Attachments) != 1 ||
+		records[1].Role != "assistant" || records[1].Text != "Fixed." {
+		t.Fatalf("unexpected response_item records: %#v", records)
+	}
+
+	both := writeSessionFile(t,
+		`{"timestamp":"2026-02-17T12:00:02Z","type":"response_item","payload":{"type":"message","role":"user","content":[{"type":"input_text","text":"fix the build"}]}}`,
+		`{"timestamp":"2026-02-17T12:00:02Z","type":"event_msg","payload":{"type":"user_message","message":"fix the build"}}`,
+		`{"timestamp":"2026-02-17T12:00:04Z","type":"event_msg","payload":{"type":"agent_message","message":"Fixed."}}`,
+		`{"timestamp":"2026-02-17T12:00:04Z","type":"response_item","payload":{"type":"message","role":"assistant","content":[{"type":"output_text","text":"Fixed."}]}}`,
+	)
+	records, err = extractRecords(both, ExtractOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || records[0].SourceLine != 2 || records[1].SourceLine != 3 {
+		t.Fatalf("expected event_msg copies only, got %#v", records)
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// showFields are the fields show --fields can print, by name.
+var showFields = map[string]func(record Record, maxChars int) string{
+	"id":          func(r Record, _ int) string { return r.ID },
+	"session_id":  func(r Record, _ int) string { return r.SessionID },
+	"timestamp":   func(r Record, _ int) string { return r.Timestamp },
+	"role":        func(r Record, _ int) string { return r.Role },
+	"model":       func(r Record, _ int) string { return r.Meta["model"] },
+	"source_file": func(r Record, _ int) string { return r.SourceFile },
+	"source_line": func(r Record, _ int) string {
+		if r.SourceLine == 0 {
+			return ""
+		}
+		return strconv.Itoa(r.SourceLine)
+	},
+	// Text is kept on one line and free of tabs so every record is one row.
+	"text": func(r Record, maxChars int) string {
+		return strings.ReplaceAll(oneLine(r.Text, maxChars), "\t", `\t`)
+	},
+}
+
+// showFieldNames lists showFields in the order usage and errors show them.
+var showFieldNames = []string{"id", "session_id", "timestamp", "role", "model", "text", "source_file", "source_line"}
+
+// parseShowFields splits a comma-separated --fields list and checks every
+// name. "session" is accepted for session_id.
+func parseShowFields(spec string) ([]string, error) {
+	var fields []string
+	for _, name := range strings.Split(spec, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if name == "session" {
+			name = "session_id"
+		}
+		if _, ok := showFields[name]; !ok {
+			return nil, fmt.Errorf("unknown field %q (use %s)", name, strings.Join(showFieldNames, ", "))
+		}
+		fields = append(fields, name)
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("--fields needs at least one field")
+	}
+	return fields, nil
+}
+
+// formatFields joins the chosen fields of a record with tabs.
+func formatFields(record Record, fields []string, maxChars int) string {
+	values := make([]string, len(fields))
+	for i, name := range fields {
+		values[i] = showFields[name](record, maxChars)
+	}
+	return strings.Join(values, "\t")
+}
@@ -0,0 +1,20 @@
+package main
+
+import "testing"
+
+func TestFormatFields(t *testing.T) {
+	fields, err := parseShowFields("id, session,TIMESTAMP,text,source_line")
+	if err != nil {
+		t.Fatal(err)
+	}
+	record := Record{ID: "r1", SessionID: "s1", Timestamp: "2026-02-17T10:00:00Z", Text: "a\tb\nc"}
+	if got := formatFields(record, fields, 0); got != "r1\ts1\t2026-02-17T10:00:00Z\ta\\tb\\nc\t" {
+		t.Fatalf("unexpected row: %q", got)
+	}
+	if _, err := parseShowFields("id,bogus"); err == nil {
+		t.Fatal("expected an error for an unknown field")
+	}
+	if _, err := parseShowFields(" , "); err == nil {
+		t.Fatal("expected an error for an empty list")
+	}
+}
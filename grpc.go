@@ -0,0 +1,329 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"codex-history-cli/historypb"
+)
+
+const defaultGRPCAddr = "127.0.0.1:50051"
+
+func runGRPC(args []string) error {
+	fs := flag.NewFlagSet("grpc", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+
+	inputPath := fs.String("in", defaultOutputFile(), "History JSONL path")
+	sessionsDir := fs.String("sessions-dir", defaultSessionsDir(), "Codex sessions directory the Sync call reads")
+	addr := fs.String("addr", defaultGRPCAddr, "Address to listen on; keep it on localhost unless the network is trusted")
+	token := fs.String("token", "", "Require this bearer token on every call (default: $"+serveTokenEnv+")")
+	tlsCert := fs.String("tls-cert", "", "Serve over TLS with this certificate (PEM); needs --tls-key")
+	tlsKey := fs.String("tls-key", "", "Private key (PEM) for --tls-cert")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected argument %q", fs.Arg(0))
+	}
+	if (*tlsCert == "") != (*tlsKey == "") {
+		return errors.New("--tls-cert and --tls-key must be given together")
+	}
+	if strings.TrimSpace(*token) == "" {
+		*token = os.Getenv(serveTokenEnv)
+	}
+	*token = strings.TrimSpace(*token)
+
+	var creds credentials.TransportCredentials
+	if *tlsCert != "" {
+		loaded, err := credentials.NewServerTLSFromFile(*tlsCert, *tlsKey)
+		if err != nil {
+			return err
+		}
+		creds = loaded
+	}
+
+	listener, err := net.Listen("tcp", *addr)
+	if err != nil {
+		return err
+	}
+	server := newGRPCServer(*inputPath, *sessionsDir, *token, creds)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		// Let calls in flight finish, but not a stuck one forever.
+		stopped := make(chan struct{})
+		go func() {
+			server.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-time.After(5 * time.Second):
+			server.Stop()
+		}
+	}()
+
+	if !isLoopbackAddr(listener.Addr()) {
+		switch {
+		case *token == "":
+			fmt.Fprintln(os.Stderr, "warning: serving beyond localhost without --token; anyone who can connect can read the history")
+		case *tlsCert == "":
+			fmt.Fprintln(os.Stderr, "warning: serving beyond localhost without TLS; the token and history cross the network in the clear")
+		}
+	}
+	fmt.Printf("serving %s over gRPC (%s) at %s\n", *inputPath, historypb.History_ServiceDesc.ServiceName, listener.Addr())
+	return server.Serve(listener)
+}
+
+// newGRPCServer returns a server for the History service, over TLS when
+// creds is not nil.
+func newGRPCServer(historyPath, sessionsDir, token string, creds credentials.TransportCredentials) *grpc.Server {
+	var options []grpc.ServerOption
+	if creds != nil {
+		options = append(options, grpc.Creds(creds))
+	}
+	if token != "" {
+		options = append(options, grpc.UnaryInterceptor(grpcAuthorized(token)))
+	}
+	server := grpc.NewServer(options...)
+	historypb.RegisterHistoryServer(server, &grpcHistoryServer{historyPath: historyPath, sessionsDir: sessionsDir})
+	return server
+}
+
+// grpcAuthorized rejects calls without the server's token, as serve does
+// for its API.
+func grpcAuthorized(token string) grpc.UnaryServerInterceptor {
+	want := []byte("Bearer " + token)
+	return func(ctx context.Context, request any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		values := md.Get("authorization")
+		if len(values) != 1 || subtle.ConstantTimeCompare([]byte(values[0]), want) != 1 {
+			return nil, status.Error(codes.Unauthenticated, "missing or invalid token")
+		}
+		return handler(ctx, request)
+	}
+}
+
+// grpcHistoryServer answers the History service from the same helpers as
+// serve's JSON API, so the two return the same data. Every call reads the
+// history afresh.
+type grpcHistoryServer struct {
+	historypb.UnimplementedHistoryServer
+	historyPath string
+	sessionsDir string
+}
+
+func (s *grpcHistoryServer) ListSessions(ctx context.Context, request *historypb.ListSessionsRequest) (*historypb.ListSessionsResponse, error) {
+	if request.Limit < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid limit %d", request.Limit)
+	}
+	summaries, err := listSessions(s.historyPath, strings.TrimSpace(request.Project), int(request.Limit))
+	if err != nil {
+		return nil, grpcStatus(err)
+	}
+	response := &historypb.ListSessionsResponse{Sessions: make([]*historypb.SessionSummary, len(summaries))}
+	for i, summary := range summaries {
+		response.Sessions[i] = &historypb.SessionSummary{
+			SessionId:       summary.SessionID,
+			Total:           int64(summary.Total),
+			User:            int64(summary.User),
+			Assistant:       int64(summary.Assistant),
+			Other:           int64(summary.Other),
+			Errors:          int64(summary.Errors),
+			Chars:           int64(summary.Chars),
+			FirstTimestamp:  summary.FirstTimestamp,
+			LastTimestamp:   summary.LastTimestamp,
+			DurationSeconds: summary.DurationSeconds,
+			Usage:           grpcTokenUsage(summary.Usage),
+			Model:           summary.Model,
+			Cwd:             summary.Cwd,
+			Preview:         summary.Preview,
+		}
+	}
+	return response, nil
+}
+
+func (s *grpcHistoryServer) GetSession(ctx context.Context, request *historypb.GetSessionRequest) (*historypb.GetSessionResponse, error) {
+	id := strings.TrimSpace(request.SessionId)
+	if id == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "session_id is required")
+	}
+	records, err := sessionRecords(s.historyPath, id)
+	if err != nil {
+		return nil, grpcStatus(err)
+	}
+	response := &historypb.GetSessionResponse{Records: make([]*historypb.Record, len(records))}
+	for i, record := range records {
+		response.Records[i] = grpcRecord(record)
+	}
+	return response, nil
+}
+
+func (s *grpcHistoryServer) GetRecord(ctx context.Context, request *historypb.GetRecordRequest) (*historypb.Record, error) {
+	id := strings.TrimSpace(request.Id)
+	if id == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "id is required")
+	}
+	record, err := findRecord(s.historyPath, id)
+	if err != nil {
+		return nil, grpcStatus(err)
+	}
+	return grpcRecord(record), nil
+}
+
+func (s *grpcHistoryServer) Search(ctx context.Context, request *historypb.SearchRequest) (*historypb.SearchResponse, error) {
+	query := strings.TrimSpace(request.Query)
+	if query == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "query is required")
+	}
+	limit := int(request.Limit)
+	switch {
+	case limit < 0:
+		return nil, status.Errorf(codes.InvalidArgument, "invalid limit %d", limit)
+	case limit == 0:
+		limit = defaultSearchLimit
+	}
+	hits, err := searchHistory(s.historyPath, query, limit)
+	if err != nil {
+		return nil, grpcStatus(err)
+	}
+	response := &historypb.SearchResponse{Hits: make([]*historypb.SearchHit, len(hits))}
+	for i, hit := range hits {
+		response.Hits[i] = &historypb.SearchHit{Id: hit.ID, SessionId: hit.SessionID, Timestamp: hit.Timestamp, Role: hit.Role, Snippet: hit.Snippet}
+	}
+	return response, nil
+}
+
+func (s *grpcHistoryServer) GetStats(ctx context.Context, request *historypb.GetStatsRequest) (*historypb.Stats, error) {
+	from, err := parseBoundTime(request.From, "from")
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "%v", err)
+	}
+	to, err := parseBoundTime(request.To, "to")
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "%v", err)
+	}
+	stats, err := filteredStats(s.historyPath, RecordFilter{
+		SessionID: strings.TrimSpace(request.SessionId),
+		Role:      strings.TrimSpace(request.Role),
+		Model:     strings.TrimSpace(request.Model),
+		Project:   strings.TrimSpace(request.Project),
+		From:      from,
+		To:        to,
+	})
+	if err != nil {
+		return nil, grpcStatus(err)
+	}
+	return &historypb.Stats{
+		Total:          int64(stats.Total),
+		User:           int64(stats.User),
+		Assistant:      int64(stats.Assistant),
+		Other:          int64(stats.Other),
+		Errors:         int64(stats.Errors),
+		SessionCount:   int64(stats.SessionCount),
+		FirstTimestamp: stats.FirstTimestamp,
+		LastTimestamp:  stats.LastTimestamp,
+		Usage:          grpcTokenUsage(stats.Usage),
+	}, nil
+}
+
+// Sync runs one sync of the server's sessions directory into its history,
+// with sync's defaults; the history lock keeps it from interleaving with a
+// running watch.
+func (s *grpcHistoryServer) Sync(ctx context.Context, request *historypb.SyncRequest) (*historypb.SyncResponse, error) {
+	result, err := syncOnce(SyncOptions{
+		SessionsDir:  s.sessionsDir,
+		OutputPath:   s.historyPath,
+		DryRun:       request.DryRun,
+		BlockSecrets: request.BlockSecrets,
+	})
+	if err != nil {
+		return nil, grpcStatus(err)
+	}
+	response := &historypb.SyncResponse{
+		Files:   int64(result.Files),
+		Scanned: int64(result.Scanned),
+		Written: int64(result.Written),
+		Secrets: make([]*historypb.SecretFinding, len(result.Secrets)),
+	}
+	for i, finding := range result.Secrets {
+		response.Secrets[i] = &historypb.SecretFinding{RecordId: finding.RecordID, SessionId: finding.SessionID, Kinds: finding.Kinds}
+	}
+	return response, nil
+}
+
+// grpcStatus maps the errors the history helpers return to status codes,
+// as serve maps them to HTTP statuses.
+func grpcStatus(err error) error {
+	switch {
+	case errors.Is(err, errSessionNotFound), errors.Is(err, errRecordNotFound):
+		return status.Errorf(codes.NotFound, "%v", err)
+	case errors.Is(err, errAmbiguousPrefix):
+		return status.Errorf(codes.InvalidArgument, "%v", err)
+	case errors.Is(err, os.ErrNotExist):
+		return status.Errorf(codes.FailedPrecondition, "%v", err)
+	}
+	return status.Errorf(codes.Internal, "%v", err)
+}
+
+func grpcRecord(record Record) *historypb.Record {
+	out := &historypb.Record{
+		Id:         record.ID,
+		SessionId:  record.SessionID,
+		Timestamp:  record.Timestamp,
+		Role:       record.Role,
+		Text:       record.Text,
+		SourceFile: record.SourceFile,
+		SourceLine: int64(record.SourceLine),
+		Meta:       record.Meta,
+		RawJson:    string(record.Raw),
+	}
+	for _, attachment := range record.Attachments {
+		out.Attachments = append(out.Attachments, &historypb.Attachment{
+			Type:   attachment.Type,
+			Mime:   attachment.MIME,
+			Path:   attachment.Path,
+			Sha256: attachment.SHA256,
+			Bytes:  int64(attachment.Bytes),
+		})
+	}
+	if tool := record.Tool; tool != nil {
+		out.Tool = &historypb.ToolCall{Name: tool.Name, CallId: tool.CallID, ArgumentsJson: string(tool.Arguments), Output: tool.Output}
+		if tool.ExitCode != nil {
+			code := int32(*tool.ExitCode)
+			out.Tool.ExitCode = &code
+		}
+	}
+	return out
+}
+
+func grpcTokenUsage(usage *TokenUsage) *historypb.TokenUsage {
+	if usage == nil {
+		return nil
+	}
+	return &historypb.TokenUsage{
+		InputTokens:           usage.InputTokens,
+		CachedInputTokens:     usage.CachedInputTokens,
+		OutputTokens:          usage.OutputTokens,
+		ReasoningOutputTokens: usage.ReasoningOutputTokens,
+		TotalTokens:           usage.TotalTokens,
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"codex-history-cli/historypb"
+)
+
+func TestGRPCServer(t *testing.T) {
+	root := t.TempDir()
+	sessionsRoot := filepath.Join(root, "sessions")
+	start := time.Date(2026, 2, 15, 9, 0, 0, 0, time.UTC)
+	if _, err := generateDemo(DemoOptions{OutDir: sessionsRoot, Days: 1, SessionsPerDay: 2, Start: start, Seed: 5}); err != nil {
+		t.Fatal(err)
+	}
+	historyPath := filepath.Join(root, "history.jsonl")
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := newGRPCServer(historyPath, sessionsRoot, "s3cret", nil)
+	go server.Serve(listener)
+	defer server.Stop()
+
+	conn, err := grpc.NewClient(listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	client := historypb.NewHistoryClient(conn)
+
+	if _, err := client.ListSessions(context.Background(), &historypb.ListSessionsRequest{}); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("expected Unauthenticated without the token, got %v", err)
+	}
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer s3cret")
+	if _, err := client.ListSessions(ctx, &historypb.ListSessionsRequest{}); status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("expected FailedPrecondition before the first sync, got %v", err)
+	}
+
+	synced, err := client.Sync(ctx, &historypb.SyncRequest{})
+	if err != nil || synced.Files != 2 || synced.Written == 0 {
+		t.Fatalf("Sync = %+v, %v", synced, err)
+	}
+	if again, err := client.Sync(ctx, &historypb.SyncRequest{}); err != nil || again.Written != 0 {
+		t.Fatalf("second Sync = %+v, %v", again, err)
+	}
+
+	sessions, err := client.ListSessions(ctx, &historypb.ListSessionsRequest{Limit: 1})
+	if err != nil || len(sessions.Sessions) != 1 {
+		t.Fatalf("ListSessions = %+v, %v", sessions, err)
+	}
+	want, err := listSessions(historyPath, "", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := sessions.Sessions[0]
+	if got.SessionId != want[0].SessionID || got.Total != int64(want[0].Total) || got.Preview != want[0].Preview {
+		t.Fatalf("ListSessions disagrees with sessions --json: %+v vs %+v", got, want[0])
+	}
+
+	session, err := client.GetSession(ctx, &historypb.GetSessionRequest{SessionId: got.SessionId[:8]})
+	if err != nil || int64(len(session.Records)) != got.Total {
+		t.Fatalf("GetSession returned %d records, %v", len(session.Records), err)
+	}
+	first := session.Records[0]
+	record, err := client.GetRecord(ctx, &historypb.GetRecordRequest{Id: first.Id})
+	if err != nil || record.Text != first.Text || record.SessionId != got.SessionId {
+		t.Fatalf("GetRecord = %+v, %v", record, err)
+	}
+	if _, err := client.GetRecord(ctx, &historypb.GetRecordRequest{Id: "ffffffffffff"}); status.Code(err) != codes.NotFound {
+		t.Fatalf("expected NotFound, got %v", err)
+	}
+
+	word := firstWord(first.Text)
+	found, err := client.Search(ctx, &historypb.SearchRequest{Query: word})
+	if err != nil || len(found.Hits) == 0 {
+		t.Fatalf("Search(%q) = %+v, %v", word, found, err)
+	}
+	if _, err := client.Search(ctx, &historypb.SearchRequest{}); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected InvalidArgument for an empty query, got %v", err)
+	}
+
+	stats, err := client.GetStats(ctx, &historypb.GetStatsRequest{Role: "user"})
+	if err != nil || stats.Total == 0 || stats.Total != stats.User || stats.SessionCount != 2 {
+		t.Fatalf("GetStats = %+v, %v", stats, err)
+	}
+	if _, err := client.GetStats(ctx, &historypb.GetStatsRequest{From: "yesterday"}); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected InvalidArgument for a bad bound, got %v", err)
+	}
+}
+
+func firstWord(text string) string {
+	for i, r := range text {
+		if r == ' ' || r == '\n' {
+			return text[:i]
+		}
+	}
+	return text
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// HealthReport is the body of /healthz and /readyz on watch and serve, and
+// what the status command prints. Fields only watch knows are left out of
+// serve's reports.
+type HealthReport struct {
+	Status    string `json:"status"` // "ok" or "fail"
+	Component string `json:"component"`
+	StartedAt string `json:"started_at"`
+	// LastSync is the end of the last successful sync.
+	LastSync  string `json:"last_sync,omitempty"`
+	LastError string `json:"last_error,omitempty"`
+	// Backlog counts session files changed since sync last read them.
+	Backlog        *int     `json:"backlog,omitempty"`
+	OutputPath     string   `json:"output_path,omitempty"`
+	OutputWritable *bool    `json:"output_writable,omitempty"`
+	Problems       []string `json:"problems,omitempty"`
+}
+
+// watchHealth tracks watch's cycles for its health endpoints. A watcher is
+// live while cycles keep finishing, and ready once a sync has succeeded
+// recently and the history can be written.
+type watchHealth struct {
+	mu          sync.Mutex
+	sessionsDir string
+	outputPath  string
+	stuckAfter  time.Duration
+	started     time.Time
+	lastCycle   time.Time
+	lastSuccess time.Time
+	lastError   string
+}
+
+// newWatchHealth considers a watcher stuck after three missed cycles, and
+// never sooner than a minute, so one slow sync does not fail the probes.
+// interval is the longest wait between cycles, --max-interval.
+func newWatchHealth(sessionsDir, outputPath string, interval time.Duration, now time.Time) *watchHealth {
+	return &watchHealth{
+		sessionsDir: sessionsDir,
+		outputPath:  outputPath,
+		stuckAfter:  max(3*interval, time.Minute),
+		started:     now,
+	}
+}
+
+// reconfigure follows a config reload.
+func (h *watchHealth) reconfigure(sessionsDir string, interval time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.sessionsDir = sessionsDir
+	h.stuckAfter = max(3*interval, time.Minute)
+}
+
+func (h *watchHealth) recordCycle(now time.Time, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.lastCycle = now
+	if err != nil {
+		h.lastError = err.Error()
+		return
+	}
+	h.lastError = ""
+	h.lastSuccess = now
+}
+
+// report checks liveness, and with ready the readiness conditions as well.
+// Only a stalled loop fails liveness: restarting the watcher will not make
+// the history writable.
+func (h *watchHealth) report(now time.Time, ready bool) HealthReport {
+	h.mu.Lock()
+	report := HealthReport{
+		Component:  "watch",
+		StartedAt:  h.started.UTC().Format(time.RFC3339),
+		LastError:  h.lastError,
+		OutputPath: h.outputPath,
+	}
+	lastCycle, lastSuccess := h.lastCycle, h.lastSuccess
+	sessionsDir, stuckAfter := h.sessionsDir, h.stuckAfter
+	h.mu.Unlock()
+
+	if !lastSuccess.IsZero() {
+		report.LastSync = lastSuccess.UTC().Format(time.RFC3339)
+	}
+	if lastCycle.IsZero() {
+		lastCycle = h.started
+	}
+	if since := now.Sub(lastCycle); since > stuckAfter {
+		report.Problems = append(report.Problems, fmt.Sprintf("no sync cycle finished in %s", since.Round(time.Second)))
+	}
+	backlog, backlogErr := syncBacklog(sessionsDir, h.outputPath)
+	if backlogErr == nil {
+		report.Backlog = &backlog
+	}
+	writeErr := checkWritable(h.outputPath)
+	writable := writeErr == nil
+	report.OutputWritable = &writable
+	if ready {
+		if backlogErr != nil {
+			report.Problems = append(report.Problems, "backlog: "+backlogErr.Error())
+		}
+		if writeErr != nil {
+			report.Problems = append(report.Problems, "output not writable: "+writeErr.Error())
+		}
+		switch {
+		case lastSuccess.IsZero():
+			report.Problems = append(report.Problems, "no successful sync yet")
+		case now.Sub(lastSuccess) > stuckAfter:
+			report.Problems = append(report.Problems, "last successful sync was "+now.Sub(lastSuccess).Round(time.Second).String()+" ago")
+		}
+	}
+	report.Status = "ok"
+	if len(report.Problems) > 0 {
+		report.Status = "fail"
+	}
+	return report
+}
+
+// handler serves /healthz (ready false) or /readyz (ready true).
+func (h *watchHealth) handler(ready bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeHealth(w, h.report(time.Now(), ready))
+	}
+}
+
+// writeHealth answers 200 when the report is ok and 503 otherwise, the
+// convention load balancers and orchestrators probe for.
+func writeHealth(w http.ResponseWriter, report HealthReport) {
+	status := http.StatusOK
+	if report.Status != "ok" {
+		status = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.Encode(report)
+}
+
+// syncBacklog counts the session files that are new or were modified after
+// sync last scanned them, according to the sources state. Scan times have
+// one-second resolution, so a file written in the second it was scanned
+// counts until the next cycle.
+func syncBacklog(sessionsDir, outputPath string) (int, error) {
+	files, err := listSessionFiles(sessionsDir)
+	if err != nil {
+		return 0, err
+	}
+	state, err := loadSourcesState(sourcesStatePath(outputPath))
+	if err != nil {
+		return 0, err
+	}
+	backlog := 0
+	for _, path := range files {
+		entry, ok := state[path]
+		if !ok {
+			backlog++
+			continue
+		}
+		scanned, err := time.Parse(time.RFC3339, entry.ScannedAt)
+		if err != nil {
+			backlog++
+			continue
+		}
+		if info, err := os.Stat(path); err == nil && info.ModTime().Truncate(time.Second).After(scanned) {
+			backlog++
+		}
+	}
+	return backlog, nil
+}
+
+// checkWritable reports whether path can be appended to, or created when
+// it does not exist yet, without changing it.
+func checkWritable(path string) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err == nil {
+		return file.Close()
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	probe, err := os.CreateTemp(filepath.Dir(path), ".writable-*")
+	if err != nil {
+		return err
+	}
+	probe.Close()
+	return os.Remove(probe.Name())
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSyncBacklog(t *testing.T) {
+	dir := t.TempDir()
+	sessionsDir := filepath.Join(dir, "sessions")
+	if err := os.MkdirAll(sessionsDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	scanned := filepath.Join(sessionsDir, "scanned.jsonl")
+	changed := filepath.Join(sessionsDir, "changed.jsonl")
+	for _, path := range []string{scanned, changed, filepath.Join(sessionsDir, "new.jsonl")} {
+		if err := os.WriteFile(path, []byte("{}\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	scanTime := time.Now().Add(-time.Hour).UTC()
+	os.Chtimes(scanned, scanTime.Add(-time.Minute), scanTime.Add(-time.Minute))
+	output := filepath.Join(dir, "history.jsonl")
+	state := map[string]SourceState{
+		scanned: {ScannedAt: scanTime.Format(time.RFC3339)},
+		changed: {ScannedAt: scanTime.Format(time.RFC3339)},
+	}
+	if err := saveSourcesState(sourcesStatePath(output), state); err != nil {
+		t.Fatal(err)
+	}
+
+	backlog, err := syncBacklog(sessionsDir, output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if backlog != 2 {
+		t.Fatalf("expected the changed and new files in the backlog, got %d", backlog)
+	}
+}
+
+func TestWatchHealth(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Date(2026, 2, 17, 10, 0, 0, 0, time.UTC)
+	health := newWatchHealth(filepath.Join(dir, "sessions"), filepath.Join(dir, "history.jsonl"), 5*time.Second, start)
+
+	live := health.report(start.Add(10*time.Second), false)
+	ready := health.report(start.Add(10*time.Second), true)
+	if live.Status != "ok" || ready.Status != "fail" || !strings.Contains(strings.Join(ready.Problems, ";"), "no successful sync yet") {
+		t.Fatalf("before the first sync: live=%+v ready=%+v", live, ready)
+	}
+	if ready.Backlog == nil || *ready.Backlog != 0 || ready.OutputWritable == nil || !*ready.OutputWritable {
+		t.Fatalf("unexpected report: %+v", ready)
+	}
+
+	health.recordCycle(start.Add(20*time.Second), nil)
+	if report := health.report(start.Add(30*time.Second), true); report.Status != "ok" || report.LastSync != "2026-02-17T10:00:20Z" {
+		t.Fatalf("after a sync: %+v", report)
+	}
+
+	health.recordCycle(start.Add(25*time.Second), errors.New("disk full"))
+	stuck := health.report(start.Add(5*time.Minute), false)
+	if stuck.Status != "fail" || stuck.LastError != "disk full" {
+		t.Fatalf("expected a stalled watcher to fail liveness: %+v", stuck)
+	}
+}
+
+func TestFetchHealth(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/readyz" {
+			http.NotFound(w, r)
+			return
+		}
+		backlog := 3
+		writeHealth(w, HealthReport{Status: "fail", Component: "watch", Backlog: &backlog, Problems: []string{"no successful sync yet"}})
+	}))
+	defer server.Close()
+
+	report, err := fetchHealth(server.Client(), server.URL+"/readyz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out strings.Builder
+	printHealthReport(&out, report)
+	want := "status=fail component=watch started_at= backlog=3\nproblem: no successful sync yet\n"
+	if out.String() != want {
+		t.Fatalf("unexpected output:\n%s", out.String())
+	}
+	if _, err := fetchHealth(server.Client(), server.URL+"/missing"); err == nil {
+		t.Fatal("expected an error for a non-health response")
+	}
+}
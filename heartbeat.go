@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"time"
+)
+
+// Heartbeat is the small status document watch rewrites after every cycle so
+// external monitors can check the recorder is alive without parsing logs.
+type Heartbeat struct {
+	PID                 int    `json:"pid"`
+	SessionsDir         string `json:"sessions_dir"`
+	OutputPath          string `json:"output_path"`
+	StartedAt           string `json:"started_at"`
+	LastCycle           string `json:"last_cycle"`
+	LastSuccess         string `json:"last_success,omitempty"`
+	LastError           string `json:"last_error,omitempty"`
+	Day                 string `json:"day"`
+	RecordsWrittenToday int    `json:"records_written_today"`
+}
+
+func defaultHeartbeatFile() string {
+	if appConfig.Watch.Heartbeat != "" {
+		return appConfig.Watch.Heartbeat
+	}
+	return strings.TrimSuffix(defaultOutputFile(), ".jsonl") + ".heartbeat.json"
+}
+
+func loadHeartbeat(path string) (Heartbeat, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return Heartbeat{}, nil
+		}
+		return Heartbeat{}, err
+	}
+
+	var hb Heartbeat
+	if err := json.Unmarshal(data, &hb); err != nil {
+		// A damaged heartbeat is simply replaced on the next cycle.
+		return Heartbeat{}, nil
+	}
+	return hb, nil
+}
+
+// update folds one sync cycle into the heartbeat. The daily counter resets
+// when the UTC date changes.
+func (hb *Heartbeat) update(now time.Time, written int, syncErr error) {
+	day := now.UTC().Format("2006-01-02")
+	if hb.Day != day {
+		hb.Day = day
+		hb.RecordsWrittenToday = 0
+	}
+
+	hb.LastCycle = now.UTC().Format(time.RFC3339)
+	if syncErr != nil {
+		hb.LastError = syncErr.Error()
+		return
+	}
+	hb.LastError = ""
+	hb.LastSuccess = hb.LastCycle
+	hb.RecordsWrittenToday += written
+}
+
+func writeHeartbeat(path string, hb Heartbeat) error {
+	data, err := json.MarshalIndent(hb, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, append(data, '\n'))
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestHeartbeatUpdateAndRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hb", "history.heartbeat.json")
+
+	day1 := time.Date(2026, 2, 17, 23, 59, 0, 0, time.UTC)
+	var hb Heartbeat
+	hb.update(day1, 3, nil)
+	hb.update(day1.Add(10*time.Second), 2, errors.New("boom"))
+	if hb.RecordsWrittenToday != 3 || hb.LastError != "boom" {
+		t.Fatalf("unexpected heartbeat after error: %#v", hb)
+	}
+	if hb.LastSuccess != "2026-02-17T23:59:00Z" || hb.LastCycle != "2026-02-17T23:59:10Z" {
+		t.Fatalf("unexpected heartbeat timestamps: %#v", hb)
+	}
+
+	hb.update(day1.Add(2*time.Minute), 4, nil)
+	if hb.Day != "2026-02-18" || hb.RecordsWrittenToday != 4 || hb.LastError != "" {
+		t.Fatalf("expected daily counter reset: %#v", hb)
+	}
+
+	if err := writeHeartbeat(path, hb); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := loadHeartbeat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded != hb {
+		t.Fatalf("heartbeat round trip mismatch: %#v vs %#v", loaded, hb)
+	}
+}
@@ -0,0 +1,6 @@
+// Package historypb is the gRPC API of codex-history: the messages, server
+// interface, and client generated from history.proto by protoc-gen-go and
+// protoc-gen-go-grpc.
+package historypb
+
+//go:generate buf generate
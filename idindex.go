@@ -0,0 +1,188 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// idKeySize is the width of one entry in the sidecar index. Record IDs are
+// 128-bit hex strings, so standard IDs are stored losslessly.
+const idKeySize = 16
+
+type idKey [idKeySize]byte
+
+// idSet holds record IDs in their compact binary form.
+type idSet map[idKey]struct{}
+
+func keyForID(id string) idKey {
+	var key idKey
+	if len(id) == 2*idKeySize {
+		if _, err := hex.Decode(key[:], []byte(id)); err == nil {
+			return key
+		}
+	}
+	// Non-standard IDs (hand-edited or imported) are hashed to fit.
+	sum := sha256.Sum256([]byte(id))
+	copy(key[:], sum[:idKeySize])
+	return key
+}
+
+func (s idSet) has(id string) bool {
+	_, ok := s[keyForID(id)]
+	return ok
+}
+
+func (s idSet) add(id string) {
+	s[keyForID(id)] = struct{}{}
+}
+
+// idIndexMeta pins the sidecar index to the exact history file it was built
+// from; any rewrite of the history changes size or mtime and forces a rebuild.
+type idIndexMeta struct {
+	HistorySize    int64 `json:"history_size"`
+	HistoryModTime int64 `json:"history_mod_time"`
+	Entries        int64 `json:"entries"`
+}
+
+func idIndexPath(historyPath string) string {
+	return strings.TrimSuffix(historyPath, ".jsonl") + ".ids"
+}
+
+func idIndexMetaPath(historyPath string) string {
+	return idIndexPath(historyPath) + ".json"
+}
+
+// loadIDIndex returns the set of IDs already in the history, reading the
+// sidecar index when it is current and rescanning the history otherwise.
+// With persist set, a rebuilt index is written back for the next run.
+func loadIDIndex(historyPath string, persist bool) (idSet, error) {
+	info, err := os.Stat(historyPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return make(idSet), nil
+		}
+		return nil, err
+	}
+
+	if ids, ok := readIDIndex(historyPath, info); ok {
+		return ids, nil
+	}
+
+	ids, err := loadExistingIDs(historyPath)
+	if err != nil {
+		return nil, err
+	}
+	if persist {
+		if err := writeIDIndex(historyPath, ids); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: failed to write id index: %v\n", err)
+		}
+	}
+	return ids, nil
+}
+
+func readIDIndex(historyPath string, info os.FileInfo) (idSet, bool) {
+	metaData, err := os.ReadFile(idIndexMetaPath(historyPath))
+	if err != nil {
+		return nil, false
+	}
+	var meta idIndexMeta
+	if err := json.Unmarshal(metaData, &meta); err != nil {
+		return nil, false
+	}
+	if meta.HistorySize != info.Size() || meta.HistoryModTime != info.ModTime().UnixNano() {
+		return nil, false
+	}
+
+	data, err := os.ReadFile(idIndexPath(historyPath))
+	if err != nil || int64(len(data)) != meta.Entries*idKeySize {
+		return nil, false
+	}
+
+	ids := make(idSet, meta.Entries)
+	for offset := 0; offset < len(data); offset += idKeySize {
+		var key idKey
+		copy(key[:], data[offset:offset+idKeySize])
+		ids[key] = struct{}{}
+	}
+	return ids, true
+}
+
+func writeIDIndex(historyPath string, ids idSet) error {
+	data := make([]byte, 0, len(ids)*idKeySize)
+	for key := range ids {
+		data = append(data, key[:]...)
+	}
+	if err := writeFileAtomic(idIndexPath(historyPath), data); err != nil {
+		return err
+	}
+	return writeIDIndexMeta(historyPath, int64(len(ids)))
+}
+
+// appendIDIndex records IDs just appended to the history. The index is only
+// extended when it was current before the append; otherwise it is left stale
+// and rebuilt on the next load.
+func appendIDIndex(historyPath string, before os.FileInfo, records []Record) error {
+	if before == nil {
+		return rebuildIDIndexFromRecords(historyPath, records)
+	}
+	metaData, err := os.ReadFile(idIndexMetaPath(historyPath))
+	if err != nil {
+		return nil
+	}
+	var meta idIndexMeta
+	if err := json.Unmarshal(metaData, &meta); err != nil {
+		return nil
+	}
+	if meta.HistorySize != before.Size() || meta.HistoryModTime != before.ModTime().UnixNano() {
+		return nil
+	}
+
+	file, err := os.OpenFile(idIndexPath(historyPath), os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	data := make([]byte, 0, len(records)*idKeySize)
+	for _, record := range records {
+		key := keyForID(record.ID)
+		data = append(data, key[:]...)
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return writeIDIndexMeta(historyPath, meta.Entries+int64(len(records)))
+}
+
+// rebuildIDIndexFromRecords starts an index for a history that did not exist
+// before this append, so it contains exactly records.
+func rebuildIDIndexFromRecords(historyPath string, records []Record) error {
+	ids := make(idSet, len(records))
+	for _, record := range records {
+		ids.add(record.ID)
+	}
+	return writeIDIndex(historyPath, ids)
+}
+
+func writeIDIndexMeta(historyPath string, entries int64) error {
+	info, err := os.Stat(historyPath)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(idIndexMeta{
+		HistorySize:    info.Size(),
+		HistoryModTime: info.ModTime().UnixNano(),
+		Entries:        entries,
+	})
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(idIndexMetaPath(historyPath), append(data, '\n'))
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestIDIndexTracksAppendsAndRebuildsWhenStale(t *testing.T) {
+	root := t.TempDir()
+	sessionsRoot := filepath.Join(root, "sessions")
+	start := time.Date(2026, 2, 15, 9, 0, 0, 0, time.UTC)
+	if _, err := generateDemo(DemoOptions{OutDir: sessionsRoot, Days: 1, SessionsPerDay: 2, Start: start, Seed: 5}); err != nil {
+		t.Fatal(err)
+	}
+
+	outPath := filepath.Join(root, "history.jsonl")
+	first, err := syncOnce(SyncOptions{SessionsDir: sessionsRoot, OutputPath: outPath})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(outPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids, ok := readIDIndex(outPath, info)
+	if !ok || len(ids) != first.Written {
+		t.Fatalf("expected current index with %d ids, got ok=%v len=%d", first.Written, ok, len(ids))
+	}
+
+	// A second day of sessions extends the index in place.
+	if _, err := generateDemo(DemoOptions{OutDir: sessionsRoot, Days: 1, SessionsPerDay: 1, Start: start.AddDate(0, 0, 1), Seed: 6}); err != nil {
+		t.Fatal(err)
+	}
+	second, err := syncOnce(SyncOptions{SessionsDir: sessionsRoot, OutputPath: outPath})
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, _ = os.Stat(outPath)
+	ids, ok = readIDIndex(outPath, info)
+	if !ok || len(ids) != first.Written+second.Written {
+		t.Fatalf("expected extended index, got ok=%v len=%d", ok, len(ids))
+	}
+
+	// Rewriting the history invalidates the index; the next load rescans.
+	if err := rewriteHistory(outPath, func(record Record) (Record, bool) { return record, record.Role == "user" }); err != nil {
+		t.Fatal(err)
+	}
+	info, _ = os.Stat(outPath)
+	if _, ok := readIDIndex(outPath, info); ok {
+		t.Fatal("expected index to be stale after rewrite")
+	}
+	rebuilt, err := loadIDIndex(outPath, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, err := loadRecords(outPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rebuilt) != len(records) {
+		t.Fatalf("expected rebuilt index of %d ids, got %d", len(records), len(rebuilt))
+	}
+}
+
+func TestKeyForIDHandlesNonHexIDs(t *testing.T) {
+	ids := make(idSet)
+	ids.add("a2b6f0f9f8fdb7611c4eae45fcfd2947")
+	ids.add("custom-id")
+	if !ids.has("a2b6f0f9f8fdb7611c4eae45fcfd2947") || !ids.has("custom-id") || ids.has("other") {
+		t.Fatalf("unexpected id set membership: %#v", ids)
+	}
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// importer turns one foreign history source into records. Records must
+// have deterministic IDs so re-importing the same data is a no-op.
+type importer struct {
+	name        string
+	description string
+	defaultIn   func() string
+	load        func(path string, opts importOptions) ([]Record, error)
+	// usesMap marks importers that need --map.
+	usesMap bool
+}
+
+type importOptions struct {
+	Mapping FieldMapping
+}
+
+var importers = []importer{
+	{
+		name:        "codex-legacy",
+		description: "history.json (or history.jsonl) written by older Codex releases",
+		defaultIn:   func() string { return filepath.Join(codexHome(), "history.json") },
+		load:        ignoreImportOptions(loadCodexLegacyHistory),
+	},
+	{
+		name:        "claude-code",
+		description: "Claude Code transcripts (the projects directory, a project, or one .jsonl)",
+		defaultIn:   defaultClaudeProjectsDir,
+		load:        ignoreImportOptions(loadClaudeTranscripts),
+	},
+	{
+		name:        "cursor",
+		description: "Cursor's User directory or one state.vscdb (needs sqlite3)",
+		defaultIn:   defaultCursorUserDir,
+		load:        ignoreImportOptions(loadCursorChats),
+	},
+	{
+		name:        "generic",
+		description: "any JSONL file or directory of them, mapped by --map",
+		defaultIn:   func() string { return "" },
+		load:        loadGenericJSONL,
+		usesMap:     true,
+	},
+}
+
+func ignoreImportOptions(load func(path string) ([]Record, error)) func(string, importOptions) ([]Record, error) {
+	return func(path string, _ importOptions) ([]Record, error) {
+		return load(path)
+	}
+}
+
+func findImporter(name string) (importer, bool) {
+	for _, candidate := range importers {
+		if candidate.name == name {
+			return candidate, true
+		}
+	}
+	return importer{}, false
+}
+
+func importerNames() string {
+	names := make([]string, len(importers))
+	for i, candidate := range importers {
+		names[i] = candidate.name
+	}
+	return strings.Join(names, ", ")
+}
+
+func runImport(args []string) error {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return fmt.Errorf("import needs a source: %s", importerNames())
+	}
+	source, ok := findImporter(args[0])
+	if !ok {
+		return fmt.Errorf("unknown import source %q (want %s)", args[0], importerNames())
+	}
+
+	fs := flag.NewFlagSet("import "+source.name, flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+
+	inputPath := fs.String("in", source.defaultIn(), "Path to import from: "+source.description)
+	outPath := fs.String("out", defaultOutputFile(), "Output JSONL path")
+	dryRun := fs.Bool("dry-run", false, "Count records without writing")
+	var mapPath *string
+	if source.usesMap {
+		mapPath = fs.String("map", "", "Field mapping file (JSON, or flat YAML key: value lines)")
+	}
+
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if strings.TrimSpace(*inputPath) == "" {
+		return errors.New("--in is required")
+	}
+
+	var opts importOptions
+	if mapPath != nil {
+		if strings.TrimSpace(*mapPath) == "" {
+			return errors.New("--map is required")
+		}
+		mapping, err := loadFieldMapping(*mapPath)
+		if err != nil {
+			return err
+		}
+		opts.Mapping = mapping
+	}
+
+	records, err := source.load(*inputPath, opts)
+	if err != nil {
+		return fmt.Errorf("failed to import %s: %w", *inputPath, err)
+	}
+	for i := range records {
+		if records[i].Meta == nil {
+			records[i].Meta = make(map[string]string, 1)
+		}
+		records[i].Meta["source"] = source.name
+	}
+
+	written, err := mergeRecords(*outPath, records, *dryRun)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("source=%s scanned=%d new=%d output=%s\n", source.name, len(records), written, *outPath)
+	return nil
+}
+
+// mergeRecords appends the records not already in the history, oldest
+// first, and returns how many that was. Records removed on purpose
+// (delete, redact, retention, archive) are not re-imported, as with sync.
+func mergeRecords(outPath string, records []Record, dryRun bool) (int, error) {
+	if !dryRun {
+		unlock, err := lockHistory(outPath)
+		if err != nil {
+			return 0, err
+		}
+		defer unlock()
+	}
+
+	existing, err := loadIDIndex(outPath, !dryRun)
+	if err != nil {
+		return 0, err
+	}
+	removed, err := loadRemovedIDs(outPath)
+	if err != nil {
+		return 0, err
+	}
+	for key := range removed {
+		existing[key] = struct{}{}
+	}
+
+	newRecords := make([]Record, 0, len(records))
+	for _, record := range records {
+		if existing.has(record.ID) {
+			continue
+		}
+		existing.add(record.ID)
+		newRecords = append(newRecords, record)
+	}
+	if dryRun || len(newRecords) == 0 {
+		return len(newRecords), nil
+	}
+	sort.SliceStable(newRecords, func(i, j int) bool {
+		return compareTimestamp(newRecords[i].Timestamp, newRecords[j].Timestamp) < 0
+	})
+
+	before, err := os.Stat(outPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return 0, err
+	}
+	if err := appendRecords(outPath, newRecords, false); err != nil {
+		return 0, err
+	}
+	if err := appendIDIndex(outPath, before, newRecords); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: failed to update id index: %v\n", err)
+	}
+	return len(newRecords), nil
+}
+
+// newImportedRecord builds a record from imported fields, normalizing text
+// and timestamp the same way sync does.
+func newImportedRecord(sessionID, timestamp, role, text, sourceFile string, sourceLine int) (Record, bool) {
+	text = strings.TrimSpace(sanitizeText(text))
+	if text == "" || sessionID == "" || role == "" {
+		return Record{}, false
+	}
+	timestamp = normalizeTimestamp(timestamp)
+	return Record{
+		ID:         makeRecordID(sessionID, timestamp, role, text),
+		SessionID:  sessionID,
+		Timestamp:  timestamp,
+		Role:       role,
+		Text:       text,
+		SourceFile: sourceFile,
+		SourceLine: sourceLine,
+	}, true
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// claudeTranscriptLine is one line of a Claude Code transcript under
+// ~/.claude/projects/<project>/<session>.jsonl. Only "user" and
+// "assistant" lines carry conversation; the rest are summaries and
+// bookkeeping.
+type claudeTranscriptLine struct {
+	Type        string         `json:"type"`
+	UUID        string         `json:"uuid"`
+	SessionID   string         `json:"sessionId"`
+	Timestamp   string         `json:"timestamp"`
+	Cwd         string         `json:"cwd"`
+	IsMeta      bool           `json:"isMeta"`
+	IsSidechain bool           `json:"isSidechain"`
+	Message     *claudeMessage `json:"message"`
+}
+
+type claudeMessage struct {
+	Role    string          `json:"role"`
+	Model   string          `json:"model"`
+	Content json.RawMessage `json:"content"`
+}
+
+// claudeContentBlock covers the blocks of an array-valued content; only
+// text blocks become record text. Tool use, tool results, and thinking
+// are left out the way sync leaves them out without --include.
+type claudeContentBlock struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// claudeMessageText returns the text of a message whose content is either a
+// plain string or an array of blocks.
+func claudeMessageText(raw json.RawMessage) string {
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return text
+	}
+	var blocks []claudeContentBlock
+	if err := json.Unmarshal(raw, &blocks); err != nil {
+		return ""
+	}
+	parts := make([]string, 0, len(blocks))
+	for _, block := range blocks {
+		if block.Type == "text" {
+			parts = append(parts, block.Text)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// jsonlFiles lists the .jsonl files under path, or path itself when it is a
+// file.
+func jsonlFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+	var files []string
+	err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && strings.HasSuffix(d.Name(), ".jsonl") {
+			files = append(files, p)
+		}
+		return nil
+	})
+	sort.Strings(files)
+	return files, err
+}
+
+// loadClaudeTranscripts reads Claude Code transcripts. Records keep Claude's
+// session ID and note the project (the transcript's directory name) and
+// working directory in meta. A resumed session repeats earlier messages
+// under their original uuid, so messages are deduplicated on it.
+func loadClaudeTranscripts(path string) ([]Record, error) {
+	files, err := jsonlFiles(path)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	var records []Record
+	for _, file := range files {
+		fileRecords, err := loadClaudeTranscript(file, seen)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		records = append(records, fileRecords...)
+	}
+	return records, nil
+}
+
+func loadClaudeTranscript(path string, seen map[string]bool) ([]Record, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	project := filepath.Base(filepath.Dir(path))
+	var records []Record
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), scannerMaxTokenSize)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		var line claudeTranscriptLine
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			// Transcripts are appended live, so a torn last line is expected.
+			continue
+		}
+		if (line.Type != "user" && line.Type != "assistant") || line.Message == nil || line.IsMeta || line.IsSidechain {
+			continue
+		}
+		if line.UUID != "" {
+			if seen[line.UUID] {
+				continue
+			}
+			seen[line.UUID] = true
+		}
+
+		sessionID := line.SessionID
+		if sessionID == "" {
+			sessionID = sessionIDFromPath(path)
+		}
+		role := line.Message.Role
+		if role == "" {
+			role = line.Type
+		}
+		record, ok := newImportedRecord(sessionID, line.Timestamp, role, claudeMessageText(line.Message.Content), path, lineNo)
+		if !ok {
+			continue
+		}
+		record.Meta = map[string]string{"project": project}
+		if line.Cwd != "" {
+			record.Meta["cwd"] = line.Cwd
+		}
+		if line.Message.Model != "" {
+			record.Meta["model"] = line.Message.Model
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+func defaultClaudeProjectsDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(".claude", "projects")
+	}
+	return filepath.Join(home, ".claude", "projects")
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"codex-history-cli/internal/history"
+)
+
+// Cursor keeps chats in VS Code style state.vscdb SQLite databases. Older
+// releases store the chat panel's tabs as one JSON value in ItemTable;
+// newer ones store each composer conversation under composerData:<id> in
+// cursorDiskKV, with message bodies either inline or under
+// bubbleId:<composer>:<bubble>.
+const cursorChatDataKey = "workbench.panel.aichat.view.aichat.chatdata"
+
+type cursorChatData struct {
+	Tabs []struct {
+		TabID        string         `json:"tabId"`
+		LastSendTime int64          `json:"lastSendTime"`
+		Bubbles      []cursorBubble `json:"bubbles"`
+	} `json:"tabs"`
+}
+
+type cursorComposer struct {
+	ComposerID   string         `json:"composerId"`
+	CreatedAt    int64          `json:"createdAt"`
+	Conversation []cursorBubble `json:"conversation"`
+	Headers      []cursorBubble `json:"fullConversationHeadersOnly"`
+}
+
+// cursorBubble is one message. Type is "user"/"ai" in chat tabs and 1/2 in
+// composer data, so it is kept raw.
+type cursorBubble struct {
+	BubbleID  string          `json:"bubbleId"`
+	ID        string          `json:"id"`
+	Type      json.RawMessage `json:"type"`
+	Text      string          `json:"text"`
+	RawText   string          `json:"rawText"`
+	CreatedAt json.RawMessage `json:"createdAt"`
+}
+
+func (b cursorBubble) role() string {
+	switch strings.Trim(string(b.Type), `"`) {
+	case "1", "user":
+		return "user"
+	case "2", "ai":
+		return "assistant"
+	}
+	return ""
+}
+
+func (b cursorBubble) text() string {
+	if b.Text != "" {
+		return b.Text
+	}
+	return b.RawText
+}
+
+// timestamp returns the bubble's own time when it has one. Most bubbles do
+// not, so callers fall back to the conversation start plus the bubble's
+// position, which keeps messages ordered and IDs stable across imports.
+func (b cursorBubble) timestamp(start time.Time, index int) string {
+	var millis int64
+	if err := json.Unmarshal(b.CreatedAt, &millis); err == nil && millis > 0 {
+		return time.UnixMilli(millis).UTC().Format(time.RFC3339Nano)
+	}
+	var text string
+	if err := json.Unmarshal(b.CreatedAt, &text); err == nil && text != "" {
+		return text
+	}
+	return start.Add(time.Duration(index) * time.Millisecond).UTC().Format(time.RFC3339Nano)
+}
+
+// cursorDatabases lists the state databases under path, which may be
+// Cursor's User directory or a single state.vscdb.
+func cursorDatabases(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+	var files []string
+	err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && d.Name() == "state.vscdb" {
+			files = append(files, p)
+		}
+		return nil
+	})
+	sort.Strings(files)
+	return files, err
+}
+
+// loadCursorChats reads every chat in the Cursor state databases under path
+// using the sqlite3 command-line tool.
+func loadCursorChats(path string) ([]Record, error) {
+	databases, err := cursorDatabases(path)
+	if err != nil {
+		return nil, err
+	}
+	var records []Record
+	for _, db := range databases {
+		dbRecords, err := loadCursorDatabase(db)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", db, err)
+		}
+		records = append(records, dbRecords...)
+	}
+	return records, nil
+}
+
+func loadCursorDatabase(db string) ([]Record, error) {
+	cli := history.NewSQLiteCLI(db)
+	tables, err := cli.QueryJSON("SELECT name FROM sqlite_master WHERE type = 'table';")
+	if err != nil {
+		return nil, err
+	}
+	has := make(map[string]bool, len(tables))
+	for _, row := range tables {
+		if name, ok := row["name"].(string); ok {
+			has[name] = true
+		}
+	}
+
+	cwd := cursorWorkspaceFolder(filepath.Dir(db))
+	var records []Record
+	add := func(sessionID, timestamp string, bubble cursorBubble) {
+		record, ok := newImportedRecord(sessionID, timestamp, bubble.role(), bubble.text(), db, 0)
+		if !ok {
+			return
+		}
+		if cwd != "" {
+			record.Meta = map[string]string{"cwd": cwd}
+		}
+		records = append(records, record)
+	}
+
+	if has["ItemTable"] {
+		rows, err := cli.QueryJSON("SELECT CAST(value AS TEXT) AS value FROM ItemTable WHERE key = '" + cursorChatDataKey + "';")
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			var data cursorChatData
+			value, _ := row["value"].(string)
+			if err := json.Unmarshal([]byte(value), &data); err != nil {
+				continue
+			}
+			for _, tab := range data.Tabs {
+				start := time.UnixMilli(tab.LastSendTime)
+				for i, bubble := range tab.Bubbles {
+					add("cursor-"+tab.TabID, bubble.timestamp(start, i), bubble)
+				}
+			}
+		}
+	}
+
+	if has["cursorDiskKV"] {
+		rows, err := cli.QueryJSON("SELECT key, CAST(value AS TEXT) AS value FROM cursorDiskKV WHERE key LIKE 'composerData:%' OR key LIKE 'bubbleId:%';")
+		if err != nil {
+			return nil, err
+		}
+		bubbles := make(map[string]cursorBubble)
+		var composers []cursorComposer
+		for _, row := range rows {
+			key, _ := row["key"].(string)
+			value, _ := row["value"].(string)
+			if strings.HasPrefix(key, "bubbleId:") {
+				var bubble cursorBubble
+				if err := json.Unmarshal([]byte(value), &bubble); err == nil {
+					bubbles[strings.TrimPrefix(key, "bubbleId:")] = bubble
+				}
+				continue
+			}
+			var composer cursorComposer
+			if err := json.Unmarshal([]byte(value), &composer); err == nil && composer.ComposerID != "" {
+				composers = append(composers, composer)
+			}
+		}
+		for _, composer := range composers {
+			conversation := composer.Conversation
+			if len(conversation) == 0 {
+				for _, header := range composer.Headers {
+					if bubble, ok := bubbles[composer.ComposerID+":"+header.BubbleID]; ok {
+						conversation = append(conversation, bubble)
+					}
+				}
+			}
+			start := time.UnixMilli(composer.CreatedAt)
+			for i, bubble := range conversation {
+				add("cursor-"+composer.ComposerID, bubble.timestamp(start, i), bubble)
+			}
+		}
+	}
+	return records, nil
+}
+
+// cursorWorkspaceFolder returns the folder a workspace database belongs to,
+// from the workspace.json Cursor writes beside it.
+func cursorWorkspaceFolder(dir string) string {
+	data, err := os.ReadFile(filepath.Join(dir, "workspace.json"))
+	if err != nil {
+		return ""
+	}
+	var workspace struct {
+		Folder string `json:"folder"`
+	}
+	if err := json.Unmarshal(data, &workspace); err != nil || workspace.Folder == "" {
+		return ""
+	}
+	folder, err := url.Parse(workspace.Folder)
+	if err != nil || folder.Scheme != "file" {
+		return workspace.Folder
+	}
+	return folder.Path
+}
+
+func defaultCursorUserDir() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return filepath.Join(".config", "Cursor", "User")
+	}
+	return filepath.Join(dir, "Cursor", "User")
+}
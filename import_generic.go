@@ -0,0 +1,235 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FieldMapping tells the generic importer where each record field lives in
+// a foreign JSONL line. Values are dotted paths into the line's JSON object
+// ("message.content"); array elements are addressed by index ("parts.0").
+type FieldMapping struct {
+	Session   string `json:"session"`
+	Timestamp string `json:"timestamp"`
+	Role      string `json:"role"`
+	Text      string `json:"text"`
+	// Roles renames role values, e.g. {"human": "user", "bot": "assistant"}.
+	Roles map[string]string `json:"roles,omitempty"`
+}
+
+// loadFieldMapping reads a mapping file. JSON is accepted, and so is the
+// flat "key: value" subset of YAML, with a "roles:" block of indented
+// "from: to" pairs.
+func loadFieldMapping(path string) (FieldMapping, error) {
+	var mapping FieldMapping
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return mapping, err
+	}
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		if err := json.Unmarshal(trimmed, &mapping); err != nil {
+			return mapping, fmt.Errorf("invalid mapping %s: %w", path, err)
+		}
+	} else if err := parseFlatMapping(data, &mapping); err != nil {
+		return mapping, fmt.Errorf("invalid mapping %s: %w", path, err)
+	}
+	if mapping.Timestamp == "" || mapping.Role == "" || mapping.Text == "" {
+		return mapping, fmt.Errorf("mapping %s must set timestamp, role, and text", path)
+	}
+	return mapping, nil
+}
+
+func parseFlatMapping(data []byte, mapping *FieldMapping) error {
+	inRoles := false
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := scanner.Text()
+		if i := strings.Index(line, " #"); i >= 0 {
+			line = line[:i]
+		}
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(strings.TrimSpace(line), ":")
+		if !ok {
+			return fmt.Errorf("line %d: want key: value", lineNo)
+		}
+		key = unquoteMappingValue(key)
+		value = unquoteMappingValue(value)
+
+		indented := line[0] == ' ' || line[0] == '\t'
+		if inRoles && indented {
+			if mapping.Roles == nil {
+				mapping.Roles = make(map[string]string)
+			}
+			mapping.Roles[key] = value
+			continue
+		}
+		inRoles = false
+		switch key {
+		case "session":
+			mapping.Session = value
+		case "timestamp":
+			mapping.Timestamp = value
+		case "role":
+			mapping.Role = value
+		case "text":
+			mapping.Text = value
+		case "roles":
+			if value != "" {
+				return fmt.Errorf("line %d: roles must be an indented block", lineNo)
+			}
+			inRoles = true
+		default:
+			return fmt.Errorf("line %d: unknown field %q", lineNo, key)
+		}
+	}
+	return scanner.Err()
+}
+
+func unquoteMappingValue(value string) string {
+	value = strings.TrimSpace(value)
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
+
+// lookupPath follows a dotted path through decoded JSON.
+func lookupPath(value any, path string) (any, bool) {
+	if path == "" {
+		return nil, false
+	}
+	for _, part := range strings.Split(path, ".") {
+		switch node := value.(type) {
+		case map[string]any:
+			next, ok := node[part]
+			if !ok {
+				return nil, false
+			}
+			value = next
+		case []any:
+			index, err := strconv.Atoi(part)
+			if err != nil || index < 0 || index >= len(node) {
+				return nil, false
+			}
+			value = node[index]
+		default:
+			return nil, false
+		}
+	}
+	return value, value != nil
+}
+
+// mappedText flattens a mapped value to text. Arrays are joined by line,
+// which covers content given as a list of strings or of {"text": ...} parts.
+func mappedText(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	case []any:
+		parts := make([]string, 0, len(v))
+		for _, item := range v {
+			if part := mappedText(item); part != "" {
+				parts = append(parts, part)
+			}
+		}
+		return strings.Join(parts, "\n")
+	case map[string]any:
+		if text, ok := v["text"]; ok {
+			return mappedText(text)
+		}
+	}
+	return ""
+}
+
+// mappedTimestamp accepts timestamp strings and Unix times in seconds or
+// milliseconds.
+func mappedTimestamp(value any) string {
+	if number, ok := value.(json.Number); ok {
+		if f, err := number.Float64(); err == nil {
+			return epochTime(f).Format(time.RFC3339Nano)
+		}
+	}
+	return mappedText(value)
+}
+
+// loadGenericJSONL imports JSONL files under path using opts.Mapping. Lines
+// without text, role, or timestamp are skipped; lines without a session
+// fall into one named after the file.
+func loadGenericJSONL(path string, opts importOptions) ([]Record, error) {
+	files, err := jsonlFiles(path)
+	if err != nil {
+		return nil, err
+	}
+	var records []Record
+	for _, file := range files {
+		fileRecords, err := loadGenericFile(file, opts.Mapping)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		records = append(records, fileRecords...)
+	}
+	return records, nil
+}
+
+func loadGenericFile(path string, mapping FieldMapping) ([]Record, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	fallbackSession := sessionIDFromPath(path)
+	var records []Record
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), scannerMaxTokenSize)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		decoder := json.NewDecoder(bytes.NewReader(scanner.Bytes()))
+		decoder.UseNumber()
+		var line any
+		if err := decoder.Decode(&line); err != nil {
+			continue
+		}
+
+		sessionID := fallbackSession
+		if value, ok := lookupPath(line, mapping.Session); ok {
+			if text := mappedText(value); text != "" {
+				sessionID = text
+			}
+		}
+		timestamp, _ := lookupPath(line, mapping.Timestamp)
+		role, _ := lookupPath(line, mapping.Role)
+		text, _ := lookupPath(line, mapping.Text)
+
+		roleName := mappedText(role)
+		if renamed, ok := mapping.Roles[roleName]; ok {
+			roleName = renamed
+		}
+		ts := mappedTimestamp(timestamp)
+		if _, ok := parseRecordTime(ts); !ok {
+			continue
+		}
+		if record, ok := newImportedRecord(sessionID, ts, roleName, mappedText(text), path, lineNo); ok {
+			records = append(records, record)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
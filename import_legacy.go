@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+)
+
+// legacySessionGap splits legacy history into synthesized sessions: entries
+// further apart than this start a new one.
+const legacySessionGap = time.Hour
+
+// legacyHistoryEntry covers both legacy shapes: the history.json array of
+// {"command", "timestamp"} written by the TypeScript CLI (milliseconds) and
+// the history.jsonl lines of {"session_id", "ts", "text"} (seconds).
+type legacyHistoryEntry struct {
+	Command   string  `json:"command"`
+	Timestamp float64 `json:"timestamp"`
+	SessionID string  `json:"session_id"`
+	TS        float64 `json:"ts"`
+	Text      string  `json:"text"`
+}
+
+func (e legacyHistoryEntry) time() time.Time {
+	if e.Timestamp != 0 {
+		return epochTime(e.Timestamp)
+	}
+	return epochTime(e.TS)
+}
+
+// epochTime reads a Unix time in seconds or milliseconds. Millisecond
+// epochs are past 1e12; second epochs will not be for another few thousand
+// years.
+func epochTime(value float64) time.Time {
+	if value > 1e12 {
+		return time.UnixMilli(int64(value)).UTC()
+	}
+	return time.UnixMilli(int64(value * 1000)).UTC()
+}
+
+func (e legacyHistoryEntry) text() string {
+	if e.Command != "" {
+		return e.Command
+	}
+	return e.Text
+}
+
+// loadCodexLegacyHistory reads a legacy prompt history. Legacy files only
+// record what the user typed, so every record is a user message. Entries
+// without a session ID get one synthesized from the start of their burst
+// of activity, which stays stable as the file grows.
+func loadCodexLegacyHistory(path string) ([]Record, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := parseLegacyHistory(data)
+	if err != nil {
+		return nil, err
+	}
+
+	order := make([]int, len(entries))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return entries[order[i]].time().Before(entries[order[j]].time())
+	})
+
+	records := make([]Record, 0, len(entries))
+	var sessionID string
+	var last time.Time
+	for _, index := range order {
+		entry := entries[index]
+		at := entry.time()
+		if sessionID == "" || at.Sub(last) > legacySessionGap {
+			sessionID = "legacy-" + at.Format("20060102T150405Z")
+		}
+		last = at
+
+		id := entry.SessionID
+		if id == "" {
+			id = sessionID
+		}
+		record, ok := newImportedRecord(id, at.Format(time.RFC3339Nano), "user", entry.text(), path, index+1)
+		if ok {
+			records = append(records, record)
+		}
+	}
+	return records, nil
+}
+
+func parseLegacyHistory(data []byte) ([]legacyHistoryEntry, error) {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 {
+		return nil, nil
+	}
+	if trimmed[0] == '[' {
+		var entries []legacyHistoryEntry
+		if err := json.Unmarshal(trimmed, &entries); err != nil {
+			return nil, fmt.Errorf("invalid legacy history: %w", err)
+		}
+		return entries, nil
+	}
+
+	var entries []legacyHistoryEntry
+	scanner := bufio.NewScanner(bytes.NewReader(trimmed))
+	scanner.Buffer(make([]byte, 64*1024), scannerMaxTokenSize)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var entry legacyHistoryEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return nil, fmt.Errorf("invalid legacy history line %d: %w", lineNo, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestImportCodexLegacyHistory(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "history.json")
+	content := `[
+  {"command": "second burst", "timestamp": 1767261600000},
+  {"command": "first prompt", "timestamp": 1767254400000},
+  {"command": "follow-up", "timestamp": 1767254700000},
+  {"command": "  ", "timestamp": 1767254800000}
+]`
+	if err := os.WriteFile(input, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := loadCodexLegacyHistory(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("expected 3 records, got %+v", records)
+	}
+	if records[0].Text != "first prompt" || records[0].Timestamp != "2026-01-01T08:00:00Z" || records[0].Role != "user" {
+		t.Fatalf("unexpected first record: %+v", records[0])
+	}
+	if records[0].SessionID != "legacy-20260101T080000Z" || records[1].SessionID != records[0].SessionID {
+		t.Fatalf("expected the first two prompts in one session, got %q and %q", records[0].SessionID, records[1].SessionID)
+	}
+	if records[2].SessionID != "legacy-20260101T100000Z" {
+		t.Fatalf("expected a new session after the gap, got %q", records[2].SessionID)
+	}
+
+	output := filepath.Join(dir, "out.jsonl")
+	written, err := mergeRecords(output, records, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := mergeRecords(output, records, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if written != 3 || again != 0 {
+		t.Fatalf("expected 3 then 0 new records, got %d then %d", written, again)
+	}
+}
+
+func TestImportCodexLegacyJSONL(t *testing.T) {
+	input := filepath.Join(t.TempDir(), "history.jsonl")
+	content := `{"session_id":"abc","ts":1767254400,"text":"hello"}` + "\n"
+	if err := os.WriteFile(input, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := loadCodexLegacyHistory(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].SessionID != "abc" || records[0].Timestamp != "2026-01-01T08:00:00Z" {
+		t.Fatalf("unexpected records: %+v", records)
+	}
+}
+
+func TestImportClaudeTranscripts(t *testing.T) {
+	root := t.TempDir()
+	projectDir := filepath.Join(root, "-home-me-app")
+	if err := os.MkdirAll(projectDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	lines := []string{
+		`{"type":"summary","summary":"Fix the build"}`,
+		`{"type":"user","uuid":"u1","sessionId":"s1","timestamp":"2026-01-01T08:00:00.000Z","cwd":"/home/me/app","message":{"role":"user","content":"fix the build"}}`,
+		`{"type":"assistant","uuid":"a1","sessionId":"s1","timestamp":"2026-01-01T08:00:05.000Z","cwd":"/home/me/app","message":{"role":"assistant","model":"claude-sonnet-4","content":[{"type":"thinking","thinking":"hmm"},{"type":"text","text":"Done."},{"type":"tool_use","id":"t1","name":"Bash","input":{}}]}}`,
+		`{"type":"user","uuid":"u2","sessionId":"s1","timestamp":"2026-01-01T08:00:06.000Z","message":{"role":"user","content":[{"type":"tool_result","tool_use_id":"t1","content":"ok"}]}}`,
+		`{"type":"user","uuid":"u3","sessionId":"s1","timestamp":"2026-01-01T08:00:07.000Z","isMeta":true,"message":{"role":"user","content":"<command-name>/clear</command-name>"}}`,
+	}
+	writeLines := func(name string, lines ...string) {
+		t.Helper()
+		content := ""
+		for _, line := range lines {
+			content += line + "\n"
+		}
+		if err := os.WriteFile(filepath.Join(projectDir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeLines("s1.jsonl", lines...)
+	// A resumed session repeats the earlier messages before its own.
+	writeLines("s2.jsonl", append(lines[1:3],
+		`{"type":"user","uuid":"u4","sessionId":"s2","timestamp":"2026-01-01T09:00:00.000Z","message":{"role":"user","content":"and the tests"}}`)...)
+
+	records, err := loadClaudeTranscripts(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("expected 3 records, got %+v", records)
+	}
+	if records[0].SessionID != "s1" || records[0].Role != "user" || records[0].Meta["project"] != "-home-me-app" || records[0].Meta["cwd"] != "/home/me/app" {
+		t.Fatalf("unexpected first record: %+v", records[0])
+	}
+	if records[1].Text != "Done." || records[1].Meta["model"] != "claude-sonnet-4" {
+		t.Fatalf("unexpected assistant record: %+v", records[1])
+	}
+	if records[2].SessionID != "s2" || records[2].Text != "and the tests" {
+		t.Fatalf("unexpected resumed record: %+v", records[2])
+	}
+}
+
+func TestImportCursorChats(t *testing.T) {
+	if _, err := exec.LookPath("sqlite3"); err != nil {
+		t.Skip("sqlite3 not available")
+	}
+	dir := filepath.Join(t.TempDir(), "workspaceStorage", "abc123")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "workspace.json"), []byte(`{"folder":"file:///home/me/app"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	db := filepath.Join(dir, "state.vscdb")
+	script := strings.Join([]string{
+		`CREATE TABLE ItemTable (key TEXT UNIQUE ON CONFLICT REPLACE, value BLOB);`,
+		`CREATE TABLE cursorDiskKV (key TEXT UNIQUE ON CONFLICT REPLACE, value BLOB);`,
+		`INSERT INTO ItemTable VALUES ('workbench.panel.aichat.view.aichat.chatdata', '{"tabs":[{"tabId":"tab1","lastSendTime":1767254400000,"bubbles":[{"type":"user","id":"b1","text":"explain this"},{"type":"ai","id":"b2","rawText":"It parses flags."}]}]}');`,
+		`INSERT INTO cursorDiskKV VALUES ('composerData:c1', '{"composerId":"c1","createdAt":1767258000000,"fullConversationHeadersOnly":[{"bubbleId":"x","type":1},{"bubbleId":"y","type":2}]}');`,
+		`INSERT INTO cursorDiskKV VALUES ('bubbleId:c1:x', '{"bubbleId":"x","type":1,"text":"add a test"}');`,
+		`INSERT INTO cursorDiskKV VALUES ('bubbleId:c1:y', '{"bubbleId":"y","type":2,"text":"Added.","createdAt":"2026-01-01T09:00:09Z"}');`,
+	}, "\n")
+	cmd := exec.Command("sqlite3", db)
+	cmd.Stdin = strings.NewReader(script)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("sqlite3: %v: %s", err, out)
+	}
+
+	records, err := loadCursorChats(filepath.Dir(filepath.Dir(dir)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 4 {
+		t.Fatalf("expected 4 records, got %+v", records)
+	}
+	if records[0].SessionID != "cursor-tab1" || records[0].Role != "user" || records[0].Timestamp != "2026-01-01T08:00:00Z" || records[0].Meta["cwd"] != "/home/me/app" {
+		t.Fatalf("unexpected first record: %+v", records[0])
+	}
+	if records[1].Role != "assistant" || records[1].Text != "It parses flags." || records[1].Timestamp != "2026-01-01T08:00:00.001Z" {
+		t.Fatalf("unexpected second record: %+v", records[1])
+	}
+	if records[2].SessionID != "cursor-c1" || records[2].Text != "add a test" || records[3].Timestamp != "2026-01-01T09:00:09Z" {
+		t.Fatalf("unexpected composer records: %+v", records[2:])
+	}
+}
+
+func TestImportGenericJSONL(t *testing.T) {
+	dir := t.TempDir()
+	mappingPath := filepath.Join(dir, "mapping.yaml")
+	mapping := strings.Join([]string{
+		"# my assistant's log format",
+		"session: conv.id",
+		"timestamp: ts",
+		"role: author",
+		`text: "message.parts"`,
+		"roles:",
+		"  human: user",
+		"  bot: assistant",
+	}, "\n")
+	if err := os.WriteFile(mappingPath, []byte(mapping), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	input := filepath.Join(dir, "chat.jsonl")
+	lines := strings.Join([]string{
+		`{"conv":{"id":"c1"},"ts":1767254400,"author":"human","message":{"parts":["hello",{"text":"there"}]}}`,
+		`{"conv":{"id":"c1"},"ts":"2026-01-01T08:00:02Z","author":"bot","message":{"parts":["hi"]}}`,
+		`{"ts":1767254403000,"author":"human","message":{"parts":["no session"]}}`,
+		`{"conv":{"id":"c1"},"author":"bot","message":{"parts":["no timestamp"]}}`,
+	}, "\n")
+	if err := os.WriteFile(input, []byte(lines), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err := loadFieldMapping(mappingPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, err := loadGenericJSONL(input, importOptions{Mapping: parsed})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("expected 3 records, got %+v", records)
+	}
+	if records[0].SessionID != "c1" || records[0].Role != "user" || records[0].Text != "hello\nthere" || records[0].Timestamp != "2026-01-01T08:00:00Z" {
+		t.Fatalf("unexpected first record: %+v", records[0])
+	}
+	if records[1].Role != "assistant" || records[2].SessionID != "chat" || records[2].Timestamp != "2026-01-01T08:00:03Z" {
+		t.Fatalf("unexpected records: %+v", records[1:])
+	}
+
+	if _, err := loadFieldMapping(input); err == nil {
+		t.Fatal("expected a JSONL file to be rejected as a mapping")
+	}
+
+	// A record deleted after import stays deleted when the source is
+	// imported again.
+	output := filepath.Join(dir, "history.jsonl")
+	if written, err := mergeRecords(output, records, false); err != nil || written != 3 {
+		t.Fatalf("first import wrote %d, %v", written, err)
+	}
+	purged, err := removeRecords(output, newRecordMatcher(RecordFilter{Contains: "there"}))
+	if err != nil || purged != 1 {
+		t.Fatalf("deleted %d, %v", purged, err)
+	}
+	if written, err := mergeRecords(output, records, false); err != nil || written != 0 {
+		t.Fatalf("re-import wrote %d, %v", written, err)
+	}
+}
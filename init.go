@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// CodexInstall is what init could find out about the local Codex setup.
+type CodexInstall struct {
+	Home         string
+	HomeExists   bool
+	SessionsDir  string
+	SessionFiles int
+	Binary       string
+}
+
+func detectCodexInstall() CodexInstall {
+	install := CodexInstall{
+		Home:        codexHome(),
+		SessionsDir: defaultSessionsDir(),
+	}
+	if info, err := os.Stat(install.Home); err == nil && info.IsDir() {
+		install.HomeExists = true
+	}
+	if files, err := listSessionFiles(install.SessionsDir); err == nil {
+		install.SessionFiles = len(files)
+	}
+	if path, err := exec.LookPath("codex"); err == nil {
+		install.Binary = path
+	}
+	return install
+}
+
+// prompter asks questions on out and reads answers from in. With assumeYes
+// set every question silently takes its proposed default.
+type prompter struct {
+	in        *bufio.Reader
+	out       io.Writer
+	assumeYes bool
+}
+
+func (p prompter) ask(question, proposed string) (string, error) {
+	if p.assumeYes {
+		return proposed, nil
+	}
+	fmt.Fprintf(p.out, "%s [%s]: ", question, proposed)
+	answer, err := p.in.ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	answer = strings.TrimSpace(answer)
+	if answer == "" {
+		return proposed, nil
+	}
+	return answer, nil
+}
+
+func (p prompter) confirm(question string, proposed bool) (bool, error) {
+	def := "y/N"
+	if proposed {
+		def = "Y/n"
+	}
+	for {
+		answer, err := p.ask(question, def)
+		if err != nil {
+			return false, err
+		}
+		if answer == def {
+			return proposed, nil
+		}
+		switch strings.ToLower(answer) {
+		case "y", "yes":
+			return true, nil
+		case "n", "no":
+			return false, nil
+		}
+		fmt.Fprintln(p.out, "please answer y or n")
+	}
+}
+
+func runInit(args []string) error {
+	fs := flag.NewFlagSet("init", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+
+	configPath := fs.String("config", defaultConfigPath(), "Config file to write")
+	assumeYes := fs.Bool("yes", false, "Accept all proposed defaults without prompting")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	return initWizard(prompter{in: bufio.NewReader(os.Stdin), out: os.Stdout, assumeYes: *assumeYes}, *configPath, currentServiceManager)
+}
+
+// initWizard walks through the setup. services finds the service manager
+// the last step offers to install watch under; tests pass a fake.
+func initWizard(p prompter, configPath string, services func() (serviceManager, error)) error {
+	install := detectCodexInstall()
+
+	fmt.Fprintln(p.out, "codex-history setup")
+	if install.HomeExists {
+		fmt.Fprintf(p.out, "  found Codex home %s (%d session files)\n", install.Home, install.SessionFiles)
+	} else {
+		fmt.Fprintf(p.out, "  Codex home %s does not exist yet\n", install.Home)
+	}
+	if install.Binary != "" {
+		fmt.Fprintf(p.out, "  found codex binary %s\n", install.Binary)
+	} else {
+		fmt.Fprintln(p.out, "  codex binary not found on PATH")
+	}
+
+	existing, err := loadConfig(configPath)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(configPath); err == nil {
+		overwrite, err := p.confirm(fmt.Sprintf("config %s exists, update it?", configPath), true)
+		if err != nil {
+			return err
+		}
+		if !overwrite {
+			fmt.Fprintln(p.out, "leaving existing config unchanged")
+			return nil
+		}
+	}
+
+	cfg := existing
+	if cfg.SessionsDir, err = p.ask("Sessions directory", install.SessionsDir); err != nil {
+		return err
+	}
+	if cfg.Output, err = p.ask("History output file", defaultOutputFile()); err != nil {
+		return err
+	}
+
+	for {
+		interval, err := p.ask("Watch interval", defaultWatchInterval().String())
+		if err != nil {
+			return err
+		}
+		if parsed, err := time.ParseDuration(interval); err == nil && parsed > 0 {
+			cfg.Watch.Interval = parsed.String()
+			break
+		}
+		fmt.Fprintf(p.out, "invalid duration %q, e.g. 5s or 1m\n", interval)
+	}
+	if cfg.Watch.RelativeSources, err = p.confirm("Store source paths relative to the sessions directory?", cfg.Watch.RelativeSources); err != nil {
+		return err
+	}
+
+	if err := saveConfig(configPath, cfg); err != nil {
+		return err
+	}
+	fmt.Fprintf(p.out, "wrote %s\n", configPath)
+
+	syncNow, err := p.confirm("Run an initial sync now?", true)
+	if err != nil {
+		return err
+	}
+	if syncNow {
+		result, err := syncOnce(SyncOptions{
+			SessionsDir:     cfg.SessionsDir,
+			OutputPath:      cfg.Output,
+			RelativeSources: cfg.Watch.RelativeSources,
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(p.out, "files=%d scanned=%d new=%d output=%s\n", result.Files, result.Scanned, result.Written, cfg.Output)
+	}
+
+	manager, err := services()
+	if err != nil {
+		fmt.Fprintf(p.out, "background service not available: %v\n", err)
+		fmt.Fprintln(p.out, "start recording with: codex-history watch")
+		return nil
+	}
+	installNow, err := p.confirm(fmt.Sprintf("Install a %s service that runs watch in the background?", manager.name), false)
+	if err != nil {
+		return err
+	}
+	if !installNow {
+		fmt.Fprintln(p.out, "start recording with: codex-history watch (or codex-history service install)")
+		return nil
+	}
+	spec, err := newServiceSpec(nil)
+	if err != nil {
+		return err
+	}
+	if configPath != defaultConfigPath() {
+		// The service would otherwise read the default config, not this one.
+		spec.Env = append(spec.Env, [2]string{"CODEX_HISTORY_CONFIG", configPath})
+	}
+	return installService(manager, spec)
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInitWizardWritesConfig(t *testing.T) {
+	root := t.TempDir()
+	codexHomeOverride = filepath.Join(root, "codex")
+	t.Cleanup(func() { codexHomeOverride = "" })
+
+	configPath := filepath.Join(root, "config", "config.json")
+	outPath := filepath.Join(root, "history.jsonl")
+	answers := strings.Join([]string{
+		"",      // sessions dir: accept proposal
+		outPath, // output file
+		"soon",  // invalid interval, asked again
+		"30s",   // interval
+		"y",     // relative sources
+		"n",     // skip initial sync
+	}, "\n") + "\n"
+
+	p := prompter{in: bufio.NewReader(strings.NewReader(answers)), out: io.Discard}
+	if err := initWizard(p, configPath, noServiceManager); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := loadConfig(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.SessionsDir != filepath.Join(root, "codex", "sessions") || cfg.Output != outPath {
+		t.Fatalf("unexpected paths in config: %#v", cfg)
+	}
+	if cfg.Watch.Interval != "30s" || !cfg.Watch.RelativeSources {
+		t.Fatalf("unexpected watch config: %#v", cfg.Watch)
+	}
+}
+
+func TestInitWizardAssumeYes(t *testing.T) {
+	root := t.TempDir()
+	codexHomeOverride = filepath.Join(root, "codex")
+	t.Cleanup(func() { codexHomeOverride = "" })
+
+	configPath := filepath.Join(root, "config.json")
+	p := prompter{out: io.Discard, assumeYes: true}
+	if err := initWizard(p, configPath, noServiceManager); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := loadConfig(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Output != filepath.Join(root, "codex", "conversation_history.jsonl") || cfg.Watch.Interval != "5s" {
+		t.Fatalf("unexpected default config: %#v", cfg)
+	}
+}
+
+func noServiceManager() (serviceManager, error) {
+	return serviceManager{}, errors.New("not supported in tests")
+}
+
+func TestInitWizardInstallsService(t *testing.T) {
+	root := t.TempDir()
+	codexHomeOverride = filepath.Join(root, "codex")
+	t.Cleanup(func() { codexHomeOverride = "" })
+	t.Setenv("CODEX_HOME", "")
+	t.Setenv("CODEX_HISTORY_CONFIG", "")
+
+	unitPath := filepath.Join(root, "units", "codex-history-watch.service")
+	fake := serviceManager{
+		name:    "fake",
+		path:    unitPath,
+		render:  systemdUnit,
+		install: [][]string{{"true"}},
+	}
+	configPath := filepath.Join(root, "config.json")
+	answers := strings.Join([]string{"", "", "", "", "n", "y"}, "\n") + "\n"
+	p := prompter{in: bufio.NewReader(strings.NewReader(answers)), out: io.Discard}
+	if err := initWizard(p, configPath, func() (serviceManager, error) { return fake, nil }); err != nil {
+		t.Fatal(err)
+	}
+
+	unit, err := os.ReadFile(unitPath)
+	if err != nil {
+		t.Fatalf("service was not installed: %v", err)
+	}
+	for _, want := range []string{" --codex-home " + codexHomeOverride + " watch\n", "Environment=CODEX_HISTORY_CONFIG=" + configPath + "\n"} {
+		if !strings.Contains(string(unit), want) {
+			t.Errorf("unit lacks %q:\n%s", want, unit)
+		}
+	}
+}
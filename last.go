@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+func runLast(args []string) error {
+	fs := flag.NewFlagSet("last", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+
+	inputPath := fs.String("in", defaultOutputFile(), "Input JSONL path")
+	maxChars := fs.Int("max-chars", 0, "Max chars per message line, 0 means no truncation")
+	color := fs.String("color", "auto", "Color roles and timestamps: auto, always, or never")
+	jsonOut := fs.Bool("json", false, "Print exchanges as JSONL")
+
+	// N may come before or after the flags.
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	count := 1
+	if fs.NArg() > 0 {
+		n, err := strconv.Atoi(fs.Arg(0))
+		if err != nil || n < 1 {
+			return fmt.Errorf("N must be a positive number of exchanges, got %q", fs.Arg(0))
+		}
+		count = n
+		if err := fs.Parse(fs.Args()[1:]); err != nil {
+			return err
+		}
+		if fs.NArg() > 0 {
+			return fmt.Errorf("unexpected argument %q", fs.Arg(0))
+		}
+	}
+	colored, err := colorEnabled(*color)
+	if err != nil {
+		return err
+	}
+
+	records, err := lastSessionRecords(*inputPath)
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return errors.New("the history has no sessions yet")
+	}
+	exchanges := pairExchanges(records)
+	if len(exchanges) > count {
+		exchanges = exchanges[len(exchanges)-count:]
+	}
+
+	if *jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetEscapeHTML(false)
+		for _, ex := range exchanges {
+			if err := enc.Encode(ex); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	fmt.Printf("session %s (%s to %s) %s\n\n", records[0].SessionID, records[0].Timestamp, records[len(records)-1].Timestamp, sessionTitle(records, 80))
+	printExchanges(os.Stdout, exchanges, *maxChars, colored)
+	return nil
+}
+
+// lastSessionRecords returns the records of the session with the most
+// recent activity, in chronological order. The history is read twice so
+// only that session is ever held in memory.
+func lastSessionRecords(path string) ([]Record, error) {
+	latest := Record{}
+	err := forEachRecord(path, func(record Record) error {
+		if latest.SessionID == "" || compareTimestamp(record.Timestamp, latest.Timestamp) > 0 {
+			latest = record
+		}
+		return nil
+	})
+	if err != nil || latest.SessionID == "" {
+		return nil, err
+	}
+
+	var records []Record
+	err = forEachRecord(path, func(record Record) error {
+		if record.SessionID == latest.SessionID {
+			records = append(records, record)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sortRecordsChronological(records)
+	return records, nil
+}
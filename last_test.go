@@ -0,0 +1,27 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestLastSessionRecords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	records := []Record{
+		{ID: "1", SessionID: "old", Timestamp: "2026-02-17T09:00:00Z", Role: "user", Text: "old"},
+		{ID: "2", SessionID: "new", Timestamp: "2026-02-17T10:00:00Z", Role: "user", Text: "first"},
+		{ID: "3", SessionID: "new", Timestamp: "2026-02-17T10:05:00Z", Role: "assistant", Text: "reply"},
+		// A session that started earlier but was active last wins.
+		{ID: "4", SessionID: "old", Timestamp: "2026-02-17T10:10:00Z", Role: "assistant", Text: "late"},
+	}
+	if err := appendRecords(path, records, false); err != nil {
+		t.Fatal(err)
+	}
+	got, err := lastSessionRecords(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].ID != "1" || got[1].ID != "4" {
+		t.Fatalf("unexpected records: %+v", got)
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// LatencyStats summarizes how long the assistant took to reply: the delay
+// from each user message to the first assistant message after it in the
+// same session.
+type LatencyStats struct {
+	Count      int     `json:"count"`
+	P50Seconds float64 `json:"p50_seconds"`
+	P90Seconds float64 `json:"p90_seconds"`
+}
+
+type latencyEvent struct {
+	at   time.Time
+	user bool
+	seq  int
+}
+
+// latencyTracker collects user and assistant message times per session.
+// Records may arrive in any order, so replies are only paired once every
+// record has been seen.
+type latencyTracker struct {
+	events map[string][]latencyEvent
+	seq    int
+}
+
+func newLatencyTracker() *latencyTracker {
+	return &latencyTracker{events: make(map[string][]latencyEvent)}
+}
+
+func (l *latencyTracker) add(record Record) {
+	role := strings.ToLower(strings.TrimSpace(record.Role))
+	if role != "user" && role != "assistant" {
+		return
+	}
+	at, ok := parseRecordTime(record.Timestamp)
+	if !ok {
+		return
+	}
+	l.seq++
+	l.events[record.SessionID] = append(l.events[record.SessionID], latencyEvent{at: at, user: role == "user", seq: l.seq})
+}
+
+// session returns the reply delays in one session. A reply is timed from
+// the latest user message before it, so a follow-up sent before the answer
+// restarts the clock; later assistant messages in the same turn are not
+// replies.
+func (l *latencyTracker) session(sessionID string) []time.Duration {
+	events := l.events[sessionID]
+	sort.SliceStable(events, func(i, j int) bool {
+		if !events[i].at.Equal(events[j].at) {
+			return events[i].at.Before(events[j].at)
+		}
+		return events[i].seq < events[j].seq
+	})
+	var delays []time.Duration
+	var pending *time.Time
+	for i := range events {
+		switch {
+		case events[i].user:
+			pending = &events[i].at
+		case pending != nil:
+			delays = append(delays, events[i].at.Sub(*pending))
+			pending = nil
+		}
+	}
+	return delays
+}
+
+// all returns the reply delays across every session.
+func (l *latencyTracker) all() []time.Duration {
+	var delays []time.Duration
+	for sessionID := range l.events {
+		delays = append(delays, l.session(sessionID)...)
+	}
+	return delays
+}
+
+// summarizeLatency returns nearest-rank percentiles of delays, or nil when
+// there are none.
+func summarizeLatency(delays []time.Duration) *LatencyStats {
+	if len(delays) == 0 {
+		return nil
+	}
+	sort.Slice(delays, func(i, j int) bool { return delays[i] < delays[j] })
+	rank := func(p float64) float64 {
+		idx := max(int(math.Ceil(p*float64(len(delays))))-1, 0)
+		return delays[idx].Seconds()
+	}
+	return &LatencyStats{Count: len(delays), P50Seconds: rank(0.50), P90Seconds: rank(0.90)}
+}
+
+// formatLatency prints seconds as a rounded duration such as 12s or 1m30s.
+func formatLatency(seconds float64) string {
+	return (time.Duration(seconds * float64(time.Second))).Round(time.Second).String()
+}
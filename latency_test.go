@@ -0,0 +1,43 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLatencyTracker(t *testing.T) {
+	tracker := newLatencyTracker()
+	records := []Record{
+		// Out of order on purpose: pairing happens on sorted times.
+		{SessionID: "s1", Timestamp: "2026-02-17T10:00:30Z", Role: "assistant"},
+		{SessionID: "s1", Timestamp: "2026-02-17T10:00:00Z", Role: "user"},
+		{SessionID: "s1", Timestamp: "2026-02-17T10:00:40Z", Role: "assistant"},
+		{SessionID: "s1", Timestamp: "2026-02-17T10:01:00Z", Role: "user"},
+		{SessionID: "s1", Timestamp: "2026-02-17T10:02:00Z", Role: "user"},
+		{SessionID: "s1", Timestamp: "2026-02-17T10:02:10Z", Role: "tool"},
+		{SessionID: "s1", Timestamp: "2026-02-17T10:03:00Z", Role: "assistant"},
+		{SessionID: "s2", Timestamp: "2026-02-17T11:00:00Z", Role: "assistant"},
+		{SessionID: "s2", Timestamp: "2026-02-17T11:00:05Z", Role: "user"},
+	}
+	for _, record := range records {
+		tracker.add(record)
+	}
+	if got := tracker.session("s1"); !reflect.DeepEqual(got, []time.Duration{30 * time.Second, time.Minute}) {
+		t.Fatalf("unexpected s1 delays: %v", got)
+	}
+	if got := tracker.session("s2"); len(got) != 0 {
+		t.Fatalf("unanswered and unprompted messages should not count: %v", got)
+	}
+
+	stats := summarizeLatency(tracker.all())
+	if stats == nil || stats.Count != 2 || stats.P50Seconds != 30 || stats.P90Seconds != 60 {
+		t.Fatalf("unexpected summary: %+v", stats)
+	}
+	if summarizeLatency(nil) != nil {
+		t.Fatal("expected nil for no replies")
+	}
+	if got := formatLatency(90.4); got != "1m30s" {
+		t.Fatalf("unexpected format: %s", got)
+	}
+}
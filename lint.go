@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// knownEnvelopeTypes and knownEventTypes are the rollout shapes this tool has
+// seen in the wild; anything else is reported by lint as format drift.
+var knownEnvelopeTypes = map[string]bool{
+	"session_meta":  true,
+	"event_msg":     true,
+	"turn_context":  true,
+	"response_item": true,
+	"compacted":     true,
+}
+
+var knownEventTypes = map[string]bool{
+	"user_message":       true,
+	"agent_message":      true,
+	"agent_reasoning":    true,
+	"token_count":        true,
+	"exec_command_begin": true,
+	"exec_command_end":   true,
+	"patch_apply_begin":  true,
+	"patch_apply_end":    true,
+	"error":              true,
+	"stream_error":       true,
+	"turn_aborted":       true,
+}
+
+type LintIssue struct {
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+type FileLint struct {
+	Path          string         `json:"path"`
+	Lines         int            `json:"lines"`
+	Malformed     []LintIssue    `json:"malformed,omitempty"`
+	UnknownTypes  map[string]int `json:"unknown_types,omitempty"`
+	UnknownEvents map[string]int `json:"unknown_events,omitempty"`
+	// Variants maps "type/payload_type" to the distinct payload key sets seen,
+	// so a field appearing or vanishing shows up as a new variant.
+	Variants map[string][]string `json:"variants,omitempty"`
+}
+
+func (f FileLint) clean() bool {
+	return len(f.Malformed) == 0 && len(f.UnknownTypes) == 0 && len(f.UnknownEvents) == 0
+}
+
+func lintSessionFile(path string) (FileLint, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return FileLint{}, err
+	}
+	defer file.Close()
+
+	report := FileLint{
+		Path:          path,
+		UnknownTypes:  map[string]int{},
+		UnknownEvents: map[string]int{},
+		Variants:      map[string][]string{},
+	}
+	seenVariants := map[string]map[string]bool{}
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), scannerMaxTokenSize)
+
+	for scanner.Scan() {
+		report.Lines++
+		line := scanner.Bytes()
+		if report.Lines == 1 {
+			line = bytes.TrimPrefix(line, utf8BOM)
+		}
+		line = bytes.TrimRight(line, "\r")
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+
+		var item envelope
+		if err := json.Unmarshal(line, &item); err != nil {
+			report.Malformed = append(report.Malformed, LintIssue{Line: report.Lines, Message: err.Error()})
+			continue
+		}
+		if item.Type == "" {
+			report.Malformed = append(report.Malformed, LintIssue{Line: report.Lines, Message: "missing envelope type"})
+			continue
+		}
+		if !knownEnvelopeTypes[item.Type] {
+			report.UnknownTypes[item.Type]++
+		}
+
+		var payload map[string]json.RawMessage
+		if err := json.Unmarshal(item.Payload, &payload); err != nil {
+			report.Malformed = append(report.Malformed, LintIssue{Line: report.Lines, Message: fmt.Sprintf("%s payload is not an object", item.Type)})
+			continue
+		}
+
+		payloadType := ""
+		if raw, ok := payload["type"]; ok {
+			if err := json.Unmarshal(raw, &payloadType); err != nil {
+				report.Malformed = append(report.Malformed, LintIssue{Line: report.Lines, Message: fmt.Sprintf("%s payload type is not a string", item.Type)})
+				continue
+			}
+		}
+		if item.Type == "event_msg" && !knownEventTypes[payloadType] {
+			report.UnknownEvents[payloadType]++
+		}
+
+		keys := make([]string, 0, len(payload))
+		for key := range payload {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		variantKey := item.Type
+		if payloadType != "" {
+			variantKey += "/" + payloadType
+		}
+		signature := strings.Join(keys, ",")
+		if seenVariants[variantKey] == nil {
+			seenVariants[variantKey] = map[string]bool{}
+		}
+		if !seenVariants[variantKey][signature] {
+			seenVariants[variantKey][signature] = true
+			report.Variants[variantKey] = append(report.Variants[variantKey], signature)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return FileLint{}, err
+	}
+	return report, nil
+}
+
+func runLint(args []string) error {
+	fs := flag.NewFlagSet("lint", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+
+	sessionsDir := fs.String("sessions-dir", defaultSessionsDir(), "Codex sessions directory")
+	all := fs.Bool("all", false, "Also list files without issues")
+	variants := fs.Bool("variants", false, "Print payload key-set variants per event type")
+	strict := fs.Bool("strict", false, "Exit with an error when any issue is found")
+	jsonOut := fs.Bool("json", false, "Print as JSON")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	files, err := listSessionFiles(*sessionsDir)
+	if err != nil {
+		return err
+	}
+
+	reports := make([]FileLint, 0, len(files))
+	issues := 0
+	for _, path := range files {
+		report, err := lintSessionFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		if !report.clean() {
+			issues++
+		}
+		if *all || !report.clean() {
+			reports = append(reports, report)
+		}
+	}
+
+	if *jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(reports); err != nil {
+			return err
+		}
+	} else {
+		for _, report := range reports {
+			fmt.Printf("%s lines=%d malformed=%d\n", report.Path, report.Lines, len(report.Malformed))
+			for _, issue := range report.Malformed {
+				fmt.Printf("  line %d: %s\n", issue.Line, issue.Message)
+			}
+			for _, name := range sortedKeys(report.UnknownTypes) {
+				fmt.Printf("  unknown type %q x%d\n", name, report.UnknownTypes[name])
+			}
+			for _, name := range sortedKeys(report.UnknownEvents) {
+				fmt.Printf("  unknown event_msg %q x%d\n", name, report.UnknownEvents[name])
+			}
+			if *variants {
+				for _, name := range sortedKeys(report.Variants) {
+					for _, signature := range report.Variants[name] {
+						fmt.Printf("  variant %s {%s}\n", name, signature)
+					}
+				}
+			}
+		}
+		fmt.Printf("files=%d with_issues=%d\n", len(files), issues)
+	}
+
+	if *strict && issues > 0 {
+		return errors.New("lint found issues")
+	}
+	return nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
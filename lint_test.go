@@ -0,0 +1,38 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLintSessionFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rollout.jsonl")
+	content := strings.Join([]string{
+		`{"timestamp":"2026-02-17T12:00:00Z","type":"session_meta","payload":{"id":"s1"}}`,
+		`{"timestamp":"2026-02-17T12:00:01Z","type":"event_msg","payload":{"type":"user_message","message":"hello"}}`,
+		`{"timestamp":"2026-02-17T12:00:02Z","type":"event_msg","payload":{"type":"user_message","message":"hi","images":[]}}`,
+		`{"timestamp":"2026-02-17T12:00:03Z","type":"event_msg","payload":{"type":"plan_update"}}`,
+		`{"timestamp":"2026-02-17T12:00:04Z","type":"brand_new","payload":{}}`,
+		`{"timestamp":"2026-02-17T12:00:05Z","type":"event_msg","payload":"oops"}`,
+		`{"timestamp":"2026-02-17T12:00:06Z","type":"event_msg","payl`,
+	}, "\n") + "\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := lintSessionFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Lines != 7 || len(report.Malformed) != 2 || report.Malformed[0].Line != 6 || report.Malformed[1].Line != 7 {
+		t.Fatalf("unexpected malformed lines: %#v", report.Malformed)
+	}
+	if report.UnknownTypes["brand_new"] != 1 || report.UnknownEvents["plan_update"] != 1 {
+		t.Fatalf("unexpected unknown types: %#v %#v", report.UnknownTypes, report.UnknownEvents)
+	}
+	if got := report.Variants["event_msg/user_message"]; len(got) != 2 || got[0] != "message,type" || got[1] != "images,message,type" {
+		t.Fatalf("unexpected user_message variants: %#v", got)
+	}
+}
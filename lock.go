@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// historyLockTimeout bounds how long a writer waits for another sync, watch,
+// or rewrite of the same history to finish.
+const historyLockTimeout = 30 * time.Second
+
+func historyLockPath(historyPath string) string {
+	return historyPath + ".lock"
+}
+
+// lockHistory takes an exclusive cross-process lock on the history file,
+// so concurrent sync/watch/rewrite commands cannot interleave writes. The
+// returned func releases it.
+func lockHistory(historyPath string) (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(historyPath), 0o755); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(historyLockPath(historyPath), os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(historyLockTimeout)
+	for {
+		locked, err := tryLockFile(file)
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+		if locked {
+			break
+		}
+		if time.Now().After(deadline) {
+			file.Close()
+			return nil, fmt.Errorf("history %s is locked by another process", historyPath)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	return func() {
+		_ = unlockFile(file)
+		file.Close()
+	}, nil
+}
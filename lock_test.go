@@ -0,0 +1,78 @@
+package main
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestConcurrentSyncsDoNotDuplicate(t *testing.T) {
+	root := t.TempDir()
+	sessionsRoot := filepath.Join(root, "sessions")
+	start := time.Date(2026, 2, 15, 9, 0, 0, 0, time.UTC)
+	if _, err := generateDemo(DemoOptions{OutDir: sessionsRoot, Days: 2, SessionsPerDay: 2, Start: start, Seed: 9}); err != nil {
+		t.Fatal(err)
+	}
+	outPath := filepath.Join(root, "history.jsonl")
+
+	var wg sync.WaitGroup
+	errs := make([]error, 4)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = syncOnce(SyncOptions{SessionsDir: sessionsRoot, OutputPath: outPath, Fsync: true})
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	records, err := loadRecords(outPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := map[string]bool{}
+	for _, record := range records {
+		if seen[record.ID] {
+			t.Fatalf("duplicate record %s", record.ID)
+		}
+		seen[record.ID] = true
+	}
+	if len(records) == 0 {
+		t.Fatal("expected records")
+	}
+}
+
+func TestLockHistoryExcludesSecondHolder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	unlock, err := lockHistory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	acquired := make(chan struct{})
+	go func() {
+		release, err := lockHistory(path)
+		if err == nil {
+			release()
+		}
+		close(acquired)
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("second lock acquired while first was held")
+	case <-time.After(200 * time.Millisecond):
+	}
+	unlock()
+	select {
+	case <-acquired:
+	case <-time.After(5 * time.Second):
+		t.Fatal("second lock not acquired after release")
+	}
+}
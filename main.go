@@ -2,7 +2,7 @@ package main
 
 import (
 	"bufio"
-	"context"
+	"bytes"
 	"crypto/sha256"
 	"encoding/csv"
 	"encoding/hex"
@@ -11,28 +11,43 @@ import (
 	"flag"
 	"fmt"
 	"io/fs"
+	"math"
+	"math/bits"
 	"os"
-	"os/signal"
 	"path/filepath"
 	"regexp"
+	"runtime"
 	"sort"
+	"strconv"
 	"strings"
-	"syscall"
+	"sync"
+	"text/template"
 	"time"
+	"unicode"
+	"unicode/utf8"
 )
 
 const scannerMaxTokenSize = 16 * 1024 * 1024
 
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
 var sessionIDPattern = regexp.MustCompile(`[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}`)
 
 type Record struct {
-	ID         string `json:"id"`
-	SessionID  string `json:"session_id"`
-	Timestamp  string `json:"timestamp"`
-	Role       string `json:"role"`
-	Text       string `json:"text"`
-	SourceFile string `json:"source_file,omitempty"`
-	SourceLine int    `json:"source_line,omitempty"`
+	ID         string            `json:"id"`
+	SessionID  string            `json:"session_id"`
+	Timestamp  string            `json:"timestamp"`
+	Role       string            `json:"role"`
+	Text       string            `json:"text"`
+	SourceFile string            `json:"source_file,omitempty"`
+	SourceLine int               `json:"source_line,omitempty"`
+	Meta       map[string]string `json:"meta,omitempty"`
+	// Attachments lists images attached to the message.
+	Attachments []Attachment `json:"attachments,omitempty"`
+	// Tool is the structured call behind a role=tool record.
+	Tool *ToolCall `json:"tool,omitempty"`
+	// Raw is the verbatim payload of a passthrough (role=event) record.
+	Raw json.RawMessage `json:"raw,omitempty"`
 }
 
 type envelope struct {
@@ -42,25 +57,90 @@ type envelope struct {
 }
 
 type sessionMetaPayload struct {
-	ID string `json:"id"`
+	ID  string `json:"id"`
+	Cwd string `json:"cwd"`
+}
+
+type turnContextPayload struct {
+	Cwd            string          `json:"cwd"`
+	Model          string          `json:"model"`
+	ApprovalPolicy string          `json:"approval_policy"`
+	SandboxPolicy  json.RawMessage `json:"sandbox_policy"`
 }
 
 type eventPayload struct {
-	Type    string `json:"type"`
-	Message string `json:"message"`
+	Type    string   `json:"type"`
+	Message string   `json:"message"`
+	Text    string   `json:"text"`
+	Reason  string   `json:"reason"`
+	Images  []string `json:"images"`
+	Cwd     string   `json:"cwd"`
+	// Set on exec_command_end events.
+	Stdout           string   `json:"stdout"`
+	Stderr           string   `json:"stderr"`
+	AggregatedOutput string   `json:"aggregated_output"`
+	ExitCode         *int     `json:"exit_code"`
+	CallID           string   `json:"call_id"`
+	Command          []string `json:"command"`
+	// Info is set on token_count events.
+	Info *tokenCountInfo `json:"info"`
+	// Changes is set on patch_apply_begin events, keyed by file path.
+	Changes map[string]patchChange `json:"changes"`
+}
+
+type tokenCountInfo struct {
+	TotalTokenUsage *TokenUsage `json:"total_token_usage"`
 }
 
 type SyncOptions struct {
-	SessionsDir string
-	OutputPath  string
-	Since       time.Time
-	DryRun      bool
+	SessionsDir     string
+	OutputPath      string
+	Since           time.Time
+	DryRun          bool
+	RelativeSources bool
+	MaxTextBytes    int
+	NoSanitize      bool
+	// Workers is the number of files parsed concurrently; <= 0 uses NumCPU.
+	Workers int
+	// Fsync flushes appended records to disk before the sync returns.
+	Fsync bool
+	// Include enables optional record kinds; see ExtractOptions.
+	Include includeSet
+	// AssetsDir receives inline image attachments; see ExtractOptions.
+	AssetsDir string
+	// Events enables passthrough records; see ExtractOptions.
+	Events *eventSet
+	// HashPaths replaces file paths with stable hashes; see hashRecordPaths.
+	HashPaths bool
+	// BlockSecrets leaves out new records that appear to contain
+	// credentials instead of only reporting them.
+	BlockSecrets bool
+}
+
+// ExtractOptions controls how a single session file is turned into records.
+type ExtractOptions struct {
+	Since time.Time
+	// NoSanitize keeps message text byte-for-byte instead of repairing
+	// invalid UTF-8 and stripping terminal control characters.
+	NoSanitize bool
+	// Include enables optional record kinds such as tool calls.
+	Include includeSet
+	// AssetsDir, when set, receives inline images attached to messages.
+	AssetsDir string
+	// Events selects lines recorded verbatim as role=event records when no
+	// other record was extracted from them; nil disables passthrough.
+	Events *eventSet
 }
 
 type SyncResult struct {
 	Files   int
 	Scanned int
 	Written int
+	// NewRecords are the records appended by this sync (or that would have
+	// been, for a dry run).
+	NewRecords []Record
+	// Secrets are the new records that appear to contain credentials.
+	Secrets []SecretFinding
 }
 
 type RecordFilter struct {
@@ -69,6 +149,35 @@ type RecordFilter struct {
 	Contains  string
 	From      time.Time
 	To        time.Time
+	// Model matches meta.model, the model active when the record was written.
+	Model string
+	// Match is a regular expression the text must match; with MatchRole set
+	// it is only tested against records of that role, and other records are
+	// filtered out.
+	Match     *regexp.Regexp
+	MatchRole string
+	// CaseSensitive and Word tighten Contains to an exact-case or
+	// whole-word match.
+	CaseSensitive bool
+	Word          bool
+	// Project matches the working directory (meta.cwd) or imported project
+	// name: an absolute path matches that directory and those under it,
+	// anything else is a case-insensitive substring.
+	Project string
+}
+
+// ExportOptions tweaks how renderExport shapes its output.
+type ExportOptions struct {
+	// NoSources omits provenance (source_file, source_line) and the local
+	// paths records carry, so transcripts can be shared without leaking
+	// local filesystem layout; see stripRecordSources.
+	NoSources bool
+	// Compression ("gzip" or "zstd") is applied to the written files;
+	// renderExport itself ignores it.
+	Compression string
+	// Template and PerSession drive --format template.
+	Template   *template.Template
+	PerSession bool
 }
 
 type HistoryStats struct {
@@ -76,9 +185,28 @@ type HistoryStats struct {
 	User           int    `json:"user"`
 	Assistant      int    `json:"assistant"`
 	Other          int    `json:"other"`
+	Errors         int    `json:"errors"`
 	SessionCount   int    `json:"session_count"`
 	FirstTimestamp string `json:"first_timestamp,omitempty"`
 	LastTimestamp  string `json:"last_timestamp,omitempty"`
+	// Lengths holds message-length percentiles keyed by role.
+	Lengths map[string]LengthStats `json:"lengths,omitempty"`
+	// Usage totals token usage across the matched sessions, when sync has
+	// seen token_count events for them.
+	Usage *TokenUsage `json:"usage,omitempty"`
+	// Latency is how long replies took, when any user message was answered.
+	Latency *LatencyStats `json:"latency,omitempty"`
+}
+
+type LengthStats struct {
+	Chars Percentiles `json:"chars"`
+	Words Percentiles `json:"words"`
+}
+
+type Percentiles struct {
+	P50 int `json:"p50"`
+	P90 int `json:"p90"`
+	P99 int `json:"p99"`
 }
 
 type SessionSummary struct {
@@ -87,30 +215,111 @@ type SessionSummary struct {
 	User           int    `json:"user"`
 	Assistant      int    `json:"assistant"`
 	Other          int    `json:"other"`
+	Errors         int    `json:"errors"`
+	Chars          int    `json:"chars"`
 	FirstTimestamp string `json:"first_timestamp,omitempty"`
 	LastTimestamp  string `json:"last_timestamp,omitempty"`
+	// DurationSeconds is the time from the first to the last record.
+	DurationSeconds int64         `json:"duration_seconds"`
+	Usage           *TokenUsage   `json:"usage,omitempty"`
+	Latency         *LatencyStats `json:"latency,omitempty"`
+	Model           string        `json:"model,omitempty"`
+	Cwd             string        `json:"cwd,omitempty"`
+	ApprovalPolicy  string        `json:"approval_policy,omitempty"`
+	SandboxMode     string        `json:"sandbox_mode,omitempty"`
+	FilesChanged    []FileChange  `json:"files_changed,omitempty"`
+	// Preview is the start of the first user message, on one line.
+	Preview string `json:"preview,omitempty"`
 }
 
+// codexHomeOverride is set by the global --codex-home flag and takes
+// precedence over the config file and $CODEX_HOME when resolving defaults.
+var codexHomeOverride string
+
 func main() {
+	args, err := extractCodexHomeFlag(os.Args[1:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(2)
+	}
+	os.Args = append(os.Args[:1], args...)
+
+	appConfig, err = loadConfig(defaultConfigPath())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(2)
+	}
+
 	if len(os.Args) < 2 {
 		printUsage()
 		os.Exit(2)
 	}
 
-	var err error
 	switch os.Args[1] {
+	case "init":
+		err = runInit(os.Args[2:])
 	case "sync":
 		err = runSync(os.Args[2:])
 	case "watch":
 		err = runWatch(os.Args[2:])
 	case "show":
 		err = runShow(os.Args[2:])
+	case "search":
+		err = runSearch(os.Args[2:])
+	case "index":
+		err = runIndex(os.Args[2:])
+	case "similar":
+		err = runSimilar(os.Args[2:])
+	case "open":
+		err = runOpen(os.Args[2:])
+	case "last":
+		err = runLast(os.Args[2:])
+	case "replay":
+		err = runReplay(os.Args[2:])
+	case "report":
+		err = runReport(os.Args[2:])
 	case "stats":
 		err = runStats(os.Args[2:])
 	case "sessions":
 		err = runSessions(os.Args[2:])
 	case "export":
 		err = runExport(os.Args[2:])
+	case "site":
+		err = runSite(os.Args[2:])
+	case "serve":
+		err = runServe(os.Args[2:])
+	case "grpc":
+		err = runGRPC(os.Args[2:])
+	case "status":
+		err = runStatus(os.Args[2:])
+	case "service":
+		err = runService(os.Args[2:])
+	case "sources":
+		err = runSources(os.Args[2:])
+	case "orphans":
+		err = runOrphans(os.Args[2:])
+	case "relink":
+		err = runRelink(os.Args[2:])
+	case "redact":
+		err = runRedact(os.Args[2:])
+	case "delete":
+		err = runDelete(os.Args[2:])
+	case "archive":
+		err = runArchive(os.Args[2:])
+	case "restore":
+		err = runRestore(os.Args[2:])
+	case "compact":
+		err = runCompact(os.Args[2:])
+	case "verify":
+		err = runVerify(os.Args[2:])
+	case "repair":
+		err = runRepair(os.Args[2:])
+	case "import":
+		err = runImport(os.Args[2:])
+	case "demo":
+		err = runDemo(os.Args[2:])
+	case "lint":
+		err = runLint(os.Args[2:])
 	case "help", "-h", "--help":
 		printUsage()
 		return
@@ -129,33 +338,108 @@ func printUsage() {
 	fmt.Printf(`codex-history: record Codex conversations from ~/.codex/sessions
 
 Usage:
-  codex-history sync     [--sessions-dir DIR] [--out FILE] [--from RFC3339] [--dry-run]
-  codex-history watch    [--sessions-dir DIR] [--out FILE] [--from RFC3339] [--interval 5s]
-  codex-history show     [--in FILE] [--session ID] [--role user|assistant] [--from RFC3339] [--to RFC3339] [--contains WORD] [--limit 20] [--desc] [--json]
-  codex-history stats    [--in FILE] [--session ID] [--role user|assistant] [--from RFC3339] [--to RFC3339] [--contains WORD] [--json]
-  codex-history sessions [--in FILE] [--from RFC3339] [--to RFC3339] [--contains WORD] [--limit 20] [--json]
-  codex-history export   [--in FILE] [--out FILE] [--format markdown|csv|jsonl] [--session ID] [--role user|assistant] [--from RFC3339] [--to RFC3339] [--contains WORD] [--limit 20] [--desc]
+  codex-history init     [--config FILE] [--yes]
+  codex-history sync     [--sessions-dir DIR] [--out FILE] [--from RFC3339] [--dry-run] [--relative-sources] [--max-text-bytes N] [--no-sanitize] [--workers N] [--fsync] [--include tools,reasoning,patches] [--assets-dir DIR] [--include-events TYPE,...|--all-events] [--block-secrets] [--hash-paths]
+  codex-history watch    [--sessions-dir DIR] [--out FILE] [--from RFC3339] [--interval 5s] [--max-interval 1m] [--debounce 1s] [--heartbeat FILE] [--max-duration D] [--exit-after-idle D] [--relative-sources] [--max-text-bytes N] [--no-sanitize] [--workers N] [--fsync] [--include tools,reasoning,patches] [--assets-dir DIR] [--include-events TYPE,...|--all-events] [--block-secrets] [--hash-paths] [--retain-max-age 90d] [--retain-max-size 200M] [--retention-every 1h] [--metrics-addr ADDR] [--log-file FILE [--log-max-size 10M] [--log-max-age 7d] [--log-keep 5]] [--webhook URL [--webhook-filter KEY=VALUE ...]] [--notify] [--daemon] [--pid-file FILE]
+  codex-history watch    --status|--stop [--pid-file FILE]
+  codex-history show     [--in FILE] [--saved NAME] [--session ID] [--role user|assistant] [--from RFC3339] [--to RFC3339] [--contains WORD [--case-sensitive] [--word]] [--match REGEX [--match-role ROLE]] [--model NAME] [--project PATH] [--limit 20] [--desc] [-C N|-B N|-A N] [--pairs] [--render] [--color auto|always|never] [--format TEMPLATE] [--fields LIST] [--json] [--no-sources]
+  codex-history search   QUERY [--in FILE] [--session ID] [--role ROLE] [--from RFC3339] [--to RFC3339] [--model NAME] [--case-sensitive] [--word] [--limit N] [--fuzzy [--min-score 0.75] | --semantic [--embed-url URL] [--embed-model NAME]] [-C N|-B N|-A N] [--color auto|always|never] [-l|--count] [--json] [--no-index]
+  codex-history index    [--in FILE]
+  codex-history similar  --session ID [--in FILE] [--limit 10] [--json]
+  codex-history open     RECORD_ID [--in FILE] [--sessions-dir DIR] [--print]
+  codex-history last     [N] [--in FILE] [--max-chars N] [--color auto|always|never] [--json]
+  codex-history replay   --session ID [--in FILE] [--delay D] [--color auto|always|never]
+  codex-history stats    [--in FILE] [--saved NAME] [--session ID] [--role user|assistant] [--from RFC3339] [--to RFC3339] [--contains WORD [--case-sensitive] [--word]] [--match REGEX [--match-role ROLE]] [--model NAME] [--project PATH] [--by day|week|month|model|--heatmap] [--costs [--by model|session|day|week|month]] [--compare-with day|week|month|Nd] [--spark [--days 30]] [--tz ZONE] [--json]
+  codex-history sessions [--in FILE] [--saved NAME] [--from RFC3339] [--to RFC3339] [--contains WORD [--case-sensitive] [--word]] [--match REGEX [--match-role ROLE]] [--project PATH] [--role ROLE] [--min-messages N] [--min-user N] [--top-by total|assistant|chars|duration] [--group-by project] [--no-preview] [--limit 20] [--json]
+  codex-history report   [--in FILE] [--period day|week|month|Nd] [--to RFC3339] [--out FILE]
+  codex-history export   [--in FILE] [--saved NAME] [--out FILE] [--format markdown|csv|jsonl|html|pdf|openai-chat|sharegpt|parquet|sqlite|arrow|atom|template] [--template FILE] [--per record|session] [--session ID] [--role user|assistant] [--from RFC3339] [--to RFC3339] [--contains WORD [--case-sensitive] [--word]] [--match REGEX [--match-role ROLE]] [--model NAME] [--project PATH] [--limit N] [--desc] [--no-sources] [--split session --out-dir DIR] [--gzip|--zstd]
+  codex-history site     --out DIR [--in FILE] [--from RFC3339] [--to RFC3339]
+  codex-history serve    [--in FILE | --source NAME=PATH ...] [--addr 127.0.0.1:8080] [--token TOKEN] [--tls-cert FILE --tls-key FILE]
+  codex-history grpc     [--in FILE] [--sessions-dir DIR] [--addr 127.0.0.1:50051] [--token TOKEN] [--tls-cert FILE --tls-key FILE]
+  codex-history status   [--url http://127.0.0.1:9464] [--timeout 5s] [--json]
+  codex-history service  install [--print] [-- WATCH-FLAGS...] | uninstall | status
+  codex-history sources  [--sessions-dir DIR] [--in FILE] [--errors] [--json]
+  codex-history orphans  [--in FILE] [--sessions-dir DIR] [--annotate|--prune] [--dry-run] [--json]
+  codex-history relink   [--in FILE] --old-prefix DIR --new-prefix DIR [--dry-run]
+  codex-history redact   --rules FILE [--in FILE] [--dry-run]
+  codex-history delete   [--in FILE] [--session ID] [--role ROLE] [--from RFC3339] [--to RFC3339] [--contains WORD [--case-sensitive] [--word]] [--match REGEX] [--model NAME] [--project PATH] [--dry-run]
+  codex-history archive  --before DATE --out FILE.tar.zst [--in FILE] [--with-sources [--sessions-dir DIR]] [--dry-run]
+  codex-history restore  ARCHIVE [--in FILE] [--with-sources [--sessions-dir DIR]]
+  codex-history compact  [--in FILE] [--dry-run] [--json]
+  codex-history verify   [--in FILE] [--json]
+  codex-history repair   [--in FILE] [--dry-run] [--json]
+  codex-history import   codex-legacy|claude-code|cursor [--in PATH] [--out FILE] [--dry-run]
+  codex-history import   generic --in PATH --map FILE [--out FILE] [--dry-run]
+  codex-history lint     [--sessions-dir DIR] [--all] [--variants] [--strict] [--json]
+  codex-history demo     --out DIR [--days 3] [--sessions-per-day 2] [--start RFC3339] [--seed 1] [--corrupt]
+
+Global flags:
+  --codex-home DIR  Codex home used for default paths (default: $CODEX_HOME or ~/.codex)
 
 Defaults:
+  config      : %s
   sessions-dir: %s
   out/in file : %s
-`, defaultSessionsDir(), defaultOutputFile())
+`, defaultConfigPath(), defaultSessionsDir(), defaultOutputFile())
 }
 
-func defaultSessionsDir() string {
+// extractCodexHomeFlag pulls --codex-home out of the argument list wherever
+// it appears (before a bare "--"), so it works ahead of or after the
+// subcommand and feeds every command's path defaults.
+func extractCodexHomeFlag(args []string) ([]string, error) {
+	rest := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			rest = append(rest, args[i:]...)
+			break
+		}
+
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if !strings.HasPrefix(arg, "-") || name != "codex-home" {
+			rest = append(rest, arg)
+			continue
+		}
+		if !hasValue {
+			if i+1 >= len(args) {
+				return nil, errors.New("flag needs an argument: --codex-home")
+			}
+			i++
+			value = args[i]
+		}
+		codexHomeOverride = strings.TrimSpace(value)
+	}
+	return rest, nil
+}
+
+// codexHome resolves the Codex home directory: --codex-home, then
+// $CODEX_HOME (as Codex itself honors it), then ~/.codex.
+func codexHome() string {
+	if codexHomeOverride != "" {
+		return codexHomeOverride
+	}
+	if env := strings.TrimSpace(os.Getenv("CODEX_HOME")); env != "" {
+		return env
+	}
 	home, err := os.UserHomeDir()
 	if err != nil {
-		return ".codex/sessions"
+		return ".codex"
 	}
-	return filepath.Join(home, ".codex", "sessions")
+	return filepath.Join(home, ".codex")
+}
+
+func defaultSessionsDir() string {
+	if codexHomeOverride == "" && appConfig.SessionsDir != "" {
+		return appConfig.SessionsDir
+	}
+	return filepath.Join(codexHome(), "sessions")
 }
 
 func defaultOutputFile() string {
-	home, err := os.UserHomeDir()
-	if err != nil {
-		return ".codex/conversation_history.jsonl"
+	if codexHomeOverride == "" && appConfig.Output != "" {
+		return appConfig.Output
 	}
-	return filepath.Join(home, ".codex", "conversation_history.jsonl")
+	return filepath.Join(codexHome(), "conversation_history.jsonl")
 }
 
 func runSync(args []string) error {
@@ -166,10 +450,29 @@ func runSync(args []string) error {
 	outPath := fs.String("out", defaultOutputFile(), "Output JSONL path")
 	from := fs.String("from", "", "Only include records at/after this RFC3339 timestamp")
 	dryRun := fs.Bool("dry-run", false, "Scan and count records without writing")
+	relativeSources := fs.Bool("relative-sources", false, "Store source_file relative to --sessions-dir")
+	maxTextBytes := fs.Int("max-text-bytes", 0, "Truncate message text longer than N bytes, 0 means no limit")
+	noSanitize := fs.Bool("no-sanitize", false, "Keep invalid UTF-8 and control characters in message text")
+	workers := fs.Int("workers", 0, "Session files parsed in parallel, 0 means one per CPU")
+	fsync := fs.Bool("fsync", false, "Flush appended records to disk before exiting")
+	include := fs.String("include", "", "Extra record kinds to extract, comma-separated: tools,reasoning,patches")
+	assetsDir := fs.String("assets-dir", "", "Save inline image attachments into this directory")
+	includeEvents := fs.String("include-events", "", "Also record these event types verbatim, comma-separated")
+	allEvents := fs.Bool("all-events", false, "Record every event no other record was extracted from verbatim")
+	blockSecrets := fs.Bool("block-secrets", false, "Skip new records that appear to contain credentials")
+	hashPaths := fs.Bool("hash-paths", false, "Replace source_file, cwd, and file paths in text with stable hashes")
 
 	if err := fs.Parse(args); err != nil {
 		return err
 	}
+	if *maxTextBytes < 0 {
+		return errors.New("--max-text-bytes must be >= 0")
+	}
+	includes, err := parseIncludeList(*include)
+	if err != nil {
+		return err
+	}
+	events := parseEventList(*includeEvents, *allEvents)
 
 	since, err := parseBoundTime(*from, "--from")
 	if err != nil {
@@ -177,75 +480,30 @@ func runSync(args []string) error {
 	}
 
 	result, err := syncOnce(SyncOptions{
-		SessionsDir: *sessionsDir,
-		OutputPath:  *outPath,
-		Since:       since,
-		DryRun:      *dryRun,
+		SessionsDir:     *sessionsDir,
+		OutputPath:      *outPath,
+		Since:           since,
+		DryRun:          *dryRun,
+		RelativeSources: *relativeSources,
+		MaxTextBytes:    *maxTextBytes,
+		NoSanitize:      *noSanitize,
+		Workers:         *workers,
+		Fsync:           *fsync,
+		Include:         includes,
+		AssetsDir:       strings.TrimSpace(*assetsDir),
+		Events:          events,
+		BlockSecrets:    *blockSecrets,
+		HashPaths:       *hashPaths,
 	})
 	if err != nil {
 		return err
 	}
 
+	printSecretWarnings(os.Stderr, result.Secrets, *blockSecrets, *dryRun)
 	fmt.Printf("files=%d scanned=%d new=%d output=%s\n", result.Files, result.Scanned, result.Written, *outPath)
 	return nil
 }
 
-func runWatch(args []string) error {
-	fs := flag.NewFlagSet("watch", flag.ContinueOnError)
-	fs.SetOutput(os.Stderr)
-
-	sessionsDir := fs.String("sessions-dir", defaultSessionsDir(), "Codex sessions directory")
-	outPath := fs.String("out", defaultOutputFile(), "Output JSONL path")
-	from := fs.String("from", "", "Only include records at/after this RFC3339 timestamp")
-	interval := fs.Duration("interval", 5*time.Second, "Sync interval")
-
-	if err := fs.Parse(args); err != nil {
-		return err
-	}
-
-	if *interval <= 0 {
-		return errors.New("interval must be > 0")
-	}
-
-	since, err := parseBoundTime(*from, "--from")
-	if err != nil {
-		return err
-	}
-
-	opts := SyncOptions{
-		SessionsDir: *sessionsDir,
-		OutputPath:  *outPath,
-		Since:       since,
-		DryRun:      false,
-	}
-
-	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
-	defer stop()
-
-	fmt.Printf("watching %s -> %s (interval=%s)\n", opts.SessionsDir, opts.OutputPath, interval.String())
-
-	ticker := time.NewTicker(*interval)
-	defer ticker.Stop()
-
-	for {
-		result, err := syncOnce(opts)
-		if err != nil {
-			return err
-		}
-
-		if result.Written > 0 {
-			fmt.Printf("%s files=%d scanned=%d new=%d\n", time.Now().UTC().Format(time.RFC3339), result.Files, result.Scanned, result.Written)
-		}
-
-		select {
-		case <-ctx.Done():
-			fmt.Println("watch stopped")
-			return nil
-		case <-ticker.C:
-		}
-	}
-}
-
 func runShow(args []string) error {
 	fs := flag.NewFlagSet("show", flag.ContinueOnError)
 	fs.SetOutput(os.Stderr)
@@ -256,14 +514,63 @@ func runShow(args []string) error {
 	from := fs.String("from", "", "Filter records at/after this RFC3339 timestamp")
 	to := fs.String("to", "", "Filter records at/before this RFC3339 timestamp")
 	contains := fs.String("contains", "", "Case-insensitive substring filter for text")
+	caseSensitive := fs.Bool("case-sensitive", false, "Match --contains with exact case")
+	word := fs.Bool("word", false, "Match --contains only as a whole word")
+	saved := fs.String("saved", "", "Apply a saved search from the config file")
+	matchExpr := fs.String("match", "", "Regular expression filter for text (RE2 syntax)")
+	matchRole := fs.String("match-role", "", "Apply --match only to records of this role")
+	model := fs.String("model", "", "Filter by model, e.g. gpt-5-codex")
+	project := fs.String("project", "", "Filter by working directory: a path (and its subdirectories) or a substring")
 	limit := fs.Int("limit", 20, "Maximum records to print, 0 means all")
 	desc := fs.Bool("desc", false, "Show newest records first")
 	jsonOut := fs.Bool("json", false, "Print as JSONL")
 	maxChars := fs.Int("max-chars", 140, "Max chars per message line, 0 means no truncation")
+	noSources := fs.Bool("no-sources", false, "Omit source_file/source_line and local paths (cwd, tool workdir, attachment directories) from --json output")
+	contextRange := contextFlags(fs)
+	pairs := fs.Bool("pairs", false, "Group each user message with the assistant replies that followed it")
+	render := fs.Bool("render", false, "Lay out message markdown for the terminal instead of one line per record")
+	color := fs.String("color", "auto", "Color roles and timestamps: auto, always, or never")
+	format := fs.String("format", "", "Print each record with a Go template, e.g. '{{.Timestamp}} {{.Role}}: {{.Text}}'")
+	fieldList := fs.String("fields", "", "Print only these comma-separated fields, tab-separated: id, session_id, timestamp, role, model, text, source_file, source_line")
 
 	if err := fs.Parse(args); err != nil {
 		return err
 	}
+	if err := applySavedSearch(fs, *saved); err != nil {
+		return err
+	}
+	before, after, err := contextRange()
+	if err != nil {
+		return err
+	}
+	if *pairs && (before > 0 || after > 0) {
+		return errors.New("--pairs cannot be combined with -C, -B, or -A")
+	}
+	if *pairs && *render {
+		return errors.New("--pairs cannot be combined with --render")
+	}
+	colored, err := colorEnabled(*color)
+	if err != nil {
+		return err
+	}
+	var fields []string
+	if *fieldList != "" {
+		if *jsonOut || *render || *pairs || *format != "" {
+			return errors.New("--fields cannot be combined with --json, --render, --pairs, or --format")
+		}
+		if fields, err = parseShowFields(*fieldList); err != nil {
+			return err
+		}
+	}
+	var lineFormat *template.Template
+	if *format != "" {
+		if *jsonOut || *render || *pairs {
+			return errors.New("--format cannot be combined with --json, --render, or --pairs")
+		}
+		if lineFormat, err = parseLineFormat(*format); err != nil {
+			return err
+		}
+	}
 
 	fromTime, err := parseBoundTime(*from, "--from")
 	if err != nil {
@@ -276,51 +583,200 @@ func runShow(args []string) error {
 	if err := validateTimeRange(fromTime, toTime); err != nil {
 		return err
 	}
-
-	records, err := loadRecords(*inputPath)
+	pattern, err := compileMatch(*matchExpr, *matchRole)
 	if err != nil {
 		return err
 	}
 
-	filtered := filterRecords(records, RecordFilter{
-		SessionID: strings.TrimSpace(*sessionID),
-		Role:      strings.TrimSpace(*role),
-		Contains:  strings.TrimSpace(*contains),
-		From:      fromTime,
-		To:        toTime,
+	match := newRecordMatcher(RecordFilter{
+		SessionID:     strings.TrimSpace(*sessionID),
+		Role:          strings.TrimSpace(*role),
+		Contains:      strings.TrimSpace(*contains),
+		Match:         pattern,
+		MatchRole:     strings.TrimSpace(*matchRole),
+		CaseSensitive: *caseSensitive,
+		Word:          *word,
+		From:          fromTime,
+		To:            toTime,
+		Model:         strings.TrimSpace(*model),
+		Project:       strings.TrimSpace(*project),
 	})
 
-	sortRecordsChronological(filtered)
-	if *desc {
-		reverseRecords(filtered)
+	// Both orders print the newest matches, so only the last --limit records
+	// ever need to be held in memory. --pairs limits exchanges instead.
+	var filtered []Record
+	if *limit > 0 && !*pairs {
+		newest := &newestRecords{limit: *limit}
+		err = forEachRecord(*inputPath, func(record Record) error {
+			if match(record) {
+				newest.add(record)
+			}
+			return nil
+		})
+		filtered = newest.records()
+	} else {
+		err = forEachRecord(*inputPath, func(record Record) error {
+			if match(record) {
+				filtered = append(filtered, record)
+			}
+			return nil
+		})
+		sortRecordsChronological(filtered)
+	}
+	if err != nil {
+		return err
 	}
 
-	if *limit > 0 && len(filtered) > *limit {
+	if *pairs {
+		exchanges := pairExchanges(filtered)
+		if *limit > 0 && len(exchanges) > *limit {
+			exchanges = exchanges[len(exchanges)-*limit:]
+		}
 		if *desc {
-			filtered = filtered[:*limit]
-		} else {
-			filtered = filtered[len(filtered)-*limit:]
+			for left, right := 0, len(exchanges)-1; left < right; left, right = left+1, right-1 {
+				exchanges[left], exchanges[right] = exchanges[right], exchanges[left]
+			}
+		}
+		if *noSources {
+			for i := range exchanges {
+				if exchanges[i].Prompt != nil {
+					*exchanges[i].Prompt = stripRecordSources(*exchanges[i].Prompt)
+				}
+				stripSources(exchanges[i].Responses)
+			}
+		}
+		if *jsonOut {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetEscapeHTML(false)
+			for _, ex := range exchanges {
+				if err := enc.Encode(ex); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		printExchanges(os.Stdout, exchanges, *maxChars, colored)
+		return nil
+	}
+
+	// With context, each hit becomes a block of neighbouring records from
+	// its session; without, every record is its own block.
+	var blocks [][]contextLine
+	if before > 0 || after > 0 {
+		sessions, err := loadHitSessions(*inputPath, filtered)
+		if err != nil {
+			return err
+		}
+		blocks = withContext(filtered, sessions, before, after)
+	} else {
+		for _, record := range filtered {
+			blocks = append(blocks, []contextLine{{Record: record, Hit: true}})
+		}
+	}
+	if *desc {
+		for left, right := 0, len(blocks)-1; left < right; left, right = left+1, right-1 {
+			blocks[left], blocks[right] = blocks[right], blocks[left]
+		}
+	}
+
+	if *noSources {
+		for _, block := range blocks {
+			for i := range block {
+				block[i].Record = stripRecordSources(block[i].Record)
+			}
 		}
 	}
 
 	if *jsonOut {
 		enc := json.NewEncoder(os.Stdout)
 		enc.SetEscapeHTML(false)
-		for _, record := range filtered {
-			if err := enc.Encode(record); err != nil {
-				return err
+		for _, block := range blocks {
+			for _, line := range block {
+				if err := enc.Encode(line.Record); err != nil {
+					return err
+				}
 			}
 		}
 		return nil
 	}
 
-	for _, record := range filtered {
-		text := oneLine(record.Text, *maxChars)
-		fmt.Printf("%s [%s] %s: %s\n", record.Timestamp, shortSessionID(record.SessionID), record.Role, text)
+	for i, block := range blocks {
+		if i > 0 && (before > 0 || after > 0) {
+			fmt.Println("--")
+		}
+		for j, line := range block {
+			if fields != nil {
+				fmt.Println(formatFields(line.Record, fields, *maxChars))
+				continue
+			}
+			if lineFormat != nil {
+				if err := lineFormat.Execute(os.Stdout, line.Record); err != nil {
+					return fmt.Errorf("--format: %w", err)
+				}
+				fmt.Println()
+				continue
+			}
+			if !*render {
+				fmt.Println(showLine(line.Record, *maxChars, line.Hit, colored))
+				continue
+			}
+			if j > 0 || (i > 0 && before == 0 && after == 0) {
+				fmt.Println()
+			}
+			fmt.Println(showRendered(line.Record, line.Hit, colored))
+		}
 	}
 	return nil
 }
 
+// showRendered formats a record for show --render: the showLine prefix on
+// its own line, then the full text laid out as markdown and indented.
+func showRendered(record Record, hit, colored bool) string {
+	header := showPrefix(record, hit, colored)
+	body := renderTerminalMarkdown(record.Text, colored)
+	if body == "" {
+		return header
+	}
+	lines := strings.Split(body, "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = "  " + line
+		}
+	}
+	return header + "\n" + strings.Join(lines, "\n")
+}
+
+// showLine formats a record for show's plain output. Context records use
+// "-" after the role where matches use ":", as grep does.
+func showLine(record Record, maxChars int, hit, colored bool) string {
+	return showPrefix(record, hit, colored) + " " + oneLine(record.Text, maxChars)
+}
+
+// roleColors are the ANSI colors show uses for each role's label.
+var roleColors = map[string]string{
+	"user":      "\x1b[32m",
+	"assistant": "\x1b[35m",
+	"tool":      "\x1b[33m",
+	"patch":     "\x1b[34m",
+}
+
+// showPrefix is the "timestamp [session] role:" start of a show line. With
+// colored, the timestamp is dimmed and the role takes its roleColors entry.
+func showPrefix(record Record, hit, colored bool) string {
+	sep := ":"
+	if !hit {
+		sep = "-"
+	}
+	if !colored {
+		return fmt.Sprintf("%s [%s] %s%s", record.Timestamp, shortSessionID(record.SessionID), record.Role, sep)
+	}
+	role := record.Role + sep
+	if color, ok := roleColors[record.Role]; ok {
+		role = color + role + ansiReset
+	}
+	return fmt.Sprintf("%s%s%s [%s] %s", ansiDim, record.Timestamp, ansiReset, shortSessionID(record.SessionID), role)
+}
+
 func runStats(args []string) error {
 	fs := flag.NewFlagSet("stats", flag.ContinueOnError)
 	fs.SetOutput(os.Stderr)
@@ -331,11 +787,28 @@ func runStats(args []string) error {
 	from := fs.String("from", "", "Filter records at/after this RFC3339 timestamp")
 	to := fs.String("to", "", "Filter records at/before this RFC3339 timestamp")
 	contains := fs.String("contains", "", "Case-insensitive substring filter for text")
+	caseSensitive := fs.Bool("case-sensitive", false, "Match --contains with exact case")
+	word := fs.Bool("word", false, "Match --contains only as a whole word")
+	saved := fs.String("saved", "", "Apply a saved search from the config file")
+	matchExpr := fs.String("match", "", "Regular expression filter for text (RE2 syntax)")
+	matchRole := fs.String("match-role", "", "Apply --match only to records of this role")
+	model := fs.String("model", "", "Filter by model, e.g. gpt-5-codex")
+	project := fs.String("project", "", "Filter by working directory: a path (and its subdirectories) or a substring")
 	jsonOut := fs.Bool("json", false, "Print as JSON")
+	by := fs.String("by", "", "Break counts down by period (day, week, or month) or by model")
+	heatmap := fs.Bool("heatmap", false, "Print activity as a weekday by hour-of-day grid")
+	costs := fs.Bool("costs", false, "Estimate spend from token usage and the config's price table, by model or --by")
+	spark := fs.Bool("spark", false, "Print a sparkline of daily record counts over the last --days days")
+	days := fs.Int("days", 30, "Days covered by --spark, ending today or on --to")
+	compareWith := fs.String("compare-with", "", "Compare the last day, week, month, or N days (e.g. 14d) with the period before it")
+	tz := fs.String("tz", "UTC", "Time zone for --by periods, --heatmap, and --spark: an IANA name such as Europe/Berlin, or Local")
 
 	if err := fs.Parse(args); err != nil {
 		return err
 	}
+	if err := applySavedSearch(fs, *saved); err != nil {
+		return err
+	}
 
 	fromTime, err := parseBoundTime(*from, "--from")
 	if err != nil {
@@ -348,21 +821,235 @@ func runStats(args []string) error {
 	if err := validateTimeRange(fromTime, toTime); err != nil {
 		return err
 	}
+	pattern, err := compileMatch(*matchExpr, *matchRole)
+	if err != nil {
+		return err
+	}
+
+	match := newRecordMatcher(RecordFilter{
+		SessionID:     strings.TrimSpace(*sessionID),
+		Role:          strings.TrimSpace(*role),
+		Contains:      strings.TrimSpace(*contains),
+		Match:         pattern,
+		MatchRole:     strings.TrimSpace(*matchRole),
+		CaseSensitive: *caseSensitive,
+		Word:          *word,
+		From:          fromTime,
+		To:            toTime,
+		Model:         strings.TrimSpace(*model),
+		Project:       strings.TrimSpace(*project),
+	})
 
-	records, err := loadRecords(*inputPath)
+	if *by != "" && *heatmap {
+		return errors.New("--by and --heatmap cannot be combined")
+	}
+	loc, err := loadStatsLocation(*tz)
 	if err != nil {
 		return err
 	}
+	if *heatmap {
+		grid := newActivityHeatmap()
+		err = forEachRecord(*inputPath, func(record Record) error {
+			if match(record) {
+				grid.add(record, loc)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if *jsonOut {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetEscapeHTML(false)
+			return enc.Encode(grid)
+		}
+		grid.render(os.Stdout)
+		return nil
+	}
+	if *spark {
+		if *by != "" || *heatmap || *costs || *compareWith != "" {
+			return errors.New("--spark cannot be combined with --by, --heatmap, --costs, or --compare-with")
+		}
+		if *days < 1 {
+			return errors.New("--days must be >= 1")
+		}
+		end := toTime
+		if end.IsZero() {
+			end = time.Now()
+		}
+		activity, err := newActivityAccumulator("day", loc)
+		if err != nil {
+			return err
+		}
+		err = forEachRecord(*inputPath, func(record Record) error {
+			if match(record) {
+				activity.add(record)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		counts, first := dailyCounts(activity.result(), end, *days, loc)
+		if *jsonOut {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetEscapeHTML(false)
+			return enc.Encode(map[string]any{"first_day": first.Format("2006-01-02"), "counts": counts})
+		}
+		fmt.Println(sparkSummary(counts, first))
+		return nil
+	}
+	if *compareWith != "" {
+		if *by != "" || *heatmap || *costs {
+			return errors.New("--compare-with cannot be combined with --by, --heatmap, or --costs")
+		}
+		if !fromTime.IsZero() {
+			return errors.New("--compare-with sets its own start; use --to to choose where the current period ends")
+		}
+		length, err := comparisonLength(*compareWith)
+		if err != nil {
+			return err
+		}
+		end := toTime
+		if end.IsZero() {
+			end = time.Now().UTC()
+		}
+		comparison := newComparisonAccumulator(end, length)
+		err = forEachRecord(*inputPath, func(record Record) error {
+			if match(record) {
+				comparison.add(record)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		info, err := loadSessionInfo(sessionInfoPath(*inputPath))
+		if err != nil {
+			return err
+		}
+		result := comparison.result(info)
+		if *jsonOut {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetEscapeHTML(false)
+			return enc.Encode(result)
+		}
+		result.print(os.Stdout)
+		return nil
+	}
+	if *costs {
+		if *heatmap {
+			return errors.New("--costs and --heatmap cannot be combined")
+		}
+		lastSeen := make(map[string]string)
+		err = forEachRecord(*inputPath, func(record Record) error {
+			if match(record) {
+				lastSeen[record.SessionID] = laterTimestamp(lastSeen[record.SessionID], record.Timestamp)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		info, err := loadSessionInfo(sessionInfoPath(*inputPath))
+		if err != nil {
+			return err
+		}
+		groupBy := strings.ToLower(strings.TrimSpace(*by))
+		if groupBy == "" {
+			groupBy = "model"
+		}
+		report, err := computeCosts(lastSeen, info, appConfig.Prices, groupBy, loc)
+		if err != nil {
+			return err
+		}
+		if *jsonOut {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetEscapeHTML(false)
+			return enc.Encode(report)
+		}
+		for _, line := range report.Lines {
+			fmt.Printf("%s sessions=%d tokens=%d cost=$%.2f\n", line.Key, line.Sessions, line.Tokens, line.Cost)
+		}
+		fmt.Printf("total cost=$%.2f\n", report.Total)
+		if len(report.UnpricedModels) > 0 {
+			fmt.Fprintf(os.Stderr, "note: sessions of %s are not counted; no price is configured under \"prices\" in the config file\n", strings.Join(report.UnpricedModels, ", "))
+		}
+		return nil
+	}
+	if strings.EqualFold(strings.TrimSpace(*by), "model") {
+		models := newModelAccumulator()
+		err = forEachRecord(*inputPath, func(record Record) error {
+			if match(record) {
+				models.add(record)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		info, err := loadSessionInfo(sessionInfoPath(*inputPath))
+		if err != nil {
+			return err
+		}
+		result := models.result(info)
+		if *jsonOut {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetEscapeHTML(false)
+			return enc.Encode(result)
+		}
+		for _, stats := range result {
+			line := fmt.Sprintf("%s total=%d user=%d assistant=%d other=%d sessions=%d", stats.Model, stats.Total, stats.User, stats.Assistant, stats.Other, stats.Sessions)
+			if stats.Usage != nil {
+				line += fmt.Sprintf(" tokens=%d", stats.Usage.TotalTokens)
+			}
+			fmt.Println(line)
+		}
+		return nil
+	}
+	if *by != "" {
+		activity, err := newActivityAccumulator(strings.ToLower(strings.TrimSpace(*by)), loc)
+		if err != nil {
+			return err
+		}
+		err = forEachRecord(*inputPath, func(record Record) error {
+			if match(record) {
+				activity.add(record)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		buckets := activity.result()
+		if *jsonOut {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetEscapeHTML(false)
+			return enc.Encode(buckets)
+		}
+		for _, bucket := range buckets {
+			fmt.Printf("%s total=%d user=%d assistant=%d other=%d sessions=%d\n", bucket.Period, bucket.Total, bucket.User, bucket.Assistant, bucket.Other, bucket.Sessions)
+		}
+		return nil
+	}
 
-	filtered := filterRecords(records, RecordFilter{
-		SessionID: strings.TrimSpace(*sessionID),
-		Role:      strings.TrimSpace(*role),
-		Contains:  strings.TrimSpace(*contains),
-		From:      fromTime,
-		To:        toTime,
+	acc := newStatsAccumulator()
+	err = forEachRecord(*inputPath, func(record Record) error {
+		if match(record) {
+			acc.add(record)
+		}
+		return nil
 	})
+	if err != nil {
+		return err
+	}
+	stats := acc.result()
 
-	stats := computeStats(filtered)
+	info, err := loadSessionInfo(sessionInfoPath(*inputPath))
+	if err != nil {
+		return err
+	}
+	stats.Usage = sumUsage(info, acc.sessions)
 
 	if *jsonOut {
 		enc := json.NewEncoder(os.Stdout)
@@ -374,9 +1061,25 @@ func runStats(args []string) error {
 	fmt.Printf("user=%d\n", stats.User)
 	fmt.Printf("assistant=%d\n", stats.Assistant)
 	fmt.Printf("other=%d\n", stats.Other)
+	fmt.Printf("errors=%d\n", stats.Errors)
 	fmt.Printf("session_count=%d\n", stats.SessionCount)
 	fmt.Printf("first_timestamp=%s\n", stats.FirstTimestamp)
 	fmt.Printf("last_timestamp=%s\n", stats.LastTimestamp)
+	for _, role := range sortedKeys(stats.Lengths) {
+		lengths := stats.Lengths[role]
+		fmt.Printf("%s_chars p50=%d p90=%d p99=%d\n", role, lengths.Chars.P50, lengths.Chars.P90, lengths.Chars.P99)
+		fmt.Printf("%s_words p50=%d p90=%d p99=%d\n", role, lengths.Words.P50, lengths.Words.P90, lengths.Words.P99)
+	}
+	if stats.Usage != nil {
+		fmt.Printf("input_tokens=%d\n", stats.Usage.InputTokens)
+		fmt.Printf("cached_input_tokens=%d\n", stats.Usage.CachedInputTokens)
+		fmt.Printf("output_tokens=%d\n", stats.Usage.OutputTokens)
+		fmt.Printf("reasoning_output_tokens=%d\n", stats.Usage.ReasoningOutputTokens)
+		fmt.Printf("total_tokens=%d\n", stats.Usage.TotalTokens)
+	}
+	if stats.Latency != nil {
+		fmt.Printf("response_latency p50=%s p90=%s replies=%d\n", formatLatency(stats.Latency.P50Seconds), formatLatency(stats.Latency.P90Seconds), stats.Latency.Count)
+	}
 	return nil
 }
 
@@ -388,12 +1091,38 @@ func runSessions(args []string) error {
 	from := fs.String("from", "", "Filter records at/after this RFC3339 timestamp")
 	to := fs.String("to", "", "Filter records at/before this RFC3339 timestamp")
 	contains := fs.String("contains", "", "Case-insensitive substring filter for text")
+	caseSensitive := fs.Bool("case-sensitive", false, "Match --contains with exact case")
+	word := fs.Bool("word", false, "Match --contains only as a whole word")
+	saved := fs.String("saved", "", "Apply a saved search from the config file")
+	matchExpr := fs.String("match", "", "Regular expression filter for text (RE2 syntax)")
+	matchRole := fs.String("match-role", "", "Apply --match only to records of this role")
 	limit := fs.Int("limit", 20, "Maximum sessions to print, 0 means all")
 	jsonOut := fs.Bool("json", false, "Print as JSON")
+	project := fs.String("project", "", "Filter by working directory: a path (and its subdirectories) or a substring")
+	role := fs.String("role", "", "Only sessions with messages of this role (counted by --min-messages)")
+	minMessages := fs.Int("min-messages", 0, "Only sessions with at least N messages (of --role when set)")
+	minUser := fs.Int("min-user", 0, "Only sessions with at least N user messages")
+	topBy := fs.String("top-by", "", "Rank sessions by size instead of recency: total, assistant, chars, or duration")
+	noPreview := fs.Bool("no-preview", false, "Leave the first user message out of each line")
+	groupBy := fs.String("group-by", "", "Aggregate sessions per group instead of listing them: project")
 
 	if err := fs.Parse(args); err != nil {
 		return err
 	}
+	if err := applySavedSearch(fs, *saved); err != nil {
+		return err
+	}
+	if *minMessages < 0 || *minUser < 0 {
+		return errors.New("--min-messages and --min-user must be >= 0")
+	}
+	switch strings.ToLower(strings.TrimSpace(*groupBy)) {
+	case "", "project":
+	default:
+		return fmt.Errorf("unsupported --group-by %q (use project)", *groupBy)
+	}
+	if *groupBy != "" && *topBy != "" {
+		return errors.New("--group-by and --top-by cannot be combined")
+	}
 
 	fromTime, err := parseBoundTime(*from, "--from")
 	if err != nil {
@@ -406,23 +1135,75 @@ func runSessions(args []string) error {
 	if err := validateTimeRange(fromTime, toTime); err != nil {
 		return err
 	}
-
-	records, err := loadRecords(*inputPath)
+	pattern, err := compileMatch(*matchExpr, *matchRole)
 	if err != nil {
 		return err
 	}
 
-	filtered := filterRecords(records, RecordFilter{
-		Contains: strings.TrimSpace(*contains),
-		From:     fromTime,
-		To:       toTime,
+	match := newRecordMatcher(RecordFilter{
+		Contains:      strings.TrimSpace(*contains),
+		Match:         pattern,
+		MatchRole:     strings.TrimSpace(*matchRole),
+		CaseSensitive: *caseSensitive,
+		Word:          *word,
+		From:          fromTime,
+		To:            toTime,
+		Project:       strings.TrimSpace(*project),
 	})
 
-	summaries := buildSessionSummaries(filtered)
+	acc := newSessionAccumulator()
+	err = forEachRecord(*inputPath, func(record Record) error {
+		if match(record) {
+			acc.add(record)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	summaries := acc.summaries()
+	summaries = filterSessionsByActivity(summaries, acc.roleCounts, strings.TrimSpace(*role), *minMessages)
+	if *minUser > 0 {
+		summaries = filterSessionsByActivity(summaries, acc.roleCounts, "user", *minUser)
+	}
+	if *groupBy != "" {
+		// Every session's working directory is needed before grouping, so
+		// the sidecar is attached first and --limit counts projects.
+		info, err := loadSessionInfo(sessionInfoPath(*inputPath))
+		if err != nil {
+			return err
+		}
+		attachSessionInfo(summaries, info)
+		projects := groupSessionsByProject(summaries)
+		if *limit > 0 && len(projects) > *limit {
+			projects = projects[:*limit]
+		}
+		if *jsonOut {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetEscapeHTML(false)
+			return enc.Encode(projects)
+		}
+		for _, project := range projects {
+			fmt.Println(projectSummaryLine(project))
+		}
+		return nil
+	}
+	if *topBy != "" {
+		if err := rankSessions(summaries, *topBy); err != nil {
+			return err
+		}
+	}
 	if *limit > 0 && len(summaries) > *limit {
 		summaries = summaries[:*limit]
 	}
 
+	info, err := loadSessionInfo(sessionInfoPath(*inputPath))
+	if err != nil {
+		return err
+	}
+	attachSessionInfo(summaries, info)
+
 	if *jsonOut {
 		enc := json.NewEncoder(os.Stdout)
 		enc.SetEscapeHTML(false)
@@ -430,40 +1211,131 @@ func runSessions(args []string) error {
 	}
 
 	for _, summary := range summaries {
-		fmt.Printf("%s total=%d user=%d assistant=%d other=%d first=%s last=%s\n",
-			summary.SessionID,
-			summary.Total,
-			summary.User,
-			summary.Assistant,
-			summary.Other,
-			summary.FirstTimestamp,
-			summary.LastTimestamp,
-		)
+		line := sessionSummaryLine(summary)
+		if strings.EqualFold(strings.TrimSpace(*topBy), "chars") {
+			line += fmt.Sprintf(" chars=%d", summary.Chars)
+		}
+		if !*noPreview && summary.Preview != "" {
+			line += fmt.Sprintf(" preview=%q", summary.Preview)
+		}
+		fmt.Println(line)
 	}
 	return nil
 }
 
+func sessionSummaryLine(summary SessionSummary) string {
+	line := fmt.Sprintf("%s total=%d user=%d assistant=%d other=%d first=%s last=%s",
+		summary.SessionID,
+		summary.Total,
+		summary.User,
+		summary.Assistant,
+		summary.Other,
+		summary.FirstTimestamp,
+		summary.LastTimestamp,
+	)
+	line += " duration=" + formatSessionDuration(summary.DurationSeconds)
+	if summary.Usage != nil {
+		line += fmt.Sprintf(" tokens=%d", summary.Usage.TotalTokens)
+	}
+	if summary.Errors > 0 {
+		line += fmt.Sprintf(" errors=%d", summary.Errors)
+	}
+	if summary.Latency != nil {
+		line += " latency_p50=" + formatLatency(summary.Latency.P50Seconds)
+	}
+	if summary.Model != "" {
+		line += " model=" + summary.Model
+	}
+	if summary.Cwd != "" {
+		line += " cwd=" + summary.Cwd
+	}
+	if len(summary.FilesChanged) > 0 {
+		added, removed := 0, 0
+		for _, change := range summary.FilesChanged {
+			added += change.Added
+			removed += change.Removed
+		}
+		line += fmt.Sprintf(" files_changed=%d +%d -%d", len(summary.FilesChanged), added, removed)
+	}
+	return line
+}
+
 func runExport(args []string) error {
 	fs := flag.NewFlagSet("export", flag.ContinueOnError)
 	fs.SetOutput(os.Stderr)
 
 	inputPath := fs.String("in", defaultOutputFile(), "Input JSONL path")
 	outPath := fs.String("out", "", "Output file path (default: stdout)")
-	format := fs.String("format", "markdown", "Export format: markdown|csv|jsonl")
+	format := fs.String("format", "markdown", "Export format: markdown|csv|jsonl|html|pdf|openai-chat|sharegpt|parquet|sqlite|arrow|atom|template")
 	sessionID := fs.String("session", "", "Filter by session ID")
 	role := fs.String("role", "", "Filter by role: user or assistant")
 	from := fs.String("from", "", "Filter records at/after this RFC3339 timestamp")
 	to := fs.String("to", "", "Filter records at/before this RFC3339 timestamp")
 	contains := fs.String("contains", "", "Case-insensitive substring filter for text")
+	caseSensitive := fs.Bool("case-sensitive", false, "Match --contains with exact case")
+	word := fs.Bool("word", false, "Match --contains only as a whole word")
+	saved := fs.String("saved", "", "Apply a saved search from the config file")
+	matchExpr := fs.String("match", "", "Regular expression filter for text (RE2 syntax)")
+	matchRole := fs.String("match-role", "", "Apply --match only to records of this role")
+	model := fs.String("model", "", "Filter by model, e.g. gpt-5-codex")
+	project := fs.String("project", "", "Filter by working directory: a path (and its subdirectories) or a substring")
 	limit := fs.Int("limit", 0, "Maximum records to export, 0 means all")
 	desc := fs.Bool("desc", false, "Export newest records first")
+	noSources := fs.Bool("no-sources", false, "Omit source_file/source_line provenance and local paths (cwd, tool workdir, attachment directories)")
+	split := fs.String("split", "", "Write one file per group instead of one export: session")
+	outDir := fs.String("out-dir", "", "Directory for --split output files")
+	gzipOut := fs.Bool("gzip", false, "Compress the output with gzip (adds .gz to --out)")
+	zstdOut := fs.Bool("zstd", false, "Compress the output with zstd (adds .zst to --out)")
+	templatePath := fs.String("template", "", "Go text/template file for --format template")
+	per := fs.String("per", "record", "Execute --template once per: record or session")
 
 	if err := fs.Parse(args); err != nil {
 		return err
 	}
+	if err := applySavedSearch(fs, *saved); err != nil {
+		return err
+	}
 	if *limit < 0 {
 		return errors.New("--limit must be >= 0")
 	}
+	switch strings.TrimSpace(*split) {
+	case "":
+		if strings.TrimSpace(*outDir) != "" {
+			return errors.New("--out-dir requires --split")
+		}
+	case "session":
+		if strings.TrimSpace(*outDir) == "" {
+			return errors.New("--split requires --out-dir")
+		}
+		if strings.TrimSpace(*outPath) != "" {
+			return errors.New("--out cannot be combined with --split; use --out-dir")
+		}
+	default:
+		return fmt.Errorf("unsupported --split %q (use session)", *split)
+	}
+	compression, err := exportCompression(*gzipOut, *zstdOut)
+	if err != nil {
+		return err
+	}
+	opts := ExportOptions{NoSources: *noSources, Compression: compression}
+	switch strings.TrimSpace(*per) {
+	case "record":
+	case "session":
+		opts.PerSession = true
+	default:
+		return fmt.Errorf("unsupported --per %q (use record or session)", *per)
+	}
+	if strings.TrimSpace(*templatePath) != "" {
+		if strings.ToLower(strings.TrimSpace(*format)) != "template" {
+			return errors.New("--template requires --format template")
+		}
+		if opts.Template, err = loadExportTemplate(*templatePath); err != nil {
+			return err
+		}
+	}
+	if compression != "" {
+		*outPath = withCompressionSuffix(strings.TrimSpace(*outPath), compression)
+	}
 
 	fromTime, err := parseBoundTime(*from, "--from")
 	if err != nil {
@@ -476,36 +1348,71 @@ func runExport(args []string) error {
 	if err := validateTimeRange(fromTime, toTime); err != nil {
 		return err
 	}
-
-	records, err := loadRecords(*inputPath)
+	pattern, err := compileMatch(*matchExpr, *matchRole)
 	if err != nil {
 		return err
 	}
 
-	filtered := filterRecords(records, RecordFilter{
-		SessionID: strings.TrimSpace(*sessionID),
-		Role:      strings.TrimSpace(*role),
-		Contains:  strings.TrimSpace(*contains),
-		From:      fromTime,
-		To:        toTime,
-	})
+	filter := RecordFilter{
+		SessionID:     strings.TrimSpace(*sessionID),
+		Role:          strings.TrimSpace(*role),
+		Contains:      strings.TrimSpace(*contains),
+		Match:         pattern,
+		MatchRole:     strings.TrimSpace(*matchRole),
+		CaseSensitive: *caseSensitive,
+		Word:          *word,
+		From:          fromTime,
+		To:            toTime,
+		Model:         strings.TrimSpace(*model),
+		Project:       strings.TrimSpace(*project),
+	}
+
+	// Arrow output is in file order, so it can be streamed when nothing
+	// needs the whole history in memory to reverse or trim it.
+	if strings.ToLower(strings.TrimSpace(*format)) == "arrow" && !*desc && *limit == 0 && *split == "" {
+		count, err := streamArrowExport(*inputPath, *outPath, filter, opts)
+		if err != nil {
+			return err
+		}
+		if strings.TrimSpace(*outPath) != "" {
+			fmt.Printf("exported %d records to %s (arrow)\n", count, *outPath)
+		}
+		return nil
+	}
 
-	sortRecordsChronological(filtered)
-	if *desc {
-		reverseRecords(filtered)
+	// Filter while reading so only the exported slice is held in memory.
+	match := newRecordMatcher(filter)
+	var filtered []Record
+	err = forEachRecord(*inputPath, func(record Record) error {
+		if match(record) {
+			filtered = append(filtered, record)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
-	if *limit > 0 && len(filtered) > *limit {
-		if *desc {
-			filtered = filtered[:*limit]
-		} else {
-			filtered = filtered[len(filtered)-*limit:]
+
+	filtered = orderExport(filtered, strings.ToLower(strings.TrimSpace(*format)), *desc, *limit)
+
+	if *split != "" {
+		files, err := writeSplitExport(*outDir, strings.ToLower(strings.TrimSpace(*format)), filtered, opts)
+		if err != nil {
+			return err
 		}
+		fmt.Printf("exported %d records in %d files to %s (%s)\n", len(filtered), files, *outDir, strings.ToLower(strings.TrimSpace(*format)))
+		return nil
 	}
 
-	content, err := renderExport(strings.ToLower(strings.TrimSpace(*format)), filtered)
+	content, err := renderExport(strings.ToLower(strings.TrimSpace(*format)), filtered, opts)
 	if err != nil {
 		return err
 	}
+	if compression != "" {
+		if content, err = compressBytes(content, compression); err != nil {
+			return err
+		}
+	}
 
 	if err := writeOutput(*outPath, content); err != nil {
 		return err
@@ -517,6 +1424,27 @@ func runExport(args []string) error {
 	return nil
 }
 
+// orderExport sorts the records to export by time, newest first with desc,
+// and keeps the last limit of them (the first, with desc). Arrow output is
+// in file order instead, the order the streaming path writes, so the same
+// export does not change order when --desc, --limit, or --split is added.
+func orderExport(records []Record, format string, desc bool, limit int) []Record {
+	if format != "arrow" {
+		sortRecordsChronological(records)
+	}
+	if desc {
+		reverseRecords(records)
+	}
+	if limit > 0 && len(records) > limit {
+		if desc {
+			records = records[:limit]
+		} else {
+			records = records[len(records)-limit:]
+		}
+	}
+	return records
+}
+
 func parseBoundTime(raw string, flagName string) (time.Time, error) {
 	trimmed := strings.TrimSpace(raw)
 	if trimmed == "" {
@@ -537,49 +1465,210 @@ func validateTimeRange(from, to time.Time) error {
 	return nil
 }
 
+// sourceParseError is a session file syncOnce could not parse.
+type sourceParseError struct {
+	path string
+	err  error
+}
+
+func (e *sourceParseError) Error() string {
+	return fmt.Sprintf("failed to parse %s: %v", e.path, e.err)
+}
+
+func (e *sourceParseError) Unwrap() error { return e.err }
+
 func syncOnce(opts SyncOptions) (SyncResult, error) {
+	if !opts.DryRun {
+		if err := checkWritableHistory(opts.OutputPath); err != nil {
+			return SyncResult{}, err
+		}
+	}
 	files, err := listSessionFiles(opts.SessionsDir)
 	if err != nil {
 		return SyncResult{}, err
 	}
 
-	existing, err := loadExistingIDs(opts.OutputPath)
+	// Hold the history lock from reading existing IDs through the append so
+	// two concurrent syncs cannot both write the same records.
+	if !opts.DryRun {
+		unlock, err := lockHistory(opts.OutputPath)
+		if err != nil {
+			return SyncResult{}, err
+		}
+		defer unlock()
+	}
+
+	existing, err := loadIDIndex(opts.OutputPath, !opts.DryRun)
+	if err != nil {
+		return SyncResult{}, err
+	}
+	// Records removed on purpose (delete, redact, archive) must not come back.
+	removed, err := loadRemovedIDs(opts.OutputPath)
 	if err != nil {
 		return SyncResult{}, err
 	}
+	for key := range removed {
+		existing[key] = struct{}{}
+	}
+	// Nor may records older than what retention has already removed.
+	retainedSince, err := loadRetentionSince(opts.OutputPath)
+	if err != nil {
+		return SyncResult{}, err
+	}
+	if retainedSince.After(opts.Since) {
+		opts.Since = retainedSince
+	}
+	if opts.BlockSecrets {
+		alreadyBlocked, err := loadIDFile(blockedIDsPath(opts.OutputPath))
+		if err != nil {
+			return SyncResult{}, err
+		}
+		for key := range alreadyBlocked {
+			existing[key] = struct{}{}
+		}
+	}
+
+	var sources map[string]SourceState
+	var sessionInfo map[string]SessionInfo
+	statePath := sourcesStatePath(opts.OutputPath)
+	infoPath := sessionInfoPath(opts.OutputPath)
+	if !opts.DryRun {
+		sources, err = loadSourcesState(statePath)
+		if err != nil {
+			return SyncResult{}, err
+		}
+		sessionInfo, err = loadSessionInfo(infoPath)
+		if err != nil {
+			return SyncResult{}, err
+		}
+	}
 
 	newRecords := make([]Record, 0, 128)
+	// blocked holds the IDs of records --block-secrets left out; they are
+	// saved so later blocking runs skip them without warning again.
+	var blocked []string
 	result := SyncResult{Files: len(files)}
 
-	for _, path := range files {
-		records, err := extractRecords(path, opts.Since)
+	extracted := extractAll(files, ExtractOptions{Since: opts.Since, NoSanitize: opts.NoSanitize, Include: opts.Include, AssetsDir: opts.AssetsDir, Events: opts.Events}, opts.Workers)
+	for i, path := range files {
+		records, err := extracted[i].records, extracted[i].err
+		if sources != nil {
+			recordSourceScan(sources, path, len(records), err)
+		}
 		if err != nil {
-			return SyncResult{}, fmt.Errorf("failed to parse %s: %w", path, err)
+			if sources != nil {
+				if saveErr := saveSourcesState(statePath, sources); saveErr != nil {
+					fmt.Fprintf(os.Stderr, "warning: failed to save sources state: %v\n", saveErr)
+				}
+			}
+			return SyncResult{}, &sourceParseError{path: path, err: err}
+		}
+		if sessionInfo != nil {
+			info := extracted[i].info
+			if opts.HashPaths {
+				info = hashSessionInfoPaths(info)
+			}
+			mergeSessionInfo(sessionInfo, extracted[i].sessionID, info)
 		}
 
 		result.Scanned += len(records)
 		for _, record := range records {
-			if _, exists := existing[record.ID]; exists {
+			if opts.HashPaths {
+				record = hashRecordPaths(record)
+			}
+			if opts.MaxTextBytes > 0 {
+				record = truncateRecordText(record, opts.MaxTextBytes)
+			}
+			if existing.has(record.ID) {
 				continue
 			}
-			existing[record.ID] = struct{}{}
+			if kinds := detectSecrets(record.Text); len(kinds) > 0 {
+				result.Secrets = append(result.Secrets, SecretFinding{RecordID: record.ID, SessionID: record.SessionID, Kinds: kinds})
+				if opts.BlockSecrets {
+					existing.add(record.ID)
+					blocked = append(blocked, record.ID)
+					continue
+				}
+			}
+			if opts.RelativeSources {
+				record.SourceFile = relativeSourcePath(opts.SessionsDir, record.SourceFile)
+			}
+			existing.add(record.ID)
 			newRecords = append(newRecords, record)
 		}
 	}
-
-	result.Written = len(newRecords)
-
-	if opts.DryRun || len(newRecords) == 0 {
+
+	result.Written = len(newRecords)
+	result.NewRecords = newRecords
+
+	if opts.DryRun {
+		return result, nil
+	}
+	if err := saveSourcesState(statePath, sources); err != nil {
+		return SyncResult{}, err
+	}
+	if err := saveSessionInfo(infoPath, sessionInfo); err != nil {
+		return SyncResult{}, err
+	}
+	if err := appendIDFile(blockedIDsPath(opts.OutputPath), blocked); err != nil {
+		return SyncResult{}, err
+	}
+	if len(newRecords) == 0 {
 		return result, nil
 	}
 
-	if err := appendRecords(opts.OutputPath, newRecords); err != nil {
+	before, err := os.Stat(opts.OutputPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return SyncResult{}, err
+	}
+	if err := appendRecords(opts.OutputPath, newRecords, opts.Fsync); err != nil {
 		return SyncResult{}, err
 	}
+	if err := appendIDIndex(opts.OutputPath, before, newRecords); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: failed to update id index: %v\n", err)
+	}
 
 	return result, nil
 }
 
+type fileExtraction struct {
+	records   []Record
+	sessionID string
+	info      SessionInfo
+	err       error
+}
+
+// extractAll parses files on a pool of workers (NumCPU when workers <= 0).
+// Results come back indexed like files so the merge stays deterministic.
+func extractAll(files []string, opts ExtractOptions, workers int) []fileExtraction {
+	results := make([]fileExtraction, len(files))
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	if workers > len(files) {
+		workers = len(files)
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				session, err := extractSession(files[i], opts)
+				results[i] = fileExtraction{records: session.Records, sessionID: session.SessionID, info: session.Info, err: err}
+			}
+		}()
+	}
+	for i := range files {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return results
+}
+
 func listSessionFiles(root string) ([]string, error) {
 	files := make([]string, 0, 64)
 	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
@@ -604,11 +1693,26 @@ func listSessionFiles(root string) ([]string, error) {
 	return files, nil
 }
 
-func extractRecords(path string, since time.Time) ([]Record, error) {
-	file, err := os.Open(path)
+func extractRecords(path string, opts ExtractOptions) ([]Record, error) {
+	session, err := extractSession(path, opts)
 	if err != nil {
 		return nil, err
 	}
+	return session.Records, nil
+}
+
+// sessionExtraction is everything sync takes from one session file.
+type sessionExtraction struct {
+	SessionID string
+	Records   []Record
+	Info      SessionInfo
+}
+
+func extractSession(path string, opts ExtractOptions) (sessionExtraction, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return sessionExtraction{}, err
+	}
 	defer file.Close()
 
 	scanner := bufio.NewScanner(file)
@@ -617,65 +1721,322 @@ func extractRecords(path string, since time.Time) ([]Record, error) {
 	sessionID := sessionIDFromPath(path)
 	records := make([]Record, 0, 128)
 	lineNum := 0
+	// Codex logs a tool call both as a response_item and as an event_msg;
+	// only the first sighting of each call_id becomes a record.
+	seenCalls := make(map[string]bool)
+	var info SessionInfo
+	// model is the model from the latest turn_context and cwd the working
+	// directory from session_meta or the latest turn_context; records
+	// written after them are tagged with them.
+	model := ""
+	cwd := ""
+
+	// emit appends a record and reports whether it did; empty text and
+	// records before --from are skipped. The ID hashes the text as Codex
+	// logged it, so sanitizing never changes the ID of a record that an
+	// older history already holds.
+	emit := func(timestamp, role, text string, meta map[string]string) bool {
+		original := strings.TrimSpace(text)
+		text = original
+		if !opts.NoSanitize {
+			text = strings.TrimSpace(sanitizeText(text))
+		}
+		if text == "" {
+			return false
+		}
+
+		timestamp = normalizeTimestamp(timestamp)
+		if !opts.Since.IsZero() {
+			if parsed, ok := parseRecordTime(timestamp); ok && parsed.Before(opts.Since) {
+				return false
+			}
+		}
+		if model != "" || cwd != "" || text != original {
+			if meta == nil {
+				meta = make(map[string]string, 3)
+			}
+			if model != "" {
+				meta["model"] = model
+			}
+			if cwd != "" {
+				meta["cwd"] = cwd
+			}
+			if text != original {
+				meta["sanitized"] = "true"
+			}
+		}
+
+		records = append(records, Record{
+			ID:         makeRecordID(sessionID, timestamp, role, original),
+			SessionID:  sessionID,
+			Timestamp:  timestamp,
+			Role:       role,
+			Text:       text,
+			SourceFile: path,
+			SourceLine: lineNum,
+			Meta:       meta,
+		})
+		return true
+	}
+	seenPatches := make(map[string]bool)
+	emitPatch := func(timestamp, callID string, changes []FileChange) {
+		if len(changes) == 0 {
+			return
+		}
+		if callID != "" {
+			if seenPatches[callID] {
+				return
+			}
+			seenPatches[callID] = true
+		}
+		info.FilesChanged = mergeFileChanges(info.FilesChanged, changes)
+		var meta map[string]string
+		if callID != "" {
+			meta = map[string]string{"call_id": callID}
+		}
+		emit(timestamp, "patch", patchText(changes), meta)
+	}
+	// toolRecords maps call_id to the index of its tool record, so outputs
+	// logged later in the file can be attached to it.
+	toolRecords := make(map[string]int)
+	// lastTimestamp dates passthrough lines that carry no timestamp.
+	lastTimestamp := ""
+
+	// Newer Codex releases log each message twice, as an event_msg and as a
+	// response_item. Messages from response_items are only kept for files
+	// that have no event_msg messages at all (response_item-only rollouts).
+	sawEventMessages := false
+	responseMessages := make(map[int]bool)
+	emitMessage := func(timestamp, role, text string, images []string) error {
+		attachments, err := imageAttachments(images, opts.AssetsDir)
+		if err != nil {
+			return err
+		}
+		if strings.TrimSpace(text) == "" && len(attachments) > 0 {
+			text = attachmentPlaceholder(attachments)
+		}
+		if emit(timestamp, role, text, nil) && len(attachments) > 0 {
+			records[len(records)-1].Attachments = attachments
+		}
+		return nil
+	}
+	emitTool := func(timestamp, callID, text string, call *ToolCall) {
+		if callID != "" {
+			if seenCalls[callID] {
+				return
+			}
+			seenCalls[callID] = true
+		}
+		var meta map[string]string
+		if callID != "" {
+			meta = map[string]string{"call_id": callID}
+		}
+		if !emit(timestamp, "tool", text, meta) {
+			return
+		}
+		records[len(records)-1].Tool = call
+		if callID != "" {
+			toolRecords[callID] = len(records) - 1
+		}
+	}
+	attachToolOutput := func(callID, output string, exitCode *int) {
+		index, ok := toolRecords[callID]
+		if !ok || records[index].Tool == nil {
+			return
+		}
+		call := records[index].Tool
+		if call.Output == "" && output != "" {
+			call.Output = truncateToolOutput(output)
+		}
+		if call.ExitCode == nil && exitCode != nil {
+			call.ExitCode = exitCode
+		}
+	}
 
 	for scanner.Scan() {
 		lineNum++
 		line := scanner.Bytes()
+		if lineNum == 1 {
+			line = bytes.TrimPrefix(line, utf8BOM)
+		}
+		// Files round-tripped through Windows editors end lines with CRLF and
+		// often a trailing blank line; neither should fail the strict parse.
+		line = bytes.TrimRight(line, "\r")
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
 
 		var item envelope
 		if err := json.Unmarshal(line, &item); err != nil {
-			return nil, fmt.Errorf("line %d: %w", lineNum, err)
+			return sessionExtraction{}, fmt.Errorf("line %d: %w", lineNum, err)
 		}
 
+		before := len(records)
 		switch item.Type {
 		case "session_meta":
 			var meta sessionMetaPayload
-			if err := json.Unmarshal(item.Payload, &meta); err == nil && strings.TrimSpace(meta.ID) != "" {
+			if err := json.Unmarshal(item.Payload, &meta); err != nil {
+				break
+			}
+			if strings.TrimSpace(meta.ID) != "" {
 				sessionID = strings.TrimSpace(meta.ID)
 			}
+			if dir := strings.TrimSpace(meta.Cwd); dir != "" {
+				cwd = dir
+				info.Cwd = dir
+			}
+		case "turn_context":
+			var tc turnContextPayload
+			if err := json.Unmarshal(item.Payload, &tc); err != nil {
+				break
+			}
+			if m := strings.TrimSpace(tc.Model); m != "" {
+				model = m
+				info.Model = m
+			}
+			if dir := strings.TrimSpace(tc.Cwd); dir != "" {
+				cwd = dir
+				info.Cwd = dir
+			}
+			if tc.ApprovalPolicy != "" {
+				info.ApprovalPolicy = tc.ApprovalPolicy
+			}
+			if mode := sandboxMode(tc.SandboxPolicy); mode != "" {
+				info.SandboxMode = mode
+			}
 		case "event_msg":
 			var ev eventPayload
 			if err := json.Unmarshal(item.Payload, &ev); err != nil {
-				continue
+				break
 			}
 
-			role := ""
 			switch ev.Type {
 			case "user_message":
-				role = "user"
+				sawEventMessages = true
+				if err := emitMessage(item.Timestamp, "user", ev.Message, ev.Images); err != nil {
+					return sessionExtraction{}, fmt.Errorf("line %d: %w", lineNum, err)
+				}
 			case "agent_message":
-				role = "assistant"
+				sawEventMessages = true
+				emit(item.Timestamp, "assistant", ev.Message, nil)
+			case "error", "stream_error":
+				emit(item.Timestamp, "error", ev.Message, map[string]string{"event": ev.Type})
+			case "turn_aborted":
+				reason := ev.Reason
+				if reason == "" {
+					reason = "unknown"
+				}
+				emit(item.Timestamp, "error", "turn aborted: "+reason, map[string]string{"event": ev.Type})
+			case "agent_reasoning":
+				if opts.Include[includeReasoning] {
+					emit(item.Timestamp, "reasoning", ev.Text, nil)
+				}
+			case "token_count":
+				if ev.Info != nil && ev.Info.TotalTokenUsage != nil {
+					usage := *ev.Info.TotalTokenUsage
+					info.Usage = &usage
+				}
+			case "exec_command_begin":
+				if opts.Include[includeTools] {
+					emitTool(item.Timestamp, ev.CallID, commandString(ev.Command), execToolCall(ev))
+				}
+			case "exec_command_end":
+				if opts.Include[includeTools] {
+					attachToolOutput(ev.CallID, firstOutput(ev.AggregatedOutput, ev.Stdout+ev.Stderr), ev.ExitCode)
+				}
+			case "patch_apply_begin":
+				if opts.Include[includePatches] {
+					emitPatch(item.Timestamp, ev.CallID, patchApplyChanges(ev.Changes))
+				}
 			}
-
-			text := strings.TrimSpace(ev.Message)
-			if role == "" || text == "" {
-				continue
+		case "response_item":
+			var ri responseItemPayload
+			if err := json.Unmarshal(item.Payload, &ri); err != nil {
+				break
 			}
-
-			timestamp := normalizeTimestamp(item.Timestamp)
-			if !since.IsZero() {
-				if parsed, ok := parseRecordTime(timestamp); ok && parsed.Before(since) {
-					continue
+			if ri.Type == "message" {
+				text, images, ok := responseMessageContent(ri)
+				if !ok {
+					break
+				}
+				if err := emitMessage(item.Timestamp, ri.Role, text, images); err != nil {
+					return sessionExtraction{}, fmt.Errorf("line %d: %w", lineNum, err)
+				}
+				if len(records) > before {
+					responseMessages[len(records)-1] = true
+				}
+				break
+			}
+			if opts.Include[includePatches] {
+				if patch := applyPatchInput(ri); patch != "" {
+					emitPatch(item.Timestamp, ri.CallID, applyPatchChanges(patch))
 				}
 			}
+			if opts.Include[includeTools] {
+				if text := toolCallText(ri); text != "" {
+					emitTool(item.Timestamp, ri.CallID, text, responseToolCall(ri))
+				}
+				if ri.Type == "function_call_output" || ri.Type == "custom_tool_call_output" {
+					output, exitCode := parseToolOutput(ri.Output)
+					attachToolOutput(ri.CallID, output, exitCode)
+				}
+			}
+		}
 
-			record := Record{
-				ID:         makeRecordID(sessionID, timestamp, role, text),
-				SessionID:  sessionID,
-				Timestamp:  timestamp,
-				Role:       role,
-				Text:       text,
-				SourceFile: path,
-				SourceLine: lineNum,
+		if opts.Events != nil && len(records) == before {
+			payloadType := envelopePayloadType(item.Payload)
+			if opts.Events.matches(item.Type, payloadType) {
+				raw := new(bytes.Buffer)
+				if err := json.Compact(raw, item.Payload); err != nil || raw.Len() == 0 {
+					raw.Reset()
+					raw.WriteString("null")
+				}
+				timestamp := item.Timestamp
+				if timestamp == "" {
+					timestamp = lastTimestamp
+				}
+				if emit(timestamp, "event", eventLabel(item.Type, payloadType), nil) {
+					record := &records[len(records)-1]
+					record.Raw = json.RawMessage(raw.Bytes())
+					record.ID = recordContentID(*record)
+				}
 			}
-			records = append(records, record)
+		}
+		if item.Timestamp != "" {
+			lastTimestamp = item.Timestamp
 		}
 	}
 
 	if err := scanner.Err(); err != nil {
-		return nil, err
+		return sessionExtraction{}, err
 	}
-	return records, nil
+	if sawEventMessages && len(responseMessages) > 0 {
+		kept := records[:0]
+		for i, record := range records {
+			if !responseMessages[i] {
+				kept = append(kept, record)
+			}
+		}
+		records = kept
+	}
+	return sessionExtraction{SessionID: sessionID, Records: records, Info: info}, nil
+}
+
+// sanitizeText replaces invalid UTF-8 with U+FFFD and drops control
+// characters (other than newline and tab) that can corrupt terminals or trip
+// strict JSON consumers. CRLF line endings become LF.
+func sanitizeText(text string) string {
+	text = strings.ToValidUTF8(text, "\uFFFD")
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	return strings.Map(func(r rune) rune {
+		if r == '\n' || r == '\t' {
+			return r
+		}
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, text)
 }
 
 func normalizeTimestamp(raw string) string {
@@ -698,14 +2059,86 @@ func sessionIDFromPath(path string) string {
 	return matches[len(matches)-1]
 }
 
+// truncateRecordText caps record text at maxBytes (cut on a rune boundary),
+// appends a visible marker, and notes the original size in meta. The ID is
+// kept, so a cap added to an existing history never re-adds its records.
+func truncateRecordText(record Record, maxBytes int) Record {
+	if len(record.Text) <= maxBytes {
+		return record
+	}
+
+	kept := cutUTF8(record.Text, maxBytes)
+	cut := len(kept)
+
+	originalBytes := len(record.Text)
+	record.Text = kept + fmt.Sprintf("\n[truncated: %d of %d bytes kept]", cut, originalBytes)
+	meta := make(map[string]string, len(record.Meta)+2)
+	for key, value := range record.Meta {
+		meta[key] = value
+	}
+	meta["truncated"] = "true"
+	meta["original_bytes"] = strconv.Itoa(originalBytes)
+	record.Meta = meta
+	return record
+}
+
+// cutUTF8 returns the longest prefix of text that fits in maxBytes without
+// splitting a rune.
+func cutUTF8(text string, maxBytes int) string {
+	if len(text) <= maxBytes {
+		return text
+	}
+	cut := maxBytes
+	for cut > 0 && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+	return text[:cut]
+}
+
+// relativeSourcePath expresses path relative to the sessions directory,
+// falling back to the original path when it lies outside it.
+func relativeSourcePath(sessionsDir, path string) string {
+	rel, err := filepath.Rel(sessionsDir, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return path
+	}
+	return filepath.ToSlash(rel)
+}
+
+// resolveSourcePath turns a stored source_file back into a filesystem path;
+// relative paths are taken to be relative to the sessions directory.
+func resolveSourcePath(sessionsDir, source string) string {
+	if source == "" || filepath.IsAbs(source) {
+		return source
+	}
+	return filepath.Join(sessionsDir, filepath.FromSlash(source))
+}
+
+// recordContentID is the ID a stored record should carry. Passthrough
+// records hash their raw event too, since their text is only the type name.
+func recordContentID(record Record) string {
+	text := record.Text
+	if len(record.Raw) > 0 {
+		text += "\n" + string(record.Raw)
+	}
+	return makeRecordID(record.SessionID, record.Timestamp, record.Role, text)
+}
+
+// idHashesOriginalText reports whether a record's ID was computed before
+// its text was sanitized or truncated, so it cannot be checked against the
+// stored text.
+func idHashesOriginalText(record Record) bool {
+	return record.Meta["sanitized"] == "true" || record.Meta["truncated"] == "true"
+}
+
 func makeRecordID(sessionID, timestamp, role, text string) string {
 	raw := sessionID + "\n" + timestamp + "\n" + role + "\n" + text
 	sum := sha256.Sum256([]byte(raw))
 	return hex.EncodeToString(sum[:16])
 }
 
-func loadExistingIDs(path string) (map[string]struct{}, error) {
-	ids := make(map[string]struct{})
+func loadExistingIDs(path string) (idSet, error) {
+	ids := make(idSet)
 
 	file, err := os.Open(path)
 	if err != nil {
@@ -727,10 +2160,10 @@ func loadExistingIDs(path string) (map[string]struct{}, error) {
 
 		id := strings.TrimSpace(record.ID)
 		if id == "" && record.SessionID != "" && record.Timestamp != "" && record.Role != "" && record.Text != "" {
-			id = makeRecordID(record.SessionID, record.Timestamp, record.Role, record.Text)
+			id = recordContentID(record)
 		}
 		if id != "" {
-			ids[id] = struct{}{}
+			ids.add(id)
 		}
 	}
 
@@ -740,10 +2173,15 @@ func loadExistingIDs(path string) (map[string]struct{}, error) {
 	return ids, nil
 }
 
-func appendRecords(path string, records []Record) error {
+// appendRecords appends records as JSONL. Callers writing to a shared history
+// hold lockHistory; with fsync set the data is on disk when it returns.
+func appendRecords(path string, records []Record, fsync bool) error {
 	if len(records) == 0 {
 		return nil
 	}
+	if err := checkWritableHistory(path); err != nil {
+		return err
+	}
 
 	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
 		return err
@@ -765,124 +2203,206 @@ func appendRecords(path string, records []Record) error {
 		}
 	}
 
-	return writer.Flush()
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+	if fsync {
+		return file.Sync()
+	}
+	return nil
 }
 
-func loadRecords(path string) ([]Record, error) {
+// rewriteHistory streams the history file through fn and atomically replaces
+// it with the result. fn returns the record to keep and whether to keep it;
+// lines that do not parse as records are carried over untouched. The history
+// lock is held throughout so a concurrent sync cannot append to the file
+// being replaced.
+func rewriteHistory(path string, fn func(Record) (Record, bool)) error {
+	if err := checkWritableHistory(path); err != nil {
+		return err
+	}
+	unlock, err := lockHistory(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	file, err := os.Open(path)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer file.Close()
 
-	scanner := bufio.NewScanner(file)
-	scanner.Buffer(make([]byte, 64*1024), scannerMaxTokenSize)
+	return replaceHistoryFile(path, func(writer *bufio.Writer) error {
+		scanner := bufio.NewScanner(file)
+		scanner.Buffer(make([]byte, 64*1024), scannerMaxTokenSize)
 
-	records := make([]Record, 0, 256)
-	for scanner.Scan() {
-		var record Record
-		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
-			continue
+		encoder := json.NewEncoder(writer)
+		encoder.SetEscapeHTML(false)
+
+		for scanner.Scan() {
+			line := scanner.Bytes()
+			var record Record
+			if err := json.Unmarshal(line, &record); err != nil {
+				if _, err := writer.Write(append(line, '\n')); err != nil {
+					return err
+				}
+				continue
+			}
+
+			updated, keep := fn(record)
+			if !keep {
+				continue
+			}
+			if err := encoder.Encode(updated); err != nil {
+				return err
+			}
 		}
-		records = append(records, record)
+		return scanner.Err()
+	})
+}
+
+// replaceHistoryFile writes a new history through write into a temp file
+// beside path, syncs it, and renames it over path. Callers hold lockHistory.
+func replaceHistoryFile(path string, write func(*bufio.Writer) error) error {
+	if err := checkWritableHistory(path); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmpPath := path + ".tmp"
+	tmp, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
 	}
+	defer os.Remove(tmpPath)
+	defer tmp.Close()
 
-	if err := scanner.Err(); err != nil {
+	writer := bufio.NewWriter(tmp)
+	if err := write(writer); err != nil {
+		return err
+	}
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+func loadRecords(path string) ([]Record, error) {
+	records := make([]Record, 0, 256)
+	err := forEachRecord(path, func(record Record) error {
+		records = append(records, record)
+		return nil
+	})
+	if err != nil {
 		return nil, err
 	}
 	return records, nil
 }
 
 func filterRecords(records []Record, filter RecordFilter) []Record {
-	sessionID := strings.TrimSpace(filter.SessionID)
-	role := strings.ToLower(strings.TrimSpace(filter.Role))
-	contains := strings.ToLower(strings.TrimSpace(filter.Contains))
-
+	match := newRecordMatcher(filter)
 	filtered := make([]Record, 0, len(records))
 	for _, record := range records {
-		if sessionID != "" && record.SessionID != sessionID {
-			continue
-		}
-		if role != "" && strings.ToLower(strings.TrimSpace(record.Role)) != role {
-			continue
-		}
-		if contains != "" && !strings.Contains(strings.ToLower(record.Text), contains) {
-			continue
+		if match(record) {
+			filtered = append(filtered, record)
 		}
-		if !filter.From.IsZero() || !filter.To.IsZero() {
-			ts, ok := parseRecordTime(record.Timestamp)
-			if !ok {
-				continue
-			}
-			if !filter.From.IsZero() && ts.Before(filter.From) {
-				continue
-			}
-			if !filter.To.IsZero() && ts.After(filter.To) {
-				continue
-			}
-		}
-		filtered = append(filtered, record)
 	}
 	return filtered
 }
 
 func computeStats(records []Record) HistoryStats {
-	stats := HistoryStats{Total: len(records)}
-	sessionSet := make(map[string]struct{})
-
+	acc := newStatsAccumulator()
 	for _, record := range records {
-		sessionSet[record.SessionID] = struct{}{}
-		switch strings.ToLower(strings.TrimSpace(record.Role)) {
-		case "user":
-			stats.User++
-		case "assistant":
-			stats.Assistant++
-		default:
-			stats.Other++
-		}
-
-		stats.FirstTimestamp = earlierTimestamp(stats.FirstTimestamp, record.Timestamp)
-		stats.LastTimestamp = laterTimestamp(stats.LastTimestamp, record.Timestamp)
+		acc.add(record)
 	}
+	return acc.result()
+}
 
-	stats.SessionCount = len(sessionSet)
-	return stats
+// lengthHistogram counts message lengths in a bounded set of buckets, so
+// stats needs the same memory for any size of history. Lengths below
+// lengthExactLimit each get a bucket; longer ones share 64 buckets per
+// power of two, which keeps a percentile within 1/64 of the true length.
+type lengthHistogram struct {
+	counts map[int]int
+	total  int
 }
 
-func buildSessionSummaries(records []Record) []SessionSummary {
-	summaryBySession := make(map[string]*SessionSummary)
+const (
+	lengthExactLimit = 1024
+	lengthSubBuckets = 64
+)
 
-	for _, record := range records {
-		sessionID := record.SessionID
-		if strings.TrimSpace(sessionID) == "" {
-			sessionID = "unknown"
-		}
+func (h *lengthHistogram) add(length int) {
+	if h.counts == nil {
+		h.counts = make(map[int]int)
+	}
+	h.counts[lengthBucket(length)]++
+	h.total++
+}
 
-		summary, exists := summaryBySession[sessionID]
-		if !exists {
-			summary = &SessionSummary{SessionID: sessionID}
-			summaryBySession[sessionID] = summary
-		}
+// lengthBucket maps a length to its bucket. Buckets are numbered in order
+// of the lengths they hold.
+func lengthBucket(length int) int {
+	if length < lengthExactLimit {
+		return length
+	}
+	exp := bits.Len(uint(length)) - 1
+	sub := (length >> (exp - 6)) & (lengthSubBuckets - 1)
+	return lengthExactLimit + (exp-10)*lengthSubBuckets + sub
+}
 
-		summary.Total++
-		switch strings.ToLower(strings.TrimSpace(record.Role)) {
-		case "user":
-			summary.User++
-		case "assistant":
-			summary.Assistant++
-		default:
-			summary.Other++
-		}
+// lengthBucketStart is the smallest length in a bucket.
+func lengthBucketStart(bucket int) int {
+	if bucket < lengthExactLimit {
+		return bucket
+	}
+	exp := 10 + (bucket-lengthExactLimit)/lengthSubBuckets
+	sub := (bucket - lengthExactLimit) % lengthSubBuckets
+	return (lengthSubBuckets + sub) << (exp - 6)
+}
 
-		summary.FirstTimestamp = earlierTimestamp(summary.FirstTimestamp, record.Timestamp)
-		summary.LastTimestamp = laterTimestamp(summary.LastTimestamp, record.Timestamp)
+// percentiles uses the nearest-rank method, reporting the start of the
+// bucket the rank falls in.
+func (h *lengthHistogram) percentiles() Percentiles {
+	if h.total == 0 {
+		return Percentiles{}
+	}
+	buckets := make([]int, 0, len(h.counts))
+	for bucket := range h.counts {
+		buckets = append(buckets, bucket)
+	}
+	sort.Ints(buckets)
+	rank := func(p float64) int {
+		want := max(int(math.Ceil(p*float64(h.total))), 1)
+		seen := 0
+		for _, bucket := range buckets {
+			seen += h.counts[bucket]
+			if seen >= want {
+				return lengthBucketStart(bucket)
+			}
+		}
+		return lengthBucketStart(buckets[len(buckets)-1])
 	}
+	return Percentiles{P50: rank(0.50), P90: rank(0.90), P99: rank(0.99)}
+}
 
-	summaries := make([]SessionSummary, 0, len(summaryBySession))
-	for _, summary := range summaryBySession {
-		summaries = append(summaries, *summary)
+func buildSessionSummaries(records []Record) []SessionSummary {
+	acc := newSessionAccumulator()
+	for _, record := range records {
+		acc.add(record)
 	}
+	return acc.summaries()
+}
 
+func sortSessionSummaries(summaries []SessionSummary) {
 	sort.Slice(summaries, func(i, j int) bool {
 		cmp := compareTimestamp(summaries[i].LastTimestamp, summaries[j].LastTimestamp)
 		if cmp != 0 {
@@ -890,8 +2410,33 @@ func buildSessionSummaries(records []Record) []SessionSummary {
 		}
 		return summaries[i].SessionID < summaries[j].SessionID
 	})
+}
+
+// filterSessionsByActivity keeps sessions with at least minMessages records
+// of the given role (any role when empty). A role with minMessages 0 still
+// requires one message of that role. roleCounts maps session to role to count.
+func filterSessionsByActivity(summaries []SessionSummary, roleCounts map[string]map[string]int, role string, minMessages int) []SessionSummary {
+	role = strings.ToLower(role)
+	if role == "" && minMessages <= 0 {
+		return summaries
+	}
+	if role != "" && minMessages <= 0 {
+		minMessages = 1
+	}
 
-	return summaries
+	kept := make([]SessionSummary, 0, len(summaries))
+	for _, summary := range summaries {
+		count := 0
+		for r, n := range roleCounts[summary.SessionID] {
+			if role == "" || r == role {
+				count += n
+			}
+		}
+		if count >= minMessages {
+			kept = append(kept, summary)
+		}
+	}
+	return kept
 }
 
 func parseRecordTime(value string) (time.Time, bool) {
@@ -971,16 +2516,39 @@ func reverseRecords(records []Record) {
 	}
 }
 
-func renderExport(format string, records []Record) ([]byte, error) {
+func renderExport(format string, records []Record, opts ExportOptions) ([]byte, error) {
+	if opts.NoSources {
+		records = append([]Record(nil), records...)
+		stripSources(records)
+	}
+
 	switch format {
 	case "markdown", "md":
 		return renderMarkdown(records), nil
 	case "csv":
-		return renderCSV(records)
+		return renderCSV(records, !opts.NoSources)
 	case "jsonl":
 		return renderJSONL(records)
+	case "openai-chat":
+		return renderOpenAIChat(records)
+	case "sharegpt":
+		return renderShareGPT(records)
+	case "parquet":
+		return renderParquet(records)
+	case "sqlite":
+		return renderSQLite(records)
+	case "arrow":
+		return renderArrow(records)
+	case "html":
+		return renderHTML(records)
+	case "pdf":
+		return renderPDF(records)
+	case "atom":
+		return renderAtom(records)
+	case "template":
+		return renderTemplate(opts.Template, records, opts.PerSession)
 	default:
-		return nil, fmt.Errorf("unsupported --format %q (use markdown, csv, or jsonl)", format)
+		return nil, fmt.Errorf("unsupported --format %q (use markdown, csv, jsonl, html, pdf, openai-chat, sharegpt, parquet, sqlite, arrow, atom, or template)", format)
 	}
 }
 
@@ -1011,11 +2579,14 @@ func renderMarkdown(records []Record) []byte {
 	return []byte(builder.String())
 }
 
-func renderCSV(records []Record) ([]byte, error) {
+func renderCSV(records []Record, includeSources bool) ([]byte, error) {
 	var builder strings.Builder
 	writer := csv.NewWriter(&builder)
 
-	header := []string{"id", "session_id", "timestamp", "role", "text", "source_file", "source_line"}
+	header := []string{"id", "session_id", "timestamp", "role", "text"}
+	if includeSources {
+		header = append(header, "source_file", "source_line")
+	}
 	if err := writer.Write(header); err != nil {
 		return nil, err
 	}
@@ -1026,8 +2597,9 @@ func renderCSV(records []Record) ([]byte, error) {
 			record.Timestamp,
 			record.Role,
 			record.Text,
-			record.SourceFile,
-			fmt.Sprintf("%d", record.SourceLine),
+		}
+		if includeSources {
+			row = append(row, record.SourceFile, fmt.Sprintf("%d", record.SourceLine))
 		}
 		if err := writer.Write(row); err != nil {
 			return nil, err
@@ -1054,6 +2626,88 @@ func renderJSONL(records []Record) ([]byte, error) {
 	return []byte(builder.String()), nil
 }
 
+// stripSources applies --no-sources to records in place; see
+// stripRecordSources.
+func stripSources(records []Record) {
+	for i := range records {
+		records[i] = stripRecordSources(records[i])
+	}
+}
+
+// sourceArgumentKeys are the tool argument and raw event keys that hold
+// the directory a command ran in.
+var sourceArgumentKeys = []string{"cwd", "workdir"}
+
+// stripRecordSources drops where a record came from and the local paths it
+// carries: source_file and source_line, the working directory in meta, tool
+// arguments, and raw events, and the directories of attachment files, which
+// keep only their file names. Maps and slices are copied, so records
+// sharing them with the caller are not changed.
+func stripRecordSources(record Record) Record {
+	record.SourceFile = ""
+	record.SourceLine = 0
+	if _, ok := record.Meta["cwd"]; ok {
+		meta := make(map[string]string, len(record.Meta))
+		for key, value := range record.Meta {
+			if key != "cwd" {
+				meta[key] = value
+			}
+		}
+		record.Meta = meta
+	}
+	if len(record.Attachments) > 0 {
+		attachments := make([]Attachment, len(record.Attachments))
+		for i, attachment := range record.Attachments {
+			if attachment.Path != "" && !strings.Contains(attachment.Path, "://") {
+				attachment.Path = filepath.Base(filepath.FromSlash(attachment.Path))
+			}
+			attachments[i] = attachment
+		}
+		record.Attachments = attachments
+	}
+	if record.Tool != nil {
+		tool := *record.Tool
+		tool.Arguments = dropJSONKeys(tool.Arguments, sourceArgumentKeys)
+		record.Tool = &tool
+	}
+	record.Raw = dropJSONKeys(record.Raw, sourceArgumentKeys)
+	return record
+}
+
+// dropJSONKeys removes keys from every object in a JSON value. Data that
+// does not decode is returned as it is.
+func dropJSONKeys(data json.RawMessage, keys []string) json.RawMessage {
+	if len(data) == 0 {
+		return data
+	}
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return data
+	}
+	var drop func(any)
+	drop = func(value any) {
+		switch v := value.(type) {
+		case map[string]any:
+			for _, key := range keys {
+				delete(v, key)
+			}
+			for _, child := range v {
+				drop(child)
+			}
+		case []any:
+			for _, child := range v {
+				drop(child)
+			}
+		}
+	}
+	drop(value)
+	out, err := json.Marshal(value)
+	if err != nil {
+		return data
+	}
+	return out
+}
+
 func markdownCell(value string) string {
 	out := strings.TrimSpace(value)
 	out = strings.ReplaceAll(out, "\\", "\\\\")
@@ -1075,6 +2729,20 @@ func writeOutput(path string, content []byte) error {
 	return os.WriteFile(trimmed, content, 0o644)
 }
 
+// writeFileAtomic writes content to a temp file beside path and renames it
+// into place so readers never observe a partially written file.
+func writeFileAtomic(path string, content []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, content, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
 func shortSessionID(id string) string {
 	if len(id) <= 8 {
 		return id
@@ -2,6 +2,7 @@ package main
 
 import (
 	"encoding/csv"
+	"encoding/json"
 	"os"
 	"path/filepath"
 	"strings"
@@ -189,7 +190,7 @@ func TestRenderExportMarkdown(t *testing.T) {
 		},
 	}
 
-	content, err := renderExport("markdown", records)
+	content, err := renderExport("markdown", records, ExportOptions{})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -216,7 +217,7 @@ func TestRenderExportCSV(t *testing.T) {
 		},
 	}
 
-	content, err := renderExport("csv", records)
+	content, err := renderExport("csv", records, ExportOptions{})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -243,7 +244,7 @@ func TestRenderExportJSONL(t *testing.T) {
 		{ID: "id2", SessionID: "s1", Timestamp: "2026-02-17T10:01:00Z", Role: "assistant", Text: "hi"},
 	}
 
-	content, err := renderExport("jsonl", records)
+	content, err := renderExport("jsonl", records, ExportOptions{})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -258,7 +259,392 @@ func TestRenderExportJSONL(t *testing.T) {
 }
 
 func TestRenderExportUnknownFormat(t *testing.T) {
-	if _, err := renderExport("yaml", nil); err == nil {
+	if _, err := renderExport("yaml", nil, ExportOptions{}); err == nil {
 		t.Fatal("expected error for unsupported format")
 	}
 }
+
+func TestSyncOnceRelativeSources(t *testing.T) {
+	root := t.TempDir()
+	sessionsRoot := filepath.Join(root, "sessions")
+	sessionPath := filepath.Join(sessionsRoot, "2026", "02", "17", "rollout-2026-02-17T12-00-00-11111111-2222-3333-4444-555555555555.jsonl")
+	if err := os.MkdirAll(filepath.Dir(sessionPath), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	content := `{"timestamp":"2026-02-17T12:00:01Z","type":"event_msg","payload":{"type":"user_message","message":"hello"}}` + "\n"
+	if err := os.WriteFile(sessionPath, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	outPath := filepath.Join(root, "out", "conversation_history.jsonl")
+	if _, err := syncOnce(SyncOptions{SessionsDir: sessionsRoot, OutputPath: outPath, RelativeSources: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := loadRecords(outPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "2026/02/17/rollout-2026-02-17T12-00-00-11111111-2222-3333-4444-555555555555.jsonl"
+	if len(records) != 1 || records[0].SourceFile != want {
+		t.Fatalf("expected relative source %q, got %#v", want, records)
+	}
+	if got := resolveSourcePath(sessionsRoot, records[0].SourceFile); got != sessionPath {
+		t.Fatalf("expected resolved source %q, got %q", sessionPath, got)
+	}
+}
+
+func TestRenderExportNoSources(t *testing.T) {
+	records := []Record{
+		{ID: "id1", SessionID: "s1", Timestamp: "2026-02-17T10:00:00Z", Role: "user", Text: "hello", SourceFile: "/home/x/.codex/sessions/a.jsonl", SourceLine: 3},
+	}
+
+	content, err := renderExport("jsonl", records, ExportOptions{NoSources: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(content), "source_") {
+		t.Fatalf("expected provenance to be omitted, got: %s", content)
+	}
+	if records[0].SourceFile == "" {
+		t.Fatal("renderExport must not modify the caller's records")
+	}
+
+	exitCode := 0
+	records = append(records, Record{
+		ID: "id2", SessionID: "s1", Timestamp: "2026-02-17T10:00:01Z", Role: "tool", Text: "ls",
+		Meta:        map[string]string{"cwd": "/home/x/src/secret-project", "model": "gpt-5-codex"},
+		Attachments: []Attachment{{Type: "image", Path: "/home/x/.codex/assets/ab12.png"}, {Type: "image", Path: "https://example.com/a.png"}},
+		Tool:        &ToolCall{Name: "shell", Arguments: json.RawMessage(`{"command":["ls"],"workdir":"/home/x/src/secret-project"}`), ExitCode: &exitCode},
+		Raw:         json.RawMessage(`{"type":"turn_context","cwd":"/home/x/src/secret-project"}`),
+	})
+	content, err = renderExport("jsonl", records, ExportOptions{NoSources: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(content), "/home/x") {
+		t.Fatalf("expected local paths to be omitted, got: %s", content)
+	}
+	for _, kept := range []string{`"model":"gpt-5-codex"`, `"path":"ab12.png"`, `"path":"https://example.com/a.png"`, `"command":["ls"]`} {
+		if !strings.Contains(string(content), kept) {
+			t.Fatalf("expected %s to be kept, got: %s", kept, content)
+		}
+	}
+	if records[1].Meta["cwd"] == "" || !strings.Contains(string(records[1].Tool.Arguments), "workdir") || records[1].Attachments[0].Path != "/home/x/.codex/assets/ab12.png" {
+		t.Fatal("renderExport must not modify the caller's records")
+	}
+
+	content, err = renderExport("csv", records[:1], ExportOptions{NoSources: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := strings.SplitN(string(content), "\n", 2)[0]
+	if header != "id,session_id,timestamp,role,text" {
+		t.Fatalf("unexpected csv header: %q", header)
+	}
+}
+
+func TestTruncateRecordText(t *testing.T) {
+	text := strings.Repeat("é", 10)
+	record := Record{SessionID: "s1", Timestamp: "2026-02-17T10:00:00Z", Role: "user", Text: text}
+	record.ID = makeRecordID(record.SessionID, record.Timestamp, record.Role, record.Text)
+
+	truncated := truncateRecordText(record, 5)
+	if !strings.HasPrefix(truncated.Text, "éé\n[truncated: 4 of 20 bytes kept]") {
+		t.Fatalf("unexpected truncated text: %q", truncated.Text)
+	}
+	if truncated.Meta["truncated"] != "true" || truncated.Meta["original_bytes"] != "20" {
+		t.Fatalf("unexpected meta: %#v", truncated.Meta)
+	}
+	if truncated.ID != record.ID {
+		t.Fatal("expected truncation to keep the id of the original text")
+	}
+
+	if same := truncateRecordText(record, 20); same.Text != text || same.Meta != nil {
+		t.Fatalf("expected record within cap to be unchanged: %#v", same)
+	}
+}
+
+func TestMaxTextBytesKeepsExistingRecords(t *testing.T) {
+	root := t.TempDir()
+	sessionsRoot := filepath.Join(root, "sessions")
+	start := time.Date(2026, 2, 15, 9, 0, 0, 0, time.UTC)
+	if _, err := generateDemo(DemoOptions{OutDir: sessionsRoot, Days: 1, SessionsPerDay: 2, Start: start, Seed: 5}); err != nil {
+		t.Fatal(err)
+	}
+	outPath := filepath.Join(root, "history.jsonl")
+
+	first, err := syncOnce(SyncOptions{SessionsDir: sessionsRoot, OutputPath: outPath})
+	if err != nil || first.Written == 0 {
+		t.Fatalf("first sync = %+v, %v", first, err)
+	}
+	capped, err := syncOnce(SyncOptions{SessionsDir: sessionsRoot, OutputPath: outPath, MaxTextBytes: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if capped.Written != 0 {
+		t.Fatalf("adding --max-text-bytes re-added %d records", capped.Written)
+	}
+
+	cappedPath := filepath.Join(root, "capped.jsonl")
+	if _, err := syncOnce(SyncOptions{SessionsDir: sessionsRoot, OutputPath: cappedPath, MaxTextBytes: 10}); err != nil {
+		t.Fatal(err)
+	}
+	report, err := verifyHistory(cappedPath)
+	if err != nil || len(report.Issues) != 0 || report.Records != first.Written {
+		t.Fatalf("verify = %+v, %v", report, err)
+	}
+}
+
+func TestSanitizeText(t *testing.T) {
+	raw := "ok\x1b[31mred\x00\r\nnext\tcol\xff"
+	got := sanitizeText(raw)
+	want := "ok[31mred\nnext\tcol\uFFFD"
+	if got != want {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+}
+
+func TestSanitizingKeepsRecordIDs(t *testing.T) {
+	path := writeSessionFile(t,
+		`{"timestamp":"2026-02-17T12:00:01Z","type":"event_msg","payload":{"type":"user_message","message":"make it \u001b[31mred\u001b[0m\r\nplease"}}`,
+		`{"timestamp":"2026-02-17T12:00:02Z","type":"event_msg","payload":{"type":"agent_message","message":"done"}}`,
+	)
+	sanitized, err := extractRecords(path, ExtractOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	verbatim, err := extractRecords(path, ExtractOptions{NoSanitize: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sanitized) != 2 || len(verbatim) != 2 {
+		t.Fatalf("expected 2 records each, got %d and %d", len(sanitized), len(verbatim))
+	}
+	if sanitized[0].Text != "make it [31mred[0m\nplease" || sanitized[0].Meta["sanitized"] != "true" {
+		t.Fatalf("unexpected sanitized record: %#v", sanitized[0])
+	}
+	if sanitized[1].Meta["sanitized"] != "" {
+		t.Fatalf("clean text should not be marked: %#v", sanitized[1].Meta)
+	}
+	for i := range sanitized {
+		if sanitized[i].ID != verbatim[i].ID {
+			t.Fatalf("record %d: sanitizing changed the ID from %s to %s", i, verbatim[i].ID, sanitized[i].ID)
+		}
+	}
+
+	historyPath := filepath.Join(t.TempDir(), "history.jsonl")
+	if err := appendRecords(historyPath, sanitized, false); err != nil {
+		t.Fatal(err)
+	}
+	report, err := verifyHistory(historyPath)
+	if err != nil || len(report.Issues) != 0 {
+		t.Fatalf("verify = %+v, %v", report, err)
+	}
+}
+
+func TestExtractRecordsHandlesBOMAndCRLF(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rollout-2026-02-17T12-00-00-11111111-2222-3333-4444-555555555555.jsonl")
+	content := "\xEF\xBB\xBF" + strings.Join([]string{
+		`{"timestamp":"2026-02-17T12:00:00Z","type":"session_meta","payload":{"id":"11111111-2222-3333-4444-555555555555"}}`,
+		`{"timestamp":"2026-02-17T12:00:01Z","type":"event_msg","payload":{"type":"user_message","message":"hello"}}`,
+		`{"timestamp":"2026-02-17T12:00:02Z","type":"event_msg","payload":{"type":"agent_message","message":"hi"}}`,
+		"",
+	}, "\r\n") + "\r\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := extractRecords(path, ExtractOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || records[0].Text != "hello" || records[1].SourceLine != 3 {
+		t.Fatalf("unexpected records: %#v", records)
+	}
+}
+
+func TestCodexHomeResolution(t *testing.T) {
+	t.Cleanup(func() { codexHomeOverride = "" })
+
+	t.Setenv("CODEX_HOME", "/env/codex")
+	if got := defaultSessionsDir(); got != filepath.Join("/env/codex", "sessions") {
+		t.Fatalf("expected CODEX_HOME sessions dir, got %q", got)
+	}
+
+	args, err := extractCodexHomeFlag([]string{"show", "--codex-home=/flag/codex", "--limit", "5"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(args, " ") != "show --limit 5" {
+		t.Fatalf("unexpected remaining args: %v", args)
+	}
+	if got := defaultOutputFile(); got != filepath.Join("/flag/codex", "conversation_history.jsonl") {
+		t.Fatalf("expected --codex-home to win over CODEX_HOME, got %q", got)
+	}
+
+	if _, err := extractCodexHomeFlag([]string{"sync", "--codex-home"}); err == nil {
+		t.Fatal("expected error for --codex-home without a value")
+	}
+}
+
+func TestComputeStatsLengthPercentiles(t *testing.T) {
+	records := make([]Record, 0, 10)
+	for i := 1; i <= 10; i++ {
+		records = append(records, Record{SessionID: "s1", Role: "user", Text: strings.TrimSpace(strings.Repeat("ab ", i))})
+	}
+	records = append(records, Record{SessionID: "s1", Role: "assistant", Text: "héllo"})
+
+	stats := computeStats(records)
+	user := stats.Lengths["user"]
+	if user.Words != (Percentiles{P50: 5, P90: 9, P99: 10}) {
+		t.Fatalf("unexpected user word percentiles: %#v", user.Words)
+	}
+	if user.Chars != (Percentiles{P50: 14, P90: 26, P99: 29}) {
+		t.Fatalf("unexpected user char percentiles: %#v", user.Chars)
+	}
+	if got := stats.Lengths["assistant"].Chars; got != (Percentiles{P50: 5, P90: 5, P99: 5}) {
+		t.Fatalf("expected rune-based lengths for assistant, got %#v", got)
+	}
+}
+
+func TestLengthHistogramBoundsError(t *testing.T) {
+	var h lengthHistogram
+	for length := 0; length < 200000; length += 7 {
+		h.add(length)
+		bucket := lengthBucket(length)
+		start := lengthBucketStart(bucket)
+		if start > length || float64(length-start) > float64(length)/lengthSubBuckets {
+			t.Fatalf("length %d fell in bucket %d starting at %d", length, bucket, start)
+		}
+		if length > 0 && bucket < lengthBucket(length-7) {
+			t.Fatalf("buckets out of order at %d", length)
+		}
+	}
+	if len(h.counts) > 2000 {
+		t.Fatalf("expected a bounded number of buckets, got %d", len(h.counts))
+	}
+	got := h.percentiles()
+	for _, c := range []struct{ got, want int }{{got.P50, 100000}, {got.P90, 180000}, {got.P99, 198000}} {
+		if diff := c.want - c.got; diff < 0 || float64(diff) > float64(c.want)/lengthSubBuckets {
+			t.Fatalf("percentiles %#v too far from the true lengths", got)
+		}
+	}
+}
+
+func TestFilterSessionsByActivity(t *testing.T) {
+	records := []Record{
+		{SessionID: "s1", Timestamp: "2026-02-17T10:00:00Z", Role: "user", Text: "a"},
+		{SessionID: "s1", Timestamp: "2026-02-17T10:01:00Z", Role: "assistant", Text: "b"},
+		{SessionID: "s1", Timestamp: "2026-02-17T10:02:00Z", Role: "user", Text: "c"},
+		{SessionID: "s2", Timestamp: "2026-02-17T11:00:00Z", Role: "assistant", Text: "d"},
+		{SessionID: "s2", Timestamp: "2026-02-17T11:01:00Z", Role: "assistant", Text: "e"},
+		{SessionID: "s2", Timestamp: "2026-02-17T11:02:00Z", Role: "user", Text: "f"},
+	}
+	acc := newSessionAccumulator()
+	for _, record := range records {
+		acc.add(record)
+	}
+	summaries := acc.summaries()
+	counts := acc.roleCounts
+
+	if got := filterSessionsByActivity(summaries, counts, "user", 2); len(got) != 1 || got[0].SessionID != "s1" {
+		t.Fatalf("expected only s1 with 2 user messages, got %#v", got)
+	}
+	if got := filterSessionsByActivity(summaries, counts, "", 3); len(got) != 2 {
+		t.Fatalf("expected both sessions with 3 messages, got %#v", got)
+	}
+	if got := filterSessionsByActivity(summaries, counts, "assistant", 0); len(got) != 2 {
+		t.Fatalf("expected role alone to require one message, got %#v", got)
+	}
+	if got := filterSessionsByActivity(summaries, counts, "tool", 0); len(got) != 0 {
+		t.Fatalf("expected no sessions with tool messages, got %#v", got)
+	}
+	// --min-user applies on top of --min-messages.
+	if got := filterSessionsByActivity(filterSessionsByActivity(summaries, counts, "", 3), counts, "user", 2); len(got) != 1 || got[0].SessionID != "s1" {
+		t.Fatalf("expected only s1 with 3 messages and 2 from the user, got %#v", got)
+	}
+}
+
+func TestSyncOnceParallelMatchesSequential(t *testing.T) {
+	root := t.TempDir()
+	sessionsRoot := filepath.Join(root, "sessions")
+	start := time.Date(2026, 2, 15, 9, 0, 0, 0, time.UTC)
+	if _, err := generateDemo(DemoOptions{OutDir: sessionsRoot, Days: 4, SessionsPerDay: 3, Start: start, Seed: 3}); err != nil {
+		t.Fatal(err)
+	}
+
+	sequential, err := syncOnce(SyncOptions{SessionsDir: sessionsRoot, OutputPath: filepath.Join(root, "seq.jsonl"), Workers: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	parallel, err := syncOnce(SyncOptions{SessionsDir: sessionsRoot, OutputPath: filepath.Join(root, "par.jsonl"), Workers: 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	seqData, err := os.ReadFile(filepath.Join(root, "seq.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	parData, err := os.ReadFile(filepath.Join(root, "par.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sequential.Written == 0 || sequential.Written != parallel.Written || string(seqData) != string(parData) {
+		t.Fatalf("parallel sync diverged: seq=%d par=%d", sequential.Written, parallel.Written)
+	}
+}
+
+func TestRunExportFilters(t *testing.T) {
+	dir := t.TempDir()
+	historyPath := filepath.Join(dir, "history.jsonl")
+	records := []Record{
+		{ID: "1", SessionID: "s1", Timestamp: "2026-02-17T10:00:00Z", Role: "user", Text: "first", Meta: map[string]string{"model": "gpt-5-codex"}},
+		{ID: "2", SessionID: "s1", Timestamp: "2026-02-17T10:01:00Z", Role: "assistant", Text: "second", Meta: map[string]string{"model": "gpt-5-codex"}},
+		{ID: "3", SessionID: "s1", Timestamp: "2026-02-17T10:02:00Z", Role: "user", Text: "third", Meta: map[string]string{"model": "o3"}},
+		{ID: "4", SessionID: "s2", Timestamp: "2026-02-17T10:03:00Z", Role: "user", Text: "fourth", Meta: map[string]string{"model": "gpt-5-codex"}},
+	}
+	if err := appendRecords(historyPath, records, false); err != nil {
+		t.Fatal(err)
+	}
+
+	outPath := filepath.Join(dir, "out.jsonl")
+	err := runExport([]string{"--in", historyPath, "--out", outPath, "--format", "jsonl",
+		"--session", "s1", "--role", "user", "--model", "gpt-5-codex"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	exported, err := loadRecords(outPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(exported) != 1 || exported[0].ID != "1" {
+		t.Fatalf("unexpected export: %#v", exported)
+	}
+
+	if err := runExport([]string{"--in", historyPath, "--out", outPath, "--format", "jsonl", "--limit", "2"}); err != nil {
+		t.Fatal(err)
+	}
+	if exported, err = loadRecords(outPath); err != nil {
+		t.Fatal(err)
+	}
+	if len(exported) != 2 || exported[0].ID != "3" || exported[1].ID != "4" {
+		t.Fatalf("--limit should keep the newest records: %#v", exported)
+	}
+}
+
+func TestShowLineColors(t *testing.T) {
+	record := Record{SessionID: "abcdef1234", Timestamp: "2026-02-17T10:00:00Z", Role: "user", Text: "hi"}
+	if got := showLine(record, 0, true, false); got != "2026-02-17T10:00:00Z [abcdef12] user: hi" {
+		t.Fatalf("unexpected plain line: %q", got)
+	}
+	want := ansiDim + "2026-02-17T10:00:00Z" + ansiReset + " [abcdef12] " + roleColors["user"] + "user-" + ansiReset + " hi"
+	if got := showLine(record, 0, false, true); got != want {
+		t.Fatalf("unexpected colored line: %q", got)
+	}
+	record.Role = "system"
+	if got := showLine(record, 0, true, true); !strings.HasSuffix(got, " [abcdef12] system: hi") {
+		t.Fatalf("unknown roles should stay uncolored: %q", got)
+	}
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// metrics holds counters, gauges, and histograms and writes them in the
+// Prometheus text format, so watch and serve can be scraped without a
+// client library. Families print in the order they were registered.
+type metrics struct {
+	mu       sync.Mutex
+	families []*metricFamily
+	byName   map[string]*metricFamily
+}
+
+type metricFamily struct {
+	name    string
+	kind    string // counter, gauge, or histogram
+	help    string
+	buckets []float64
+	series  map[string]*metricSeries
+}
+
+// metricSeries is one label combination. value serves counters and gauges;
+// counts, sum, and count serve histograms, with counts per bucket (not yet
+// cumulative).
+type metricSeries struct {
+	value  float64
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// durationBuckets suit operations from a few milliseconds to a minute.
+var durationBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60}
+
+func newMetrics() *metrics {
+	return &metrics{byName: make(map[string]*metricFamily)}
+}
+
+func (m *metrics) register(name, kind, help string, buckets ...float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	family := &metricFamily{name: name, kind: kind, help: help, buckets: buckets, series: make(map[string]*metricSeries)}
+	m.families = append(m.families, family)
+	m.byName[name] = family
+}
+
+// series returns the series of a registered family for labels (as built by
+// metricLabels), creating it on first use. m.mu must be held.
+func (m *metrics) series(name, labels string) *metricSeries {
+	family, ok := m.byName[name]
+	if !ok {
+		panic("metric not registered: " + name)
+	}
+	s, ok := family.series[labels]
+	if !ok {
+		s = &metricSeries{}
+		if family.kind == "histogram" {
+			s.counts = make([]uint64, len(family.buckets))
+		}
+		family.series[labels] = s
+	}
+	return s
+}
+
+// add increases a counter.
+func (m *metrics) add(name, labels string, delta float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.series(name, labels).value += delta
+}
+
+// set sets a gauge.
+func (m *metrics) set(name, labels string, value float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.series(name, labels).value = value
+}
+
+// observe records one histogram sample.
+func (m *metrics) observe(name, labels string, value float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s := m.series(name, labels)
+	for i, bound := range m.byName[name].buckets {
+		if value <= bound {
+			s.counts[i]++
+			break
+		}
+	}
+	s.sum += value
+	s.count++
+}
+
+// metricLabels renders label pairs (name, value, name, value, ...) as they
+// appear between the braces.
+func metricLabels(pairs ...string) string {
+	var parts []string
+	for i := 0; i+1 < len(pairs); i += 2 {
+		value := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(pairs[i+1])
+		parts = append(parts, pairs[i]+`="`+value+`"`)
+	}
+	return strings.Join(parts, ",")
+}
+
+func (m *metrics) write(w io.Writer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out strings.Builder
+	for _, family := range m.families {
+		fmt.Fprintf(&out, "# HELP %s %s\n# TYPE %s %s\n", family.name, family.help, family.name, family.kind)
+		labels := make([]string, 0, len(family.series))
+		for key := range family.series {
+			labels = append(labels, key)
+		}
+		sort.Strings(labels)
+		for _, key := range labels {
+			s := family.series[key]
+			if family.kind != "histogram" {
+				fmt.Fprintf(&out, "%s%s %s\n", family.name, braced(key), formatMetricValue(s.value))
+				continue
+			}
+			var cumulative uint64
+			for i, bound := range family.buckets {
+				cumulative += s.counts[i]
+				fmt.Fprintf(&out, "%s_bucket%s %d\n", family.name, braced(joinLabels(key, metricLabels("le", formatMetricValue(bound)))), cumulative)
+			}
+			fmt.Fprintf(&out, "%s_bucket%s %d\n", family.name, braced(joinLabels(key, `le="+Inf"`)), s.count)
+			fmt.Fprintf(&out, "%s_sum%s %s\n", family.name, braced(key), formatMetricValue(s.sum))
+			fmt.Fprintf(&out, "%s_count%s %d\n", family.name, braced(key), s.count)
+		}
+	}
+	_, err := io.WriteString(w, out.String())
+	return err
+}
+
+// ServeHTTP serves the metrics as a Prometheus scrape target.
+func (m *metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.write(w)
+}
+
+func braced(labels string) string {
+	if labels == "" {
+		return ""
+	}
+	return "{" + labels + "}"
+}
+
+func joinLabels(a, b string) string {
+	if a == "" {
+		return b
+	}
+	return a + "," + b
+}
+
+func formatMetricValue(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// serveInBackground serves handler on addr until the returned shutdown
+// func is called. watch uses it for its metrics and health endpoints.
+func serveInBackground(addr string, handler http.Handler) (net.Addr, func(), error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, nil, err
+	}
+	server := &http.Server{Handler: handler, ReadHeaderTimeout: 10 * time.Second}
+	go server.Serve(listener)
+	shutdown := func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(ctx)
+	}
+	return listener.Addr(), shutdown, nil
+}
+
+// syncMetrics are the metrics watch keeps about its syncs.
+type syncMetrics struct {
+	*metrics
+}
+
+func newSyncMetrics() syncMetrics {
+	m := newMetrics()
+	m.register("codex_history_syncs_total", "counter", "Sync runs, by result.")
+	m.register("codex_history_parse_errors_total", "counter", "Sync runs that failed because a session file did not parse.")
+	m.register("codex_history_files_scanned_total", "counter", "Session files read by sync runs.")
+	m.register("codex_history_records_scanned_total", "counter", "Records extracted from session files.")
+	m.register("codex_history_records_written_total", "counter", "New records appended to the history.")
+	m.register("codex_history_sync_duration_seconds", "histogram", "Time taken by each sync run.", durationBuckets...)
+	m.register("codex_history_last_success_timestamp_seconds", "gauge", "Unix time of the last successful sync, or of the last check that found nothing to sync.")
+	m.register("codex_history_idle_checks_total", "counter", "Cycles that skipped the sync because no session file changed.")
+	m.register("codex_history_webhook_posts_total", "counter", "Cycles that sent new records to --webhook, by result.")
+	// Series start at zero so rates and alerts work before the first sync.
+	for _, result := range []string{"success", "error"} {
+		m.add("codex_history_syncs_total", metricLabels("result", result), 0)
+		m.add("codex_history_webhook_posts_total", metricLabels("result", result), 0)
+	}
+	for _, name := range []string{"codex_history_idle_checks_total", "codex_history_parse_errors_total", "codex_history_files_scanned_total", "codex_history_records_scanned_total", "codex_history_records_written_total"} {
+		m.add(name, "", 0)
+	}
+	return syncMetrics{m}
+}
+
+// recordSync updates the metrics for one sync run that started at start.
+func (m syncMetrics) recordSync(start time.Time, result SyncResult, err error) {
+	end := time.Now()
+	m.observe("codex_history_sync_duration_seconds", "", end.Sub(start).Seconds())
+	if err != nil {
+		m.add("codex_history_syncs_total", metricLabels("result", "error"), 1)
+		var parseErr *sourceParseError
+		if errors.As(err, &parseErr) {
+			m.add("codex_history_parse_errors_total", "", 1)
+		}
+		return
+	}
+	m.add("codex_history_syncs_total", metricLabels("result", "success"), 1)
+	m.add("codex_history_files_scanned_total", "", float64(result.Files))
+	m.add("codex_history_records_scanned_total", "", float64(result.Scanned))
+	m.add("codex_history_records_written_total", "", float64(result.Written))
+	m.set("codex_history_last_success_timestamp_seconds", "", float64(end.UnixNano())/1e9)
+}
+
+// recordIdle counts a cycle that found nothing to sync at now. The history
+// is as current as after a sync, so it also counts as a success.
+func (m syncMetrics) recordIdle(now time.Time) {
+	m.add("codex_history_idle_checks_total", "", 1)
+	m.set("codex_history_last_success_timestamp_seconds", "", float64(now.UnixNano())/1e9)
+}
+
+// recordWebhook counts one cycle's delivery to --webhook.
+func (m syncMetrics) recordWebhook(err error) {
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+	m.add("codex_history_webhook_posts_total", metricLabels("result", result), 1)
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetricsWrite(t *testing.T) {
+	m := newMetrics()
+	m.register("jobs_total", "counter", "Jobs run.")
+	m.register("job_seconds", "histogram", "Job time.", 1, 5)
+	m.add("jobs_total", metricLabels("kind", `say "hi"`), 2)
+	m.add("jobs_total", metricLabels("kind", "a"), 1)
+	m.observe("job_seconds", "", 0.5)
+	m.observe("job_seconds", "", 3)
+	m.observe("job_seconds", "", 9)
+
+	var out strings.Builder
+	if err := m.write(&out); err != nil {
+		t.Fatal(err)
+	}
+	want := `# HELP jobs_total Jobs run.
+# TYPE jobs_total counter
+jobs_total{kind="a"} 1
+jobs_total{kind="say \"hi\""} 2
+# HELP job_seconds Job time.
+# TYPE job_seconds histogram
+job_seconds_bucket{le="1"} 1
+job_seconds_bucket{le="5"} 2
+job_seconds_bucket{le="+Inf"} 3
+job_seconds_sum 12.5
+job_seconds_count 3
+`
+	if out.String() != want {
+		t.Fatalf("unexpected output:\n%s", out.String())
+	}
+}
+
+func TestSyncMetricsRecordSync(t *testing.T) {
+	m := newSyncMetrics()
+	start := time.Now()
+	m.recordSync(start, SyncResult{Files: 3, Scanned: 10, Written: 4}, nil)
+	m.recordSync(start, SyncResult{}, &sourceParseError{path: "a.jsonl", err: errors.New("line 2: bad")})
+
+	var out strings.Builder
+	if err := m.write(&out); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		`codex_history_syncs_total{result="error"} 1`,
+		`codex_history_syncs_total{result="success"} 1`,
+		"codex_history_parse_errors_total 1",
+		"codex_history_files_scanned_total 3",
+		"codex_history_records_written_total 4",
+		"codex_history_sync_duration_seconds_count 2",
+	} {
+		if !strings.Contains(out.String(), line+"\n") {
+			t.Errorf("missing %q in:\n%s", line, out.String())
+		}
+	}
+	if !strings.Contains(out.String(), "codex_history_last_success_timestamp_seconds 1") {
+		t.Errorf("expected a last success timestamp in:\n%s", out.String())
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+const (
+	notifyTitle   = "Codex"
+	notifyTimeout = 10 * time.Second
+)
+
+// windowsToastScript shows a toast as PowerShell, whose app ID needs no
+// registration. The title and body come from the environment so they need
+// no quoting.
+const windowsToastScript = `[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] > $null
+$xml = [Windows.UI.Notifications.ToastNotificationManager]::GetTemplateContent([Windows.UI.Notifications.ToastTemplateType]::ToastText02)
+$text = $xml.GetElementsByTagName('text')
+$text.Item(0).AppendChild($xml.CreateTextNode($env:CODEX_HISTORY_NOTIFY_TITLE)) > $null
+$text.Item(1).AppendChild($xml.CreateTextNode($env:CODEX_HISTORY_NOTIFY_BODY)) > $null
+$app = '{1AC14E77-02E7-4E5D-B744-2EB1AE5198B7}\WindowsPowerShell\v1.0\powershell.exe'
+[Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier($app).Show([Windows.UI.Notifications.ToastNotification]::new($xml))`
+
+// desktopNotifier raises a desktop notification with the platform's
+// command: notify-send on Linux, osascript on macOS, and a PowerShell toast
+// on Windows.
+type desktopNotifier struct {
+	name    string
+	command func(ctx context.Context, title, body string) *exec.Cmd
+}
+
+func desktopNotifierFor(goos string) (*desktopNotifier, error) {
+	var notifier desktopNotifier
+	switch goos {
+	case "darwin":
+		notifier = desktopNotifier{name: "osascript", command: func(ctx context.Context, title, body string) *exec.Cmd {
+			// Passed as arguments, the strings need no AppleScript quoting.
+			return exec.CommandContext(ctx, "osascript",
+				"-e", "on run argv",
+				"-e", "display notification (item 2 of argv) with title (item 1 of argv)",
+				"-e", "end run",
+				title, body)
+		}}
+	case "windows":
+		notifier = desktopNotifier{name: "powershell", command: func(ctx context.Context, title, body string) *exec.Cmd {
+			cmd := exec.CommandContext(ctx, "powershell", "-NoProfile", "-NonInteractive", "-Command", windowsToastScript)
+			cmd.Env = append(os.Environ(), "CODEX_HISTORY_NOTIFY_TITLE="+title, "CODEX_HISTORY_NOTIFY_BODY="+body)
+			return cmd
+		}}
+	case "linux", "freebsd", "openbsd", "netbsd":
+		notifier = desktopNotifier{name: "notify-send", command: func(ctx context.Context, title, body string) *exec.Cmd {
+			return exec.CommandContext(ctx, "notify-send", "--app-name=codex-history", "--", title, body)
+		}}
+	default:
+		return nil, fmt.Errorf("--notify is not supported on %s", goos)
+	}
+	if _, err := exec.LookPath(notifier.name); err != nil {
+		return nil, fmt.Errorf("--notify needs %s on PATH", notifier.name)
+	}
+	return &notifier, nil
+}
+
+// notify raises one notification for the assistant messages among records
+// that are timestamped at or after since, and returns how many it covered.
+// Older records, such as the backlog of a first sync, are not announced.
+func (n *desktopNotifier) notify(records []Record, since time.Time) (int, error) {
+	body, count := notificationBody(records, since)
+	if count == 0 {
+		return 0, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+	defer cancel()
+	output, err := n.command(ctx, notifyTitle, body).CombinedOutput()
+	if err != nil {
+		if msg := strings.TrimSpace(string(output)); msg != "" {
+			return 0, fmt.Errorf("%s: %v: %s", n.name, err, oneLine(msg, 200))
+		}
+		return 0, fmt.Errorf("%s: %w", n.name, err)
+	}
+	return count, nil
+}
+
+// notificationBody summarizes the assistant messages in records from since
+// on: the message itself when there is one, otherwise the count and the
+// latest.
+func notificationBody(records []Record, since time.Time) (string, int) {
+	var messages []Record
+	sessions := make(map[string]bool)
+	for _, record := range records {
+		if record.Role != "assistant" {
+			continue
+		}
+		if ts, err := time.Parse(time.RFC3339Nano, record.Timestamp); err == nil && ts.Before(since) {
+			continue
+		}
+		messages = append(messages, record)
+		sessions[record.SessionID] = true
+	}
+	switch len(messages) {
+	case 0:
+		return "", 0
+	case 1:
+		return fmt.Sprintf("session %s: %s", shortSessionID(messages[0].SessionID), sessionPreview(messages[0].Text)), 1
+	}
+	latest := messages[len(messages)-1]
+	return fmt.Sprintf("%d new assistant messages in %d sessions; latest in %s: %s",
+		len(messages), len(sessions), shortSessionID(latest.SessionID), sessionPreview(latest.Text)), len(messages)
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNotificationBody(t *testing.T) {
+	started := time.Date(2026, 2, 17, 9, 0, 0, 0, time.UTC)
+	backlog := Record{SessionID: "0199a1b2-old", Role: "assistant", Timestamp: "2026-02-16T18:00:00Z", Text: "yesterday"}
+	user := Record{SessionID: "0199a1b2-aaaa", Role: "user", Timestamp: "2026-02-17T09:01:00Z", Text: "run the tests"}
+	done := Record{SessionID: "0199a1b2-aaaa", Role: "assistant", Timestamp: "2026-02-17T09:02:00Z", Text: "All tests pass.\nReady to merge."}
+
+	for _, tc := range []struct {
+		name    string
+		records []Record
+		body    string
+		count   int
+	}{
+		{"nothing new", []Record{backlog, user}, "", 0},
+		{"one message", []Record{backlog, user, done}, `session 0199a1b2: All tests pass. Ready to merge.`, 1},
+		{"several", []Record{done, {SessionID: "7f00c3d4-bbbb", Role: "assistant", Text: "Deployed."}}, "2 new assistant messages in 2 sessions; latest in 7f00c3d4: Deployed.", 2},
+	} {
+		body, count := notificationBody(tc.records, started)
+		if body != tc.body || count != tc.count {
+			t.Errorf("%s: notificationBody = %q, %d; want %q, %d", tc.name, body, count, tc.body, tc.count)
+		}
+	}
+}
+
+func TestDesktopNotifierNotify(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("needs sh")
+	}
+	var got []string
+	notifier := &desktopNotifier{name: "notify-send", command: func(ctx context.Context, title, body string) *exec.Cmd {
+		got = []string{title, body}
+		return exec.CommandContext(ctx, "sh", "-c", "true")
+	}}
+	records := []Record{{SessionID: "0199a1b2-aaaa", Role: "assistant", Text: "Done."}}
+	if n, err := notifier.notify(records, time.Now()); n != 1 || err != nil {
+		t.Fatalf("notify = %d, %v", n, err)
+	}
+	if got[0] != "Codex" || got[1] != "session 0199a1b2: Done." {
+		t.Fatalf("notified %q", got)
+	}
+
+	notifier.command = func(ctx context.Context, title, body string) *exec.Cmd {
+		return exec.CommandContext(ctx, "sh", "-c", "echo cannot open display >&2; exit 1")
+	}
+	if _, err := notifier.notify(records, time.Now()); err == nil || !strings.Contains(err.Error(), "cannot open display") {
+		t.Fatalf("notify error = %v", err)
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+func runOpen(args []string) error {
+	fs := flag.NewFlagSet("open", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+
+	inputPath := fs.String("in", defaultOutputFile(), "Input JSONL path")
+	sessionsDir := fs.String("sessions-dir", defaultSessionsDir(), "Sessions directory that relative source paths are resolved against")
+	printOnly := fs.Bool("print", false, "Print file:line instead of opening an editor")
+
+	// The record ID may come before or after the flags.
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return errors.New("missing record ID")
+	}
+	id := strings.TrimSpace(fs.Arg(0))
+	if err := fs.Parse(fs.Args()[1:]); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected argument %q", fs.Arg(0))
+	}
+
+	record, err := findRecord(*inputPath, id)
+	if err != nil {
+		return err
+	}
+	if record.SourceFile == "" {
+		return fmt.Errorf("record %s has no source file", record.ID)
+	}
+	if isHashedPath(record.SourceFile) {
+		return fmt.Errorf("record %s was synced with --hash-paths, so its source file is not known", record.ID)
+	}
+	path := resolveSourcePath(*sessionsDir, record.SourceFile)
+	if *printOnly {
+		fmt.Printf("%s:%d\n", path, record.SourceLine)
+		return nil
+	}
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("source of record %s: %w (see relink if sessions moved)", record.ID, err)
+	}
+
+	argv := editorCommand(firstNonEmpty(os.Getenv("VISUAL"), os.Getenv("EDITOR"), "vi"), path, record.SourceLine)
+	cmd := exec.Command(argv[0], argv[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	return cmd.Run()
+}
+
+var (
+	// errRecordNotFound is returned by findRecord when no record matches.
+	errRecordNotFound = errors.New("record not found")
+	// errAmbiguousPrefix ends the error for an ID prefix that matches more
+	// than one record or session.
+	errAmbiguousPrefix = errors.New("ambiguous")
+)
+
+// findRecord returns the record with the given ID, or the only record whose
+// ID starts with it.
+func findRecord(path, id string) (Record, error) {
+	if id == "" {
+		return Record{}, errors.New("missing record ID")
+	}
+	var found []Record
+	err := forEachRecord(path, func(record Record) error {
+		if strings.HasPrefix(record.ID, id) {
+			found = append(found, record)
+		}
+		return nil
+	})
+	if err != nil {
+		return Record{}, err
+	}
+	for _, record := range found {
+		if record.ID == id {
+			return record, nil
+		}
+	}
+	switch len(found) {
+	case 0:
+		return Record{}, fmt.Errorf("%w: %q", errRecordNotFound, id)
+	case 1:
+		return found[0], nil
+	}
+	return Record{}, fmt.Errorf("record ID prefix %q is %w", id, errAmbiguousPrefix)
+}
+
+// editorCommand builds the command that opens path at line in editor, which
+// may include arguments ("code -w"). Most editors take +LINE before the
+// file; VS Code and its kin want --goto file:line, Sublime and Zed file:line.
+func editorCommand(editor, path string, line int) []string {
+	argv := strings.Fields(editor)
+	if line <= 0 {
+		return append(argv, path)
+	}
+	switch filepath.Base(argv[0]) {
+	case "code", "code-insiders", "codium", "cursor":
+		return append(argv, "--goto", path+":"+strconv.Itoa(line))
+	case "subl", "zed":
+		return append(argv, path+":"+strconv.Itoa(line))
+	}
+	return append(argv, "+"+strconv.Itoa(line), path)
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFindRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	records := []Record{
+		{ID: "abc123", SessionID: "s1", Timestamp: "2026-02-17T10:00:00Z", Role: "user", Text: "one"},
+		{ID: "abc456", SessionID: "s1", Timestamp: "2026-02-17T10:01:00Z", Role: "assistant", Text: "two"},
+		{ID: "abc4", SessionID: "s1", Timestamp: "2026-02-17T10:02:00Z", Role: "assistant", Text: "three"},
+	}
+	if err := appendRecords(path, records, false); err != nil {
+		t.Fatal(err)
+	}
+	if record, err := findRecord(path, "abc1"); err != nil || record.Text != "one" {
+		t.Fatalf("prefix lookup: %v %+v", err, record)
+	}
+	// An exact ID wins even when it is also a prefix of another.
+	if record, err := findRecord(path, "abc4"); err != nil || record.Text != "three" {
+		t.Fatalf("exact lookup: %v %+v", err, record)
+	}
+	if _, err := findRecord(path, "abc"); err == nil {
+		t.Fatal("expected an ambiguity error")
+	}
+	if _, err := findRecord(path, "zzz"); err == nil {
+		t.Fatal("expected a not-found error")
+	}
+}
+
+func TestEditorCommand(t *testing.T) {
+	cases := []struct {
+		editor string
+		line   int
+		want   []string
+	}{
+		{"vim", 12, []string{"vim", "+12", "/x.jsonl"}},
+		{"/usr/local/bin/code -w", 12, []string{"/usr/local/bin/code", "-w", "--goto", "/x.jsonl:12"}},
+		{"subl", 3, []string{"subl", "/x.jsonl:3"}},
+		{"nano", 0, []string{"nano", "/x.jsonl"}},
+	}
+	for _, c := range cases {
+		if got := editorCommand(c.editor, "/x.jsonl", c.line); !reflect.DeepEqual(got, c.want) {
+			t.Fatalf("%s: got %q, want %q", c.editor, got, c.want)
+		}
+	}
+}
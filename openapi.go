@@ -0,0 +1,279 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// openAPIRoute describes one GET endpoint for /openapi.json. response is a
+// value of the type the endpoint returns; its schema is generated from the
+// type's JSON tags, so the document follows the Go structs as they change.
+type openAPIRoute struct {
+	path     string
+	summary  string
+	params   []openAPIParam
+	response any
+	// public routes are served without the token.
+	public bool
+	// notFound is set for routes that answer 404 for an unknown ID.
+	notFound bool
+}
+
+type openAPIParam struct {
+	name        string
+	in          string // "path" or "query"
+	kind        string // JSON Schema type
+	description string
+}
+
+func pathParam(name, description string) openAPIParam {
+	return openAPIParam{name: name, in: "path", kind: "string", description: description}
+}
+
+func queryParam(name, kind, description string) openAPIParam {
+	return openAPIParam{name: name, in: "query", kind: kind, description: description}
+}
+
+// openAPIRoutes lists the API serve answers.
+var openAPIRoutes = []openAPIRoute{
+	{
+		path:    "/api/sessions",
+		summary: "List session summaries, newest first",
+		params: []openAPIParam{
+			queryParam("project", "string", "Only sessions from this working directory (or under it), or whose directory contains this text"),
+			queryParam("limit", "integer", "Return at most this many sessions; 0 means all"),
+		},
+		response: []SessionSummary{},
+	},
+	{
+		path:     "/api/sessions/{id}",
+		summary:  "Get a session's records, oldest first, with rendered HTML",
+		params:   []openAPIParam{pathParam("id", "Session ID or unique prefix")},
+		response: []serveMessage{},
+		notFound: true,
+	},
+	{
+		path:     "/api/records/{id}",
+		summary:  "Get one record",
+		params:   []openAPIParam{pathParam("id", "Record ID or unique prefix")},
+		response: Record{},
+		notFound: true,
+	},
+	{
+		path:    "/api/search",
+		summary: "Find records containing a phrase (case-insensitive), newest first",
+		params: []openAPIParam{
+			queryParam("q", "string", "Text to find (required)"),
+			queryParam("limit", "integer", "Return at most this many matches; default 100, 0 means all"),
+		},
+		response: []serveSearchHit{},
+	},
+	{
+		path:    "/api/stats",
+		summary: "Aggregate statistics, as stats --json prints them",
+		params: []openAPIParam{
+			queryParam("session", "string", "Only this session"),
+			queryParam("role", "string", "Only this role"),
+			queryParam("model", "string", "Only records written with this model"),
+			queryParam("project", "string", "Only records from this working directory, or whose directory contains this text"),
+			queryParam("from", "string", "Only records at/after this RFC3339 time"),
+			queryParam("to", "string", "Only records at/before this RFC3339 time"),
+		},
+		response: HistoryStats{},
+	},
+	{path: "/healthz", summary: "Liveness probe", response: HealthReport{}, public: true},
+	{path: "/readyz", summary: "Readiness probe: the history is readable", response: HealthReport{}, public: true},
+}
+
+// handleOpenAPI serves the OpenAPI 3 document. It is public, like the page:
+// it describes the API without revealing anything in the history.
+func (s *historyServer) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, openAPIDocument(s.token != "", s.basePath))
+}
+
+// openAPIDocument builds the document; withToken adds the bearer scheme to
+// every route that needs it, and a basePath (one source of several) is
+// where the paths are rooted.
+func openAPIDocument(withToken bool, basePath string) map[string]any {
+	schemas := openAPISchemas{}
+	schemas["Error"] = map[string]any{
+		"type":       "object",
+		"properties": map[string]any{"error": map[string]any{"type": "string"}},
+		"required":   []string{"error"},
+	}
+	errorResponse := func(description string) map[string]any {
+		return map[string]any{
+			"description": description,
+			"content":     map[string]any{"application/json": map[string]any{"schema": schemaRef("Error")}},
+		}
+	}
+
+	paths := make(map[string]any)
+	for _, route := range openAPIRoutes {
+		responses := map[string]any{
+			"200": map[string]any{
+				"description": "OK",
+				"content":     map[string]any{"application/json": map[string]any{"schema": schemas.of(reflect.TypeOf(route.response))}},
+			},
+		}
+		operation := map[string]any{
+			"summary":     route.summary,
+			"operationId": openAPIOperationID(route.path),
+			"responses":   responses,
+		}
+		if len(route.params) > 0 {
+			var params []any
+			for _, param := range route.params {
+				params = append(params, map[string]any{
+					"name":        param.name,
+					"in":          param.in,
+					"required":    param.in == "path" || param.name == "q",
+					"description": param.description,
+					"schema":      map[string]any{"type": param.kind},
+				})
+			}
+			operation["parameters"] = params
+		}
+		if strings.HasPrefix(route.path, "/api/") {
+			responses["400"] = errorResponse("Invalid parameters")
+			responses["500"] = errorResponse("The history could not be read")
+		} else {
+			responses["503"] = map[string]any{
+				"description": "Failing, with the problems listed",
+				"content":     map[string]any{"application/json": map[string]any{"schema": schemaRef("HealthReport")}},
+			}
+		}
+		if route.notFound {
+			responses["404"] = errorResponse("No such ID")
+		}
+		if withToken && !route.public {
+			operation["security"] = []any{map[string]any{"bearerAuth": []string{}}}
+			responses["401"] = errorResponse("Missing or invalid token")
+		}
+		paths[route.path] = map[string]any{"get": operation}
+	}
+
+	components := map[string]any{"schemas": schemas}
+	if withToken {
+		components["securitySchemes"] = map[string]any{
+			"bearerAuth": map[string]any{"type": "http", "scheme": "bearer"},
+		}
+	}
+	doc := map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":       "codex-history",
+			"version":     "1",
+			"description": "Read-only API over a Codex conversation history, served by codex-history serve.",
+		},
+		"paths":      paths,
+		"components": components,
+	}
+	if basePath != "" {
+		doc["servers"] = []any{map[string]any{"url": basePath}}
+	}
+	return doc
+}
+
+// openAPIOperationID names an operation after its path:
+// /api/sessions/{id} becomes getSessionsById.
+func openAPIOperationID(path string) string {
+	id := "get"
+	for _, part := range strings.Split(strings.TrimPrefix(path, "/api"), "/") {
+		part = strings.Trim(part, "{}")
+		if part == "" {
+			continue
+		}
+		if part == "id" {
+			part = "byId"
+		}
+		id += upperFirst(part)
+	}
+	return id
+}
+
+// openAPISchemas collects the component schemas of named struct types.
+type openAPISchemas map[string]any
+
+var rawMessageType = reflect.TypeOf(json.RawMessage(nil))
+
+// of returns the schema for t, registering named structs as components and
+// referring to them.
+func (s openAPISchemas) of(t reflect.Type) map[string]any {
+	if t == rawMessageType {
+		return map[string]any{"description": "Any JSON value"}
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		return s.of(t.Elem())
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": s.of(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": s.of(t.Elem())}
+	case reflect.Struct:
+		name := upperFirst(t.Name())
+		if _, ok := s[name]; !ok {
+			s[name] = nil // placeholder, for types that refer to themselves
+			schema := map[string]any{"type": "object"}
+			properties := make(map[string]any)
+			var required []string
+			s.addFields(t, properties, &required)
+			schema["properties"] = properties
+			if len(required) > 0 {
+				schema["required"] = required
+			}
+			s[name] = schema
+		}
+		return schemaRef(name)
+	}
+	return map[string]any{}
+}
+
+// addFields adds t's JSON fields to properties, flattening embedded structs
+// as encoding/json does. Fields without omitempty are required.
+func (s openAPISchemas) addFields(t reflect.Type, properties map[string]any, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		if field.Anonymous && tag == "" && field.Type.Kind() == reflect.Struct {
+			s.addFields(field.Type, properties, required)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = field.Name
+		}
+		properties[name] = s.of(field.Type)
+		if !strings.Contains(options, "omitempty") {
+			*required = append(*required, name)
+		}
+	}
+}
+
+func schemaRef(name string) map[string]any {
+	return map[string]any{"$ref": "#/components/schemas/" + name}
+}
+
+func upperFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestOpenAPIDocument(t *testing.T) {
+	doc := openAPIDocument(true, "")
+	data, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded struct {
+		OpenAPI string `json:"openapi"`
+		Paths   map[string]struct {
+			Get struct {
+				OperationID string           `json:"operationId"`
+				Security    []map[string]any `json:"security"`
+			} `json:"get"`
+		} `json:"paths"`
+		Components struct {
+			Schemas map[string]struct {
+				Properties map[string]any `json:"properties"`
+				Required   []string       `json:"required"`
+			} `json:"schemas"`
+			SecuritySchemes map[string]any `json:"securitySchemes"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.OpenAPI != "3.0.3" || len(decoded.Paths) != len(openAPIRoutes) {
+		t.Fatalf("unexpected document: %s", data)
+	}
+	if got := decoded.Paths["/api/sessions/{id}"].Get.OperationID; got != "getSessionsById" {
+		t.Fatalf("unexpected operation ID %q", got)
+	}
+	if len(decoded.Paths["/api/stats"].Get.Security) != 1 || len(decoded.Paths["/healthz"].Get.Security) != 0 || decoded.Components.SecuritySchemes["bearerAuth"] == nil {
+		t.Fatalf("unexpected security: %s", data)
+	}
+
+	message := decoded.Components.Schemas["ServeMessage"]
+	if message.Properties["html"] == nil || message.Properties["text"] == nil || message.Properties["meta"] == nil {
+		t.Fatalf("expected the embedded record's fields to be flattened: %+v", message)
+	}
+	record := decoded.Components.Schemas["Record"]
+	if strings.Join(record.Required, ",") != "id,session_id,timestamp,role,text" {
+		t.Fatalf("unexpected required fields %v", record.Required)
+	}
+	if _, ok := decoded.Components.Schemas["ToolCall"]; !ok {
+		t.Fatal("expected nested structs to become components")
+	}
+
+	if _, ok := openAPIDocument(false, "")["components"].(map[string]any)["securitySchemes"]; ok {
+		t.Fatal("expected no security scheme without a token")
+	}
+}
+
+func TestOpenAPIRoutesAreServed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	if err := appendRecords(path, []Record{{ID: "r1", SessionID: "s1", Timestamp: "2026-02-17T10:00:00Z", Role: "user", Text: "hi"}}, false); err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(newHistoryServer(path, "").routes())
+	defer server.Close()
+
+	urls := []string{"/openapi.json"}
+	for _, route := range openAPIRoutes {
+		id := "r1"
+		if strings.HasPrefix(route.path, "/api/sessions/") {
+			id = "s1"
+		}
+		url := strings.ReplaceAll(route.path, "{id}", id)
+		if route.path == "/api/search" {
+			url += "?q=hi"
+		}
+		urls = append(urls, url)
+	}
+	for _, url := range urls {
+		resp, err := http.Get(server.URL + url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("GET %s: %d %s", url, resp.StatusCode, resp.Header.Get("Content-Type"))
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+const orphanedMetaKey = "orphaned"
+
+type OrphanReport struct {
+	SourceFile string `json:"source_file"`
+	Records    int    `json:"records"`
+}
+
+// sourceExistsCache remembers whether a SourceFile is still on disk, since
+// many records share one session file.
+type sourceExistsCache map[string]bool
+
+func (c sourceExistsCache) exists(path string) bool {
+	if known, ok := c[path]; ok {
+		return known
+	}
+	_, err := os.Stat(path)
+	exists := err == nil || !errors.Is(err, os.ErrNotExist)
+	c[path] = exists
+	return exists
+}
+
+// isOrphaned reports whether record's source file is gone. Records without
+// a source file, or whose source was hashed by sync --hash-paths, cannot be
+// checked and are never orphaned.
+func isOrphaned(record Record, sessionsDir string, cache sourceExistsCache) bool {
+	source := strings.TrimSpace(record.SourceFile)
+	if source == "" || isHashedPath(source) {
+		return false
+	}
+	return !cache.exists(resolveSourcePath(sessionsDir, source))
+}
+
+func findOrphans(records []Record, sessionsDir string) []OrphanReport {
+	cache := make(sourceExistsCache)
+	counts := make(map[string]int)
+	for _, record := range records {
+		if isOrphaned(record, sessionsDir, cache) {
+			counts[record.SourceFile]++
+		}
+	}
+
+	reports := make([]OrphanReport, 0, len(counts))
+	for source, count := range counts {
+		reports = append(reports, OrphanReport{SourceFile: source, Records: count})
+	}
+	sort.Slice(reports, func(i, j int) bool {
+		return reports[i].SourceFile < reports[j].SourceFile
+	})
+	return reports
+}
+
+func runOrphans(args []string) error {
+	fs := flag.NewFlagSet("orphans", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+
+	inputPath := fs.String("in", defaultOutputFile(), "History JSONL path")
+	sessionsDir := fs.String("sessions-dir", defaultSessionsDir(), "Sessions directory that relative source paths are resolved against")
+	annotate := fs.Bool("annotate", false, "Mark orphaned records with meta.orphaned=true")
+	prune := fs.Bool("prune", false, "Remove orphaned records from the history")
+	dryRun := fs.Bool("dry-run", false, "Report what --annotate/--prune would change without writing")
+	jsonOut := fs.Bool("json", false, "Print as JSON")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *annotate && *prune {
+		return errors.New("--annotate and --prune are mutually exclusive")
+	}
+
+	records, err := loadRecords(*inputPath)
+	if err != nil {
+		return err
+	}
+	reports := findOrphans(records, *sessionsDir)
+	hashed := 0
+	for _, record := range records {
+		if isHashedPath(record.SourceFile) {
+			hashed++
+		}
+	}
+	if hashed > 0 {
+		fmt.Fprintf(os.Stderr, "note: %d records have source paths hashed by --hash-paths and were not checked\n", hashed)
+	}
+
+	total := 0
+	for _, report := range reports {
+		total += report.Records
+	}
+
+	if (*annotate || *prune) && !*dryRun && total > 0 {
+		cache := make(sourceExistsCache)
+		err := rewriteHistory(*inputPath, func(record Record) (Record, bool) {
+			if !isOrphaned(record, *sessionsDir, cache) {
+				return record, true
+			}
+			if *prune {
+				return record, false
+			}
+			if record.Meta == nil {
+				record.Meta = make(map[string]string)
+			}
+			record.Meta[orphanedMetaKey] = "true"
+			return record, true
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if *jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetEscapeHTML(false)
+		return enc.Encode(reports)
+	}
+
+	for _, report := range reports {
+		fmt.Printf("%s records=%d\n", report.SourceFile, report.Records)
+	}
+
+	action := "found"
+	switch {
+	case *prune && *dryRun:
+		action = "would prune"
+	case *prune:
+		action = "pruned"
+	case *annotate && *dryRun:
+		action = "would annotate"
+	case *annotate:
+		action = "annotated"
+	}
+	fmt.Printf("%s %d orphaned records across %d missing source files\n", action, total, len(reports))
+	return nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFindOrphansAndRewrite(t *testing.T) {
+	root := t.TempDir()
+	present := filepath.Join(root, "present.jsonl")
+	if err := os.WriteFile(present, []byte("{}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	missing := filepath.Join(root, "gone.jsonl")
+
+	historyPath := filepath.Join(root, "history.jsonl")
+	records := []Record{
+		{ID: "a", SessionID: "s1", Timestamp: "2026-02-17T10:00:00Z", Role: "user", Text: "kept", SourceFile: present, SourceLine: 1},
+		{ID: "b", SessionID: "s2", Timestamp: "2026-02-17T10:01:00Z", Role: "user", Text: "lost", SourceFile: missing, SourceLine: 1},
+		{ID: "c", SessionID: "s2", Timestamp: "2026-02-17T10:02:00Z", Role: "assistant", Text: "lost too", SourceFile: missing, SourceLine: 2},
+		{ID: "d", SessionID: "s3", Timestamp: "2026-02-17T10:03:00Z", Role: "user", Text: "no source"},
+	}
+	if err := appendRecords(historyPath, records, false); err != nil {
+		t.Fatal(err)
+	}
+
+	reports := findOrphans(records, root)
+	if len(reports) != 1 || reports[0].SourceFile != missing || reports[0].Records != 2 {
+		t.Fatalf("unexpected orphan reports: %#v", reports)
+	}
+
+	if err := runOrphans([]string{"--in", historyPath, "--annotate"}); err != nil {
+		t.Fatal(err)
+	}
+	annotated, err := loadRecords(historyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(annotated) != 4 || annotated[1].Meta[orphanedMetaKey] != "true" || annotated[0].Meta != nil {
+		t.Fatalf("unexpected annotated records: %#v", annotated)
+	}
+
+	if err := runOrphans([]string{"--in", historyPath, "--prune"}); err != nil {
+		t.Fatal(err)
+	}
+	pruned, err := loadRecords(historyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pruned) != 2 || pruned[0].ID != "a" || pruned[1].ID != "d" {
+		t.Fatalf("unexpected pruned records: %#v", pruned)
+	}
+}
+
+func TestOrphansSkipsHashedSources(t *testing.T) {
+	root := t.TempDir()
+	sessionsRoot := filepath.Join(root, "sessions")
+	start := time.Date(2026, 2, 15, 9, 0, 0, 0, time.UTC)
+	if _, err := generateDemo(DemoOptions{OutDir: sessionsRoot, Days: 1, SessionsPerDay: 2, Start: start, Seed: 5}); err != nil {
+		t.Fatal(err)
+	}
+	historyPath := filepath.Join(root, "history.jsonl")
+	synced, err := syncOnce(SyncOptions{SessionsDir: sessionsRoot, OutputPath: historyPath, HashPaths: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Every source is a hash; none can be shown missing, so none is pruned.
+	if err := runOrphans([]string{"--in", historyPath, "--sessions-dir", sessionsRoot, "--prune"}); err != nil {
+		t.Fatal(err)
+	}
+	records, err := loadRecords(historyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != synced.Written {
+		t.Fatalf("prune removed %d of %d hashed records", synced.Written-len(records), synced.Written)
+	}
+}
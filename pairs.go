@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// exchange is a user message and the assistant messages that followed it
+// in the same session, for show --pairs. Prompt is nil for assistant
+// messages that came before any user message.
+type exchange struct {
+	SessionID string   `json:"session_id"`
+	Prompt    *Record  `json:"prompt,omitempty"`
+	Responses []Record `json:"responses"`
+}
+
+// pairExchanges groups chronological records into exchanges, in the order
+// they start. Records other than user and assistant messages are skipped.
+func pairExchanges(records []Record) []exchange {
+	var exchanges []exchange
+	open := make(map[string]int)
+	for _, record := range records {
+		switch record.Role {
+		case "user":
+			prompt := record
+			open[record.SessionID] = len(exchanges)
+			exchanges = append(exchanges, exchange{SessionID: record.SessionID, Prompt: &prompt, Responses: []Record{}})
+		case "assistant":
+			i, ok := open[record.SessionID]
+			if !ok {
+				i = len(exchanges)
+				open[record.SessionID] = i
+				exchanges = append(exchanges, exchange{SessionID: record.SessionID})
+			}
+			exchanges[i].Responses = append(exchanges[i].Responses, record)
+		}
+	}
+	return exchanges
+}
+
+// printExchanges writes each exchange as its prompt line followed by the
+// indented responses, with a blank line between exchanges.
+func printExchanges(w io.Writer, exchanges []exchange, maxChars int, colored bool) {
+	for i, ex := range exchanges {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		if ex.Prompt != nil {
+			fmt.Fprintln(w, showLine(*ex.Prompt, maxChars, true, colored))
+		} else {
+			fmt.Fprintf(w, "[%s] (no user message)\n", shortSessionID(ex.SessionID))
+		}
+		for _, response := range ex.Responses {
+			fmt.Fprintf(w, "%s%s\n", strings.Repeat(" ", 4), showLine(response, maxChars, true, colored))
+		}
+	}
+}
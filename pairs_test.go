@@ -0,0 +1,39 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestPairExchanges(t *testing.T) {
+	records := []Record{
+		{ID: "1", SessionID: "s1", Timestamp: "2026-02-17T10:00:00Z", Role: "assistant", Text: "resumed"},
+		{ID: "2", SessionID: "s1", Timestamp: "2026-02-17T10:01:00Z", Role: "user", Text: "fix the test"},
+		{ID: "3", SessionID: "s2", Timestamp: "2026-02-17T10:02:00Z", Role: "user", Text: "other session"},
+		{ID: "4", SessionID: "s1", Timestamp: "2026-02-17T10:03:00Z", Role: "tool", Text: "go test ./..."},
+		{ID: "5", SessionID: "s1", Timestamp: "2026-02-17T10:04:00Z", Role: "assistant", Text: "done"},
+		{ID: "6", SessionID: "s1", Timestamp: "2026-02-17T10:05:00Z", Role: "assistant", Text: "also fixed lint"},
+	}
+	exchanges := pairExchanges(records)
+	if len(exchanges) != 3 {
+		t.Fatalf("expected 3 exchanges, got %d: %+v", len(exchanges), exchanges)
+	}
+	if exchanges[0].Prompt != nil || len(exchanges[0].Responses) != 1 || exchanges[0].Responses[0].ID != "1" {
+		t.Fatalf("unexpected leading exchange: %+v", exchanges[0])
+	}
+	if exchanges[1].Prompt == nil || exchanges[1].Prompt.ID != "2" || len(exchanges[1].Responses) != 2 {
+		t.Fatalf("unexpected s1 exchange: %+v", exchanges[1])
+	}
+	if exchanges[2].Prompt.ID != "3" || len(exchanges[2].Responses) != 0 {
+		t.Fatalf("unexpected s2 exchange: %+v", exchanges[2])
+	}
+
+	var out bytes.Buffer
+	printExchanges(&out, exchanges[1:2], 0, false)
+	want := "2026-02-17T10:01:00Z [s1] user: fix the test\n" +
+		"    2026-02-17T10:04:00Z [s1] assistant: done\n" +
+		"    2026-02-17T10:05:00Z [s1] assistant: also fixed lint\n"
+	if out.String() != want {
+		t.Fatalf("unexpected output:\n%s", out.String())
+	}
+}
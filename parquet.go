@@ -0,0 +1,323 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// This file writes just enough of the Parquet format for export: a flat
+// schema, uncompressed PLAIN-encoded v1 data pages, one page per column
+// per row group, and no statistics. Every Parquet reader handles that
+// subset, and it avoids a dependency for a single export format.
+
+const parquetMagic = "PAR1"
+
+// parquetRowGroupSize bounds how many records share a row group, so readers
+// can skip or parallelize over parts of a large history.
+const parquetRowGroupSize = 50000
+
+// Parquet enum values, from parquet.thrift.
+const (
+	parquetTypeInt64     = 2
+	parquetTypeByteArray = 6
+
+	parquetRequired = 0
+	parquetOptional = 1
+
+	parquetConvertedUTF8            = 0
+	parquetConvertedTimestampMicros = 10
+
+	parquetEncodingPlain = 0
+	parquetEncodingRLE   = 3
+
+	parquetPageData = 0
+)
+
+type parquetChunk struct {
+	offset int64
+	size   int64
+	values int64
+}
+
+// renderParquet encodes records as a Parquet file.
+func renderParquet(records []Record) ([]byte, error) {
+	var out bytes.Buffer
+	out.WriteString(parquetMagic)
+
+	var rowGroups [][]parquetChunk
+	for start := 0; start < len(records); start += parquetRowGroupSize {
+		end := start + parquetRowGroupSize
+		if end > len(records) {
+			end = len(records)
+		}
+		group := make([]parquetChunk, len(recordColumns))
+		for i, column := range recordColumns {
+			offset := int64(out.Len())
+			page := column.encodePage(records[start:end])
+			out.Write(page)
+			group[i] = parquetChunk{offset: offset, size: int64(len(page)), values: int64(end - start)}
+		}
+		rowGroups = append(rowGroups, group)
+	}
+
+	footer := parquetFileMetadata(int64(len(records)), rowGroups)
+	out.Write(footer)
+	binary.Write(&out, binary.LittleEndian, uint32(len(footer)))
+	out.WriteString(parquetMagic)
+	return out.Bytes(), nil
+}
+
+// encodePage returns a page header followed by the page: definition levels
+// for optional columns, then the non-null values.
+func (c recordColumn) encodePage(records []Record) []byte {
+	var levels []bool
+	var values bytes.Buffer
+	for _, record := range records {
+		present := true
+		if c.str != nil {
+			var value string
+			value, present = c.str(record)
+			if present || !c.optional {
+				binary.Write(&values, binary.LittleEndian, uint32(len(value)))
+				values.WriteString(value)
+				present = true
+			}
+		} else {
+			var value int64
+			value, present = c.int(record)
+			if present || !c.optional {
+				binary.Write(&values, binary.LittleEndian, value)
+				present = true
+			}
+		}
+		levels = append(levels, present)
+	}
+
+	var body bytes.Buffer
+	if c.optional {
+		encoded := rleBooleans(levels)
+		binary.Write(&body, binary.LittleEndian, uint32(len(encoded)))
+		body.Write(encoded)
+	}
+	body.Write(values.Bytes())
+
+	var header thriftWriter
+	header.i32Field(1, parquetPageData)
+	header.i32Field(2, int32(body.Len()))
+	header.i32Field(3, int32(body.Len()))
+	header.structBegin(5)
+	header.i32Field(1, int32(len(records)))
+	header.i32Field(2, parquetEncodingPlain)
+	header.i32Field(3, parquetEncodingRLE)
+	header.i32Field(4, parquetEncodingRLE)
+	header.structEnd()
+	header.stop()
+
+	return append(header.buf.Bytes(), body.Bytes()...)
+}
+
+// rleBooleans encodes definition levels (bit width 1) as runs of the
+// RLE/bit-packing hybrid encoding.
+func rleBooleans(levels []bool) []byte {
+	var out []byte
+	for i := 0; i < len(levels); {
+		j := i
+		for j < len(levels) && levels[j] == levels[i] {
+			j++
+		}
+		out = binary.AppendUvarint(out, uint64(j-i)<<1)
+		if levels[i] {
+			out = append(out, 1)
+		} else {
+			out = append(out, 0)
+		}
+		i = j
+	}
+	return out
+}
+
+func parquetFileMetadata(numRows int64, rowGroups [][]parquetChunk) []byte {
+	var w thriftWriter
+	w.i32Field(1, 1)
+
+	w.listBegin(2, thriftStruct, len(recordColumns)+1)
+	w.elemBegin()
+	w.binaryField(4, "schema")
+	w.i32Field(5, int32(len(recordColumns)))
+	w.elemEnd()
+	for _, column := range recordColumns {
+		w.elemBegin()
+		repetition := int32(parquetRequired)
+		if column.optional {
+			repetition = parquetOptional
+		}
+		if column.str != nil {
+			w.i32Field(1, parquetTypeByteArray)
+			w.i32Field(3, repetition)
+			w.binaryField(4, column.name)
+			w.i32Field(6, parquetConvertedUTF8)
+			w.structBegin(10)
+			w.emptyStructField(1) // STRING
+			w.structEnd()
+		} else {
+			w.i32Field(1, parquetTypeInt64)
+			w.i32Field(3, repetition)
+			w.binaryField(4, column.name)
+			if column.timestamp {
+				w.i32Field(6, parquetConvertedTimestampMicros)
+				w.structBegin(10)
+				w.structBegin(8) // TIMESTAMP
+				w.boolField(1, true)
+				w.structBegin(2)
+				w.emptyStructField(2) // MICROS
+				w.structEnd()
+				w.structEnd()
+				w.structEnd()
+			}
+		}
+		w.elemEnd()
+	}
+
+	w.i64Field(3, numRows)
+
+	w.listBegin(4, thriftStruct, len(rowGroups))
+	for _, group := range rowGroups {
+		w.elemBegin()
+		var total int64
+		w.listBegin(1, thriftStruct, len(group))
+		for i, chunk := range group {
+			column := recordColumns[i]
+			physical := int32(parquetTypeByteArray)
+			if column.int != nil {
+				physical = parquetTypeInt64
+			}
+			w.elemBegin()
+			w.i64Field(2, chunk.offset)
+			w.structBegin(3)
+			w.i32Field(1, physical)
+			w.listBegin(2, thriftI32, 2)
+			w.varint(parquetEncodingPlain)
+			w.varint(parquetEncodingRLE)
+			w.listBegin(3, thriftBinary, 1)
+			w.binary(column.name)
+			w.i32Field(4, 0) // UNCOMPRESSED
+			w.i64Field(5, chunk.values)
+			w.i64Field(6, chunk.size)
+			w.i64Field(7, chunk.size)
+			w.i64Field(9, chunk.offset)
+			w.structEnd()
+			w.elemEnd()
+			total += chunk.size
+		}
+		w.i64Field(2, total)
+		w.i64Field(3, group[0].values)
+		w.elemEnd()
+	}
+
+	w.binaryField(6, "codex-history")
+	w.stop()
+	return w.buf.Bytes()
+}
+
+// Thrift compact protocol type codes.
+const (
+	thriftTrue   = 1
+	thriftFalse  = 2
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftWriter emits the Thrift compact protocol, which Parquet uses for
+// page headers and the file footer.
+type thriftWriter struct {
+	buf       bytes.Buffer
+	lastField []int16
+	current   int16
+}
+
+func (w *thriftWriter) fieldHeader(id int16, kind byte) {
+	if delta := id - w.current; delta > 0 && delta <= 15 {
+		w.buf.WriteByte(byte(delta)<<4 | kind)
+	} else {
+		w.buf.WriteByte(kind)
+		w.varint(int64(id))
+	}
+	w.current = id
+}
+
+// varint writes a zigzag-encoded integer.
+func (w *thriftWriter) varint(value int64) {
+	w.buf.Write(binary.AppendUvarint(nil, uint64((value<<1)^(value>>63))))
+}
+
+func (w *thriftWriter) binary(value string) {
+	w.buf.Write(binary.AppendUvarint(nil, uint64(len(value))))
+	w.buf.WriteString(value)
+}
+
+func (w *thriftWriter) i32Field(id int16, value int32) {
+	w.fieldHeader(id, thriftI32)
+	w.varint(int64(value))
+}
+
+func (w *thriftWriter) i64Field(id int16, value int64) {
+	w.fieldHeader(id, thriftI64)
+	w.varint(value)
+}
+
+func (w *thriftWriter) boolField(id int16, value bool) {
+	if value {
+		w.fieldHeader(id, thriftTrue)
+	} else {
+		w.fieldHeader(id, thriftFalse)
+	}
+}
+
+func (w *thriftWriter) binaryField(id int16, value string) {
+	w.fieldHeader(id, thriftBinary)
+	w.binary(value)
+}
+
+func (w *thriftWriter) listBegin(id int16, elemType byte, size int) {
+	w.fieldHeader(id, thriftList)
+	if size < 15 {
+		w.buf.WriteByte(byte(size)<<4 | elemType)
+		return
+	}
+	w.buf.WriteByte(0xF0 | elemType)
+	w.buf.Write(binary.AppendUvarint(nil, uint64(size)))
+}
+
+func (w *thriftWriter) structBegin(id int16) {
+	w.fieldHeader(id, thriftStruct)
+	w.elemBegin()
+}
+
+func (w *thriftWriter) structEnd() {
+	w.elemEnd()
+}
+
+func (w *thriftWriter) emptyStructField(id int16) {
+	w.structBegin(id)
+	w.structEnd()
+}
+
+// elemBegin and elemEnd bracket a struct that is a list element (and, via
+// structBegin, any nested struct): field IDs restart inside it.
+func (w *thriftWriter) elemBegin() {
+	w.lastField = append(w.lastField, w.current)
+	w.current = 0
+}
+
+func (w *thriftWriter) elemEnd() {
+	w.stop()
+	w.current = w.lastField[len(w.lastField)-1]
+	w.lastField = w.lastField[:len(w.lastField)-1]
+}
+
+func (w *thriftWriter) stop() {
+	w.buf.WriteByte(0)
+}
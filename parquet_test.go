@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// goldenExportRecords are the records behind testdata/export.parquet and
+// testdata/export.arrow: every column, a null in each optional one, an
+// unparseable timestamp, and multi-byte text.
+func goldenExportRecords() []Record {
+	exitCode := 0
+	return []Record{
+		{
+			ID:          "a2b6f0f9c3d84e1f0a9b8c7d6e5f4a3b",
+			SessionID:   "019c6699-1b2c-7d3e-8f40-5a6b7c8d9e0f",
+			Timestamp:   "2026-02-17T11:56:20Z",
+			Role:        "user",
+			Text:        "héllo 👋\nrun the tests",
+			SourceFile:  "2026/02/17/rollout.jsonl",
+			SourceLine:  115,
+			Meta:        map[string]string{"cwd": "/src", "model": "gpt-5-codex"},
+			Attachments: []Attachment{{Type: "image", MIME: "image/png", Path: "a.png", SHA256: "ab12", Bytes: 2048}},
+		},
+		{
+			ID:         "5ed180df0c1e4a7b9f1f0d2a3c4b5e6f",
+			SessionID:  "019c6699-1b2c-7d3e-8f40-5a6b7c8d9e0f",
+			Timestamp:  "2026-02-17T11:56:22.113Z",
+			Role:       "tool",
+			Text:       "go test ./...",
+			SourceFile: "2026/02/17/rollout.jsonl",
+			SourceLine: 116,
+			Tool:       &ToolCall{Name: "shell", CallID: "c1", Arguments: json.RawMessage(`{"command":["go","test"]}`), Output: "ok", ExitCode: &exitCode},
+			Raw:        json.RawMessage(`{"type":"exec"}`),
+		},
+		{ID: "0f1e2d3c4b5a69788796a5b4c3d2e1f0", SessionID: "s2", Timestamp: "not a time", Role: "assistant", Text: "done"},
+	}
+}
+
+func TestRenderParquetLayout(t *testing.T) {
+	records := []Record{
+		{ID: "id1", SessionID: "s1", Timestamp: "2026-02-17T10:00:00Z", Role: "user", Text: "hello", SourceFile: "a.jsonl", SourceLine: 3},
+		{ID: "id2", SessionID: "s1", Timestamp: "not a time", Role: "assistant", Text: "hi", Meta: map[string]string{"model": "gpt-5"}},
+	}
+	content, err := renderExport("parquet", records, ExportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(content, []byte("PAR1")) || !bytes.HasSuffix(content, []byte("PAR1")) {
+		t.Fatal("missing Parquet magic")
+	}
+	footerLen := int(binary.LittleEndian.Uint32(content[len(content)-8:]))
+	footer := content[len(content)-8-footerLen : len(content)-8]
+	for _, name := range []string{"schema", "id", "timestamp", "source_line", "meta", "raw", "codex-history"} {
+		if !bytes.Contains(footer, []byte(name)) {
+			t.Fatalf("footer is missing %q", name)
+		}
+	}
+	// The text column's page holds both values, length-prefixed.
+	if !bytes.Contains(content, []byte("\x05\x00\x00\x00hello\x02\x00\x00\x00hi")) {
+		t.Fatal("text values not found in PLAIN encoding")
+	}
+}
+
+// TestRenderParquetGolden pins the output to a file an independent reader
+// has decoded; see testdata/readers. After an intended change to the
+// format, regenerate the file and re-run that check before committing it.
+func TestRenderParquetGolden(t *testing.T) {
+	content, err := renderExport("parquet", goldenExportRecords(), ExportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden, err := os.ReadFile(filepath.Join("testdata", "export.parquet"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(content, golden) {
+		t.Fatal("Parquet output differs from testdata/export.parquet")
+	}
+}
+
+func TestRLEBooleans(t *testing.T) {
+	got := rleBooleans([]bool{true, true, true, false, true})
+	want := []byte{3 << 1, 1, 1 << 1, 0, 1 << 1, 1}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("rleBooleans = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// FileChange is the diffstat of one file touched by Codex patches.
+type FileChange struct {
+	Path    string `json:"path"`
+	Kind    string `json:"kind"` // add, delete, or update
+	Added   int    `json:"added"`
+	Removed int    `json:"removed"`
+}
+
+// patchChange is one entry of a patch_apply_begin "changes" map.
+type patchChange struct {
+	Add *struct {
+		Content string `json:"content"`
+	} `json:"add"`
+	Delete *struct {
+		Content string `json:"content"`
+	} `json:"delete"`
+	Update *struct {
+		UnifiedDiff string  `json:"unified_diff"`
+		MovePath    *string `json:"move_path"`
+	} `json:"update"`
+}
+
+// patchApplyChanges turns a patch_apply_begin changes map into diffstats,
+// sorted by path.
+func patchApplyChanges(changes map[string]patchChange) []FileChange {
+	out := make([]FileChange, 0, len(changes))
+	for path, change := range changes {
+		fc := FileChange{Path: path}
+		switch {
+		case change.Add != nil:
+			fc.Kind = "add"
+			fc.Added = countLines(change.Add.Content)
+		case change.Delete != nil:
+			fc.Kind = "delete"
+			fc.Removed = countLines(change.Delete.Content)
+		case change.Update != nil:
+			fc.Kind = "update"
+			if change.Update.MovePath != nil && *change.Update.MovePath != "" {
+				fc.Path = *change.Update.MovePath
+			}
+			fc.Added, fc.Removed = unifiedDiffStat(change.Update.UnifiedDiff)
+		default:
+			continue
+		}
+		out = append(out, fc)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Path < out[j].Path })
+	return out
+}
+
+// applyPatchChanges parses the "*** Begin Patch" envelope the apply_patch
+// tool takes as input, for sessions that only log the tool call.
+func applyPatchChanges(patch string) []FileChange {
+	var out []FileChange
+	var current *FileChange
+	for _, line := range strings.Split(patch, "\n") {
+		line = strings.TrimRight(line, "\r")
+		switch {
+		case strings.HasPrefix(line, "*** Add File: "):
+			out = append(out, FileChange{Path: strings.TrimPrefix(line, "*** Add File: "), Kind: "add"})
+			current = &out[len(out)-1]
+		case strings.HasPrefix(line, "*** Delete File: "):
+			out = append(out, FileChange{Path: strings.TrimPrefix(line, "*** Delete File: "), Kind: "delete"})
+			current = &out[len(out)-1]
+		case strings.HasPrefix(line, "*** Update File: "):
+			out = append(out, FileChange{Path: strings.TrimPrefix(line, "*** Update File: "), Kind: "update"})
+			current = &out[len(out)-1]
+		case strings.HasPrefix(line, "*** Move to: "):
+			if current != nil {
+				current.Path = strings.TrimPrefix(line, "*** Move to: ")
+			}
+		case strings.HasPrefix(line, "***"):
+			// Begin/End Patch and End of File markers.
+		case current == nil:
+		case strings.HasPrefix(line, "+"):
+			current.Added++
+		case strings.HasPrefix(line, "-"):
+			current.Removed++
+		}
+	}
+	return out
+}
+
+func unifiedDiffStat(diff string) (added, removed int) {
+	for _, line := range strings.Split(diff, "\n") {
+		switch {
+		case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"):
+		case strings.HasPrefix(line, "+"):
+			added++
+		case strings.HasPrefix(line, "-"):
+			removed++
+		}
+	}
+	return added, removed
+}
+
+func countLines(content string) int {
+	if content == "" {
+		return 0
+	}
+	n := strings.Count(content, "\n")
+	if !strings.HasSuffix(content, "\n") {
+		n++
+	}
+	return n
+}
+
+// patchText renders diffstats as the text of a patch record, one file per
+// line: "M path/to/file +3 -1".
+func patchText(changes []FileChange) string {
+	lines := make([]string, len(changes))
+	for i, change := range changes {
+		kind := map[string]string{"add": "A", "delete": "D", "update": "M"}[change.Kind]
+		lines[i] = fmt.Sprintf("%s %s +%d -%d", kind, change.Path, change.Added, change.Removed)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// applyPatchInput returns the patch body of an apply_patch tool call, which
+// is either the raw custom_tool_call input or a function_call whose
+// arguments carry it under "input".
+func applyPatchInput(item responseItemPayload) string {
+	if item.Name != "apply_patch" {
+		return ""
+	}
+	if item.Input != "" {
+		return item.Input
+	}
+	var args struct {
+		Input string `json:"input"`
+	}
+	if err := json.Unmarshal([]byte(item.Arguments), &args); err == nil {
+		return args.Input
+	}
+	return ""
+}
+
+// mergeFileChanges folds changes into a per-path total, keeping the latest
+// kind for each file.
+func mergeFileChanges(into []FileChange, changes []FileChange) []FileChange {
+	for _, change := range changes {
+		found := false
+		for i := range into {
+			if into[i].Path == change.Path {
+				into[i].Kind = change.Kind
+				into[i].Added += change.Added
+				into[i].Removed += change.Removed
+				found = true
+				break
+			}
+		}
+		if !found {
+			into = append(into, change)
+		}
+	}
+	sort.Slice(into, func(i, j int) bool { return into[i].Path < into[j].Path })
+	return into
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtractPatchRecords(t *testing.T) {
+	path := writeSessionFile(t,
+		`{"timestamp":"2026-02-17T12:00:00Z","type":"session_meta","payload":{"id":"s1"}}`,
+		`{"timestamp":"2026-02-17T12:00:01Z","type":"response_item","payload":{"type":"custom_tool_call","name":"apply_patch","call_id":"p1","input":"*** Begin Patch\n*** Update File: main.go\n@@\n-old\n+new\n+more\n*** Add File: notes.md\n+# Notes\n*** End Patch"}}`,
+		`{"timestamp":"2026-02-17T12:00:01Z","type":"event_msg","payload":{"type":"patch_apply_begin","call_id":"p1","changes":{"main.go":{"update":{"unified_diff":"@@ -1 +1,2 @@\n-old\n+new\n+more\n","move_path":null}},"notes.md":{"add":{"content":"# Notes\n"}}}}}`,
+		`{"timestamp":"2026-02-17T12:00:02Z","type":"event_msg","payload":{"type":"patch_apply_begin","call_id":"p2","changes":{"old.go":{"delete":{"content":"a\nb\nc"}},"main.go":{"update":{"unified_diff":"--- a/main.go\n+++ b/main.go\n-x\n+y\n"}}}}}`,
+	)
+
+	session, err := extractSession(path, ExtractOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(session.Records) != 0 || len(session.Info.FilesChanged) != 0 {
+		t.Fatalf("patches must be opt-in, got %#v", session)
+	}
+
+	session, err = extractSession(path, ExtractOptions{Include: includeSet{includePatches: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(session.Records) != 2 || session.Records[0].Role != "patch" || session.Records[0].Text != "M main.go +2 -1\nA notes.md +1 -0" {
+		t.Fatalf("unexpected patch records: %#v", session.Records)
+	}
+	want := []FileChange{
+		{Path: "main.go", Kind: "update", Added: 3, Removed: 2},
+		{Path: "notes.md", Kind: "add", Added: 1},
+		{Path: "old.go", Kind: "delete", Removed: 3},
+	}
+	if !reflect.DeepEqual(session.Info.FilesChanged, want) {
+		t.Fatalf("got %+v, want %+v", session.Info.FilesChanged, want)
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
+// textPathPattern finds file paths in message text: absolute Unix paths
+// and ~/ paths with at least two components, and Windows drive paths. The
+// leading group keeps URLs (https://host/...) and fractions (1/2) out.
+var textPathPattern = regexp.MustCompile(`(^|[\s"'(\[=:,` + "`" + `])((?:~|/[\w.@+-]+)(?:/[\w.@+-]+)+/?|[A-Za-z]:\\(?:[\w.@+ -]+\\)*[\w.@+-]+)`)
+
+// hashedPathPrefix starts every token hashPath produces.
+const hashedPathPrefix = "path:"
+
+// hashPath replaces path with a stable, opaque token: the same path always
+// becomes the same token, so records still group by file and directory
+// without revealing either.
+func hashPath(path string) string {
+	if path == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(path))
+	return hashedPathPrefix + hex.EncodeToString(sum[:6])
+}
+
+// isHashedPath reports whether path is a hashPath token. It names no file,
+// so commands that look source files up on disk must skip it rather than
+// treat the file as missing.
+func isHashedPath(path string) bool {
+	return strings.HasPrefix(path, hashedPathPrefix)
+}
+
+// hashPathsInText hashes every path textPathPattern finds in text.
+func hashPathsInText(text string) string {
+	return textPathPattern.ReplaceAllStringFunc(text, func(match string) string {
+		groups := textPathPattern.FindStringSubmatch(match)
+		return groups[1] + hashPath(groups[2])
+	})
+}
+
+// hashRecordPaths applies sync --hash-paths to a record: source_file and
+// the working directory become hashes, and paths inside the text, metadata,
+// tool call, and raw event are hashed in place. The ID is recomputed so it
+// keeps matching the stored text.
+func hashRecordPaths(record Record) Record {
+	record.SourceFile = hashPath(record.SourceFile)
+	record.Text = hashPathsInText(record.Text)
+	if len(record.Meta) > 0 {
+		meta := make(map[string]string, len(record.Meta))
+		for key, value := range record.Meta {
+			if key == "cwd" {
+				meta[key] = hashPath(value)
+			} else {
+				meta[key] = hashPathsInText(value)
+			}
+		}
+		record.Meta = meta
+	}
+	if record.Tool != nil {
+		tool := *record.Tool
+		tool.Output = hashPathsInText(tool.Output)
+		if len(tool.Arguments) > 0 {
+			var args any
+			if err := json.Unmarshal(tool.Arguments, &args); err == nil {
+				if data, err := json.Marshal(hashJSONPaths(args)); err == nil {
+					tool.Arguments = data
+				}
+			}
+		}
+		record.Tool = &tool
+	}
+	if len(record.Raw) > 0 {
+		var raw any
+		if err := json.Unmarshal(record.Raw, &raw); err == nil {
+			if data, err := json.Marshal(hashJSONPaths(raw)); err == nil {
+				record.Raw = data
+			}
+		}
+	}
+	record.ID = recordContentID(record)
+	return record
+}
+
+// hashJSONPaths hashes paths inside every string of a decoded JSON value.
+func hashJSONPaths(value any) any {
+	switch v := value.(type) {
+	case string:
+		if strings.HasPrefix(v, "/") || strings.HasPrefix(v, "~/") {
+			if !strings.ContainsAny(v, " \n\t") {
+				return hashPath(v)
+			}
+		}
+		return hashPathsInText(v)
+	case []any:
+		for i := range v {
+			v[i] = hashJSONPaths(v[i])
+		}
+	case map[string]any:
+		for key := range v {
+			v[key] = hashJSONPaths(v[key])
+		}
+	}
+	return value
+}
+
+// hashSessionInfoPaths hashes the working directory and changed file paths
+// kept in the session sidecar.
+func hashSessionInfoPaths(info SessionInfo) SessionInfo {
+	info.Cwd = hashPath(info.Cwd)
+	if len(info.FilesChanged) > 0 {
+		changes := make([]FileChange, len(info.FilesChanged))
+		for i, change := range info.FilesChanged {
+			change.Path = hashPath(change.Path)
+			changes[i] = change
+		}
+		info.FilesChanged = changes
+	}
+	return info
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestHashPathsInText(t *testing.T) {
+	home := hashPath("/home/al/src/api/main.go")
+	if home != hashPath("/home/al/src/api/main.go") || !strings.HasPrefix(home, "path:") || len(home) != len("path:")+12 {
+		t.Fatalf("unexpected hash %q", home)
+	}
+	cases := map[string]string{
+		"open /home/al/src/api/main.go now":     "open " + home + " now",
+		"see (`/home/al/src/api/main.go`)":      "see (`" + home + "`)",
+		"cd ~/src/api && ls":                    "cd " + hashPath("~/src/api") + " && ls",
+		`edit C:\Users\al\notes.txt`:            "edit " + hashPath(`C:\Users\al\notes.txt`),
+		"fetch https://example.com/a/b and 1/2": "fetch https://example.com/a/b and 1/2",
+		"the /tmp directory":                    "the /tmp directory",
+	}
+	for text, want := range cases {
+		if got := hashPathsInText(text); got != want {
+			t.Errorf("hashPathsInText(%q) = %q, want %q", text, got, want)
+		}
+	}
+}
+
+func TestHashRecordPaths(t *testing.T) {
+	record := Record{
+		SessionID:  "s1",
+		Timestamp:  "2026-02-17T10:00:00Z",
+		Role:       "tool",
+		Text:       "cat /home/al/src/api/go.mod",
+		SourceFile: "/home/al/.codex/sessions/2026/02/17/rollout.jsonl",
+		Meta:       map[string]string{"cwd": "/home/al/src/api", "model": "o4-mini"},
+		Tool:       &ToolCall{Name: "shell", Arguments: json.RawMessage(`{"command":["cat","/home/al/src/api/go.mod"],"workdir":"/home/al/src/api"}`)},
+	}
+	record.ID = recordContentID(record)
+	hashed := hashRecordPaths(record)
+
+	if hashed.SourceFile != hashPath(record.SourceFile) || hashed.Meta["cwd"] != hashPath("/home/al/src/api") || hashed.Meta["model"] != "o4-mini" {
+		t.Fatalf("unexpected record: %+v", hashed)
+	}
+	for _, field := range []string{hashed.Text, string(hashed.Tool.Arguments)} {
+		if strings.Contains(field, "/home/al") {
+			t.Fatalf("path left in %q", field)
+		}
+	}
+	if hashed.ID != recordContentID(hashed) || hashed.ID == record.ID {
+		t.Fatal("expected the ID to follow the hashed text")
+	}
+	if record.Tool.Arguments[0] != '{' || !strings.Contains(string(record.Tool.Arguments), "/home/al") {
+		t.Fatal("the original record was modified")
+	}
+
+	info := hashSessionInfoPaths(SessionInfo{Cwd: "/home/al/src/api", FilesChanged: []FileChange{{Path: "/home/al/src/api/go.mod"}}})
+	if info.Cwd != hashPath("/home/al/src/api") || info.FilesChanged[0].Path != hashPath("/home/al/src/api/go.mod") {
+		t.Fatalf("unexpected session info: %+v", info)
+	}
+}
@@ -0,0 +1,327 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// RedactRule replaces every match of Pattern with Replacement, which may
+// refer to capture groups as $1 or ${name}. Replacement defaults to
+// [REDACTED:<name>].
+type RedactRule struct {
+	Name        string `json:"name"`
+	Pattern     string `json:"pattern"`
+	Replacement string `json:"replacement,omitempty"`
+
+	re *regexp.Regexp
+}
+
+// redactRuleFile is the JSON form of a rules file; a bare array of rules
+// is accepted too.
+type redactRuleFile struct {
+	Rules []RedactRule `json:"rules"`
+}
+
+func runRedact(args []string) error {
+	fs := flag.NewFlagSet("redact", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+
+	inputPath := fs.String("in", defaultOutputFile(), "History JSONL path")
+	rulesPath := fs.String("rules", "", "Rules file (YAML or JSON) of name, pattern, and replacement")
+	dryRun := fs.Bool("dry-run", false, "Count redactions without rewriting the history")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if strings.TrimSpace(*rulesPath) == "" {
+		return errors.New("--rules is required")
+	}
+	rules, err := loadRedactRules(*rulesPath)
+	if err != nil {
+		return err
+	}
+
+	counts, records, err := redactHistory(*inputPath, rules, *dryRun)
+	if err != nil {
+		return err
+	}
+
+	for i, rule := range rules {
+		fmt.Printf("rule=%s redactions=%d\n", rule.Name, counts[i])
+	}
+	verb := "redacted"
+	if *dryRun {
+		verb = "would redact"
+	}
+	fmt.Printf("%s %d records in %s\n", verb, records, *inputPath)
+	if !*dryRun && records > 0 {
+		noteDerivedCopies(*inputPath, "unredacted")
+	}
+	return nil
+}
+
+// redactHistory applies rules to every record in the history and returns
+// each rule's match count and how many records changed. A changed record
+// gets a new ID; the old one goes into the removed set first, so that sync
+// does not bring the unredacted record back from the rollout file. With
+// dryRun the history is only read.
+func redactHistory(path string, rules []RedactRule, dryRun bool) ([]int, int, error) {
+	counts := make([]int, len(rules))
+	var oldIDs []string
+	err := forEachRecord(path, func(record Record) error {
+		if _, changed := redactRecord(record, rules, counts); changed {
+			oldIDs = append(oldIDs, record.ID)
+		}
+		return nil
+	})
+	if err != nil || dryRun || len(oldIDs) == 0 {
+		return counts, len(oldIDs), err
+	}
+	if err := appendRemovedIDs(path, oldIDs); err != nil {
+		return nil, 0, err
+	}
+	scratch := make([]int, len(rules))
+	err = rewriteHistory(path, func(record Record) (Record, bool) {
+		if redacted, changed := redactRecord(record, rules, scratch); changed {
+			redacted.ID = recordContentID(redacted)
+			return redacted, true
+		}
+		return record, true
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return counts, len(oldIDs), nil
+}
+
+// noteDerivedCopies tells the user which files derived from the history
+// (the full-text index and the vector cache) still hold the old text after
+// a rewrite meant to remove it; what describes that text.
+func noteDerivedCopies(historyPath, what string) {
+	for _, path := range []string{textIndexPath(historyPath), vectorCachePath(historyPath)} {
+		if _, err := os.Stat(path); err == nil {
+			fmt.Fprintf(os.Stderr, "note: %s still holds %s text; delete or rebuild it before sharing\n", path, what)
+		}
+	}
+}
+
+// redactRecord applies rules to every string a record carries besides its
+// identity (ID, session, timestamp, role): the text, source file, metadata
+// values, attachment paths, the tool call's arguments and output, and the
+// raw event, adding each rule's match count to counts. It reports whether
+// anything changed.
+func redactRecord(record Record, rules []RedactRule, counts []int) (Record, bool) {
+	changed := false
+	redact := func(text string) string {
+		for i, rule := range rules {
+			matches := len(rule.re.FindAllStringIndex(text, -1))
+			if matches == 0 {
+				continue
+			}
+			counts[i] += matches
+			text = rule.re.ReplaceAllString(text, rule.Replacement)
+			changed = true
+		}
+		return text
+	}
+	record.Text = redact(record.Text)
+	record.SourceFile = redact(record.SourceFile)
+	if len(record.Meta) > 0 {
+		meta := make(map[string]string, len(record.Meta))
+		for key, value := range record.Meta {
+			meta[key] = redact(value)
+		}
+		record.Meta = meta
+	}
+	if len(record.Attachments) > 0 {
+		attachments := make([]Attachment, len(record.Attachments))
+		for i, attachment := range record.Attachments {
+			attachment.Path = redact(attachment.Path)
+			attachments[i] = attachment
+		}
+		record.Attachments = attachments
+	}
+	if record.Tool != nil {
+		tool := *record.Tool
+		tool.Arguments = redactJSON(tool.Arguments, redact)
+		tool.Output = redact(tool.Output)
+		record.Tool = &tool
+	}
+	record.Raw = redactJSON(record.Raw, redact)
+	return record, changed
+}
+
+// redactJSON applies redact to every string value in a JSON document, so a
+// replacement cannot break its syntax. The document is only re-encoded
+// when a value changed; data that does not decode is redacted as text.
+func redactJSON(data json.RawMessage, redact func(string) string) json.RawMessage {
+	if len(data) == 0 {
+		return data
+	}
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return json.RawMessage(redact(string(data)))
+	}
+	changed := false
+	var walk func(any) any
+	walk = func(value any) any {
+		switch v := value.(type) {
+		case string:
+			if redacted := redact(v); redacted != v {
+				changed = true
+				return redacted
+			}
+		case []any:
+			for i := range v {
+				v[i] = walk(v[i])
+			}
+		case map[string]any:
+			for key := range v {
+				v[key] = walk(v[key])
+			}
+		}
+		return value
+	}
+	value = walk(value)
+	if !changed {
+		return data
+	}
+	var out strings.Builder
+	enc := json.NewEncoder(&out)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(value); err != nil {
+		return data
+	}
+	return json.RawMessage(strings.TrimSuffix(out.String(), "\n"))
+}
+
+// loadRedactRules reads and compiles a rules file. Files ending in .json
+// are JSON; anything else is read as the YAML subset parseRedactRulesYAML
+// understands.
+func loadRedactRules(path string) ([]RedactRule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var rules []RedactRule
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		trimmed := strings.TrimSpace(string(data))
+		if strings.HasPrefix(trimmed, "[") {
+			err = json.Unmarshal(data, &rules)
+		} else {
+			var file redactRuleFile
+			err = json.Unmarshal(data, &file)
+			rules = file.Rules
+		}
+	} else {
+		rules, err = parseRedactRulesYAML(string(data))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid rules %s: %w", path, err)
+	}
+	if err := compileRedactRules(rules); err != nil {
+		return nil, fmt.Errorf("invalid rules %s: %w", path, err)
+	}
+	return rules, nil
+}
+
+func compileRedactRules(rules []RedactRule) error {
+	if len(rules) == 0 {
+		return errors.New("no rules")
+	}
+	seen := make(map[string]bool, len(rules))
+	for i := range rules {
+		rule := &rules[i]
+		rule.Name = strings.TrimSpace(rule.Name)
+		if rule.Name == "" {
+			return fmt.Errorf("rule %d has no name", i+1)
+		}
+		if seen[rule.Name] {
+			return fmt.Errorf("duplicate rule %q", rule.Name)
+		}
+		seen[rule.Name] = true
+		if rule.Pattern == "" {
+			return fmt.Errorf("rule %q has no pattern", rule.Name)
+		}
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return fmt.Errorf("rule %q: %w", rule.Name, err)
+		}
+		rule.re = re
+		if rule.Replacement == "" {
+			rule.Replacement = "[REDACTED:" + rule.Name + "]"
+		}
+	}
+	return nil
+}
+
+// parseRedactRulesYAML reads the small part of YAML a rules file needs: a
+// list of flat mappings, optionally under a top-level "rules:" key, with
+// plain, 'single-quoted', or "double-quoted" values and # comment lines.
+// Single quotes suit regular expressions, since backslashes stay literal.
+func parseRedactRulesYAML(text string) ([]RedactRule, error) {
+	var rules []RedactRule
+	for n, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") || trimmed == "---" {
+			continue
+		}
+		if trimmed == "rules:" && line == trimmed {
+			continue
+		}
+		if item, ok := strings.CutPrefix(trimmed, "-"); ok && (item == "" || item[0] == ' ') {
+			rules = append(rules, RedactRule{})
+			trimmed = strings.TrimSpace(item)
+			if trimmed == "" {
+				continue
+			}
+		}
+		if len(rules) == 0 {
+			return nil, fmt.Errorf("line %d: expected a list item starting with -", n+1)
+		}
+		key, raw, ok := strings.Cut(trimmed, ":")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected key: value", n+1)
+		}
+		value, err := yamlScalar(strings.TrimSpace(raw))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n+1, err)
+		}
+		rule := &rules[len(rules)-1]
+		switch strings.TrimSpace(key) {
+		case "name":
+			rule.Name = value
+		case "pattern":
+			rule.Pattern = value
+		case "replacement":
+			rule.Replacement = value
+		default:
+			return nil, fmt.Errorf("line %d: unknown key %q (use name, pattern, or replacement)", n+1, strings.TrimSpace(key))
+		}
+	}
+	return rules, nil
+}
+
+// yamlScalar unquotes a YAML scalar value.
+func yamlScalar(raw string) (string, error) {
+	switch {
+	case strings.HasPrefix(raw, "'"):
+		if len(raw) < 2 || !strings.HasSuffix(raw, "'") {
+			return "", errors.New("unterminated single-quoted value")
+		}
+		return strings.ReplaceAll(raw[1:len(raw)-1], "''", "'"), nil
+	case strings.HasPrefix(raw, `"`):
+		var value string
+		if err := json.Unmarshal([]byte(raw), &value); err != nil {
+			return "", fmt.Errorf("invalid double-quoted value %s", raw)
+		}
+		return value, nil
+	}
+	return raw, nil
+}
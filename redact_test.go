@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseRedactRulesYAML(t *testing.T) {
+	rules, err := parseRedactRulesYAML(`# shared before handing history to the team
+rules:
+  - name: openai-key
+    pattern: 'sk-[A-Za-z0-9]{8,}'
+  - name: email
+    pattern: '[\w.+-]+@[\w-]+\.[\w.]+'
+    replacement: "<email>"
+  - name: host
+    pattern: '\b(\w+)\.corp\.example\.com'
+    replacement: '$1.internal'
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := compileRedactRules(rules); err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != 3 || rules[0].Replacement != "[REDACTED:openai-key]" || rules[1].Pattern != `[\w.+-]+@[\w-]+\.[\w.]+` || rules[1].Replacement != "<email>" {
+		t.Fatalf("unexpected rules: %+v", rules)
+	}
+
+	counts := make([]int, len(rules))
+	record := Record{
+		Text: "key sk-abcdef123456 and sk-zyxwvu987654, mail me at al@example.org on build.corp.example.com",
+		Meta: map[string]string{"cwd": "/home/al"},
+	}
+	redacted, changed := redactRecord(record, rules, counts)
+	want := "key [REDACTED:openai-key] and [REDACTED:openai-key], mail me at <email> on build.internal"
+	if !changed || redacted.Text != want {
+		t.Fatalf("got %q, want %q", redacted.Text, want)
+	}
+	if counts[0] != 2 || counts[1] != 1 || counts[2] != 1 {
+		t.Fatalf("unexpected counts: %v", counts)
+	}
+	if _, changed := redactRecord(Record{Text: "nothing here"}, rules, counts); changed {
+		t.Fatal("expected no change")
+	}
+
+	// Tool calls, attachments, and raw events carry text too.
+	record = Record{
+		Role:        "tool",
+		Text:        "curl",
+		SourceFile:  "/home/al/.codex/sessions/a.jsonl",
+		Attachments: []Attachment{{Type: "image", Path: "/tmp/sk-abcdef123456.png"}},
+		Tool: &ToolCall{
+			Name:      "shell",
+			Arguments: json.RawMessage(`{"command":["curl","-H","Authorization: Bearer sk-abcdef123456"]}`),
+			Output:    "mail al@example.org",
+		},
+		Raw: json.RawMessage(`{"type":"exec","env":{"OPENAI_API_KEY":"sk-abcdef123456"}}`),
+	}
+	redacted, changed = redactRecord(record, rules, make([]int, len(rules)))
+	encoded, _ := json.Marshal(redacted)
+	if !changed || strings.Contains(string(encoded), "sk-abcdef") || strings.Contains(string(encoded), "al@example.org") {
+		t.Fatalf("secrets left in %s", encoded)
+	}
+	if !json.Valid(redacted.Tool.Arguments) || !json.Valid(redacted.Raw) {
+		t.Fatalf("redaction broke JSON: %s / %s", redacted.Tool.Arguments, redacted.Raw)
+	}
+	if !strings.Contains(string(record.Tool.Arguments), "sk-abcdef") {
+		t.Fatal("redactRecord must not modify the caller's record")
+	}
+}
+
+func TestLoadRedactRulesErrors(t *testing.T) {
+	dir := t.TempDir()
+	cases := map[string]string{
+		"bad.yaml":  "- name: x\n  pattern: '(unclosed'\n",
+		"dup.yaml":  "- name: x\n  pattern: a\n- name: x\n  pattern: b\n",
+		"key.yaml":  "- name: x\n  regex: a\n",
+		"list.yaml": "name: x\n",
+		"none.json": `{"rules": []}`,
+	}
+	for name, content := range cases {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := loadRedactRules(path); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+
+	path := filepath.Join(dir, "rules.json")
+	if err := os.WriteFile(path, []byte(`[{"name": "digits", "pattern": "\\d+"}]`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	rules, err := loadRedactRules(path)
+	if err != nil || len(rules) != 1 || rules[0].re.String() != `\d+` {
+		t.Fatalf("got %+v, %v", rules, err)
+	}
+}
+
+func TestRedactHistoryKeepsIDsVerifiable(t *testing.T) {
+	root := t.TempDir()
+	sessionsRoot := filepath.Join(root, "sessions")
+	start := time.Date(2026, 2, 15, 9, 0, 0, 0, time.UTC)
+	if _, err := generateDemo(DemoOptions{OutDir: sessionsRoot, Days: 1, SessionsPerDay: 2, Start: start, Seed: 5}); err != nil {
+		t.Fatal(err)
+	}
+	historyPath := filepath.Join(root, "history.jsonl")
+	first, err := syncOnce(SyncOptions{SessionsDir: sessionsRoot, OutputPath: historyPath})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rules := []RedactRule{{Name: "vowel", Pattern: "[aeiou]", Replacement: "*"}}
+	if err := compileRedactRules(rules); err != nil {
+		t.Fatal(err)
+	}
+	counts, changed, err := redactHistory(historyPath, rules, false)
+	if err != nil || changed == 0 || counts[0] == 0 {
+		t.Fatalf("redacted %d records (%v), %v", changed, counts, err)
+	}
+
+	report, err := verifyHistory(historyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Issues) != 0 {
+		t.Fatalf("verify after redact: %+v", report.Issues)
+	}
+
+	// The rollout files still hold the originals; sync must not restore
+	// them, even when it rereads every file from the start.
+	if err := os.Remove(sourcesStatePath(historyPath)); err != nil {
+		t.Fatal(err)
+	}
+	again, err := syncOnce(SyncOptions{SessionsDir: sessionsRoot, OutputPath: historyPath})
+	if err != nil || again.Written != 0 {
+		t.Fatalf("second sync wrote %d, %v", again.Written, err)
+	}
+	records, err := loadRecords(historyPath)
+	if err != nil || len(records) != first.Written {
+		t.Fatalf("expected %d records, got %d (%v)", first.Written, len(records), err)
+	}
+}
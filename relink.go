@@ -0,0 +1,91 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// relinkPath swaps oldPrefix for newPrefix when path lies under oldPrefix.
+// Prefixes only match whole path components, so /home/al does not match
+// /home/alice/...
+func relinkPath(path, oldPrefix, newPrefix string) (string, bool) {
+	if path == oldPrefix {
+		return newPrefix, true
+	}
+	if !strings.HasPrefix(path, oldPrefix+string(filepath.Separator)) {
+		return path, false
+	}
+	return newPrefix + path[len(oldPrefix):], true
+}
+
+func runRelink(args []string) error {
+	fs := flag.NewFlagSet("relink", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+
+	inputPath := fs.String("in", defaultOutputFile(), "History JSONL path")
+	oldPrefix := fs.String("old-prefix", "", "Source path prefix to replace, e.g. /old/home/.codex")
+	newPrefix := fs.String("new-prefix", "", "Replacement prefix, e.g. /new/home/.codex")
+	dryRun := fs.Bool("dry-run", false, "Count records that would be rewritten without writing")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	oldClean := strings.TrimSpace(*oldPrefix)
+	newClean := strings.TrimSpace(*newPrefix)
+	if oldClean == "" || newClean == "" {
+		return errors.New("--old-prefix and --new-prefix are required")
+	}
+	oldClean = filepath.Clean(oldClean)
+	newClean = filepath.Clean(newClean)
+
+	relinked := 0
+	if *dryRun {
+		records, err := loadRecords(*inputPath)
+		if err != nil {
+			return err
+		}
+		for _, record := range records {
+			if _, ok := relinkPath(record.SourceFile, oldClean, newClean); ok {
+				relinked++
+			}
+		}
+		fmt.Printf("would relink %d records from %s to %s\n", relinked, oldClean, newClean)
+		return nil
+	}
+
+	err := rewriteHistory(*inputPath, func(record Record) (Record, bool) {
+		if updated, ok := relinkPath(record.SourceFile, oldClean, newClean); ok {
+			record.SourceFile = updated
+			relinked++
+		}
+		return record, true
+	})
+	if err != nil {
+		return err
+	}
+
+	// Keep sync's per-file scan state keyed by the new paths too.
+	statePath := sourcesStatePath(*inputPath)
+	state, err := loadSourcesState(statePath)
+	if err != nil {
+		return err
+	}
+	if len(state) > 0 {
+		moved := make(map[string]SourceState, len(state))
+		for path, entry := range state {
+			updated, _ := relinkPath(path, oldClean, newClean)
+			moved[updated] = entry
+		}
+		if err := saveSourcesState(statePath, moved); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("relinked %d records from %s to %s\n", relinked, oldClean, newClean)
+	return nil
+}
@@ -0,0 +1,22 @@
+package main
+
+import "testing"
+
+func TestRelinkPath(t *testing.T) {
+	cases := []struct {
+		path string
+		want string
+		ok   bool
+	}{
+		{"/old/home/.codex/sessions/a.jsonl", "/new/.codex/sessions/a.jsonl", true},
+		{"/old/home/.codex", "/new/.codex", true},
+		{"/old/home/.codex-other/a.jsonl", "/old/home/.codex-other/a.jsonl", false},
+		{"", "", false},
+	}
+	for _, tc := range cases {
+		got, ok := relinkPath(tc.path, "/old/home/.codex", "/new/.codex")
+		if got != tc.want || ok != tc.ok {
+			t.Fatalf("relinkPath(%q) = %q, %v; want %q, %v", tc.path, got, ok, tc.want, tc.ok)
+		}
+	}
+}
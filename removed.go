@@ -0,0 +1,120 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Sync re-reads every session file and appends whatever IDs the history
+// lacks, so a record removed from the history on purpose would come back
+// on the next cycle. Removed IDs are therefore kept in a sidecar next to
+// the history, in the ID index's 16-byte key format, and sync treats them
+// as already recorded.
+
+func removedIDsPath(historyPath string) string {
+	return strings.TrimSuffix(historyPath, ".jsonl") + ".removed"
+}
+
+// loadRemovedIDs returns the IDs removed from the history, or an empty set
+// when none were.
+func loadRemovedIDs(historyPath string) (idSet, error) {
+	return loadIDFile(removedIDsPath(historyPath))
+}
+
+func appendRemovedIDs(historyPath string, ids []string) error {
+	return appendIDFile(removedIDsPath(historyPath), ids)
+}
+
+// loadIDFile reads a sidecar of 16-byte ID keys, or returns an empty set
+// when it does not exist.
+func loadIDFile(path string) (idSet, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return make(idSet), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(data)%idKeySize != 0 {
+		return nil, fmt.Errorf("%s is corrupt: size %d is not a multiple of %d", path, len(data), idKeySize)
+	}
+	ids := make(idSet, len(data)/idKeySize)
+	for offset := 0; offset < len(data); offset += idKeySize {
+		var key idKey
+		copy(key[:], data[offset:offset+idKeySize])
+		ids[key] = struct{}{}
+	}
+	return ids, nil
+}
+
+func appendIDFile(path string, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	data := make([]byte, 0, len(ids)*idKeySize)
+	for _, id := range ids {
+		key := keyForID(id)
+		data = append(data, key[:]...)
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// removeRecords drops the records drop selects from the history and
+// remembers their IDs so sync does not restore them. The IDs are saved
+// before the rewrite: if it fails, sync skips records that are still in
+// the history anyway. It returns how many records were removed.
+func removeRecords(historyPath string, drop func(Record) bool) (int, error) {
+	var ids []string
+	dropped := make(idSet)
+	err := forEachRecord(historyPath, func(record Record) error {
+		if drop(record) {
+			ids = append(ids, record.ID)
+			dropped.add(record.ID)
+		}
+		return nil
+	})
+	if err != nil || len(ids) == 0 {
+		return 0, err
+	}
+	if err := appendRemovedIDs(historyPath, ids); err != nil {
+		return 0, err
+	}
+	removed := 0
+	err = rewriteHistory(historyPath, func(record Record) (Record, bool) {
+		if dropped.has(record.ID) {
+			removed++
+			return record, false
+		}
+		return record, true
+	})
+	return removed, err
+}
+
+// forgetRemovedIDs takes ids out of the removed set, for records that are
+// being put back into the history.
+func forgetRemovedIDs(historyPath string, ids idSet) error {
+	removed, err := loadRemovedIDs(historyPath)
+	if err != nil || len(removed) == 0 {
+		return err
+	}
+	data := make([]byte, 0, len(removed)*idKeySize)
+	for key := range removed {
+		if _, ok := ids[key]; !ok {
+			data = append(data, key[:]...)
+		}
+	}
+	if len(data) == len(removed)*idKeySize {
+		return nil
+	}
+	return writeFileAtomic(removedIDsPath(historyPath), data)
+}
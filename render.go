@@ -0,0 +1,151 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// renderTerminalMarkdown formats message text for show --render: the markdown
+// Codex writes (headings, lists, quotes, fenced code, inline code, bold,
+// links) is laid out for a terminal instead of being flattened to one
+// line. styled adds ANSI bold, underline, and color; without it the layout
+// alone carries the structure.
+func renderTerminalMarkdown(text string, styled bool) string {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	var out []string
+	fence := ""
+	blank := false
+	emit := func(line string) {
+		if strings.TrimSpace(line) == "" {
+			// Collapse runs of blank lines into one.
+			if blank || len(out) == 0 {
+				return
+			}
+			blank = true
+			out = append(out, "")
+			return
+		}
+		blank = false
+		out = append(out, line)
+	}
+
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if fence != "" {
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+				continue
+			}
+			code := strings.TrimRight(line, " \t")
+			if code == "" {
+				// Blank lines inside code are kept as they are.
+				blank = false
+				out = append(out, "")
+				continue
+			}
+			code = "    " + code
+			if styled {
+				code = ansiCyan + code + ansiReset
+			}
+			blank = false
+			out = append(out, code)
+			continue
+		}
+		if marker := fenceMarker(trimmed); marker != "" {
+			fence = marker
+			if lang := strings.TrimSpace(strings.TrimLeft(trimmed, marker[:1])); lang != "" {
+				label := "    [" + lang + "]"
+				if styled {
+					label = ansiDim + label + ansiReset
+				}
+				emit(label)
+			}
+			continue
+		}
+
+		switch {
+		case markdownHeading.MatchString(trimmed):
+			m := markdownHeading.FindStringSubmatch(trimmed)
+			title := renderInline(m[2], false)
+			if styled {
+				style := ansiBold
+				if len(m[1]) == 1 {
+					style += ansiUnderline
+				}
+				emit(style + title + ansiReset)
+			} else {
+				emit(title)
+				if len(m[1]) <= 2 {
+					underline := "="
+					if len(m[1]) == 2 {
+						underline = "-"
+					}
+					emit(strings.Repeat(underline, len([]rune(title))))
+				}
+			}
+		case markdownRule.MatchString(trimmed):
+			emit(strings.Repeat("─", 40))
+		case markdownBullet.MatchString(line):
+			m := markdownBullet.FindStringSubmatch(line)
+			emit(m[1] + "• " + renderInline(m[2], styled))
+		case strings.HasPrefix(trimmed, ">"):
+			quote := "│ " + renderInline(strings.TrimSpace(strings.TrimPrefix(trimmed, ">")), styled)
+			if styled {
+				quote = ansiDim + quote + ansiReset
+			}
+			emit(quote)
+		default:
+			emit(renderInline(strings.TrimRight(line, " \t"), styled))
+		}
+	}
+	for len(out) > 0 && out[len(out)-1] == "" {
+		out = out[:len(out)-1]
+	}
+	return strings.Join(out, "\n")
+}
+
+var (
+	markdownHeading = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*$`)
+	markdownRule    = regexp.MustCompile(`^([-*_])(\s*[-*_]){2,}$`)
+	markdownBullet  = regexp.MustCompile(`^(\s*)[-*+]\s+(.*)$`)
+	markdownCode    = regexp.MustCompile("`([^`]+)`")
+	markdownStrong  = regexp.MustCompile(`\*\*([^*]+)\*\*|__([^_]+)__`)
+	markdownLink    = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+)
+
+// fenceMarker returns the ``` or ~~~ run that opens a code fence on line,
+// or "" when the line is not a fence.
+func fenceMarker(line string) string {
+	for _, marker := range []string{"```", "~~~"} {
+		if strings.HasPrefix(line, marker) {
+			return marker
+		}
+	}
+	return ""
+}
+
+// renderInline handles inline code, bold, and links within one line. Code
+// spans are set aside first so their contents are not treated as markup.
+func renderInline(line string, styled bool) string {
+	var spans []string
+	line = markdownCode.ReplaceAllStringFunc(line, func(match string) string {
+		code := match
+		if styled {
+			code = ansiCyan + match[1:len(match)-1] + ansiReset
+		}
+		spans = append(spans, code)
+		return "\x00"
+	})
+	line = markdownLink.ReplaceAllString(line, "$1 ($2)")
+	line = markdownStrong.ReplaceAllStringFunc(line, func(match string) string {
+		inner := match[2 : len(match)-2]
+		if styled {
+			return ansiBold + inner + ansiReset
+		}
+		return inner
+	})
+	for _, code := range spans {
+		line = strings.Replace(line, "\x00", code, 1)
+	}
+	return line
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"html"
+	"html/template"
+	"regexp"
+	"strings"
+)
+
+var (
+	markdownOrdered  = regexp.MustCompile(`^\s*(\d{1,9})[.)]\s+(.*)$`)
+	markdownEmphasis = regexp.MustCompile(`(^|[^\w*])\*([^*\s][^*]*?)\*`)
+)
+
+// markdownHTML renders message text as HTML for serve: the same markdown
+// renderTerminalMarkdown understands (headings, lists, quotes, rules,
+// fenced and inline code, bold, links), plus numbered lists and *italics*.
+// Everything is escaped first, so the result is safe to insert into a page
+// as is; links are kept only for http, https, and mailto URLs.
+func markdownHTML(text string) template.HTML {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	var out strings.Builder
+	var paragraph []string
+	list := ""
+	flush := func() {
+		if len(paragraph) > 0 {
+			out.WriteString("<p>" + strings.Join(paragraph, "<br>\n") + "</p>\n")
+			paragraph = paragraph[:0]
+		}
+		if list != "" {
+			out.WriteString("</" + list + ">\n")
+			list = ""
+		}
+	}
+	openList := func(tag string) {
+		if len(paragraph) > 0 || (list != "" && list != tag) {
+			flush()
+		}
+		if list == "" {
+			out.WriteString("<" + tag + ">\n")
+			list = tag
+		}
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+		if marker := fenceMarker(trimmed); marker != "" {
+			flush()
+			language := strings.TrimSpace(strings.TrimLeft(trimmed, marker[:1]))
+			var code []string
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), marker); i++ {
+				code = append(code, lines[i])
+			}
+			out.WriteString("<pre><code")
+			if language != "" {
+				out.WriteString(` class="language-` + html.EscapeString(language) + `"`)
+			}
+			out.WriteString(">" + html.EscapeString(strings.Join(code, "\n")) + "</code></pre>\n")
+			continue
+		}
+
+		switch {
+		case trimmed == "":
+			flush()
+		case markdownHeading.MatchString(trimmed):
+			flush()
+			m := markdownHeading.FindStringSubmatch(trimmed)
+			level := string(rune('0' + len(m[1])))
+			out.WriteString("<h" + level + ">" + inlineMarkdownHTML(m[2]) + "</h" + level + ">\n")
+		case markdownRule.MatchString(trimmed):
+			flush()
+			out.WriteString("<hr>\n")
+		case markdownBullet.MatchString(line):
+			openList("ul")
+			out.WriteString("<li>" + inlineMarkdownHTML(markdownBullet.FindStringSubmatch(line)[2]) + "</li>\n")
+		case markdownOrdered.MatchString(line):
+			openList("ol")
+			out.WriteString("<li>" + inlineMarkdownHTML(markdownOrdered.FindStringSubmatch(line)[2]) + "</li>\n")
+		case strings.HasPrefix(trimmed, ">"):
+			flush()
+			out.WriteString("<blockquote>" + inlineMarkdownHTML(strings.TrimSpace(strings.TrimPrefix(trimmed, ">"))) + "</blockquote>\n")
+		default:
+			if list != "" {
+				flush()
+			}
+			paragraph = append(paragraph, inlineMarkdownHTML(strings.TrimRight(line, " \t")))
+		}
+	}
+	flush()
+	return template.HTML(out.String())
+}
+
+// inlineMarkdownHTML escapes one line and renders its code spans, links,
+// bold, and italics. As in renderInline, code spans are set aside first
+// so their contents are not treated as markup.
+func inlineMarkdownHTML(line string) string {
+	var spans []string
+	line = markdownCode.ReplaceAllStringFunc(line, func(match string) string {
+		spans = append(spans, "<code>"+html.EscapeString(match[1:len(match)-1])+"</code>")
+		return "\x00"
+	})
+	line = html.EscapeString(line)
+	line = markdownLink.ReplaceAllStringFunc(line, func(match string) string {
+		m := markdownLink.FindStringSubmatch(match)
+		if !safeLinkURL(html.UnescapeString(m[2])) {
+			return match
+		}
+		return `<a href="` + m[2] + `" rel="noopener noreferrer">` + m[1] + "</a>"
+	})
+	line = markdownStrong.ReplaceAllStringFunc(line, func(match string) string {
+		return "<strong>" + match[2:len(match)-2] + "</strong>"
+	})
+	line = markdownEmphasis.ReplaceAllString(line, "$1<em>$2</em>")
+	for _, code := range spans {
+		line = strings.Replace(line, "\x00", code, 1)
+	}
+	return line
+}
+
+// safeLinkURL reports whether a markdown link may become a live link.
+func safeLinkURL(url string) bool {
+	lower := strings.ToLower(url)
+	for _, scheme := range []string{"http://", "https://", "mailto:"} {
+		if strings.HasPrefix(lower, scheme) {
+			return true
+		}
+	}
+	return false
+}
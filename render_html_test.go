@@ -0,0 +1,31 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMarkdownHTML(t *testing.T) {
+	text := "# Plan\n\nRun `go test` and see **all** *green*.\nSecond line.\n\n- one\n- [docs](https://go.dev/doc)\n1. first\n\n> quoted <b>\n\n```go\nfmt.Println(\"<hi>\")\n```\n[bad](javascript:alert(1))"
+	got := string(markdownHTML(text))
+	want := []string{
+		"<h1>Plan</h1>",
+		"<p>Run <code>go test</code> and see <strong>all</strong> <em>green</em>.<br>\nSecond line.</p>",
+		"<ul>\n<li>one</li>\n<li><a href=\"https://go.dev/doc\" rel=\"noopener noreferrer\">docs</a></li>\n</ul>\n<ol>\n<li>first</li>\n</ol>",
+		"<blockquote>quoted &lt;b&gt;</blockquote>",
+		"<pre><code class=\"language-go\">fmt.Println(&#34;&lt;hi&gt;&#34;)</code></pre>",
+		"<p>[bad](javascript:alert(1))</p>",
+	}
+	for _, fragment := range want {
+		if !strings.Contains(got, fragment) {
+			t.Errorf("missing %q in:\n%s", fragment, got)
+		}
+	}
+}
+
+func TestInlineMarkdownHTMLKeepsCodeLiteral(t *testing.T) {
+	got := inlineMarkdownHTML("use `**not bold** <x>` here")
+	if got != "use <code>**not bold** &lt;x&gt;</code> here" {
+		t.Fatalf("unexpected output %q", got)
+	}
+}
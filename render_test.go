@@ -0,0 +1,50 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderTerminalMarkdown(t *testing.T) {
+	text := strings.Join([]string{
+		"## Fix",
+		"",
+		"",
+		"The **race** is in `run()`, see [docs](https://example.com).",
+		"- first",
+		"  * nested",
+		"> quoted",
+		"```go",
+		"func run() {",
+		"",
+		"}",
+		"```",
+		"---",
+		"",
+	}, "\n")
+	want := strings.Join([]string{
+		"Fix",
+		"---",
+		"",
+		"The race is in `run()`, see docs (https://example.com).",
+		"• first",
+		"  • nested",
+		"│ quoted",
+		"    [go]",
+		"    func run() {",
+		"",
+		"    }",
+		strings.Repeat("─", 40),
+	}, "\n")
+	if got := renderTerminalMarkdown(text, false); got != want {
+		t.Fatalf("unexpected rendering:\n%s\nwant:\n%s", got, want)
+	}
+
+	styled := renderTerminalMarkdown("# Title\nuse `a**b**` and **this**", true)
+	if !strings.Contains(styled, ansiBold+ansiUnderline+"Title"+ansiReset) {
+		t.Fatalf("heading not styled: %q", styled)
+	}
+	if !strings.Contains(styled, ansiCyan+"a**b**"+ansiReset+" and "+ansiBold+"this"+ansiReset) {
+		t.Fatalf("inline markup not styled: %q", styled)
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+type RepairResult struct {
+	Lines       int    `json:"lines"`
+	Kept        int    `json:"kept"`
+	Rejected    int    `json:"rejected"`
+	Blank       int    `json:"blank"`
+	IDsRestored int    `json:"ids_restored"`
+	RejectsPath string `json:"rejects_path,omitempty"`
+}
+
+func rejectsPath(historyPath string) string {
+	return strings.TrimSuffix(historyPath, ".jsonl") + ".rejects"
+}
+
+// repairHistory moves unparseable lines into the rejects file, fills in
+// missing record IDs, drops blank lines, and atomically replaces the history.
+// Rejects are appended, so repeated repairs never lose earlier quarantines.
+func repairHistory(path string, dryRun bool) (RepairResult, error) {
+	if err := checkWritableHistory(path); err != nil {
+		return RepairResult{}, err
+	}
+	unlock, err := lockHistory(path)
+	if err != nil {
+		return RepairResult{}, err
+	}
+	defer unlock()
+
+	file, err := os.Open(path)
+	if err != nil {
+		return RepairResult{}, err
+	}
+	defer file.Close()
+
+	var result RepairResult
+	var rejects bytes.Buffer
+	var records []Record
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), scannerMaxTokenSize)
+	for scanner.Scan() {
+		result.Lines++
+		line := scanner.Bytes()
+		if len(bytes.TrimSpace(line)) == 0 {
+			result.Blank++
+			continue
+		}
+		var record Record
+		if err := json.Unmarshal(line, &record); err != nil {
+			result.Rejected++
+			rejects.Write(line)
+			rejects.WriteByte('\n')
+			continue
+		}
+		if strings.TrimSpace(record.ID) == "" {
+			record.ID = recordContentID(record)
+			result.IDsRestored++
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return RepairResult{}, err
+	}
+	result.Kept = len(records)
+
+	if dryRun || (result.Rejected == 0 && result.Blank == 0 && result.IDsRestored == 0) {
+		return result, nil
+	}
+
+	if result.Rejected > 0 {
+		result.RejectsPath = rejectsPath(path)
+		if err := appendRejects(result.RejectsPath, rejects.Bytes()); err != nil {
+			return RepairResult{}, err
+		}
+	}
+
+	err = replaceHistoryFile(path, func(writer *bufio.Writer) error {
+		encoder := json.NewEncoder(writer)
+		encoder.SetEscapeHTML(false)
+		for _, record := range records {
+			if err := encoder.Encode(record); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return RepairResult{}, err
+	}
+	return result, nil
+}
+
+func appendRejects(path string, data []byte) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func runRepair(args []string) error {
+	fs := flag.NewFlagSet("repair", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+
+	inputPath := fs.String("in", defaultOutputFile(), "History JSONL path to repair in place")
+	dryRun := fs.Bool("dry-run", false, "Report what would change without writing")
+	jsonOut := fs.Bool("json", false, "Print as JSON")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	result, err := repairHistory(*inputPath, *dryRun)
+	if err != nil {
+		return err
+	}
+
+	if *jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetEscapeHTML(false)
+		return enc.Encode(result)
+	}
+
+	fmt.Printf("lines=%d kept=%d rejected=%d blank=%d ids_restored=%d\n",
+		result.Lines, result.Kept, result.Rejected, result.Blank, result.IDsRestored)
+	if result.RejectsPath != "" {
+		fmt.Printf("quarantined %d lines in %s\n", result.Rejected, result.RejectsPath)
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRepairHistory(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "history.jsonl")
+	content := strings.Join([]string{
+		`{"session_id":"s","timestamp":"2026-02-17T09:00:00Z","role":"user","text":"no id"}`,
+		``,
+		`{"id":"x","session_id":"s","timestamp":"2026-02-17T09:00:01Z","role":"assistant","text":"ok"}`,
+		`{"id":"y","session_id":"s","timest`,
+	}, "\n")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := repairHistory(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Kept != 2 || result.Rejected != 1 || result.Blank != 1 || result.IDsRestored != 1 {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+
+	rejects, err := os.ReadFile(filepath.Join(dir, "history.rejects"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(rejects) != `{"id":"y","session_id":"s","timest`+"\n" {
+		t.Fatalf("unexpected rejects: %q", rejects)
+	}
+
+	records, err := loadRecords(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || records[0].ID != makeRecordID("s", "2026-02-17T09:00:00Z", "user", "no id") {
+		t.Fatalf("unexpected repaired records: %+v", records)
+	}
+
+	// A clean history is left untouched.
+	again, err := repairHistory(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Rejected != 0 || again.IDsRestored != 0 || again.RejectsPath != "" {
+		t.Fatalf("expected no-op repair, got %+v", again)
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+func runReplay(args []string) error {
+	fs := flag.NewFlagSet("replay", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+
+	inputPath := fs.String("in", defaultOutputFile(), "Input JSONL path")
+	sessionID := fs.String("session", "", "Session to replay (ID or unique prefix)")
+	delay := fs.Duration("delay", 0, "Pause between records, e.g. 1s, for a paced playback")
+	color := fs.String("color", "auto", "Style markdown and color roles: auto, always, or never")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if strings.TrimSpace(*sessionID) == "" {
+		return errors.New("--session is required")
+	}
+	if *delay < 0 {
+		return errors.New("--delay must be >= 0")
+	}
+	colored, err := colorEnabled(*color)
+	if err != nil {
+		return err
+	}
+
+	records, err := sessionRecords(*inputPath, strings.TrimSpace(*sessionID))
+	if err != nil {
+		return err
+	}
+	printReplay(os.Stdout, records, colored, func() { time.Sleep(*delay) })
+	return nil
+}
+
+// errSessionNotFound is returned by sessionRecords when no session matches.
+var errSessionNotFound = errors.New("session not found")
+
+// sessionRecords returns the records of the session whose ID is id or
+// starts with it, in chronological order. Only sessions matching the
+// prefix are held in memory.
+func sessionRecords(path, id string) ([]Record, error) {
+	sessions := make(map[string][]Record)
+	err := forEachRecord(path, func(record Record) error {
+		if strings.HasPrefix(record.SessionID, id) {
+			sessions[record.SessionID] = append(sessions[record.SessionID], record)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	records, ok := sessions[id]
+	if !ok {
+		switch len(sessions) {
+		case 0:
+			return nil, fmt.Errorf("%w: %q", errSessionNotFound, id)
+		case 1:
+			for _, found := range sessions {
+				records = found
+			}
+		default:
+			return nil, fmt.Errorf("session prefix %q is %w", id, errAmbiguousPrefix)
+		}
+	}
+	sortRecordsChronological(records)
+	return records, nil
+}
+
+// printReplay prints a session header and then every record in full with
+// its markdown rendered, calling pause between records.
+func printReplay(w io.Writer, records []Record, colored bool, pause func()) {
+	if len(records) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "session %s (%s to %s) %s\n", records[0].SessionID, records[0].Timestamp, records[len(records)-1].Timestamp, sessionTitle(records, 80))
+	for i, record := range records {
+		if i > 0 {
+			pause()
+		}
+		fmt.Fprintf(w, "\n%s\n", showRendered(record, true, colored))
+	}
+}
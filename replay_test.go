@@ -0,0 +1,58 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSessionRecords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	records := []Record{
+		{ID: "2", SessionID: "abc-1", Timestamp: "2026-02-17T10:05:00Z", Role: "assistant", Text: "reply"},
+		{ID: "1", SessionID: "abc-1", Timestamp: "2026-02-17T10:00:00Z", Role: "user", Text: "first"},
+		{ID: "3", SessionID: "abd-2", Timestamp: "2026-02-17T11:00:00Z", Role: "user", Text: "other"},
+		{ID: "4", SessionID: "ab", Timestamp: "2026-02-17T12:00:00Z", Role: "user", Text: "exact"},
+	}
+	if err := appendRecords(path, records, false); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := sessionRecords(path, "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].ID != "1" || got[1].ID != "2" {
+		t.Fatalf("unexpected records: %+v", got)
+	}
+	// An exact ID wins over the sessions it is a prefix of.
+	if got, err := sessionRecords(path, "ab"); err != nil || len(got) != 1 || got[0].ID != "4" {
+		t.Fatalf("exact ID: got %+v, %v", got, err)
+	}
+	if _, err := sessionRecords(path, "a"); err == nil || !strings.Contains(err.Error(), "ambiguous") {
+		t.Fatalf("expected an ambiguous prefix error, got %v", err)
+	}
+	if _, err := sessionRecords(path, "zzz"); err == nil {
+		t.Fatal("expected an error for a missing session")
+	}
+}
+
+func TestPrintReplay(t *testing.T) {
+	records := []Record{
+		{SessionID: "s1", Timestamp: "2026-02-17T10:00:00Z", Role: "user", Text: "Explain the **retry** loop"},
+		{SessionID: "s1", Timestamp: "2026-02-17T10:01:00Z", Role: "assistant", Text: "# Retry\n\n- backs off\n- gives up after 5 tries"},
+	}
+	var out strings.Builder
+	pauses := 0
+	printReplay(&out, records, false, func() { pauses++ })
+
+	want := "session s1 (2026-02-17T10:00:00Z to 2026-02-17T10:01:00Z) Explain the **retry** loop\n" +
+		"\n2026-02-17T10:00:00Z [s1] user:\n  Explain the retry loop\n" +
+		"\n2026-02-17T10:01:00Z [s1] assistant:\n  Retry\n  =====\n\n  • backs off\n  • gives up after 5 tries\n"
+	if out.String() != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", out.String(), want)
+	}
+	if pauses != 1 {
+		t.Fatalf("expected 1 pause between 2 records, got %d", pauses)
+	}
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// reportTop is how many rows each report section lists.
+const reportTop = 5
+
+// reportStopwords are left out of the top terms: English filler and words
+// that appear in nearly every coding prompt.
+var reportStopwords = func() map[string]bool {
+	words := make(map[string]bool)
+	for _, word := range strings.Fields(`the and for that this with from have are was were not but you your
+		can could would should will what when where which who why how all any also into just like make more
+		need now only our out some than then them there these they use using want way its does did done get
+		got has had one two see run same been being each other over such please let lets about after before
+		here very file files code add change fix`) {
+		words[word] = true
+	}
+	return words
+}()
+
+// reportCount is a name with a count, for the report's ranked lists.
+type reportCount struct {
+	Name  string
+	Count int
+}
+
+// reportSession is a session in the report's longest conversations.
+type reportSession struct {
+	ID       string
+	Title    string
+	Messages int
+	Duration time.Duration
+}
+
+// periodReport holds everything the report command prints.
+type periodReport struct {
+	From, To        time.Time
+	Messages        int
+	User, Assistant int
+	SessionsActive  int
+	SessionsStarted int
+	Tokens          int64
+	Projects        []reportCount
+	Longest         []reportSession
+	Errors          int
+	ErrorKinds      []reportCount
+	FailedCommands  int
+	Terms           []reportCount
+}
+
+func runReport(args []string) error {
+	fs := flag.NewFlagSet("report", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+
+	inputPath := fs.String("in", defaultOutputFile(), "Input JSONL path")
+	outPath := fs.String("out", "", "Output file path (default: stdout)")
+	period := fs.String("period", "week", "Period to report on, ending now or at --to: day, week, month, or N days (e.g. 14d)")
+	to := fs.String("to", "", "End the period at this RFC3339 timestamp instead of now")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	length, err := comparisonLength(*period)
+	if err != nil {
+		return fmt.Errorf("--period: %w", err)
+	}
+	end, err := parseBoundTime(*to, "--to")
+	if err != nil {
+		return err
+	}
+	if end.IsZero() {
+		end = time.Now().UTC()
+	}
+
+	records, err := loadRecords(*inputPath)
+	if err != nil {
+		return err
+	}
+	sortRecordsChronological(records)
+	info, err := loadSessionInfo(sessionInfoPath(*inputPath))
+	if err != nil {
+		return err
+	}
+	report := buildPeriodReport(records, info, end.Add(-length), end)
+	return writeOutput(*outPath, []byte(renderPeriodReport(report)))
+}
+
+// buildPeriodReport summarizes the records in [from, to]. records must be
+// chronological; records before from only decide whether a session started
+// in the period.
+func buildPeriodReport(records []Record, info map[string]SessionInfo, from, to time.Time) periodReport {
+	report := periodReport{From: from, To: to}
+	inPeriod := func(record Record) bool {
+		t, ok := parseRecordTime(record.Timestamp)
+		return ok && !t.Before(from) && !t.After(to)
+	}
+
+	projects := make(map[string]map[string]struct{})
+	projectMessages := make(map[string]int)
+	errorKinds := make(map[string]int)
+	terms := make(map[string]int)
+	active := make(map[string]struct{})
+
+	for _, session := range groupSessions(records) {
+		var window []Record
+		for _, record := range session {
+			if inPeriod(record) {
+				window = append(window, record)
+			}
+		}
+		if len(window) == 0 {
+			continue
+		}
+		sessionID := session[0].SessionID
+		active[sessionID] = struct{}{}
+		if inPeriod(session[0]) {
+			report.SessionsStarted++
+		}
+
+		if project := sessionProject(session); project != "" {
+			if projects[project] == nil {
+				projects[project] = make(map[string]struct{})
+			}
+			projects[project][sessionID] = struct{}{}
+			projectMessages[project] += len(window)
+		}
+
+		messages := 0
+		for _, record := range window {
+			report.Messages++
+			switch record.Role {
+			case "user":
+				report.User++
+				messages++
+				for _, word := range searchWords(record.Text) {
+					if utf8.RuneCountInString(word) >= 3 && !reportStopwords[word] && strings.IndexFunc(word, isASCIIDigit) < 0 {
+						terms[word]++
+					}
+				}
+			case "assistant":
+				report.Assistant++
+				messages++
+			case "error":
+				report.Errors++
+				errorKinds[firstNonEmpty(record.Meta["event"], "error")]++
+			case "tool":
+				if record.Tool != nil && record.Tool.ExitCode != nil && *record.Tool.ExitCode != 0 {
+					report.FailedCommands++
+				}
+			}
+		}
+		first, _ := parseRecordTime(window[0].Timestamp)
+		last, _ := parseRecordTime(window[len(window)-1].Timestamp)
+		report.Longest = append(report.Longest, reportSession{
+			ID:       sessionID,
+			Title:    sessionTitle(session, 80),
+			Messages: messages,
+			Duration: last.Sub(first),
+		})
+	}
+
+	report.SessionsActive = len(active)
+	if usage := sumUsage(info, active); usage != nil {
+		report.Tokens = usage.TotalTokens
+	}
+	sort.SliceStable(report.Longest, func(i, j int) bool { return report.Longest[i].Messages > report.Longest[j].Messages })
+	if len(report.Longest) > reportTop {
+		report.Longest = report.Longest[:reportTop]
+	}
+	for project, sessions := range projects {
+		report.Projects = append(report.Projects, reportCount{Name: project, Count: len(sessions)})
+	}
+	sort.Slice(report.Projects, func(i, j int) bool {
+		a, b := report.Projects[i], report.Projects[j]
+		if projectMessages[a.Name] != projectMessages[b.Name] {
+			return projectMessages[a.Name] > projectMessages[b.Name]
+		}
+		return a.Name < b.Name
+	})
+	if len(report.Projects) > reportTop {
+		report.Projects = report.Projects[:reportTop]
+	}
+	report.ErrorKinds = topCounts(errorKinds, 0)
+	report.Terms = topCounts(terms, 2*reportTop)
+	return report
+}
+
+func isASCIIDigit(r rune) bool { return r >= '0' && r <= '9' }
+
+// topCounts sorts counts largest first, by name on ties, keeping at most
+// limit entries when limit > 0.
+func topCounts(counts map[string]int, limit int) []reportCount {
+	result := make([]reportCount, 0, len(counts))
+	for name, count := range counts {
+		result = append(result, reportCount{Name: name, Count: count})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].Name < result[j].Name
+	})
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+	return result
+}
+
+// renderPeriodReport formats the report as markdown for a journal.
+func renderPeriodReport(report periodReport) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Codex activity %s to %s\n\n", report.From.Format("2006-01-02"), report.To.Format("2006-01-02"))
+	fmt.Fprintf(&b, "- Sessions: %d active, %d started\n", report.SessionsActive, report.SessionsStarted)
+	fmt.Fprintf(&b, "- Messages: %d (%d from you, %d from Codex)\n", report.Messages, report.User, report.Assistant)
+	if report.Tokens > 0 {
+		fmt.Fprintf(&b, "- Tokens: %d\n", report.Tokens)
+	}
+
+	if len(report.Projects) > 0 {
+		b.WriteString("\n## Most active projects\n\n")
+		for _, project := range report.Projects {
+			fmt.Fprintf(&b, "- %s (%d sessions)\n", project.Name, project.Count)
+		}
+	}
+
+	if len(report.Longest) > 0 {
+		b.WriteString("\n## Longest conversations\n\n")
+		for _, session := range report.Longest {
+			title := session.Title
+			if title == "" {
+				title = "(no user message)"
+			}
+			fmt.Fprintf(&b, "- `%s` %s (%d messages, %s)\n", shortSessionID(session.ID), title, session.Messages, session.Duration.Round(time.Minute))
+		}
+	}
+
+	b.WriteString("\n## Errors\n\n")
+	if report.Errors == 0 && report.FailedCommands == 0 {
+		b.WriteString("None.\n")
+	} else {
+		fmt.Fprintf(&b, "- %d error events", report.Errors)
+		if len(report.ErrorKinds) > 0 {
+			kinds := make([]string, len(report.ErrorKinds))
+			for i, kind := range report.ErrorKinds {
+				kinds[i] = fmt.Sprintf("%s %d", kind.Name, kind.Count)
+			}
+			fmt.Fprintf(&b, " (%s)", strings.Join(kinds, ", "))
+		}
+		fmt.Fprintf(&b, "\n- %d failed commands\n", report.FailedCommands)
+	}
+
+	if len(report.Terms) > 0 {
+		b.WriteString("\n## Top terms\n\n")
+		terms := make([]string, len(report.Terms))
+		for i, term := range report.Terms {
+			terms[i] = fmt.Sprintf("%s (%d)", term.Name, term.Count)
+		}
+		b.WriteString(strings.Join(terms, ", ") + "\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuildPeriodReport(t *testing.T) {
+	exit := 1
+	records := []Record{
+		{SessionID: "old", Timestamp: "2026-02-01T10:00:00Z", Role: "user", Text: "start the scheduler work", Meta: map[string]string{"cwd": "/src/scheduler"}},
+		{SessionID: "old", Timestamp: "2026-02-16T10:00:00Z", Role: "user", Text: "scheduler deadlock again"},
+		{SessionID: "old", Timestamp: "2026-02-16T10:30:00Z", Role: "assistant", Text: "fixed"},
+		{SessionID: "new", Timestamp: "2026-02-15T09:00:00Z", Role: "user", Text: "the scheduler test is flaky", Meta: map[string]string{"cwd": "/src/scheduler"}},
+		{SessionID: "new", Timestamp: "2026-02-15T09:05:00Z", Role: "tool", Text: "go test", Tool: &ToolCall{Name: "shell", ExitCode: &exit}},
+		{SessionID: "new", Timestamp: "2026-02-15T09:06:00Z", Role: "error", Meta: map[string]string{"event": "stream_error"}},
+		{SessionID: "docs", Timestamp: "2026-02-14T09:00:00Z", Role: "user", Text: "docs please", Meta: map[string]string{"project": "website"}},
+	}
+	info := map[string]SessionInfo{"new": {Usage: &TokenUsage{TotalTokens: 500}}}
+	from := time.Date(2026, 2, 10, 12, 0, 0, 0, time.UTC)
+	to := time.Date(2026, 2, 17, 12, 0, 0, 0, time.UTC)
+	report := buildPeriodReport(records, info, from, to)
+
+	if report.SessionsActive != 3 || report.SessionsStarted != 2 || report.Messages != 6 || report.User != 3 || report.Tokens != 500 {
+		t.Fatalf("unexpected totals: %+v", report)
+	}
+	if len(report.Projects) != 2 || report.Projects[0].Name != "scheduler" || report.Projects[0].Count != 2 {
+		t.Fatalf("unexpected projects: %+v", report.Projects)
+	}
+	if report.Longest[0].ID != "old" || report.Longest[0].Messages != 2 || report.Longest[0].Duration != 30*time.Minute {
+		t.Fatalf("unexpected longest: %+v", report.Longest)
+	}
+	if report.Errors != 1 || report.FailedCommands != 1 || report.ErrorKinds[0].Name != "stream_error" {
+		t.Fatalf("unexpected errors: %+v", report)
+	}
+	if report.Terms[0] != (reportCount{Name: "scheduler", Count: 2}) {
+		t.Fatalf("unexpected terms: %+v", report.Terms)
+	}
+
+	markdown := renderPeriodReport(report)
+	for _, want := range []string{
+		"# Codex activity 2026-02-10 to 2026-02-17\n",
+		"- Sessions: 3 active, 2 started\n",
+		"## Most active projects\n\n- scheduler (2 sessions)\n",
+		"- `old` start the scheduler work (2 messages, 30m0s)\n",
+		"- 1 error events (stream_error 1)\n- 1 failed commands\n",
+		"scheduler (2)",
+	} {
+		if !strings.Contains(markdown, want) {
+			t.Fatalf("report is missing %q:\n%s", want, markdown)
+		}
+	}
+}
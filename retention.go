@@ -0,0 +1,263 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultRetentionEvery is how often watch enforces a retention policy when
+// none is configured.
+const defaultRetentionEvery = time.Hour
+
+// RetentionConfig bounds the history watch keeps. MaxAge is a number of
+// days ("90d") or a Go duration; MaxSize is a byte count with an optional
+// K, M, or G suffix ("200M"); Every is how often watch checks.
+type RetentionConfig struct {
+	MaxAge  string `json:"max_age,omitempty"`
+	MaxSize string `json:"max_size,omitempty"`
+	Every   string `json:"every,omitempty"`
+}
+
+// retentionPolicy is a parsed RetentionConfig. Zero limits are off.
+type retentionPolicy struct {
+	maxAge   time.Duration
+	maxBytes int64
+	every    time.Duration
+}
+
+func (p retentionPolicy) enabled() bool {
+	return p.maxAge > 0 || p.maxBytes > 0
+}
+
+func parseRetentionPolicy(maxAge, maxSize, every string) (retentionPolicy, error) {
+	policy := retentionPolicy{every: defaultRetentionEvery}
+	var err error
+	if strings.TrimSpace(maxAge) != "" {
+		if policy.maxAge, err = parseRetentionAge(maxAge); err != nil {
+			return retentionPolicy{}, err
+		}
+	}
+	if strings.TrimSpace(maxSize) != "" {
+		if policy.maxBytes, err = parseByteSize(maxSize); err != nil {
+			return retentionPolicy{}, err
+		}
+	}
+	if strings.TrimSpace(every) != "" {
+		policy.every, err = time.ParseDuration(strings.TrimSpace(every))
+		if err != nil || policy.every <= 0 {
+			return retentionPolicy{}, fmt.Errorf("invalid retention interval %q", every)
+		}
+	}
+	return policy, nil
+}
+
+// parseRetentionAge accepts a number of days such as 90d, or any Go
+// duration such as 36h.
+func parseRetentionAge(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n > 0 {
+			return time.Duration(n) * 24 * time.Hour, nil
+		}
+	}
+	age, err := time.ParseDuration(value)
+	if err != nil || age <= 0 {
+		return 0, fmt.Errorf("invalid retention age %q (use days like 90d or a duration like 36h)", value)
+	}
+	return age, nil
+}
+
+// parseByteSize reads a byte count with an optional K, M, or G suffix
+// (powers of 1024), with or without a trailing B or iB: 500M, 1.5GB, 2GiB.
+func parseByteSize(value string) (int64, error) {
+	trimmed := strings.ToUpper(strings.TrimSpace(value))
+	number := strings.TrimSuffix(strings.TrimSuffix(trimmed, "B"), "I")
+	multiplier := int64(1)
+	for i, unit := range []string{"K", "M", "G"} {
+		if strings.HasSuffix(number, unit) {
+			number = strings.TrimSuffix(number, unit)
+			multiplier = int64(1) << (10 * (i + 1))
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(strings.TrimSpace(number), 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid size %q (use bytes or a K, M, or G suffix like 200M)", value)
+	}
+	return int64(n * float64(multiplier)), nil
+}
+
+// Remembering every record retention removes in the removed set would grow
+// that file without bound. Retention instead saves the time before which
+// it has removed everything, and sync skips records older than that as it
+// skips those before --from.
+
+type retentionState struct {
+	Since string `json:"since"`
+}
+
+func retentionStatePath(historyPath string) string {
+	return strings.TrimSuffix(historyPath, ".jsonl") + ".retention.json"
+}
+
+// loadRetentionSince returns the time before which retention has emptied
+// the history, or the zero time when it never removed anything.
+func loadRetentionSince(historyPath string) (time.Time, error) {
+	path := retentionStatePath(historyPath)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	var state retentionState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return time.Time{}, fmt.Errorf("invalid retention state %s: %w", path, err)
+	}
+	since, err := time.Parse(time.RFC3339Nano, state.Since)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid retention state %s: %w", path, err)
+	}
+	return since, nil
+}
+
+// saveRetentionSince records since unless an earlier run already saved a
+// later time.
+func saveRetentionSince(historyPath string, since time.Time) error {
+	previous, err := loadRetentionSince(historyPath)
+	if err != nil {
+		return err
+	}
+	if !since.After(previous) {
+		return nil
+	}
+	data, err := json.MarshalIndent(retentionState{Since: since.UTC().Format(time.RFC3339Nano)}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(retentionStatePath(historyPath), append(data, '\n'))
+}
+
+// enforceRetention removes records older than the policy's age limit and
+// then, while the history is still over its size limit, the oldest records
+// that remain. Records whose timestamp does not parse are never removed
+// for age. It returns how many records were removed.
+func enforceRetention(historyPath string, policy retentionPolicy, now time.Time) (int, error) {
+	info, err := os.Stat(historyPath)
+	if errors.Is(err, os.ErrNotExist) || !policy.enabled() {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if err := checkWritableHistory(historyPath); err != nil {
+		return 0, err
+	}
+
+	var cutoff time.Time
+	if policy.maxAge > 0 {
+		cutoff = now.Add(-policy.maxAge)
+	}
+	tooOld := func(record Record) bool {
+		t, ok := parseRecordTime(record.Timestamp)
+		return !cutoff.IsZero() && ok && t.Before(cutoff)
+	}
+
+	oversized := make(idSet)
+	if policy.maxBytes > 0 && info.Size() > policy.maxBytes {
+		// Line sizes as rewriteHistory will write them, to pick the oldest
+		// records whose removal brings the file under the limit. Only the
+		// ID and timestamp are kept per record; removeRecords streams the
+		// history again to drop the chosen IDs.
+		type sizedRecord struct {
+			id        string
+			timestamp string
+			size      int64
+		}
+		var remaining []sizedRecord
+		var line bytes.Buffer
+		encoder := json.NewEncoder(&line)
+		encoder.SetEscapeHTML(false)
+		excess := info.Size() - policy.maxBytes
+		err := forEachRecord(historyPath, func(record Record) error {
+			line.Reset()
+			if err := encoder.Encode(record); err != nil {
+				return err
+			}
+			size := int64(line.Len())
+			if tooOld(record) {
+				excess -= size
+			} else {
+				remaining = append(remaining, sizedRecord{id: record.ID, timestamp: record.Timestamp, size: size})
+			}
+			return nil
+		})
+		if err != nil {
+			return 0, err
+		}
+		sort.SliceStable(remaining, func(i, j int) bool {
+			return compareTimestamp(remaining[i].timestamp, remaining[j].timestamp) < 0
+		})
+		for _, item := range remaining {
+			if excess <= 0 {
+				break
+			}
+			oversized.add(item.id)
+			excess -= item.size
+		}
+	}
+
+	drop := func(record Record) bool {
+		return tooOld(record) || oversized.has(record.ID)
+	}
+	// since ends up just after the newest record removed, so sync skips
+	// exactly what retention took. A record removed for size whose
+	// timestamp does not parse can only be remembered by ID.
+	since := cutoff
+	var unparsed []string
+	count := 0
+	err = forEachRecord(historyPath, func(record Record) error {
+		if !drop(record) {
+			return nil
+		}
+		count++
+		if t, ok := parseRecordTime(record.Timestamp); ok {
+			if next := t.Add(time.Nanosecond); next.After(since) {
+				since = next
+			}
+		} else {
+			unparsed = append(unparsed, record.ID)
+		}
+		return nil
+	})
+	if err != nil || count == 0 {
+		return 0, err
+	}
+	// Saved before the rewrite, as removeRecords saves IDs: if it fails,
+	// sync only skips records the history still holds.
+	if !since.IsZero() {
+		if err := saveRetentionSince(historyPath, since); err != nil {
+			return 0, err
+		}
+	}
+	if err := appendRemovedIDs(historyPath, unparsed); err != nil {
+		return 0, err
+	}
+	removed := 0
+	err = rewriteHistory(historyPath, func(record Record) (Record, bool) {
+		if drop(record) {
+			removed++
+			return record, false
+		}
+		return record, true
+	})
+	return removed, err
+}
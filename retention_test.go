@@ -0,0 +1,145 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseRetentionPolicy(t *testing.T) {
+	policy, err := parseRetentionPolicy("90d", "1.5M", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if policy.maxAge != 90*24*time.Hour || policy.maxBytes != 1536*1024 || policy.every != defaultRetentionEvery || !policy.enabled() {
+		t.Fatalf("unexpected policy: %+v", policy)
+	}
+	for _, size := range []string{"200", "200B", "2K", "2KB", "2KiB", "1g"} {
+		if _, err := parseByteSize(size); err != nil {
+			t.Errorf("parseByteSize(%q): %v", size, err)
+		}
+	}
+	if n, _ := parseByteSize("2GiB"); n != 2<<30 {
+		t.Fatalf("got %d", n)
+	}
+	for _, bad := range [][3]string{{"ninety", "", ""}, {"", "lots", ""}, {"", "-5M", ""}, {"30d", "", "0s"}} {
+		if _, err := parseRetentionPolicy(bad[0], bad[1], bad[2]); err == nil {
+			t.Errorf("expected an error for %q", bad)
+		}
+	}
+	if policy, _ := parseRetentionPolicy("", "", "5m"); policy.enabled() {
+		t.Fatal("expected no limits")
+	}
+}
+
+func TestEnforceRetention(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	now := time.Date(2026, 2, 17, 12, 0, 0, 0, time.UTC)
+	var records []Record
+	for day := 10; day >= 1; day-- {
+		records = append(records, Record{
+			ID:        fmt.Sprintf("%032d", day),
+			SessionID: "s1",
+			Timestamp: now.AddDate(0, 0, -day).Format(time.RFC3339),
+			Role:      "user",
+			Text:      "same size text",
+		})
+	}
+	if err := appendRecords(path, records, false); err != nil {
+		t.Fatal(err)
+	}
+
+	removed, err := enforceRetention(path, retentionPolicy{maxAge: 7 * 24 * time.Hour}, now)
+	if err != nil || removed != 3 {
+		t.Fatalf("age: removed %d, %v", removed, err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Each line is the same size; keep room for four of the seven left.
+	lineSize := info.Size() / 7
+	removed, err = enforceRetention(path, retentionPolicy{maxBytes: 4 * lineSize}, now)
+	if err != nil || removed != 3 {
+		t.Fatalf("size: removed %d, %v", removed, err)
+	}
+	left, err := loadRecords(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(left) != 4 || left[0].ID != fmt.Sprintf("%032d", 4) {
+		t.Fatalf("expected the four newest records, got %+v", left)
+	}
+
+	gone, err := loadRemovedIDs(path)
+	if err != nil || len(gone) != 0 {
+		t.Fatalf("retention should not grow the removed set: %d IDs, %v", len(gone), err)
+	}
+	since, err := loadRetentionSince(path)
+	if want := now.AddDate(0, 0, -5).Add(time.Nanosecond); err != nil || !since.Equal(want) {
+		t.Fatalf("retention since = %v, %v; want %v", since, err, want)
+	}
+}
+
+func TestSyncSkipsRecordsRetentionRemoved(t *testing.T) {
+	root := t.TempDir()
+	sessionsRoot := filepath.Join(root, "sessions")
+	start := time.Date(2026, 2, 15, 9, 0, 0, 0, time.UTC)
+	if _, err := generateDemo(DemoOptions{OutDir: sessionsRoot, Days: 3, SessionsPerDay: 1, Start: start, Seed: 5}); err != nil {
+		t.Fatal(err)
+	}
+	opts := SyncOptions{SessionsDir: sessionsRoot, OutputPath: filepath.Join(root, "history.jsonl")}
+	if _, err := syncOnce(opts); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2026, 2, 17, 12, 0, 0, 0, time.UTC)
+	removed, err := enforceRetention(opts.OutputPath, retentionPolicy{maxAge: 36 * time.Hour}, now)
+	if err != nil || removed == 0 {
+		t.Fatalf("removed %d, %v", removed, err)
+	}
+	again, err := syncOnce(opts)
+	if err != nil || again.Written != 0 {
+		t.Fatalf("sync restored %d records retention removed, %v", again.Written, err)
+	}
+	if _, err := os.Stat(removedIDsPath(opts.OutputPath)); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("retention should not write the removed set: %v", err)
+	}
+}
+
+func TestEnforceRetentionSizesLinesAsWritten(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	now := time.Date(2026, 2, 17, 12, 0, 0, 0, time.UTC)
+	var records []Record
+	for day := 6; day >= 1; day-- {
+		records = append(records, Record{
+			ID:        fmt.Sprintf("%032d", day),
+			SessionID: "s1",
+			Timestamp: now.AddDate(0, 0, -day).Format(time.RFC3339),
+			Role:      "user",
+			// The history is written without HTML escaping, so these are
+			// one byte each on disk, not six.
+			Text: strings.Repeat("<&>", 40),
+		})
+	}
+	if err := appendRecords(path, records, false); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lineSize := info.Size() / 6
+	removed, err := enforceRetention(path, retentionPolicy{maxBytes: 2 * lineSize}, now)
+	if err != nil || removed != 4 {
+		t.Fatalf("removed %d, %v", removed, err)
+	}
+	if info, err := os.Stat(path); err != nil || info.Size() > 2*lineSize {
+		t.Fatalf("history still over the limit: %v %v", info.Size(), err)
+	}
+}
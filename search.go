@@ -0,0 +1,351 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// ANSI sequences for terminal output.
+const (
+	ansiHighlight = "\x1b[1;31m"
+	ansiBold      = "\x1b[1m"
+	ansiUnderline = "\x1b[4m"
+	ansiCyan      = "\x1b[36m"
+	ansiDim       = "\x1b[2m"
+	ansiReset     = "\x1b[0m"
+)
+
+// colorEnabled resolves --color auto|always|never. auto colors only when
+// stdout is a terminal and NO_COLOR is unset.
+func colorEnabled(mode string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(mode)) {
+	case "", "auto":
+		if os.Getenv("NO_COLOR") != "" {
+			return false, nil
+		}
+		info, err := os.Stdout.Stat()
+		return err == nil && info.Mode()&os.ModeCharDevice != 0, nil
+	case "always":
+		return true, nil
+	case "never":
+		return false, nil
+	}
+	return false, fmt.Errorf("unsupported --color %q (use auto, always, or never)", mode)
+}
+
+// highlightMatches wraps every match of pattern in text with ANSI bold red.
+func highlightMatches(text string, pattern *regexp.Regexp) string {
+	return pattern.ReplaceAllStringFunc(text, func(match string) string {
+		return ansiHighlight + match + ansiReset
+	})
+}
+
+// matchingLines returns the lines of text that contain a match, or the
+// first line when the match spans lines.
+func matchingLines(text string, pattern *regexp.Regexp) []string {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	var out []string
+	for _, line := range lines {
+		if pattern.MatchString(line) {
+			out = append(out, line)
+		}
+	}
+	if len(out) == 0 {
+		out = lines[:1]
+	}
+	return out
+}
+
+// sessionMatchCount is one line of search --count output.
+type sessionMatchCount struct {
+	SessionID string `json:"session_id"`
+	Records   int    `json:"records"`
+	Matches   int    `json:"matches"`
+}
+
+// parseWithQuery parses flags that may come before or after the query
+// argument and returns the query.
+func parseWithQuery(fs *flag.FlagSet, args []string) (string, error) {
+	if err := fs.Parse(args); err != nil {
+		return "", err
+	}
+	if fs.NArg() == 0 {
+		return "", errors.New("missing search query")
+	}
+	query := fs.Arg(0)
+	if err := fs.Parse(fs.Args()[1:]); err != nil {
+		return "", err
+	}
+	if fs.NArg() > 0 {
+		return "", fmt.Errorf("unexpected argument %q (quote multi-word queries)", fs.Arg(0))
+	}
+	return query, nil
+}
+
+func runSearch(args []string) error {
+	fs := flag.NewFlagSet("search", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+
+	inputPath := fs.String("in", defaultOutputFile(), "Input JSONL path")
+	sessionID := fs.String("session", "", "Filter by session ID")
+	role := fs.String("role", "", "Filter by role: user or assistant")
+	from := fs.String("from", "", "Filter records at/after this RFC3339 timestamp")
+	to := fs.String("to", "", "Filter records at/before this RFC3339 timestamp")
+	model := fs.String("model", "", "Filter by model, e.g. gpt-5-codex")
+	limit := fs.Int("limit", 0, "Maximum records to print (newest, or best with --fuzzy), 0 means all")
+	color := fs.String("color", "auto", "Highlight matches: auto, always, or never")
+	var sessionsOnly bool
+	fs.BoolVar(&sessionsOnly, "l", false, "Print only the IDs of sessions with matches")
+	fs.BoolVar(&sessionsOnly, "sessions-with-matches", false, "Print only the IDs of sessions with matches")
+	countOut := fs.Bool("count", false, "Print matching records and matches per session")
+	jsonOut := fs.Bool("json", false, "Print matching records (or --count) as JSON")
+	fuzzy := fs.Bool("fuzzy", false, "Rank approximate matches of the query's words by score")
+	minScore := fs.Float64("min-score", 0.75, "Lowest --fuzzy score to report, from 0 to 1")
+	caseSensitive := fs.Bool("case-sensitive", false, "Match the query with exact case")
+	word := fs.Bool("word", false, "Match the query only as a whole word")
+	contextRange := contextFlags(fs)
+	noIndex := fs.Bool("no-index", false, "Scan the history even when a full-text index exists")
+	semantic := fs.Bool("semantic", false, "Rank records by meaning using an embeddings endpoint")
+	embedURL := fs.String("embed-url", "", "OpenAI-compatible API base URL for --semantic (default: config embeddings.url or OpenAI)")
+	embedModel := fs.String("embed-model", "", "Embedding model for --semantic (default: config embeddings.model or "+defaultEmbeddingsModel+")")
+
+	query, err := parseWithQuery(fs, args)
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(query) == "" {
+		return errors.New("search query is empty")
+	}
+	if *limit < 0 {
+		return errors.New("--limit must be >= 0")
+	}
+	if *minScore < 0 || *minScore > 1 {
+		return errors.New("--min-score must be between 0 and 1")
+	}
+	if *fuzzy && *semantic {
+		return errors.New("--fuzzy and --semantic cannot be combined")
+	}
+	if (*fuzzy || *semantic) && (*caseSensitive || *word) {
+		return errors.New("--case-sensitive and --word only apply to literal searches")
+	}
+	before, after, err := contextRange()
+	if err != nil {
+		return err
+	}
+	colored, err := colorEnabled(*color)
+	if err != nil {
+		return err
+	}
+	fromTime, err := parseBoundTime(*from, "--from")
+	if err != nil {
+		return err
+	}
+	toTime, err := parseBoundTime(*to, "--to")
+	if err != nil {
+		return err
+	}
+	if err := validateTimeRange(fromTime, toTime); err != nil {
+		return err
+	}
+
+	pattern := textPattern(query, *caseSensitive, *word)
+	match := newRecordMatcher(RecordFilter{
+		SessionID: strings.TrimSpace(*sessionID),
+		Role:      strings.TrimSpace(*role),
+		From:      fromTime,
+		To:        toTime,
+		Model:     strings.TrimSpace(*model),
+	})
+
+	var hits []searchHit
+	switch {
+	case *semantic:
+		client := newEmbeddingClient(*embedURL, *embedModel)
+		if err := client.check(); err != nil {
+			return err
+		}
+		hits, err = semanticSearch(*inputPath, match, query, client, *limit)
+	case *fuzzy:
+		hits, err = fuzzySearch(*inputPath, match, query, *minScore, *limit)
+	default:
+		each := func(fn func(Record) error) error { return forEachRecord(*inputPath, fn) }
+		if !*noIndex {
+			candidates, ok, err := textIndexCandidates(*inputPath, query)
+			switch {
+			case errors.Is(err, errTextIndexStale):
+				fmt.Fprintf(os.Stderr, "note: %v\n", err)
+			case err != nil:
+				return err
+			case ok:
+				each = func(fn func(Record) error) error {
+					for _, record := range candidates {
+						if err := fn(record); err != nil {
+							return err
+						}
+					}
+					return nil
+				}
+			}
+		}
+		hits, err = exactSearch(each, match, pattern, *limit)
+	}
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case *countOut:
+		return printSearchCounts(os.Stdout, searchCounts(hits), *jsonOut)
+	case sessionsOnly:
+		for _, count := range searchCounts(hits) {
+			fmt.Println(count.SessionID)
+		}
+		return nil
+	case *jsonOut:
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetEscapeHTML(false)
+		for _, hit := range hits {
+			if err := enc.Encode(hit.Record); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	withScore := *fuzzy || *semantic
+	if before == 0 && after == 0 {
+		for _, hit := range hits {
+			printSearchHit(os.Stdout, hit, colored, withScore)
+		}
+		return nil
+	}
+
+	records := make([]Record, len(hits))
+	byID := make(map[string]searchHit, len(hits))
+	for i, hit := range hits {
+		records[i] = hit.Record
+		byID[hit.Record.ID] = hit
+	}
+	sessions, err := loadHitSessions(*inputPath, records)
+	if err != nil {
+		return err
+	}
+	for i, block := range withContext(records, sessions, before, after) {
+		if i > 0 {
+			fmt.Println("--")
+		}
+		for _, line := range block {
+			if hit, ok := byID[line.Record.ID]; ok {
+				printSearchHit(os.Stdout, hit, colored, withScore)
+			} else {
+				printSearchContext(os.Stdout, line.Record, colored)
+			}
+		}
+	}
+	return nil
+}
+
+// searchHit is a matching record with the pattern to highlight in it and,
+// for fuzzy searches, its score.
+type searchHit struct {
+	Record  Record
+	Pattern *regexp.Regexp
+	Score   float64
+}
+
+// exactSearch returns the records from each whose text matches pattern,
+// oldest first, keeping only the newest limit when limit > 0.
+func exactSearch(each func(func(Record) error) error, match func(Record) bool, pattern *regexp.Regexp, limit int) ([]searchHit, error) {
+	var records []Record
+	newest := &newestRecords{limit: limit}
+	err := each(func(record Record) error {
+		if !match(record) || !pattern.MatchString(record.Text) {
+			return nil
+		}
+		if limit > 0 {
+			newest.add(record)
+		} else {
+			records = append(records, record)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if limit > 0 {
+		records = newest.records()
+	} else {
+		sortRecordsChronological(records)
+	}
+	hits := make([]searchHit, len(records))
+	for i, record := range records {
+		hits[i] = searchHit{Record: record, Pattern: pattern}
+	}
+	return hits, nil
+}
+
+// searchCounts tallies matching records and individual matches per
+// session, in the order sessions first match.
+func searchCounts(hits []searchHit) []sessionMatchCount {
+	var counts []sessionMatchCount
+	index := make(map[string]int)
+	for _, hit := range hits {
+		i, ok := index[hit.Record.SessionID]
+		if !ok {
+			i = len(counts)
+			index[hit.Record.SessionID] = i
+			counts = append(counts, sessionMatchCount{SessionID: hit.Record.SessionID})
+		}
+		counts[i].Records++
+		counts[i].Matches += len(hit.Pattern.FindAllStringIndex(hit.Record.Text, -1))
+	}
+	return counts
+}
+
+func printSearchCounts(w io.Writer, counts []sessionMatchCount, jsonOut bool) error {
+	if jsonOut {
+		enc := json.NewEncoder(w)
+		enc.SetEscapeHTML(false)
+		return enc.Encode(counts)
+	}
+	for _, count := range counts {
+		fmt.Fprintf(w, "%s records=%d matches=%d\n", count.SessionID, count.Records, count.Matches)
+	}
+	return nil
+}
+
+// printSearchHit prints each matching line of a record grep-style, prefixed
+// with the record's timestamp, short session ID, and role, and its score
+// when withScore is set.
+func printSearchHit(w io.Writer, hit searchHit, colored, withScore bool) {
+	record := hit.Record
+	prefix := fmt.Sprintf("%s [%s] %s:", record.Timestamp, shortSessionID(record.SessionID), record.Role)
+	if withScore {
+		prefix = fmt.Sprintf("%.2f %s", hit.Score, prefix)
+	}
+	if colored {
+		prefix = ansiDim + prefix + ansiReset
+	}
+	for _, line := range matchingLines(record.Text, hit.Pattern) {
+		if colored {
+			line = highlightMatches(line, hit.Pattern)
+		}
+		fmt.Fprintf(w, "%s %s\n", prefix, line)
+	}
+}
+
+// printSearchContext prints the first line of a record shown for context,
+// marked with "-" after the role instead of ":".
+func printSearchContext(w io.Writer, record Record, colored bool) {
+	prefix := fmt.Sprintf("%s [%s] %s-", record.Timestamp, shortSessionID(record.SessionID), record.Role)
+	if colored {
+		prefix = ansiDim + prefix + ansiReset
+	}
+	line, _, _ := strings.Cut(strings.TrimSpace(record.Text), "\n")
+	fmt.Fprintf(w, "%s %s\n", prefix, line)
+}
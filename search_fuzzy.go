@@ -0,0 +1,126 @@
+package main
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// fuzzySearch scores every record against query and returns those scoring
+// at least minScore, best first (newest first on ties), keeping the best
+// limit when limit > 0.
+func fuzzySearch(path string, match func(Record) bool, query string, minScore float64, limit int) ([]searchHit, error) {
+	terms := searchWords(query)
+	var hits []searchHit
+	err := forEachRecord(path, func(record Record) error {
+		if !match(record) {
+			return nil
+		}
+		score, matched := fuzzyScore(terms, record.Text)
+		if score < minScore {
+			return nil
+		}
+		hits = append(hits, searchHit{Record: record, Score: score, Pattern: wordsPattern(matched)})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return compareTimestamp(hits[i].Record.Timestamp, hits[j].Record.Timestamp) > 0
+	})
+	if limit > 0 && len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits, nil
+}
+
+// searchWords splits text into lowercase words of letters and digits.
+func searchWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// fuzzyScore rates text against the query terms from 0 to 1: each term is
+// paired with the most similar word in text, and the score is the mean of
+// those similarities. It also returns the words that were paired, for
+// highlighting.
+func fuzzyScore(terms []string, text string) (float64, []string) {
+	if len(terms) == 0 {
+		return 0, nil
+	}
+	words := make(map[string]bool)
+	for _, word := range searchWords(text) {
+		words[word] = true
+	}
+	var total float64
+	var matched []string
+	for _, term := range terms {
+		best, bestWord := 0.0, ""
+		for word := range words {
+			if sim := wordSimilarity(term, word); sim > best || (sim == best && word < bestWord) {
+				best, bestWord = sim, word
+			}
+		}
+		total += best
+		if best > 0 {
+			matched = append(matched, bestWord)
+		}
+	}
+	return total / float64(len(terms)), matched
+}
+
+// wordSimilarity is 1 minus the edit distance between a and b relative to
+// the longer word. A term that is a prefix of the word (three letters or
+// more) scores at least 0.9, so "config" finds "configuration".
+func wordSimilarity(term, word string) float64 {
+	if term == word {
+		return 1
+	}
+	a, b := []rune(term), []rune(word)
+	longest := max(len(a), len(b))
+	sim := 1 - float64(levenshtein(a, b))/float64(longest)
+	if len(a) >= 3 && strings.HasPrefix(word, term) {
+		sim = max(sim, 0.9)
+	}
+	return max(sim, 0)
+}
+
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+// wordsPattern matches any of words, case-insensitively, longest first so
+// overlapping words highlight whole.
+func wordsPattern(words []string) *regexp.Regexp {
+	sort.Slice(words, func(i, j int) bool { return len(words[i]) > len(words[j]) })
+	quoted := make([]string, len(words))
+	for i, word := range words {
+		quoted[i] = regexp.QuoteMeta(word)
+	}
+	if len(quoted) == 0 {
+		return regexp.MustCompile(`$^`)
+	}
+	return regexp.MustCompile("(?i)" + strings.Join(quoted, "|"))
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestSearchHighlightAndCounts(t *testing.T) {
+	pattern := textPattern("Panic", false, false)
+	hits := []Record{
+		{SessionID: "4f163f5f-0f9a", Timestamp: "2026-02-17T10:00:00Z", Role: "assistant", Text: "first line\npanic: boom, PANIC again\nlast line"},
+		{SessionID: "4f163f5f-0f9a", Timestamp: "2026-02-17T10:01:00Z", Role: "user", Text: "why the panic?"},
+		{SessionID: "other", Timestamp: "2026-02-17T10:02:00Z", Role: "user", Text: "a.*panic"},
+	}
+
+	var out bytes.Buffer
+	printSearchHit(&out, searchHit{Record: hits[0], Pattern: pattern}, false, false)
+	if out.String() != "2026-02-17T10:00:00Z [4f163f5f] assistant: panic: boom, PANIC again\n" {
+		t.Fatalf("unexpected plain hit: %q", out.String())
+	}
+	out.Reset()
+	printSearchHit(&out, searchHit{Record: hits[1], Pattern: pattern}, true, false)
+	if !strings.Contains(out.String(), "why the "+ansiHighlight+"panic"+ansiReset+"?") {
+		t.Fatalf("match not highlighted: %q", out.String())
+	}
+
+	var scored []searchHit
+	for _, record := range hits {
+		scored = append(scored, searchHit{Record: record, Pattern: pattern})
+	}
+	counts := searchCounts(scored)
+	if len(counts) != 2 || counts[0].Records != 2 || counts[0].Matches != 3 || counts[1].Matches != 1 {
+		t.Fatalf("unexpected counts: %+v", counts)
+	}
+
+	literal := textPattern("a.*", false, false)
+	if literal.MatchString("abc") || !literal.MatchString(hits[2].Text) {
+		t.Fatal("query should match literally")
+	}
+}
+
+func TestParseWithQuery(t *testing.T) {
+	fs := flag.NewFlagSet("search", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	role := fs.String("role", "", "")
+	query, err := parseWithQuery(fs, []string{"--role", "user", "nil pointer", "--role", "assistant"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if query != "nil pointer" || *role != "assistant" {
+		t.Fatalf("unexpected parse: %q %q", query, *role)
+	}
+	if _, err := parseWithQuery(fs, []string{"nil", "pointer"}); err == nil {
+		t.Fatal("expected an error for an unquoted second word")
+	}
+}
+
+func TestFuzzyScore(t *testing.T) {
+	terms := searchWords("scheduler race fix")
+	near, matched := fuzzyScore(terms, "Fixed the data race in the schedular loop.")
+	far, _ := fuzzyScore(terms, "Updated the README table of contents.")
+	if near < 0.75 || far >= 0.75 || near <= far {
+		t.Fatalf("unexpected scores: near=%.2f far=%.2f", near, far)
+	}
+	if strings.Join(matched, ",") != "schedular,race,fixed" {
+		t.Fatalf("unexpected matched words: %v", matched)
+	}
+	if sim := wordSimilarity("config", "configuration"); sim < 0.9 {
+		t.Fatalf("prefix similarity too low: %.2f", sim)
+	}
+	pattern := wordsPattern([]string{"race", "schedular"})
+	if got := highlightMatches("schedular race", pattern); got != ansiHighlight+"schedular"+ansiReset+" "+ansiHighlight+"race"+ansiReset {
+		t.Fatalf("unexpected highlight: %q", got)
+	}
+}
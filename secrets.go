@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// secretPatterns are the credential shapes sync warns about. They favor
+// precision over recall: each has a fixed prefix or header, so ordinary
+// code and prose rarely trip them.
+var secretPatterns = []struct {
+	kind    string
+	pattern *regexp.Regexp
+}{
+	{"aws-access-key", regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{"github-token", regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{40,})\b`)},
+	{"openai-key", regexp.MustCompile(`\bsk-(?:proj-)?[A-Za-z0-9_-]{32,}`)},
+	{"slack-token", regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}`)},
+	{"google-api-key", regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}\b`)},
+	{"private-key", regexp.MustCompile(`-----BEGIN (?:[A-Z]+ )?PRIVATE KEY-----`)},
+}
+
+// blockedIDsPath is the sidecar of records --block-secrets left out. Unlike
+// the removed set it is consulted only while the flag is on, so a plain sync
+// writes those records after all.
+func blockedIDsPath(historyPath string) string {
+	return strings.TrimSuffix(historyPath, ".jsonl") + ".blocked"
+}
+
+// SecretFinding is a new record that appears to contain credentials.
+type SecretFinding struct {
+	RecordID  string
+	SessionID string
+	Kinds     []string
+}
+
+// detectSecrets returns the kinds of credentials text appears to contain,
+// in secretPatterns order.
+func detectSecrets(text string) []string {
+	var kinds []string
+	for _, secret := range secretPatterns {
+		if secret.pattern.MatchString(text) {
+			kinds = append(kinds, secret.kind)
+		}
+	}
+	return kinds
+}
+
+// printSecretWarnings summarizes findings on w: one line with the count per
+// kind, then one per record. blocked says the records were not written, and
+// dryRun that nothing was.
+func printSecretWarnings(w io.Writer, findings []SecretFinding, blocked, dryRun bool) {
+	if len(findings) == 0 {
+		return
+	}
+	perKind := make(map[string]int)
+	for _, finding := range findings {
+		for _, kind := range finding.Kinds {
+			perKind[kind]++
+		}
+	}
+	kinds := make([]string, 0, len(perKind))
+	for kind, count := range perKind {
+		kinds = append(kinds, fmt.Sprintf("%s=%d", kind, count))
+	}
+	sort.Strings(kinds)
+
+	action := "were recorded; review them with codex-history open, then redact or delete"
+	switch {
+	case dryRun && blocked:
+		action = "would be skipped"
+	case dryRun:
+		action = "would be recorded"
+	case blocked:
+		action = "were skipped (--block-secrets)"
+	}
+	fmt.Fprintf(w, "warning: %d new records appear to contain credentials (%s) and %s\n", len(findings), strings.Join(kinds, " "), action)
+	for _, finding := range findings {
+		fmt.Fprintf(w, "  %s session=%s kinds=%s\n", finding.RecordID, shortSessionID(finding.SessionID), strings.Join(finding.Kinds, ","))
+	}
+}
@@ -0,0 +1,317 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Semantic search embeds records through an OpenAI-compatible /embeddings
+// endpoint and caches the vectors next to the history, so each record is
+// only sent once per model. The cache is a flat binary file of entries
+// (16-byte ID key, then dims little-endian float32s) with a JSON sidecar
+// naming the model; switching models starts a new cache.
+const (
+	defaultEmbeddingsURL    = "https://api.openai.com/v1"
+	defaultEmbeddingsModel  = "text-embedding-3-small"
+	defaultEmbeddingsKeyEnv = "OPENAI_API_KEY"
+	// embeddingBatch is how many texts go into one request.
+	embeddingBatch = 64
+	// embeddingMaxBytes cuts long records to stay under model input limits.
+	embeddingMaxBytes = 8000
+	// semanticDefaultLimit applies when search --semantic has no --limit.
+	semanticDefaultLimit = 10
+)
+
+// EmbeddingsConfig configures the endpoint semantic search uses.
+type EmbeddingsConfig struct {
+	URL       string `json:"url,omitempty"`
+	Model     string `json:"model,omitempty"`
+	APIKeyEnv string `json:"api_key_env,omitempty"`
+}
+
+// embeddingClient calls an OpenAI-compatible embeddings endpoint.
+type embeddingClient struct {
+	url    string
+	model  string
+	apiKey string
+	keyEnv string
+	http   *http.Client
+}
+
+func newEmbeddingClient(url, model string) embeddingClient {
+	cfg := appConfig.Embeddings
+	if url == "" {
+		url = firstNonEmpty(cfg.URL, defaultEmbeddingsURL)
+	}
+	if model == "" {
+		model = firstNonEmpty(cfg.Model, defaultEmbeddingsModel)
+	}
+	keyEnv := firstNonEmpty(cfg.APIKeyEnv, defaultEmbeddingsKeyEnv)
+	return embeddingClient{
+		url:    strings.TrimSuffix(url, "/"),
+		model:  model,
+		apiKey: os.Getenv(keyEnv),
+		keyEnv: keyEnv,
+		http:   &http.Client{Timeout: 2 * time.Minute},
+	}
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if strings.TrimSpace(value) != "" {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
+
+type embeddingRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+type embeddingResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+}
+
+// embed returns one vector per text, in order.
+func (c embeddingClient) embed(texts []string) ([][]float32, error) {
+	body, err := json.Marshal(embeddingRequest{Model: c.model, Input: texts})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodPost, c.url+"/embeddings", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("embeddings: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("embeddings: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("embeddings: %s: %s", resp.Status, strings.TrimSpace(cutUTF8(string(data), 500)))
+	}
+
+	var parsed embeddingResponse
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return nil, fmt.Errorf("embeddings: invalid response: %w", err)
+	}
+	vectors := make([][]float32, len(texts))
+	for _, item := range parsed.Data {
+		if item.Index < 0 || item.Index >= len(texts) {
+			return nil, fmt.Errorf("embeddings: response index %d out of range", item.Index)
+		}
+		vectors[item.Index] = item.Embedding
+	}
+	for i, vector := range vectors {
+		if len(vector) == 0 {
+			return nil, fmt.Errorf("embeddings: no vector for input %d", i)
+		}
+	}
+	return vectors, nil
+}
+
+// vectorCacheMeta describes the vector cache file.
+type vectorCacheMeta struct {
+	Model   string `json:"model"`
+	Dims    int    `json:"dims"`
+	Entries int64  `json:"entries"`
+}
+
+func vectorCachePath(historyPath string) string {
+	return strings.TrimSuffix(historyPath, ".jsonl") + ".vectors"
+}
+
+func vectorCacheMetaPath(historyPath string) string {
+	return vectorCachePath(historyPath) + ".json"
+}
+
+// vectorCache maps record ID keys to their embeddings for one model.
+type vectorCache struct {
+	meta    vectorCacheMeta
+	vectors map[idKey][]float32
+}
+
+// loadVectorCache reads the cache for model, or returns an empty one when
+// there is none, it was built with another model, or it does not parse.
+func loadVectorCache(historyPath, model string) *vectorCache {
+	cache := &vectorCache{meta: vectorCacheMeta{Model: model}, vectors: make(map[idKey][]float32)}
+	metaData, err := os.ReadFile(vectorCacheMetaPath(historyPath))
+	if err != nil {
+		return cache
+	}
+	var meta vectorCacheMeta
+	if err := json.Unmarshal(metaData, &meta); err != nil || meta.Model != model || meta.Dims <= 0 {
+		return cache
+	}
+	data, err := os.ReadFile(vectorCachePath(historyPath))
+	entrySize := idKeySize + 4*meta.Dims
+	if err != nil || int64(len(data)) != meta.Entries*int64(entrySize) {
+		return cache
+	}
+	for offset := 0; offset < len(data); offset += entrySize {
+		var key idKey
+		copy(key[:], data[offset:offset+idKeySize])
+		vector := make([]float32, meta.Dims)
+		for i := range vector {
+			bits := binary.LittleEndian.Uint32(data[offset+idKeySize+4*i:])
+			vector[i] = math.Float32frombits(bits)
+		}
+		cache.vectors[key] = vector
+	}
+	cache.meta = meta
+	return cache
+}
+
+// appendVectors adds new entries to the cache file, starting a new file
+// when the cache was empty (including after a model change).
+func (c *vectorCache) appendVectors(historyPath string, keys []idKey, vectors [][]float32) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	if c.meta.Dims == 0 {
+		c.meta.Dims = len(vectors[0])
+	}
+	var data bytes.Buffer
+	for i, vector := range vectors {
+		if len(vector) != c.meta.Dims {
+			return fmt.Errorf("embeddings: got %d dimensions, expected %d", len(vector), c.meta.Dims)
+		}
+		data.Write(keys[i][:])
+		for _, value := range vector {
+			binary.Write(&data, binary.LittleEndian, math.Float32bits(value))
+		}
+		c.vectors[keys[i]] = vector
+	}
+
+	flags := os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	if c.meta.Entries == 0 {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+	file, err := os.OpenFile(vectorCachePath(historyPath), flags, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data.Bytes()); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	c.meta.Entries += int64(len(keys))
+	meta, err := json.Marshal(c.meta)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(vectorCacheMetaPath(historyPath), append(meta, '\n'))
+}
+
+// semanticSearch embeds the query and every matching record not yet in the
+// cache, and returns the records most similar to the query by cosine
+// similarity, best first.
+func semanticSearch(historyPath string, match func(Record) bool, query string, client embeddingClient, limit int) ([]searchHit, error) {
+	var records []Record
+	err := forEachRecord(historyPath, func(record Record) error {
+		if match(record) && strings.TrimSpace(record.Text) != "" {
+			records = append(records, record)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	cache := loadVectorCache(historyPath, client.model)
+	var missing []Record
+	for _, record := range records {
+		if _, ok := cache.vectors[keyForID(record.ID)]; !ok {
+			missing = append(missing, record)
+		}
+	}
+	if len(missing) > 0 {
+		fmt.Fprintf(os.Stderr, "embedding %d records with %s\n", len(missing), client.model)
+	}
+	for start := 0; start < len(missing); start += embeddingBatch {
+		batch := missing[start:min(start+embeddingBatch, len(missing))]
+		texts := make([]string, len(batch))
+		keys := make([]idKey, len(batch))
+		for i, record := range batch {
+			texts[i] = cutUTF8(record.Text, embeddingMaxBytes)
+			keys[i] = keyForID(record.ID)
+		}
+		vectors, err := client.embed(texts)
+		if err != nil {
+			return nil, err
+		}
+		// Save each batch so an interrupted run keeps its progress.
+		if err := cache.appendVectors(historyPath, keys, vectors); err != nil {
+			return nil, err
+		}
+	}
+
+	queryVectors, err := client.embed([]string{query})
+	if err != nil {
+		return nil, err
+	}
+	hits := make([]searchHit, 0, len(records))
+	never := wordsPattern(nil)
+	for _, record := range records {
+		vector := cache.vectors[keyForID(record.ID)]
+		hits = append(hits, searchHit{Record: record, Pattern: never, Score: cosineSimilarity(queryVectors[0], vector)})
+	}
+	sort.SliceStable(hits, func(i, j int) bool { return hits[i].Score > hits[j].Score })
+	if limit <= 0 {
+		limit = semanticDefaultLimit
+	}
+	if len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits, nil
+}
+
+func cosineSimilarity(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
+
+// check fails early when the hosted default endpoint would be called
+// without a key.
+func (c embeddingClient) check() error {
+	if c.apiKey == "" && c.url == defaultEmbeddingsURL {
+		return fmt.Errorf("semantic search needs an API key in $%s, or embeddings.url set to a local endpoint", c.keyEnv)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSemanticSearchCachesVectors(t *testing.T) {
+	// The fake endpoint embeds text as counts of a few topic words.
+	topics := []string{"race", "scheduler", "readme", "docs"}
+	var embedded []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/embeddings" || r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		var req embeddingRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Model != "test-model" {
+			http.Error(w, "bad body", http.StatusBadRequest)
+			return
+		}
+		var resp embeddingResponse
+		resp.Data = make([]struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		}, len(req.Input))
+		for i, text := range req.Input {
+			embedded = append(embedded, text)
+			vector := make([]float32, len(topics)+1)
+			vector[len(topics)] = 0.1
+			for j, topic := range topics {
+				vector[j] = float32(strings.Count(strings.ToLower(text), topic))
+			}
+			resp.Data[i].Index = i
+			resp.Data[i].Embedding = vector
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+	t.Setenv("OPENAI_API_KEY", "secret")
+
+	historyPath := filepath.Join(t.TempDir(), "history.jsonl")
+	records := []Record{
+		{ID: "1", SessionID: "s1", Timestamp: "2026-02-17T10:00:00Z", Role: "user", Text: "fix the race in the scheduler"},
+		{ID: "2", SessionID: "s2", Timestamp: "2026-02-17T11:00:00Z", Role: "user", Text: "update the README docs"},
+		{ID: "3", SessionID: "s2", Timestamp: "2026-02-17T11:01:00Z", Role: "assistant", Text: "  "},
+	}
+	if err := appendRecords(historyPath, records, false); err != nil {
+		t.Fatal(err)
+	}
+
+	client := newEmbeddingClient(server.URL+"/v1/", "test-model")
+	matchAll := newRecordMatcher(RecordFilter{})
+	hits, err := semanticSearch(historyPath, matchAll, "scheduler race condition", client, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hits) != 1 || hits[0].Record.ID != "1" || hits[0].Score < 0.9 {
+		t.Fatalf("unexpected hits: %+v", hits)
+	}
+	if len(embedded) != 3 {
+		t.Fatalf("expected 2 records and the query to be embedded, got %q", embedded)
+	}
+
+	// A second search only embeds the new query.
+	embedded = nil
+	hits, err = semanticSearch(historyPath, matchAll, "readme", client, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(embedded) != 1 || len(hits) != 2 || hits[0].Record.ID != "2" {
+		t.Fatalf("cache not used: embedded=%q hits=%+v", embedded, hits)
+	}
+	if cache := loadVectorCache(historyPath, "other-model"); len(cache.vectors) != 0 {
+		t.Fatal("cache should be per model")
+	}
+}
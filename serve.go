@@ -0,0 +1,543 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"embed"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// webFiles is the browser UI serve hands out: a single page that talks to
+// the /api endpoints below.
+//
+//go:embed web
+var webFiles embed.FS
+
+const (
+	defaultServeAddr   = "127.0.0.1:8080"
+	defaultSearchLimit = 100
+)
+
+func runServe(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+
+	inputPath := fs.String("in", defaultOutputFile(), "History JSONL path")
+	addr := fs.String("addr", defaultServeAddr, "Address to listen on; keep it on localhost unless the network is trusted")
+	token := fs.String("token", "", "Require this bearer token on API requests (default: $"+serveTokenEnv+")")
+	tlsCert := fs.String("tls-cert", "", "Serve HTTPS with this certificate (PEM); needs --tls-key")
+	tlsKey := fs.String("tls-key", "", "Private key (PEM) for --tls-cert")
+	var sources []serveSource
+	fs.Func("source", "Serve the history at PATH under /sources/NAME/ (NAME=PATH); repeat for each person, instead of --in", func(value string) error {
+		source, err := parseServeSource(value)
+		if err != nil {
+			return err
+		}
+		sources = append(sources, source)
+		return nil
+	})
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected argument %q", fs.Arg(0))
+	}
+	inSet := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "in" {
+			inSet = true
+		}
+	})
+	if inSet && len(sources) > 0 {
+		return errors.New("use either --in or --source, not both")
+	}
+	if (*tlsCert == "") != (*tlsKey == "") {
+		return errors.New("--tls-cert and --tls-key must be given together")
+	}
+	if strings.TrimSpace(*token) == "" {
+		*token = os.Getenv(serveTokenEnv)
+	}
+	*token = strings.TrimSpace(*token)
+
+	var handler http.Handler
+	served := *inputPath
+	var single *historyServer
+	if len(sources) > 0 {
+		multi, err := newMultiSourceServer(sources, *token)
+		if err != nil {
+			return err
+		}
+		handler = multi.routes()
+		served = fmt.Sprintf("%d histories", len(sources))
+	} else {
+		if _, err := os.Stat(*inputPath); err != nil {
+			return err
+		}
+		single = newHistoryServer(*inputPath, *token)
+		handler = single.routes()
+	}
+	current := &swappableHandler{}
+	current.set(handler)
+
+	listener, err := net.Listen("tcp", *addr)
+	if err != nil {
+		return err
+	}
+	server := &http.Server{
+		Handler:           current,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
+	go func() {
+		for range hangup {
+			// The config only supplies the default --in, so there is
+			// nothing to reload when --in or --source was given.
+			if inSet || single == nil {
+				fmt.Fprintln(os.Stderr, "config reload: nothing to change (--in or --source was given)")
+				continue
+			}
+			cfg, err := loadConfig(defaultConfigPath())
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "warning: config not reloaded: %v\n", err)
+				continue
+			}
+			appConfig = cfg
+			path := defaultOutputFile()
+			if path == single.historyPath {
+				continue
+			}
+			if _, err := os.Stat(path); err != nil {
+				fmt.Fprintf(os.Stderr, "warning: config not reloaded: %v\n", err)
+				continue
+			}
+			single = single.withHistory(path)
+			current.set(single.routes())
+			fmt.Printf("reloaded config: serving %s\n", path)
+		}
+	}()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	scheme := "http"
+	if *tlsCert != "" {
+		scheme = "https"
+	}
+	if !isLoopbackAddr(listener.Addr()) {
+		switch {
+		case *token == "":
+			fmt.Fprintln(os.Stderr, "warning: serving beyond localhost without --token; anyone who can connect can read the history")
+		case scheme == "http":
+			fmt.Fprintln(os.Stderr, "warning: serving beyond localhost without TLS; the token and history cross the network in the clear")
+		}
+	}
+	fmt.Printf("serving %s at %s://%s/\n", served, scheme, listener.Addr())
+	if scheme == "https" {
+		err = server.ServeTLS(listener, *tlsCert, *tlsKey)
+	} else {
+		err = server.Serve(listener)
+	}
+	if !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// serveTokenEnv supplies serve's token without putting it on the command
+// line, where other users can see it in the process list.
+const serveTokenEnv = "CODEX_HISTORY_TOKEN"
+
+func isLoopbackAddr(addr net.Addr) bool {
+	tcp, ok := addr.(*net.TCPAddr)
+	return ok && tcp.IP.IsLoopback()
+}
+
+// swappableHandler serves with whichever handler was set last, so a config
+// reload can point serve at another history without dropping connections.
+type swappableHandler struct {
+	handler atomic.Pointer[http.Handler]
+}
+
+func (h *swappableHandler) set(handler http.Handler) {
+	h.handler.Store(&handler)
+}
+
+func (h *swappableHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	(*h.handler.Load()).ServeHTTP(w, r)
+}
+
+// historyServer answers the web UI's requests. Every request reads the
+// history afresh, so a running watch shows up on the next reload. With a
+// token, API requests must carry it as "Authorization: Bearer <token>";
+// the page itself holds no history and stays public so it can ask for it.
+type historyServer struct {
+	historyPath string
+	token       string
+	metrics     *metrics
+	started     time.Time
+	// source and basePath are set when the server is one of several
+	// mounted by serve --source.
+	source   string
+	basePath string
+}
+
+func newHistoryServer(historyPath, token string) *historyServer {
+	return &historyServer{historyPath: historyPath, token: token, metrics: newServeMetrics(), started: time.Now()}
+}
+
+func newServeMetrics() *metrics {
+	m := newMetrics()
+	m.register("codex_history_http_requests_total", "counter", "API requests, by handler and status code.")
+	m.register("codex_history_http_request_duration_seconds", "histogram", "Time taken to answer API requests, by handler.", durationBuckets...)
+	return m
+}
+
+// withHistory returns a copy serving historyPath that keeps counting into
+// the same metrics.
+func (s *historyServer) withHistory(historyPath string) *historyServer {
+	copied := *s
+	copied.historyPath = historyPath
+	return &copied
+}
+
+// serveMessage is a record with its text rendered for the conversation view.
+type serveMessage struct {
+	Record
+	HTML string `json:"html"`
+}
+
+// serveSearchHit is one search result: where the match is and the lines of
+// text it is on.
+type serveSearchHit struct {
+	ID        string `json:"id"`
+	SessionID string `json:"session_id"`
+	Timestamp string `json:"timestamp"`
+	Role      string `json:"role"`
+	Snippet   string `json:"snippet"`
+}
+
+func (s *historyServer) routes() http.Handler {
+	static, err := fs.Sub(webFiles, "web")
+	if err != nil {
+		panic(err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("GET /api/sessions", s.instrumented("sessions", s.authorized(s.handleSessions)))
+	mux.Handle("GET /api/sessions/{id}", s.instrumented("session", s.authorized(s.handleSession)))
+	mux.Handle("GET /api/search", s.instrumented("search", s.authorized(s.handleSearch)))
+	mux.Handle("GET /api/records/{id}", s.instrumented("record", s.authorized(s.handleRecord)))
+	mux.Handle("GET /api/stats", s.instrumented("stats", s.authorized(s.handleStats)))
+	mux.Handle("GET /metrics", s.authorized(s.metrics.ServeHTTP))
+	mux.HandleFunc("GET /openapi.json", s.handleOpenAPI)
+	// Probes come from orchestrators without the token, so the health
+	// endpoints are public and say nothing about the history's contents.
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) { writeHealth(w, s.health(false)) })
+	mux.HandleFunc("GET /readyz", func(w http.ResponseWriter, r *http.Request) { writeHealth(w, s.health(true)) })
+	mux.HandleFunc("GET /base.css", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/css; charset=utf-8")
+		fmt.Fprint(w, htmlStyle)
+	})
+	mux.Handle("GET /", http.FileServerFS(static))
+	return mux
+}
+
+// authorized rejects requests without the server's token, if it has one.
+func (s *historyServer) authorized(handler http.HandlerFunc) http.Handler {
+	if s.token == "" {
+		return handler
+	}
+	want := []byte("Bearer " + s.token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), want) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="codex-history"`)
+			writeJSONError(w, http.StatusUnauthorized, errors.New("missing or invalid token"))
+			return
+		}
+		handler(w, r)
+	})
+}
+
+// instrumented counts a handler's requests by status code and times them,
+// by source as well when there are several.
+func (s *historyServer) instrumented(name string, handler http.Handler) http.Handler {
+	labels := metricLabels("handler", name)
+	if s.source != "" {
+		labels = metricLabels("source", s.source, "handler", name)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		handler.ServeHTTP(recorder, r)
+		s.metrics.add("codex_history_http_requests_total", joinLabels(labels, metricLabels("code", strconv.Itoa(recorder.status))), 1)
+		s.metrics.observe("codex_history_http_request_duration_seconds", labels, time.Since(start).Seconds())
+	})
+}
+
+// statusRecorder remembers the status code a handler wrote.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// health reports serve as live while it answers, and ready while the
+// history can be read.
+func (s *historyServer) health(ready bool) HealthReport {
+	report := HealthReport{Status: "ok", Component: "serve", StartedAt: s.started.UTC().Format(time.RFC3339)}
+	if ready {
+		file, err := os.Open(s.historyPath)
+		if err != nil {
+			report.Status = "fail"
+			report.Problems = append(report.Problems, "history not readable")
+		} else {
+			file.Close()
+		}
+	}
+	return report
+}
+
+// handleSessions lists session summaries, newest first. ?project= narrows
+// them like --project and ?limit= caps how many are returned.
+func (s *historyServer) handleSessions(w http.ResponseWriter, r *http.Request) {
+	limit, err := queryLimit(r, 0)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+	summaries, err := listSessions(s.historyPath, strings.TrimSpace(r.URL.Query().Get("project")), limit)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, summaries)
+}
+
+// listSessions returns the summaries of the sessions in project (all of
+// them when it is empty), newest first and at most limit unless it is 0.
+func listSessions(historyPath, project string, limit int) ([]SessionSummary, error) {
+	match := newRecordMatcher(RecordFilter{Project: project})
+	acc := newSessionAccumulator()
+	err := forEachRecord(historyPath, func(record Record) error {
+		if match(record) {
+			acc.add(record)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	summaries := acc.summaries()
+	sortSessionSummaries(summaries)
+	if limit > 0 && len(summaries) > limit {
+		summaries = summaries[:limit]
+	}
+	info, err := loadSessionInfo(sessionInfoPath(historyPath))
+	if err != nil {
+		return nil, err
+	}
+	attachSessionInfo(summaries, info)
+	if summaries == nil {
+		summaries = []SessionSummary{}
+	}
+	return summaries, nil
+}
+
+// handleSession returns one session's records, oldest first, each with its
+// markdown rendered.
+func (s *historyServer) handleSession(w http.ResponseWriter, r *http.Request) {
+	records, err := sessionRecords(s.historyPath, r.PathValue("id"))
+	switch {
+	case errors.Is(err, errSessionNotFound):
+		writeJSONError(w, http.StatusNotFound, err)
+		return
+	case errors.Is(err, errAmbiguousPrefix):
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	case err != nil:
+		writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+	messages := make([]serveMessage, len(records))
+	for i, record := range records {
+		messages[i] = serveMessage{Record: record, HTML: string(markdownHTML(record.Text))}
+	}
+	writeJSON(w, messages)
+}
+
+// handleRecord returns one record by ID or unique ID prefix, as open does.
+func (s *historyServer) handleRecord(w http.ResponseWriter, r *http.Request) {
+	record, err := findRecord(s.historyPath, r.PathValue("id"))
+	switch {
+	case errors.Is(err, errRecordNotFound):
+		writeJSONError(w, http.StatusNotFound, err)
+	case errors.Is(err, errAmbiguousPrefix):
+		writeJSONError(w, http.StatusBadRequest, err)
+	case err != nil:
+		writeJSONError(w, http.StatusInternalServerError, err)
+	default:
+		writeJSON(w, record)
+	}
+}
+
+// handleStats returns what stats --json prints, narrowed by the query
+// parameters session, role, model, project, from, and to.
+func (s *historyServer) handleStats(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	from, err := parseBoundTime(query.Get("from"), "from")
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+	to, err := parseBoundTime(query.Get("to"), "to")
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+	stats, err := filteredStats(s.historyPath, RecordFilter{
+		SessionID: strings.TrimSpace(query.Get("session")),
+		Role:      strings.TrimSpace(query.Get("role")),
+		Model:     strings.TrimSpace(query.Get("model")),
+		Project:   strings.TrimSpace(query.Get("project")),
+		From:      from,
+		To:        to,
+	})
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, stats)
+}
+
+// filteredStats returns what stats --json prints for the records filter
+// matches.
+func filteredStats(historyPath string, filter RecordFilter) (HistoryStats, error) {
+	match := newRecordMatcher(filter)
+	acc := newStatsAccumulator()
+	err := forEachRecord(historyPath, func(record Record) error {
+		if match(record) {
+			acc.add(record)
+		}
+		return nil
+	})
+	if err != nil {
+		return HistoryStats{}, err
+	}
+	stats := acc.result()
+	info, err := loadSessionInfo(sessionInfoPath(historyPath))
+	if err != nil {
+		return HistoryStats{}, err
+	}
+	stats.Usage = sumUsage(info, acc.sessions)
+	return stats, nil
+}
+
+// handleSearch finds records containing ?q= (case-insensitive), newest
+// first, through the full-text index when an up-to-date one exists.
+func (s *historyServer) handleSearch(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		writeJSONError(w, http.StatusBadRequest, errors.New("q is required"))
+		return
+	}
+	limit, err := queryLimit(r, defaultSearchLimit)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	results, err := searchHistory(s.historyPath, query, limit)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, results)
+}
+
+// searchHistory finds up to limit records containing query
+// (case-insensitive), newest first, through the full-text index when an
+// up-to-date one exists.
+func searchHistory(historyPath, query string, limit int) ([]serveSearchHit, error) {
+	each := func(fn func(Record) error) error { return forEachRecord(historyPath, fn) }
+	// A stale or unusable index only costs speed, so the history is
+	// scanned instead.
+	if candidates, ok, err := textIndexCandidates(historyPath, query); err == nil && ok {
+		each = func(fn func(Record) error) error {
+			for _, record := range candidates {
+				if err := fn(record); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+	pattern := textPattern(query, false, false)
+	hits, err := exactSearch(each, func(Record) bool { return true }, pattern, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]serveSearchHit, 0, len(hits))
+	for i := len(hits) - 1; i >= 0; i-- {
+		record := hits[i].Record
+		results = append(results, serveSearchHit{
+			ID:        record.ID,
+			SessionID: record.SessionID,
+			Timestamp: record.Timestamp,
+			Role:      record.Role,
+			Snippet:   strings.Join(matchingLines(record.Text, pattern), "\n"),
+		})
+	}
+	return results, nil
+}
+
+// queryLimit reads ?limit=, which must be a non-negative integer.
+func queryLimit(r *http.Request, fallback int) (int, error) {
+	raw := r.URL.Query().Get("limit")
+	if raw == "" {
+		return fallback, nil
+	}
+	limit, err := strconv.Atoi(raw)
+	if err != nil || limit < 0 {
+		return 0, fmt.Errorf("invalid limit %q", raw)
+	}
+	return limit, nil
+}
+
+func writeJSON(w http.ResponseWriter, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.Encode(value)
+}
+
+func writeJSONError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
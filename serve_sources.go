@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// serveSource is one named history given to serve with --source.
+type serveSource struct {
+	Name string `json:"name"`
+	Path string `json:"-"`
+}
+
+var sourceNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// parseServeSource reads a --source NAME=PATH value.
+func parseServeSource(value string) (serveSource, error) {
+	name, path, ok := strings.Cut(value, "=")
+	name, path = strings.TrimSpace(name), strings.TrimSpace(path)
+	if !ok || name == "" || path == "" {
+		return serveSource{}, fmt.Errorf("invalid --source %q: expected NAME=PATH", value)
+	}
+	if !sourceNamePattern.MatchString(name) {
+		return serveSource{}, fmt.Errorf("invalid --source name %q: use letters, digits, '.', '_', and '-'", name)
+	}
+	return serveSource{Name: name, Path: path}, nil
+}
+
+// multiSourceServer serves several histories from one instance. Each is
+// mounted whole (page, API, and OpenAPI document) under /sources/NAME/, so
+// every endpoint is scoped to one source; the root lists the sources and
+// answers metrics and health for all of them.
+type multiSourceServer struct {
+	sources []serveSource
+	servers []*historyServer
+	token   string
+	metrics *metrics
+	started time.Time
+}
+
+func newMultiSourceServer(sources []serveSource, token string) (*multiSourceServer, error) {
+	m := &multiSourceServer{sources: sources, token: token, metrics: newServeMetrics(), started: time.Now()}
+	seen := make(map[string]bool)
+	for _, source := range sources {
+		if seen[source.Name] {
+			return nil, fmt.Errorf("duplicate --source name %q", source.Name)
+		}
+		seen[source.Name] = true
+		if _, err := os.Stat(source.Path); err != nil {
+			return nil, fmt.Errorf("source %s: %w", source.Name, err)
+		}
+		server := newHistoryServer(source.Path, token)
+		server.metrics = m.metrics
+		server.source = source.Name
+		server.basePath = sourceBasePath(source.Name)
+		m.servers = append(m.servers, server)
+	}
+	return m, nil
+}
+
+func sourceBasePath(name string) string {
+	return "/sources/" + name
+}
+
+func (m *multiSourceServer) routes() http.Handler {
+	mux := http.NewServeMux()
+	for _, server := range m.servers {
+		mux.Handle(server.basePath+"/", http.StripPrefix(server.basePath, server.routes()))
+	}
+	// The sources share one token and one set of metrics, labelled by
+	// source; health at the root covers every source.
+	auth := &historyServer{token: m.token}
+	mux.Handle("GET /api/sources", auth.authorized(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, m.sources)
+	}))
+	mux.Handle("GET /metrics", auth.authorized(m.metrics.ServeHTTP))
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) { writeHealth(w, m.health(false)) })
+	mux.HandleFunc("GET /readyz", func(w http.ResponseWriter, r *http.Request) { writeHealth(w, m.health(true)) })
+	mux.HandleFunc("GET /{$}", m.handleIndex)
+	return mux
+}
+
+// health is ready only while every source's history can be read.
+func (m *multiSourceServer) health(ready bool) HealthReport {
+	report := HealthReport{Status: "ok", Component: "serve", StartedAt: m.started.UTC().Format(time.RFC3339)}
+	for _, server := range m.servers {
+		sourceReport := server.health(ready)
+		for _, problem := range sourceReport.Problems {
+			report.Problems = append(report.Problems, server.source+": "+problem)
+		}
+	}
+	if len(report.Problems) > 0 {
+		report.Status = "fail"
+	}
+	return report
+}
+
+// handleIndex lists the sources. Names are not secret, and each source's
+// page asks for the token itself.
+func (m *multiSourceServer) handleIndex(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := sourceIndexTemplate.Execute(w, m.sources); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+var sourceIndexTemplate = template.Must(template.New("sources").Funcs(template.FuncMap{
+	"style": func() template.CSS { return htmlStyle },
+	"base":  sourceBasePath,
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Codex History</title>
+<style>{{style}}
+li { margin: .4rem 0; font-size: 1.1rem; }
+</style>
+</head>
+<body>
+<h1>Codex History</h1>
+<p class="meta">{{len .}} histories</p>
+<ul>
+{{range .}}<li><a href="{{base .Name}}/">{{.Name}}</a></li>
+{{end}}</ul>
+</body>
+</html>
+`))
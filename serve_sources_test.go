@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseServeSource(t *testing.T) {
+	source, err := parseServeSource("alice=/home/alice/.codex/history.jsonl")
+	if err != nil || source.Name != "alice" || source.Path != "/home/alice/.codex/history.jsonl" {
+		t.Fatalf("got %+v, %v", source, err)
+	}
+	for _, value := range []string{"alice", "=/tmp/h.jsonl", "alice=", "al/ice=/tmp/h.jsonl", ".alice=/tmp/h.jsonl"} {
+		if _, err := parseServeSource(value); err == nil {
+			t.Errorf("parseServeSource(%q) should fail", value)
+		}
+	}
+}
+
+func TestMultiSourceServer(t *testing.T) {
+	dir := t.TempDir()
+	alice := filepath.Join(dir, "alice.jsonl")
+	bob := filepath.Join(dir, "bob.jsonl")
+	if err := appendRecords(alice, []Record{{ID: "a1", SessionID: "s-alice", Timestamp: "2026-02-17T10:00:00Z", Role: "user", Text: "alice's parser"}}, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := appendRecords(bob, []Record{{ID: "b1", SessionID: "s-bob", Timestamp: "2026-02-17T11:00:00Z", Role: "user", Text: "bob's parser"}}, false); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := newMultiSourceServer([]serveSource{{"alice", alice}, {"alice", bob}}, ""); err == nil {
+		t.Fatal("duplicate names should fail")
+	}
+	if _, err := newMultiSourceServer([]serveSource{{"carol", filepath.Join(dir, "missing.jsonl")}}, ""); err == nil {
+		t.Fatal("a missing history should fail")
+	}
+
+	multi, err := newMultiSourceServer([]serveSource{{"alice", alice}, {"bob", bob}}, "s3cret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(multi.routes())
+	defer server.Close()
+
+	get := func(url string, wantStatus int) string {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodGet, server.URL+url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer s3cret")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode != wantStatus {
+			t.Fatalf("GET %s: status %d, want %d", url, resp.StatusCode, wantStatus)
+		}
+		return string(body)
+	}
+
+	var hits []serveSearchHit
+	if err := json.Unmarshal([]byte(get("/sources/bob/api/search?q=parser", http.StatusOK)), &hits); err != nil {
+		t.Fatal(err)
+	}
+	if len(hits) != 1 || hits[0].ID != "b1" {
+		t.Fatalf("bob's search should only see bob's records: %+v", hits)
+	}
+	get("/sources/alice/api/records/a1", http.StatusOK)
+	get("/sources/alice/api/records/b1", http.StatusNotFound)
+	get("/sources/carol/api/sessions", http.StatusNotFound)
+	get("/api/sessions", http.StatusNotFound)
+
+	var sources []serveSource
+	if err := json.Unmarshal([]byte(get("/api/sources", http.StatusOK)), &sources); err != nil {
+		t.Fatal(err)
+	}
+	if len(sources) != 2 || sources[1].Name != "bob" || sources[1].Path != "" {
+		t.Fatalf("unexpected sources: %+v", sources)
+	}
+	if index := get("/", http.StatusOK); !strings.Contains(index, `href="/sources/alice/"`) {
+		t.Fatalf("index does not link alice:\n%s", index)
+	}
+	if page := get("/sources/alice/", http.StatusOK); !strings.Contains(page, "app.js") {
+		t.Fatal("source page should be the web UI")
+	}
+	if doc := get("/sources/alice/openapi.json", http.StatusOK); !strings.Contains(doc, `"url":"/sources/alice"`) {
+		t.Fatalf("OpenAPI document should be rooted at the source:\n%s", doc)
+	}
+	if metrics := get("/metrics", http.StatusOK); !strings.Contains(metrics, `codex_history_http_requests_total{source="bob",handler="search",code="200"} 1`) {
+		t.Fatalf("metrics should be labelled by source:\n%s", metrics)
+	}
+	get("/readyz", http.StatusOK)
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHistoryServer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	records := []Record{
+		{ID: "r1", SessionID: "s-old", Timestamp: "2026-02-17T10:00:00Z", Role: "user", Text: "Fix the parser"},
+		{ID: "r2", SessionID: "s-old", Timestamp: "2026-02-17T10:01:00Z", Role: "assistant", Text: "Done: **parser** fixed"},
+		{ID: "r3", SessionID: "s-new", Timestamp: "2026-02-18T09:00:00Z", Role: "user", Text: "Add a parser test"},
+	}
+	if err := appendRecords(path, records, false); err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(newHistoryServer(path, "").routes())
+	defer server.Close()
+
+	get := func(url string, wantStatus int, into any) {
+		t.Helper()
+		resp, err := http.Get(server.URL + url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != wantStatus {
+			t.Fatalf("GET %s: status %d, want %d", url, resp.StatusCode, wantStatus)
+		}
+		if into != nil {
+			if err := json.NewDecoder(resp.Body).Decode(into); err != nil {
+				t.Fatalf("GET %s: %v", url, err)
+			}
+		}
+	}
+
+	var sessions []SessionSummary
+	get("/api/sessions", http.StatusOK, &sessions)
+	if len(sessions) != 2 || sessions[0].SessionID != "s-new" || sessions[1].Preview != "Fix the parser" {
+		t.Fatalf("unexpected sessions: %+v", sessions)
+	}
+
+	var messages []serveMessage
+	get("/api/sessions/s-old", http.StatusOK, &messages)
+	if len(messages) != 2 || messages[1].HTML != "<p>Done: <strong>parser</strong> fixed</p>\n" {
+		t.Fatalf("unexpected messages: %+v", messages)
+	}
+	get("/api/sessions/missing", http.StatusNotFound, nil)
+
+	var hits []serveSearchHit
+	get("/api/search?q=PARSER&limit=2", http.StatusOK, &hits)
+	if len(hits) != 2 || hits[0].ID != "r3" || hits[1].ID != "r2" {
+		t.Fatalf("unexpected hits: %+v", hits)
+	}
+	get("/api/search", http.StatusBadRequest, nil)
+	get("/api/search?q=x&limit=-1", http.StatusBadRequest, nil)
+
+	metricsResp, err := http.Get(server.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer metricsResp.Body.Close()
+	exposition, err := io.ReadAll(metricsResp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(exposition), `codex_history_http_requests_total{handler="session",code="404"} 1`) {
+		t.Fatalf("unexpected metrics:\n%s", exposition)
+	}
+
+	resp, err := http.Get(server.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	page, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(page), `<script src="app.js">`) {
+		t.Fatalf("unexpected index page (%d): %s", resp.StatusCode, page)
+	}
+}
+
+func TestHistoryServerToken(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	if err := appendRecords(path, []Record{{ID: "r1", SessionID: "s1", Timestamp: "2026-02-17T10:00:00Z", Role: "user", Text: "hi"}}, false); err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(newHistoryServer(path, "s3cret").routes())
+	defer server.Close()
+
+	status := func(url, authorization string) int {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodGet, server.URL+url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	cases := []struct {
+		url, authorization string
+		want               int
+	}{
+		{"/api/sessions", "", http.StatusUnauthorized},
+		{"/api/sessions", "Bearer wrong", http.StatusUnauthorized},
+		{"/api/search?q=hi", "s3cret", http.StatusUnauthorized},
+		{"/api/sessions", "Bearer s3cret", http.StatusOK},
+		{"/api/sessions/s1", "Bearer s3cret", http.StatusOK},
+		{"/", "", http.StatusOK},
+		{"/app.js", "", http.StatusOK},
+		{"/healthz", "", http.StatusOK},
+		{"/readyz", "", http.StatusOK},
+	}
+	for _, tc := range cases {
+		if got := status(tc.url, tc.authorization); got != tc.want {
+			t.Errorf("GET %s with %q: status %d, want %d", tc.url, tc.authorization, got, tc.want)
+		}
+	}
+}
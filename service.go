@@ -0,0 +1,299 @@
+package main
+
+import (
+	"encoding/xml"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+const (
+	systemdUnitName = "codex-history-watch.service"
+	launchdLabel    = "com.codex-history.watch"
+)
+
+// serviceEnvVars are passed from the installing shell into the service,
+// which does not see the login environment.
+var serviceEnvVars = []string{"CODEX_HOME", "CODEX_HISTORY_CONFIG"}
+
+// serviceSpec is what the installed service runs: the watch command line,
+// where its output goes when the manager does not collect it, and the
+// environment it needs.
+type serviceSpec struct {
+	Command []string
+	LogFile string
+	Env     [][2]string
+}
+
+// serviceManager is a per-user init system: systemd on Linux, launchd on
+// macOS. The commands run after the file is written (install), before it
+// is removed (uninstall), and for status.
+type serviceManager struct {
+	name      string
+	path      string
+	render    func(serviceSpec) string
+	install   [][]string
+	uninstall [][]string
+	status    []string
+}
+
+func serviceManagerFor(goos, home string) (serviceManager, error) {
+	switch goos {
+	case "linux":
+		configDir := os.Getenv("XDG_CONFIG_HOME")
+		if configDir == "" {
+			configDir = filepath.Join(home, ".config")
+		}
+		systemctl := func(args ...string) []string { return append([]string{"systemctl", "--user"}, args...) }
+		return serviceManager{
+			name:   "systemd",
+			path:   filepath.Join(configDir, "systemd", "user", systemdUnitName),
+			render: systemdUnit,
+			install: [][]string{
+				systemctl("daemon-reload"),
+				systemctl("enable", systemdUnitName),
+				// Starts the service, or restarts it with a changed unit.
+				systemctl("restart", systemdUnitName),
+			},
+			uninstall: [][]string{systemctl("disable", "--now", systemdUnitName)},
+			status:    systemctl("status", "--no-pager", systemdUnitName),
+		}, nil
+	case "darwin":
+		domain := "gui/" + strconv.Itoa(os.Getuid())
+		path := filepath.Join(home, "Library", "LaunchAgents", launchdLabel+".plist")
+		return serviceManager{
+			name:      "launchd",
+			path:      path,
+			render:    launchdPlist,
+			install:   [][]string{{"launchctl", "bootstrap", domain, path}},
+			uninstall: [][]string{{"launchctl", "bootout", domain + "/" + launchdLabel}},
+			status:    []string{"launchctl", "print", domain + "/" + launchdLabel},
+		}, nil
+	}
+	return serviceManager{}, fmt.Errorf("service is not supported on %s; run watch --daemon from a startup script instead", goos)
+}
+
+// currentServiceManager is the service manager of this machine and user.
+func currentServiceManager() (serviceManager, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return serviceManager{}, err
+	}
+	return serviceManagerFor(runtime.GOOS, home)
+}
+
+func runService(args []string) error {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return errors.New("service needs an action: install, uninstall, or status")
+	}
+	action := args[0]
+
+	fs := flag.NewFlagSet("service "+action, flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	var printOnly *bool
+	if action == "install" {
+		printOnly = fs.Bool("print", false, "Print the unit or plist instead of installing it")
+	}
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	manager, err := currentServiceManager()
+	if err != nil {
+		return err
+	}
+
+	switch action {
+	case "install":
+		spec, err := newServiceSpec(fs.Args())
+		if err != nil {
+			return err
+		}
+		if *printOnly {
+			fmt.Print(manager.render(spec))
+			return nil
+		}
+		return installService(manager, spec)
+	case "uninstall", "status":
+		if fs.NArg() > 0 {
+			return fmt.Errorf("unexpected argument %q", fs.Arg(0))
+		}
+		if _, err := os.Stat(manager.path); err != nil {
+			return fmt.Errorf("no service installed (%s not found)", manager.path)
+		}
+		if action == "status" {
+			fmt.Printf("installed=%s manager=%s\n", manager.path, manager.name)
+			return runServiceCommand(manager.status)
+		}
+		return uninstallService(manager)
+	}
+	return fmt.Errorf("unknown service action %q (want install, uninstall, or status)", action)
+}
+
+// newServiceSpec runs this binary's watch with watchArgs, carrying over
+// --codex-home and the environment variables that choose its paths.
+func newServiceSpec(watchArgs []string) (serviceSpec, error) {
+	for _, arg := range watchArgs {
+		name, _, _ := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if strings.HasPrefix(arg, "-") && (name == "daemon" || name == "status" || name == "stop") {
+			return serviceSpec{}, fmt.Errorf("%s cannot be used in a service: the service manager runs and stops watch", arg)
+		}
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return serviceSpec{}, err
+	}
+	if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+		exe = resolved
+	}
+	command := []string{exe}
+	if codexHomeOverride != "" {
+		command = append(command, "--codex-home", codexHomeOverride)
+	}
+	command = append(command, "watch")
+	command = append(command, watchArgs...)
+
+	spec := serviceSpec{Command: command, LogFile: defaultDaemonLogFile()}
+	for _, name := range serviceEnvVars {
+		if value := os.Getenv(name); value != "" {
+			spec.Env = append(spec.Env, [2]string{name, value})
+		}
+	}
+	return spec, nil
+}
+
+func installService(manager serviceManager, spec serviceSpec) error {
+	if err := os.MkdirAll(filepath.Dir(manager.path), 0o755); err != nil {
+		return err
+	}
+	if manager.name == "launchd" {
+		// bootstrap fails for a loaded agent, so unload the old one first.
+		if _, err := os.Stat(manager.path); err == nil {
+			for _, argv := range manager.uninstall {
+				exec.Command(argv[0], argv[1:]...).Run()
+			}
+		}
+	}
+	if err := writeFileAtomic(manager.path, []byte(manager.render(spec))); err != nil {
+		return err
+	}
+	fmt.Printf("wrote %s\n", manager.path)
+	for _, argv := range manager.install {
+		if err := runServiceCommand(argv); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("installed %s service: %s\n", manager.name, strings.Join(spec.Command, " "))
+	return nil
+}
+
+func uninstallService(manager serviceManager) error {
+	for _, argv := range manager.uninstall {
+		if err := runServiceCommand(argv); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+		}
+	}
+	if err := os.Remove(manager.path); err != nil {
+		return err
+	}
+	if manager.name == "systemd" {
+		runServiceCommand([]string{"systemctl", "--user", "daemon-reload"})
+	}
+	fmt.Printf("removed %s\n", manager.path)
+	return nil
+}
+
+func runServiceCommand(argv []string) error {
+	cmd := exec.Command(argv[0], argv[1:]...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s: %w", strings.Join(argv, " "), err)
+	}
+	return nil
+}
+
+// systemdUnit renders a user unit. Output goes to the journal:
+// journalctl --user -u codex-history-watch.
+func systemdUnit(spec serviceSpec) string {
+	var b strings.Builder
+	b.WriteString("[Unit]\nDescription=Record Codex conversations (codex-history watch)\n\n[Service]\n")
+	quoted := make([]string, len(spec.Command))
+	for i, arg := range spec.Command {
+		// ExecStart expands $VAR; Environment= does not.
+		quoted[i] = systemdQuote(strings.ReplaceAll(arg, "$", "$$"))
+	}
+	fmt.Fprintf(&b, "ExecStart=%s\n", strings.Join(quoted, " "))
+	for _, env := range spec.Env {
+		fmt.Fprintf(&b, "Environment=%s\n", systemdQuote(env[0]+"="+env[1]))
+	}
+	// watch re-reads the config on SIGHUP.
+	b.WriteString("ExecReload=/bin/kill -HUP $MAINPID\n")
+	b.WriteString("Restart=on-failure\nRestartSec=10\n\n[Install]\nWantedBy=default.target\n")
+	return b.String()
+}
+
+// systemdQuote quotes a unit file value when it needs it. systemd expands
+// % specifiers even inside quotes.
+func systemdQuote(arg string) string {
+	arg = strings.ReplaceAll(arg, "%", "%%")
+	if arg != "" && !strings.ContainsAny(arg, " \t\"'\\;") {
+		return arg
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(arg) + `"`
+}
+
+// launchdPlist renders a launch agent that starts at login and is
+// restarted when watch exits with an error.
+func launchdPlist(spec serviceSpec) string {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>Label</key>
+	<string>` + launchdLabel + `</string>
+	<key>ProgramArguments</key>
+	<array>
+`)
+	for _, arg := range spec.Command {
+		fmt.Fprintf(&b, "\t\t<string>%s</string>\n", xmlText(arg))
+	}
+	b.WriteString("\t</array>\n")
+	if len(spec.Env) > 0 {
+		b.WriteString("\t<key>EnvironmentVariables</key>\n\t<dict>\n")
+		for _, env := range spec.Env {
+			fmt.Fprintf(&b, "\t\t<key>%s</key>\n\t\t<string>%s</string>\n", xmlText(env[0]), xmlText(env[1]))
+		}
+		b.WriteString("\t</dict>\n")
+	}
+	fmt.Fprintf(&b, `	<key>RunAtLoad</key>
+	<true/>
+	<key>KeepAlive</key>
+	<dict>
+		<key>SuccessfulExit</key>
+		<false/>
+	</dict>
+	<key>ThrottleInterval</key>
+	<integer>10</integer>
+	<key>StandardOutPath</key>
+	<string>%s</string>
+	<key>StandardErrorPath</key>
+	<string>%s</string>
+</dict>
+</plist>
+`, xmlText(spec.LogFile), xmlText(spec.LogFile))
+	return b.String()
+}
+
+func xmlText(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
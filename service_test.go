@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSystemdUnit(t *testing.T) {
+	unit := systemdUnit(serviceSpec{
+		Command: []string{"/usr/local/bin/codex-history", "watch", "--out", "/home/x/My History/h.jsonl", "--interval", "30s", "--from", "50%$x"},
+		Env:     [][2]string{{"CODEX_HOME", "/home/x/.codex$1"}},
+	})
+	for _, want := range []string{
+		`ExecStart=/usr/local/bin/codex-history watch --out "/home/x/My History/h.jsonl" --interval 30s --from 50%%$$x` + "\n",
+		"Environment=CODEX_HOME=/home/x/.codex$1\n",
+		"ExecReload=/bin/kill -HUP $MAINPID\n",
+		"Restart=on-failure\n",
+		"WantedBy=default.target\n",
+	} {
+		if !strings.Contains(unit, want) {
+			t.Errorf("unit lacks %q:\n%s", want, unit)
+		}
+	}
+}
+
+func TestLaunchdPlist(t *testing.T) {
+	plist := launchdPlist(serviceSpec{
+		Command: []string{"/opt/codex-history", "watch", "--out", "/Users/x/a&b <h>.jsonl"},
+		LogFile: "/Users/x/.codex/conversation_history.watch.log",
+		Env:     [][2]string{{"CODEX_HOME", "/Users/x/.codex"}},
+	})
+	if !strings.Contains(plist, "<string>/Users/x/a&amp;b &lt;h&gt;.jsonl</string>") {
+		t.Fatalf("argument not escaped:\n%s", plist)
+	}
+	if !strings.Contains(plist, "<key>CODEX_HOME</key>") || !strings.Contains(plist, "<string>"+launchdLabel+"</string>") {
+		t.Fatalf("missing label or environment:\n%s", plist)
+	}
+	dec := xml.NewDecoder(strings.NewReader(plist))
+	for {
+		if _, err := dec.Token(); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			t.Fatalf("plist is not well-formed: %v\n%s", err, plist)
+		}
+	}
+}
+
+func TestServiceManagerFor(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", "")
+	linux, err := serviceManagerFor("linux", "/home/x")
+	if err != nil || linux.path != filepath.Join("/home/x", ".config", "systemd", "user", systemdUnitName) {
+		t.Fatalf("linux: %+v, %v", linux, err)
+	}
+	darwin, err := serviceManagerFor("darwin", "/Users/x")
+	if err != nil || darwin.path != filepath.Join("/Users/x", "Library", "LaunchAgents", launchdLabel+".plist") {
+		t.Fatalf("darwin: %+v, %v", darwin, err)
+	}
+	if _, err := serviceManagerFor("windows", `C:\Users\x`); err == nil {
+		t.Fatal("windows should be unsupported")
+	}
+}
+
+func TestNewServiceSpec(t *testing.T) {
+	if _, err := newServiceSpec([]string{"--interval", "30s", "--daemon"}); err == nil {
+		t.Fatal("--daemon should be rejected")
+	}
+	t.Setenv("CODEX_HOME", "/srv/codex")
+	t.Setenv("CODEX_HISTORY_CONFIG", "")
+	spec, err := newServiceSpec([]string{"--interval", "30s"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(spec.Command[1:], " "); got != "watch --interval 30s" {
+		t.Fatalf("command: %q", got)
+	}
+	if len(spec.Env) != 1 || spec.Env[0] != [2]string{"CODEX_HOME", "/srv/codex"} {
+		t.Fatalf("env: %v", spec.Env)
+	}
+}
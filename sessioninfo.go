@@ -0,0 +1,163 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// TokenUsage mirrors the total_token_usage object of Codex token_count
+// events. Codex reports running totals, so the last event of a session is
+// its usage.
+type TokenUsage struct {
+	InputTokens           int64 `json:"input_tokens"`
+	CachedInputTokens     int64 `json:"cached_input_tokens"`
+	OutputTokens          int64 `json:"output_tokens"`
+	ReasoningOutputTokens int64 `json:"reasoning_output_tokens"`
+	TotalTokens           int64 `json:"total_tokens"`
+}
+
+func (u *TokenUsage) add(other TokenUsage) {
+	u.InputTokens += other.InputTokens
+	u.CachedInputTokens += other.CachedInputTokens
+	u.OutputTokens += other.OutputTokens
+	u.ReasoningOutputTokens += other.ReasoningOutputTokens
+	u.TotalTokens += other.TotalTokens
+}
+
+// SessionInfo is what sync knows about a session beyond its records. It is
+// kept in a sidecar next to the history because it describes whole
+// sessions, not individual messages.
+type SessionInfo struct {
+	Usage *TokenUsage `json:"usage,omitempty"`
+	// Model, ApprovalPolicy, and SandboxMode come from the session's latest
+	// turn_context.
+	Model          string `json:"model,omitempty"`
+	ApprovalPolicy string `json:"approval_policy,omitempty"`
+	SandboxMode    string `json:"sandbox_mode,omitempty"`
+	// Cwd is the working directory from session_meta or the latest
+	// turn_context.
+	Cwd string `json:"cwd,omitempty"`
+	// FilesChanged is only collected with --include patches.
+	FilesChanged []FileChange `json:"files_changed,omitempty"`
+}
+
+func (s SessionInfo) empty() bool {
+	return s.Usage == nil && s.Model == "" && s.ApprovalPolicy == "" && s.SandboxMode == "" && s.Cwd == "" && len(s.FilesChanged) == 0
+}
+
+func sessionInfoPath(outputPath string) string {
+	return strings.TrimSuffix(outputPath, ".jsonl") + ".sessions.json"
+}
+
+func loadSessionInfo(path string) (map[string]SessionInfo, error) {
+	state := make(map[string]SessionInfo)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return state, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("invalid session info %s: %w", path, err)
+	}
+	return state, nil
+}
+
+func saveSessionInfo(path string, state map[string]SessionInfo) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, append(data, '\n'))
+}
+
+// mergeSessionInfo folds what one scan of a session file found into state.
+// Usage only grows within a session, so the larger total wins; this keeps a
+// rescan of an older copy of the file from rolling the numbers back.
+func mergeSessionInfo(state map[string]SessionInfo, sessionID string, found SessionInfo) {
+	current := state[sessionID]
+	if found.Usage != nil && (current.Usage == nil || found.Usage.TotalTokens >= current.Usage.TotalTokens) {
+		usage := *found.Usage
+		current.Usage = &usage
+	}
+	if found.Model != "" {
+		current.Model = found.Model
+	}
+	if found.ApprovalPolicy != "" {
+		current.ApprovalPolicy = found.ApprovalPolicy
+	}
+	if found.SandboxMode != "" {
+		current.SandboxMode = found.SandboxMode
+	}
+	if found.Cwd != "" {
+		current.Cwd = found.Cwd
+	}
+	// Each scan sees the whole session file, so its file list replaces the
+	// previous one rather than adding to it.
+	if len(found.FilesChanged) > 0 {
+		current.FilesChanged = found.FilesChanged
+	}
+	if !current.empty() {
+		state[sessionID] = current
+	}
+}
+
+// sumUsage totals the usage of the given sessions, or returns nil when none
+// of them has usage recorded.
+func sumUsage(state map[string]SessionInfo, sessions map[string]struct{}) *TokenUsage {
+	var total *TokenUsage
+	for sessionID := range sessions {
+		info, ok := state[sessionID]
+		if !ok || info.Usage == nil {
+			continue
+		}
+		if total == nil {
+			total = &TokenUsage{}
+		}
+		total.add(*info.Usage)
+	}
+	return total
+}
+
+// attachSessionInfo copies sidecar data onto the matching summaries.
+func attachSessionInfo(summaries []SessionSummary, state map[string]SessionInfo) {
+	for i := range summaries {
+		info, ok := state[summaries[i].SessionID]
+		if !ok {
+			continue
+		}
+		summaries[i].Usage = info.Usage
+		summaries[i].Model = info.Model
+		summaries[i].ApprovalPolicy = info.ApprovalPolicy
+		summaries[i].SandboxMode = info.SandboxMode
+		if info.Cwd != "" {
+			summaries[i].Cwd = info.Cwd
+		}
+		summaries[i].FilesChanged = info.FilesChanged
+	}
+}
+
+// sandboxMode reads the mode from a turn_context sandbox_policy, which is an
+// object with a "mode" field in current Codex and a bare string in older
+// releases.
+func sandboxMode(raw json.RawMessage) string {
+	if len(raw) == 0 {
+		return ""
+	}
+	var mode string
+	if err := json.Unmarshal(raw, &mode); err == nil {
+		return mode
+	}
+	var policy struct {
+		Mode string `json:"mode"`
+	}
+	if err := json.Unmarshal(raw, &policy); err == nil {
+		return policy.Mode
+	}
+	return ""
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSyncRecordsTokenUsage(t *testing.T) {
+	root := t.TempDir()
+	path := writeSessionFile(t,
+		`{"timestamp":"2026-02-17T12:00:00Z","type":"session_meta","payload":{"id":"s1"}}`,
+		`{"timestamp":"2026-02-17T12:00:01Z","type":"event_msg","payload":{"type":"user_message","message":"hi"}}`,
+		`{"timestamp":"2026-02-17T12:00:02Z","type":"event_msg","payload":{"type":"token_count","info":{"total_token_usage":{"input_tokens":100,"cached_input_tokens":40,"output_tokens":10,"total_tokens":110}}}}`,
+		`{"timestamp":"2026-02-17T12:00:03Z","type":"event_msg","payload":{"type":"token_count","info":null}}`,
+		`{"timestamp":"2026-02-17T12:00:04Z","type":"event_msg","payload":{"type":"token_count","info":{"total_token_usage":{"input_tokens":300,"cached_input_tokens":200,"output_tokens":30,"total_tokens":330}}}}`,
+	)
+	outPath := filepath.Join(root, "history.jsonl")
+	if _, err := syncOnce(SyncOptions{SessionsDir: filepath.Dir(path), OutputPath: outPath}); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := loadSessionInfo(sessionInfoPath(outPath))
+	if err != nil {
+		t.Fatal(err)
+	}
+	usage := info["s1"].Usage
+	if usage == nil || usage.InputTokens != 300 || usage.CachedInputTokens != 200 || usage.TotalTokens != 330 {
+		t.Fatalf("unexpected usage: %+v", usage)
+	}
+
+	// An older, smaller total never replaces a larger one.
+	mergeSessionInfo(info, "s1", SessionInfo{Usage: &TokenUsage{TotalTokens: 5}})
+	if info["s1"].Usage.TotalTokens != 330 {
+		t.Fatalf("usage rolled back: %+v", info["s1"].Usage)
+	}
+
+	total := sumUsage(info, map[string]struct{}{"s1": {}, "missing": {}})
+	if total == nil || total.OutputTokens != 30 {
+		t.Fatalf("unexpected total: %+v", total)
+	}
+	if sumUsage(info, map[string]struct{}{"missing": {}}) != nil {
+		t.Fatal("expected nil usage for sessions without data")
+	}
+}
+
+func TestExtractTurnContextModel(t *testing.T) {
+	path := writeSessionFile(t,
+		`{"timestamp":"2026-02-17T12:00:00Z","type":"session_meta","payload":{"id":"s1"}}`,
+		`{"timestamp":"2026-02-17T12:00:01Z","type":"event_msg","payload":{"type":"user_message","message":"before any context"}}`,
+		`{"timestamp":"2026-02-17T12:00:02Z","type":"turn_context","payload":{"model":"gpt-5-codex","approval_policy":"on-request","sandbox_policy":{"mode":"workspace-write"}}}`,
+		`{"timestamp":"2026-02-17T12:00:03Z","type":"event_msg","payload":{"type":"user_message","message":"first"}}`,
+		`{"timestamp":"2026-02-17T12:00:04Z","type":"turn_context","payload":{"model":"o4-mini","sandbox_policy":"read-only"}}`,
+		`{"timestamp":"2026-02-17T12:00:05Z","type":"event_msg","payload":{"type":"agent_message","message":"second"}}`,
+	)
+
+	session, err := extractSession(path, ExtractOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	records := session.Records
+	if len(records) != 3 || records[0].Meta["model"] != "" || records[1].Meta["model"] != "gpt-5-codex" || records[2].Meta["model"] != "o4-mini" {
+		t.Fatalf("unexpected model tags: %#v", records)
+	}
+	want := SessionInfo{Model: "o4-mini", ApprovalPolicy: "on-request", SandboxMode: "read-only"}
+	if !reflect.DeepEqual(session.Info, want) {
+		t.Fatalf("got %+v, want %+v", session.Info, want)
+	}
+
+	match := newRecordMatcher(RecordFilter{Model: "GPT-5-codex"})
+	if match(records[0]) || !match(records[1]) || match(records[2]) {
+		t.Fatal("unexpected --model matches")
+	}
+}
+
+func TestExtractSessionCwd(t *testing.T) {
+	path := writeSessionFile(t,
+		`{"timestamp":"2026-02-17T12:00:00Z","type":"session_meta","payload":{"id":"s1","cwd":"/home/me/api"}}`,
+		`{"timestamp":"2026-02-17T12:00:01Z","type":"event_msg","payload":{"type":"user_message","message":"first"}}`,
+		`{"timestamp":"2026-02-17T12:00:02Z","type":"turn_context","payload":{"cwd":"/home/me/api/server"}}`,
+		`{"timestamp":"2026-02-17T12:00:03Z","type":"event_msg","payload":{"type":"agent_message","message":"second"}}`,
+	)
+
+	session, err := extractSession(path, ExtractOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	records := session.Records
+	if len(records) != 2 || records[0].Meta["cwd"] != "/home/me/api" || records[1].Meta["cwd"] != "/home/me/api/server" {
+		t.Fatalf("unexpected cwd tags: %#v", records)
+	}
+	if session.Info.Cwd != "/home/me/api/server" {
+		t.Fatalf("got cwd %q", session.Info.Cwd)
+	}
+
+	for _, tc := range []struct {
+		project string
+		first   bool
+		second  bool
+	}{
+		{"/home/me/api", true, true},
+		{"/home/me/api/", true, true},
+		{"/home/me/api/server", false, true},
+		{"/home/me/ap", false, false},
+		{"API", true, true},
+		{"server", false, true},
+	} {
+		match := newRecordMatcher(RecordFilter{Project: tc.project})
+		if match(records[0]) != tc.first || match(records[1]) != tc.second {
+			t.Errorf("--project %q: got %v %v", tc.project, match(records[0]), match(records[1]))
+		}
+	}
+}
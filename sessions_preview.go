@@ -0,0 +1,17 @@
+package main
+
+import "strings"
+
+// sessionPreviewChars is how much of the first user message sessions shows.
+const sessionPreviewChars = 60
+
+// sessionPreview collapses whitespace in text to single spaces and cuts it
+// to sessionPreviewChars runes, marking the cut with "…".
+func sessionPreview(text string) string {
+	preview := strings.Join(strings.Fields(text), " ")
+	runes := []rune(preview)
+	if len(runes) <= sessionPreviewChars {
+		return preview
+	}
+	return strings.TrimSpace(string(runes[:sessionPreviewChars])) + "…"
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSessionPreview(t *testing.T) {
+	if got := sessionPreview("  fix the\n\tflaky   test "); got != "fix the flaky test" {
+		t.Fatalf("got %q", got)
+	}
+	long := strings.Repeat("é", sessionPreviewChars+5)
+	if got := sessionPreview(long); got != strings.Repeat("é", sessionPreviewChars)+"…" {
+		t.Fatalf("got %q", got)
+	}
+}
+
+func TestSessionAccumulatorPreview(t *testing.T) {
+	acc := newSessionAccumulator()
+	for _, record := range []Record{
+		{SessionID: "s1", Timestamp: "2026-02-17T10:02:00Z", Role: "user", Text: "second prompt"},
+		{SessionID: "s1", Timestamp: "2026-02-17T10:00:00Z", Role: "assistant", Text: "hello"},
+		{SessionID: "s1", Timestamp: "2026-02-17T10:01:00Z", Role: "user", Text: "first prompt"},
+		{SessionID: "s2", Timestamp: "2026-02-17T11:00:00Z", Role: "assistant", Text: "no prompt"},
+	} {
+		acc.add(record)
+	}
+	for _, summary := range acc.summaries() {
+		want := map[string]string{"s1": "first prompt", "s2": ""}[summary.SessionID]
+		if summary.Preview != want {
+			t.Fatalf("%s: got preview %q, want %q", summary.SessionID, summary.Preview, want)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// unknownProject groups sessions with no known working directory.
+const unknownProject = "unknown"
+
+// ProjectSummary aggregates the sessions that ran in one working directory,
+// for sessions --group-by project.
+type ProjectSummary struct {
+	Project        string `json:"project"`
+	Sessions       int    `json:"sessions"`
+	Total          int    `json:"total"`
+	User           int    `json:"user"`
+	Assistant      int    `json:"assistant"`
+	Other          int    `json:"other"`
+	Errors         int    `json:"errors,omitempty"`
+	Tokens         int64  `json:"tokens,omitempty"`
+	FirstTimestamp string `json:"first_timestamp,omitempty"`
+	LastTimestamp  string `json:"last_timestamp,omitempty"`
+}
+
+// groupSessionsByProject folds session summaries into one summary per
+// working directory, most recently active first.
+func groupSessionsByProject(summaries []SessionSummary) []ProjectSummary {
+	var projects []ProjectSummary
+	index := make(map[string]int)
+	for _, summary := range summaries {
+		name := firstNonEmpty(summary.Cwd, unknownProject)
+		i, ok := index[name]
+		if !ok {
+			i = len(projects)
+			index[name] = i
+			projects = append(projects, ProjectSummary{Project: name})
+		}
+		project := &projects[i]
+		project.Sessions++
+		project.Total += summary.Total
+		project.User += summary.User
+		project.Assistant += summary.Assistant
+		project.Other += summary.Other
+		project.Errors += summary.Errors
+		if summary.Usage != nil {
+			project.Tokens += summary.Usage.TotalTokens
+		}
+		project.FirstTimestamp = earlierTimestamp(project.FirstTimestamp, summary.FirstTimestamp)
+		project.LastTimestamp = laterTimestamp(project.LastTimestamp, summary.LastTimestamp)
+	}
+	sort.SliceStable(projects, func(i, j int) bool {
+		return compareTimestamp(projects[i].LastTimestamp, projects[j].LastTimestamp) > 0
+	})
+	return projects
+}
+
+func projectSummaryLine(project ProjectSummary) string {
+	line := fmt.Sprintf("%s sessions=%d total=%d user=%d assistant=%d other=%d first=%s last=%s",
+		project.Project,
+		project.Sessions,
+		project.Total,
+		project.User,
+		project.Assistant,
+		project.Other,
+		project.FirstTimestamp,
+		project.LastTimestamp,
+	)
+	if project.Tokens > 0 {
+		line += fmt.Sprintf(" tokens=%d", project.Tokens)
+	}
+	if project.Errors > 0 {
+		line += fmt.Sprintf(" errors=%d", project.Errors)
+	}
+	return line
+}
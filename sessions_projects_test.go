@@ -0,0 +1,25 @@
+package main
+
+import "testing"
+
+func TestGroupSessionsByProject(t *testing.T) {
+	summaries := []SessionSummary{
+		{SessionID: "a", Cwd: "/src/api", Total: 4, User: 2, Assistant: 2, FirstTimestamp: "2026-02-17T10:00:00Z", LastTimestamp: "2026-02-17T10:05:00Z", Usage: &TokenUsage{TotalTokens: 100}},
+		{SessionID: "b", Total: 1, Other: 1, Errors: 1, FirstTimestamp: "2026-02-18T08:00:00Z", LastTimestamp: "2026-02-18T08:00:00Z"},
+		{SessionID: "c", Cwd: "/src/api", Total: 6, User: 3, Assistant: 3, FirstTimestamp: "2026-02-15T08:00:00Z", LastTimestamp: "2026-02-15T09:00:00Z", Usage: &TokenUsage{TotalTokens: 50}},
+	}
+
+	projects := groupSessionsByProject(summaries)
+	if len(projects) != 2 || projects[0].Project != unknownProject || projects[1].Project != "/src/api" {
+		t.Fatalf("unexpected projects: %+v", projects)
+	}
+	api := projects[1]
+	if api.Sessions != 2 || api.Total != 10 || api.User != 5 || api.Tokens != 150 ||
+		api.FirstTimestamp != "2026-02-15T08:00:00Z" || api.LastTimestamp != "2026-02-17T10:05:00Z" {
+		t.Fatalf("unexpected totals: %+v", api)
+	}
+	want := "/src/api sessions=2 total=10 user=5 assistant=5 other=0 first=2026-02-15T08:00:00Z last=2026-02-17T10:05:00Z tokens=150"
+	if got := projectSummaryLine(api); got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// sessionRankings are the measures sessions --top-by can rank by.
+var sessionRankings = map[string]func(SessionSummary) int64{
+	"total":     func(s SessionSummary) int64 { return int64(s.Total) },
+	"assistant": func(s SessionSummary) int64 { return int64(s.Assistant) },
+	"chars":     func(s SessionSummary) int64 { return int64(s.Chars) },
+	"duration":  func(s SessionSummary) int64 { return s.DurationSeconds },
+}
+
+// rankSessions orders summaries by the measure named by, largest first.
+// Ties keep their existing order, most recently active first.
+func rankSessions(summaries []SessionSummary, by string) error {
+	measure, ok := sessionRankings[strings.ToLower(strings.TrimSpace(by))]
+	if !ok {
+		return fmt.Errorf("unsupported --top-by %q (use total, assistant, chars, or duration)", by)
+	}
+	sort.SliceStable(summaries, func(i, j int) bool { return measure(summaries[i]) > measure(summaries[j]) })
+	return nil
+}
+
+// sessionDuration is the time between a session's first and last record,
+// or zero when either timestamp does not parse.
+func sessionDuration(summary SessionSummary) time.Duration {
+	first, okFirst := parseRecordTime(summary.FirstTimestamp)
+	last, okLast := parseRecordTime(summary.LastTimestamp)
+	if !okFirst || !okLast || last.Before(first) {
+		return 0
+	}
+	return last.Sub(first)
+}
+
+// formatSessionDuration prints seconds compactly, to the minute once a
+// session runs past an hour: 45s, 12m30s, 2h5m.
+func formatSessionDuration(seconds int64) string {
+	d := time.Duration(seconds) * time.Second
+	if d >= time.Hour {
+		d = d.Round(time.Minute)
+		return strings.TrimSuffix(d.String(), "0s")
+	}
+	return d.String()
+}
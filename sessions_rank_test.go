@@ -0,0 +1,53 @@
+package main
+
+import "testing"
+
+func TestRankSessions(t *testing.T) {
+	summaries := []SessionSummary{
+		{SessionID: "recent", Total: 5, Assistant: 2, Chars: 900, FirstTimestamp: "2026-02-17T10:00:00Z", LastTimestamp: "2026-02-17T10:05:00Z"},
+		{SessionID: "long", Total: 5, Assistant: 1, Chars: 100, FirstTimestamp: "2026-02-16T08:00:00Z", LastTimestamp: "2026-02-16T12:00:00Z"},
+		{SessionID: "big", Total: 40, Assistant: 15, Chars: 300, FirstTimestamp: "2026-02-15T08:00:00Z", LastTimestamp: "2026-02-15T08:30:00Z"},
+	}
+	for i := range summaries {
+		summaries[i].DurationSeconds = int64(sessionDuration(summaries[i]).Seconds())
+	}
+	order := func() string {
+		ids := ""
+		for _, summary := range summaries {
+			ids += summary.SessionID + " "
+		}
+		return ids
+	}
+	cases := map[string]string{
+		"total":     "big recent long ",
+		"chars":     "recent big long ",
+		"duration":  "long big recent ",
+		"assistant": "big recent long ",
+	}
+	for by, want := range cases {
+		if err := rankSessions(summaries, by); err != nil {
+			t.Fatal(err)
+		}
+		if got := order(); got != want {
+			t.Fatalf("--top-by %s: got %q, want %q", by, got, want)
+		}
+	}
+	if err := rankSessions(summaries, "tokens"); err == nil {
+		t.Fatal("expected an error for an unknown measure")
+	}
+}
+
+func TestFormatSessionDuration(t *testing.T) {
+	cases := map[int64]string{
+		0:    "0s",
+		45:   "45s",
+		750:  "12m30s",
+		7530: "2h6m",
+		7200: "2h0m",
+	}
+	for seconds, want := range cases {
+		if got := formatSessionDuration(seconds); got != want {
+			t.Errorf("formatSessionDuration(%d) = %q, want %q", seconds, got, want)
+		}
+	}
+}
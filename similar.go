@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+)
+
+// similarSession is one result of the similar command.
+type similarSession struct {
+	SessionID      string  `json:"session_id"`
+	Score          float64 `json:"score"`
+	FirstTimestamp string  `json:"first_timestamp,omitempty"`
+	Title          string  `json:"title,omitempty"`
+}
+
+func runSimilar(args []string) error {
+	fs := flag.NewFlagSet("similar", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+
+	inputPath := fs.String("in", defaultOutputFile(), "Input JSONL path")
+	sessionID := fs.String("session", "", "Session to compare against (ID or unique prefix)")
+	limit := fs.Int("limit", 10, "Maximum sessions to print, 0 means all")
+	jsonOut := fs.Bool("json", false, "Print as JSON")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if strings.TrimSpace(*sessionID) == "" {
+		return errors.New("--session is required")
+	}
+	if *limit < 0 {
+		return errors.New("--limit must be >= 0")
+	}
+
+	records, err := loadRecords(*inputPath)
+	if err != nil {
+		return err
+	}
+	sortRecordsChronological(records)
+	sessions := groupSessions(records)
+	target, err := resolveSession(sessions, strings.TrimSpace(*sessionID))
+	if err != nil {
+		return err
+	}
+
+	results := rankSimilarSessions(sessions, target)
+	if *limit > 0 && len(results) > *limit {
+		results = results[:*limit]
+	}
+
+	if *jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetEscapeHTML(false)
+		return enc.Encode(results)
+	}
+	for _, result := range results {
+		fmt.Printf("%.3f %s %s %s\n", result.Score, result.SessionID, result.FirstTimestamp, result.Title)
+	}
+	return nil
+}
+
+// resolveSession finds a session by exact ID or by a unique ID prefix, such
+// as the short IDs show prints.
+func resolveSession(sessions [][]Record, id string) (int, error) {
+	found := -1
+	for i, session := range sessions {
+		if session[0].SessionID == id {
+			return i, nil
+		}
+		if strings.HasPrefix(session[0].SessionID, id) {
+			if found >= 0 {
+				return 0, fmt.Errorf("session prefix %q is ambiguous", id)
+			}
+			found = i
+		}
+	}
+	if found < 0 {
+		return 0, fmt.Errorf("session %q not found", id)
+	}
+	return found, nil
+}
+
+// rankSimilarSessions compares every session with sessions[target] by the
+// cosine similarity of their TF-IDF weighted words, best first. Only user
+// and assistant messages count; tool output is mostly file contents and
+// command noise that would make unrelated sessions look alike.
+func rankSimilarSessions(sessions [][]Record, target int) []similarSession {
+	termCounts := make([]map[string]float64, len(sessions))
+	docFreq := make(map[string]int)
+	for i, session := range sessions {
+		counts := make(map[string]float64)
+		for _, record := range session {
+			if record.Role != "user" && record.Role != "assistant" {
+				continue
+			}
+			for _, word := range searchWords(record.Text) {
+				if len([]rune(word)) > 1 {
+					counts[word]++
+				}
+			}
+		}
+		for word := range counts {
+			docFreq[word]++
+		}
+		termCounts[i] = counts
+	}
+
+	weights := make([]map[string]float64, len(sessions))
+	for i, counts := range termCounts {
+		weights[i] = make(map[string]float64, len(counts))
+		for word, count := range counts {
+			idf := math.Log(float64(len(sessions)) / float64(docFreq[word]))
+			weights[i][word] = (1 + math.Log(count)) * idf
+		}
+	}
+
+	var results []similarSession
+	for i, session := range sessions {
+		if i == target {
+			continue
+		}
+		score := sparseCosine(weights[target], weights[i])
+		if score <= 0 {
+			continue
+		}
+		results = append(results, similarSession{
+			SessionID:      session[0].SessionID,
+			Score:          score,
+			FirstTimestamp: session[0].Timestamp,
+			Title:          sessionTitle(session, 80),
+		})
+	}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Score > results[j].Score })
+	return results
+}
+
+func sparseCosine(a, b map[string]float64) float64 {
+	var dot, normA, normB float64
+	for word, weight := range a {
+		dot += weight * b[word]
+		normA += weight * weight
+	}
+	for _, weight := range b {
+		normB += weight * weight
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
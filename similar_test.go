@@ -0,0 +1,34 @@
+package main
+
+import "testing"
+
+func TestRankSimilarSessions(t *testing.T) {
+	sessions := groupSessions([]Record{
+		{SessionID: "target-1", Timestamp: "2026-02-17T10:00:00Z", Role: "user", Text: "the scheduler deadlocks when the worker pool drains"},
+		{SessionID: "target-1", Timestamp: "2026-02-17T10:01:00Z", Role: "tool", Text: "readme readme readme"},
+		{SessionID: "sched-2", Timestamp: "2026-01-10T10:00:00Z", Role: "user", Text: "scheduler worker pool starves under load"},
+		{SessionID: "docs-3", Timestamp: "2026-01-11T10:00:00Z", Role: "user", Text: "update the readme"},
+		{SessionID: "docs-4", Timestamp: "2026-01-12T10:00:00Z", Role: "assistant", Text: "the readme now lists the flags"},
+	})
+
+	target, err := resolveSession(sessions, "target")
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := rankSimilarSessions(sessions, target)
+	if len(results) != 3 || results[0].SessionID != "sched-2" || results[0].Title != "scheduler worker pool starves under load" {
+		t.Fatalf("unexpected results: %+v", results)
+	}
+	// The tool output mentioning the readme is ignored, so the docs
+	// sessions only share "the".
+	if results[1].Score > results[0].Score/4 {
+		t.Fatalf("docs sessions ranked too close: %+v", results)
+	}
+
+	if _, err := resolveSession(sessions, "docs"); err == nil {
+		t.Fatal("expected an ambiguous prefix error")
+	}
+	if _, err := resolveSession(sessions, "missing"); err == nil {
+		t.Fatal("expected a not found error")
+	}
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// siteSession is one session as listed on the site index.
+type siteSession struct {
+	ID       string
+	Title    string
+	Project  string
+	First    string
+	Time     string
+	URL      string
+	Messages []htmlMessage
+}
+
+type siteDay struct {
+	Date     string
+	Sessions []siteSession
+}
+
+// siteSearchEntry is one message in search.json.
+type siteSearchEntry struct {
+	SessionID string `json:"session_id"`
+	URL       string `json:"url"`
+	Title     string `json:"title"`
+	Timestamp string `json:"timestamp"`
+	Role      string `json:"role"`
+	Text      string `json:"text"`
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// sessionFileName turns a session ID into a safe file name stem.
+func sessionFileName(sessionID string) string {
+	name := strings.Trim(unsafeFileChars.ReplaceAllString(sessionID, "_"), "._")
+	if name == "" {
+		return "session"
+	}
+	return name
+}
+
+// sessionProject names the project a session ran in, from the meta that
+// importers record, or "" when unknown.
+func sessionProject(records []Record) string {
+	for _, record := range records {
+		if project := record.Meta["project"]; project != "" {
+			return project
+		}
+		if cwd := record.Meta["cwd"]; cwd != "" {
+			return filepath.Base(cwd)
+		}
+	}
+	return ""
+}
+
+func runSite(args []string) error {
+	fs := flag.NewFlagSet("site", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+
+	inputPath := fs.String("in", defaultOutputFile(), "Input JSONL path")
+	outDir := fs.String("out", "", "Directory to write the site into")
+	from := fs.String("from", "", "Only include records at/after this RFC3339 timestamp")
+	to := fs.String("to", "", "Only include records at/before this RFC3339 timestamp")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if strings.TrimSpace(*outDir) == "" {
+		return errors.New("--out is required")
+	}
+	fromTime, err := parseBoundTime(*from, "--from")
+	if err != nil {
+		return err
+	}
+	toTime, err := parseBoundTime(*to, "--to")
+	if err != nil {
+		return err
+	}
+	if err := validateTimeRange(fromTime, toTime); err != nil {
+		return err
+	}
+
+	records, err := loadRecords(*inputPath)
+	if err != nil {
+		return err
+	}
+	records = filterRecords(records, RecordFilter{From: fromTime, To: toTime})
+	sortRecordsChronological(records)
+
+	sessions, err := writeSite(*outDir, records)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("wrote %d sessions (%d records) to %s\n", sessions, len(records), *outDir)
+	return nil
+}
+
+// writeSite generates index.html (sessions grouped by day, newest first),
+// one page per session under sessions/, and search.json, which the index
+// loads for full-text search. Links are relative, so the directory can be
+// served from any path.
+func writeSite(dir string, records []Record) (int, error) {
+	if err := os.MkdirAll(filepath.Join(dir, "sessions"), 0o755); err != nil {
+		return 0, err
+	}
+
+	var days []siteDay
+	var search []siteSearchEntry
+	used := make(map[string]bool)
+	for _, group := range groupSessions(records) {
+		name := sessionFileName(group[0].SessionID)
+		for base, n := name, 2; used[name]; n++ {
+			name = fmt.Sprintf("%s-%d", base, n)
+		}
+		used[name] = true
+
+		session := siteSession{
+			ID:      group[0].SessionID,
+			Title:   sessionTitle(group, 120),
+			Project: sessionProject(group),
+			First:   group[0].Timestamp,
+			URL:     "sessions/" + name + ".html",
+		}
+		date := "unknown date"
+		if t, ok := parseRecordTime(session.First); ok {
+			date = t.UTC().Format("2006-01-02")
+			session.Time = t.UTC().Format("15:04")
+		}
+		for _, record := range group {
+			session.Messages = append(session.Messages, htmlMessage{Role: record.Role, Timestamp: record.Timestamp, Body: messageHTML(record.Text)})
+			search = append(search, siteSearchEntry{
+				SessionID: session.ID,
+				URL:       session.URL,
+				Title:     session.Title,
+				Timestamp: record.Timestamp,
+				Role:      record.Role,
+				Text:      record.Text,
+			})
+		}
+
+		if err := writeSiteTemplate(filepath.Join(dir, session.URL), siteSessionTemplate, session); err != nil {
+			return 0, err
+		}
+		session.Messages = nil
+
+		if len(days) == 0 || days[len(days)-1].Date != date {
+			days = append(days, siteDay{Date: date})
+		}
+		days[len(days)-1].Sessions = append(days[len(days)-1].Sessions, session)
+	}
+	reverseDays(days)
+
+	index := map[string]any{
+		"Generated": time.Now().UTC().Format(time.RFC3339),
+		"Days":      days,
+		"Sessions":  len(used),
+		"Records":   len(records),
+	}
+	if err := writeSiteTemplate(filepath.Join(dir, "index.html"), siteIndexTemplate, index); err != nil {
+		return 0, err
+	}
+
+	data, err := json.Marshal(search)
+	if err != nil {
+		return 0, err
+	}
+	if err := writeFileAtomic(filepath.Join(dir, "search.json"), data); err != nil {
+		return 0, err
+	}
+	return len(used), nil
+}
+
+// reverseDays puts the newest day first, keeping sessions within a day in
+// chronological order.
+func reverseDays(days []siteDay) {
+	for left, right := 0, len(days)-1; left < right; left, right = left+1, right-1 {
+		days[left], days[right] = days[right], days[left]
+	}
+}
+
+func writeSiteTemplate(path string, tmpl *template.Template, data any) error {
+	var out strings.Builder
+	if err := tmpl.Execute(&out, data); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, []byte(out.String()))
+}
+
+var siteTemplates = template.Must(template.New("site").Funcs(template.FuncMap{
+	"style": func() template.CSS { return htmlStyle },
+}).Parse(`{{define "head"}}<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.}}</title>
+<style>{{style}}
+table { width: 100%; border-collapse: collapse; background: #fff; }
+td { padding: .3rem .5rem; border-bottom: 1px solid #d0d7de; vertical-align: top; }
+.project { color: #0969da; font-size: .85em; }
+</style>
+</head>
+<body>
+{{end}}`))
+
+var siteIndexTemplate = template.Must(template.Must(siteTemplates.Clone()).New("index").Parse(`{{template "head" "Codex History"}}<header>
+<h1>Codex History</h1>
+<p class="meta">Generated {{.Generated}} &middot; {{.Sessions}} sessions &middot; {{.Records}} records</p>
+<input id="search" type="search" placeholder="Search all messages">
+<div id="results"></div>
+</header>
+<main id="index">
+{{range .Days}}<h2>{{.Date}}</h2>
+<table>
+{{range .Sessions}}<tr><td class="meta">{{.Time}}</td><td><a href="{{.URL}}">{{if .Title}}{{.Title}}{{else}}{{.ID}}{{end}}</a>{{if .Project}} <span class="project">{{.Project}}</span>{{end}}</td></tr>
+{{end}}</table>
+{{else}}<p><em>No records.</em></p>
+{{end}}</main>
+<script>
+var entries = null;
+var input = document.getElementById("search");
+var results = document.getElementById("results");
+function render() {
+  var query = input.value.toLowerCase().trim();
+  document.getElementById("index").classList.toggle("hidden", query !== "");
+  results.innerHTML = "";
+  if (!query || !entries) return;
+  entries.filter(function (e) { return e.text.toLowerCase().indexOf(query) >= 0; }).slice(0, 200).forEach(function (e) {
+    var row = document.createElement("div");
+    row.className = "message " + e.role;
+    var link = document.createElement("a");
+    link.href = e.url;
+    link.textContent = e.title || e.session_id;
+    var meta = document.createElement("div");
+    meta.className = "meta";
+    meta.textContent = e.role + " · " + e.timestamp;
+    var text = document.createElement("div");
+    text.className = "text";
+    text.textContent = e.text.length > 400 ? e.text.slice(0, 400) + "…" : e.text;
+    meta.prepend(link, " · ");
+    row.append(meta, text);
+    results.append(row);
+  });
+}
+input.addEventListener("input", function () {
+  if (entries) return render();
+  fetch("search.json").then(function (r) { return r.json(); }).then(function (data) { entries = data; render(); });
+});
+</script>
+</body>
+</html>
+`))
+
+var siteSessionTemplate = template.Must(template.Must(siteTemplates.Clone()).New("session").Parse(`{{template "head" .ID}}<p><a href="../index.html">&larr; All sessions</a></p>
+<h1>{{if .Title}}{{.Title}}{{else}}{{.ID}}{{end}}</h1>
+<p class="meta">{{.ID}}{{if .Project}} &middot; {{.Project}}{{end}} &middot; {{.First}}</p>
+<div class="messages">
+{{range .Messages}}<div class="message {{.Role}}"><div class="meta">{{.Role}} &middot; {{.Timestamp}}</div>{{.Body}}</div>
+{{end}}</div>
+</body>
+</html>
+`))
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteSite(t *testing.T) {
+	records := []Record{
+		{ID: "1", SessionID: "s/1", Timestamp: "2026-02-16T10:00:00Z", Role: "user", Text: "first day", Meta: map[string]string{"cwd": "/home/me/app"}},
+		{ID: "2", SessionID: "s/1", Timestamp: "2026-02-16T10:00:05Z", Role: "assistant", Text: "ok"},
+		{ID: "3", SessionID: "s2", Timestamp: "2026-02-17T09:00:00Z", Role: "user", Text: "second day"},
+	}
+	dir := t.TempDir()
+	sessions, err := writeSite(dir, records)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sessions != 2 {
+		t.Fatalf("expected 2 sessions, got %d", sessions)
+	}
+
+	index, err := os.ReadFile(filepath.Join(dir, "index.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	page := string(index)
+	newer, older := strings.Index(page, "2026-02-17"), strings.Index(page, "2026-02-16")
+	if newer < 0 || older < 0 || newer > older {
+		t.Fatal("expected days listed newest first")
+	}
+	if !strings.Contains(page, `<a href="sessions/s_1.html">first day</a> <span class="project">app</span>`) {
+		t.Fatal("index is missing the session link with its project")
+	}
+
+	session, err := os.ReadFile(filepath.Join(dir, "sessions", "s_1.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(session), `<div class="message assistant">`) {
+		t.Fatal("session page is missing its messages")
+	}
+
+	var search []siteSearchEntry
+	data, err := os.ReadFile(filepath.Join(dir, "search.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, &search); err != nil {
+		t.Fatal(err)
+	}
+	if len(search) != 3 || search[2].URL != "sessions/s2.html" || search[2].Text != "second day" {
+		t.Fatalf("unexpected search index: %+v", search)
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SourceState is what sync remembers about one session file between runs.
+type SourceState struct {
+	Records   int    `json:"records"`
+	ScannedAt string `json:"scanned_at"`
+	Error     string `json:"error,omitempty"`
+}
+
+type SourceReport struct {
+	Path        string `json:"path"`
+	Exists      bool   `json:"exists"`
+	Records     int    `json:"records"`
+	InHistory   int    `json:"in_history"`
+	LastScanned string `json:"last_scanned,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+// sourcesStatePath keeps the per-file scan state next to the history it
+// describes, so separate histories never share state.
+func sourcesStatePath(outputPath string) string {
+	return strings.TrimSuffix(outputPath, ".jsonl") + ".sources.json"
+}
+
+func loadSourcesState(path string) (map[string]SourceState, error) {
+	state := make(map[string]SourceState)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return state, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("invalid sources state %s: %w", path, err)
+	}
+	return state, nil
+}
+
+func saveSourcesState(path string, state map[string]SourceState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, append(data, '\n'))
+}
+
+func runSources(args []string) error {
+	fs := flag.NewFlagSet("sources", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+
+	sessionsDir := fs.String("sessions-dir", defaultSessionsDir(), "Codex sessions directory")
+	inputPath := fs.String("in", defaultOutputFile(), "History JSONL path")
+	errorsOnly := fs.Bool("errors", false, "Only list files with parse errors")
+	jsonOut := fs.Bool("json", false, "Print as JSON")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	files, err := listSessionFiles(*sessionsDir)
+	if err != nil {
+		return err
+	}
+	state, err := loadSourcesState(sourcesStatePath(*inputPath))
+	if err != nil {
+		return err
+	}
+
+	records, err := loadRecords(*inputPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	reports := buildSourceReports(files, state, records, *sessionsDir)
+	if *errorsOnly {
+		kept := reports[:0]
+		for _, report := range reports {
+			if report.Error != "" {
+				kept = append(kept, report)
+			}
+		}
+		reports = kept
+	}
+
+	if *jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetEscapeHTML(false)
+		return enc.Encode(reports)
+	}
+
+	for _, report := range reports {
+		lastScanned := report.LastScanned
+		if lastScanned == "" {
+			lastScanned = "never"
+		}
+		line := fmt.Sprintf("%s records=%d in_history=%d last_scanned=%s", report.Path, report.Records, report.InHistory, lastScanned)
+		if !report.Exists {
+			line += " missing"
+		}
+		if report.Error != "" {
+			line += fmt.Sprintf(" error=%q", report.Error)
+		}
+		fmt.Println(line)
+	}
+	return nil
+}
+
+// buildSourceReports merges the files currently on disk, the scan state left
+// by sync, and the history's own SourceFile counts into one row per path.
+func buildSourceReports(files []string, state map[string]SourceState, records []Record, sessionsDir string) []SourceReport {
+	byPath := make(map[string]*SourceReport)
+	get := func(path string) *SourceReport {
+		report, ok := byPath[path]
+		if !ok {
+			report = &SourceReport{Path: path}
+			byPath[path] = report
+		}
+		return report
+	}
+
+	for _, path := range files {
+		get(path).Exists = true
+	}
+	for path, entry := range state {
+		report := get(path)
+		report.Records = entry.Records
+		report.LastScanned = entry.ScannedAt
+		report.Error = entry.Error
+	}
+	for _, record := range records {
+		// A hashed source names no file, so it has no row to count into.
+		if strings.TrimSpace(record.SourceFile) == "" || isHashedPath(record.SourceFile) {
+			continue
+		}
+		get(resolveSourcePath(sessionsDir, record.SourceFile)).InHistory++
+	}
+
+	reports := make([]SourceReport, 0, len(byPath))
+	for _, report := range byPath {
+		reports = append(reports, *report)
+	}
+	sort.Slice(reports, func(i, j int) bool {
+		return reports[i].Path < reports[j].Path
+	})
+	return reports
+}
+
+func recordSourceScan(state map[string]SourceState, path string, records int, scanErr error) {
+	entry := SourceState{
+		Records:   records,
+		ScannedAt: time.Now().UTC().Format(time.RFC3339),
+	}
+	if scanErr != nil {
+		entry.Error = scanErr.Error()
+	}
+	state[path] = entry
+}
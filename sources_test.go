@@ -0,0 +1,64 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSyncOnceRecordsSourceState(t *testing.T) {
+	root := t.TempDir()
+	sessionsRoot := filepath.Join(root, "sessions")
+	goodPath := filepath.Join(sessionsRoot, "2026", "02", "17", "rollout-2026-02-17T12-00-00-11111111-2222-3333-4444-555555555555.jsonl")
+	badPath := filepath.Join(sessionsRoot, "2026", "02", "18", "rollout-2026-02-18T12-00-00-22222222-2222-3333-4444-555555555555.jsonl")
+	for _, dir := range []string{filepath.Dir(goodPath), filepath.Dir(badPath)} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	good := strings.Join([]string{
+		`{"timestamp":"2026-02-17T12:00:01Z","type":"event_msg","payload":{"type":"user_message","message":"hello"}}`,
+		`{"timestamp":"2026-02-17T12:00:02Z","type":"event_msg","payload":{"type":"agent_message","message":"hi"}}`,
+	}, "\n") + "\n"
+	if err := os.WriteFile(goodPath, []byte(good), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	outPath := filepath.Join(root, "out", "conversation_history.jsonl")
+	if _, err := syncOnce(SyncOptions{SessionsDir: sessionsRoot, OutputPath: outPath}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(badPath, []byte("{not json\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := syncOnce(SyncOptions{SessionsDir: sessionsRoot, OutputPath: outPath}); err == nil {
+		t.Fatal("expected parse error from malformed session file")
+	}
+
+	state, err := loadSourcesState(sourcesStatePath(outPath))
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, err := loadRecords(outPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := listSessionFiles(sessionsRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reports := buildSourceReports(files, state, records, sessionsRoot)
+	if len(reports) != 2 {
+		t.Fatalf("expected 2 source reports, got %#v", reports)
+	}
+	if reports[0].Path != goodPath || reports[0].Records != 2 || reports[0].InHistory != 2 || reports[0].LastScanned == "" || reports[0].Error != "" {
+		t.Fatalf("unexpected report for good file: %#v", reports[0])
+	}
+	if reports[1].Path != badPath || reports[1].Error == "" || !reports[1].Exists {
+		t.Fatalf("expected parse error for bad file: %#v", reports[1])
+	}
+}
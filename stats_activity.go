@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ActivityBucket is one period of stats --by output.
+type ActivityBucket struct {
+	Period    string `json:"period"`
+	Total     int    `json:"total"`
+	User      int    `json:"user"`
+	Assistant int    `json:"assistant"`
+	Other     int    `json:"other"`
+	Sessions  int    `json:"sessions"`
+}
+
+// activityAccumulator counts records per day, ISO week, or month.
+type activityAccumulator struct {
+	period   string
+	loc      *time.Location
+	buckets  map[string]*ActivityBucket
+	sessions map[string]map[string]struct{}
+}
+
+func newActivityAccumulator(period string, loc *time.Location) (*activityAccumulator, error) {
+	switch period {
+	case "day", "week", "month":
+	default:
+		return nil, fmt.Errorf("unsupported --by %q (use day, week, month, or model)", period)
+	}
+	return &activityAccumulator{
+		period:   period,
+		loc:      loc,
+		buckets:  make(map[string]*ActivityBucket),
+		sessions: make(map[string]map[string]struct{}),
+	}, nil
+}
+
+// periodKey names the period t falls in: 2026-02-17, 2026-W08, or 2026-02.
+func periodKey(t time.Time, period string) string {
+	switch period {
+	case "week":
+		year, week := t.ISOWeek()
+		return fmt.Sprintf("%04d-W%02d", year, week)
+	case "month":
+		return t.Format("2006-01")
+	}
+	return t.Format("2006-01-02")
+}
+
+// add counts a record; records without a parseable timestamp are skipped.
+func (a *activityAccumulator) add(record Record) {
+	t, ok := parseRecordTime(record.Timestamp)
+	if !ok {
+		return
+	}
+	key := periodKey(t.In(a.loc), a.period)
+	bucket, ok := a.buckets[key]
+	if !ok {
+		bucket = &ActivityBucket{Period: key}
+		a.buckets[key] = bucket
+		a.sessions[key] = make(map[string]struct{})
+	}
+	bucket.Total++
+	switch strings.ToLower(strings.TrimSpace(record.Role)) {
+	case "user":
+		bucket.User++
+	case "assistant":
+		bucket.Assistant++
+	default:
+		bucket.Other++
+	}
+	a.sessions[key][record.SessionID] = struct{}{}
+}
+
+// result returns the buckets oldest first. Periods without records are
+// left out.
+func (a *activityAccumulator) result() []ActivityBucket {
+	buckets := make([]ActivityBucket, 0, len(a.buckets))
+	for key, bucket := range a.buckets {
+		bucket.Sessions = len(a.sessions[key])
+		buckets = append(buckets, *bucket)
+	}
+	sort.Slice(buckets, func(i, j int) bool { return buckets[i].Period < buckets[j].Period })
+	return buckets
+}
+
+// loadStatsLocation resolves --tz: an IANA zone name, "Local", or UTC when
+// empty.
+func loadStatsLocation(name string) (*time.Location, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || strings.EqualFold(name, "utc") {
+		return time.UTC, nil
+	}
+	if strings.EqualFold(name, "local") {
+		return time.Local, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("--tz: %w", err)
+	}
+	return loc, nil
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestActivityBuckets(t *testing.T) {
+	records := []Record{
+		{SessionID: "s1", Timestamp: "2026-02-16T23:30:00Z", Role: "user"},
+		{SessionID: "s1", Timestamp: "2026-02-17T00:10:00Z", Role: "assistant"},
+		{SessionID: "s2", Timestamp: "2026-02-17T09:00:00Z", Role: "tool"},
+		{SessionID: "s2", Timestamp: "2026-03-02T09:00:00Z", Role: "user"},
+		{SessionID: "s3", Timestamp: "not a time", Role: "user"},
+	}
+	run := func(period string, loc *time.Location) []ActivityBucket {
+		acc, err := newActivityAccumulator(period, loc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, record := range records {
+			acc.add(record)
+		}
+		return acc.result()
+	}
+
+	days := run("day", time.UTC)
+	want := []ActivityBucket{
+		{Period: "2026-02-16", Total: 1, User: 1, Sessions: 1},
+		{Period: "2026-02-17", Total: 2, Assistant: 1, Other: 1, Sessions: 2},
+		{Period: "2026-03-02", Total: 1, User: 1, Sessions: 1},
+	}
+	if !reflect.DeepEqual(days, want) {
+		t.Fatalf("unexpected days: %+v", days)
+	}
+
+	// An hour east of UTC, the first record moves to the next day.
+	east := time.FixedZone("UTC+1", 3600)
+	if days := run("day", east); days[0].Period != "2026-02-17" || days[0].Total != 3 {
+		t.Fatalf("unexpected shifted days: %+v", days)
+	}
+	if weeks := run("week", time.UTC); len(weeks) != 2 || weeks[0].Period != "2026-W08" || weeks[0].Total != 3 || weeks[1].Period != "2026-W10" {
+		t.Fatalf("unexpected weeks: %+v", weeks)
+	}
+	if months := run("month", time.UTC); len(months) != 2 || months[0].Period != "2026-02" || months[1].Sessions != 1 {
+		t.Fatalf("unexpected months: %+v", months)
+	}
+	if _, err := newActivityAccumulator("year", time.UTC); err == nil {
+		t.Fatal("expected an error for an unsupported period")
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PeriodCounts are the totals of one side of stats --compare-with.
+type PeriodCounts struct {
+	From      string `json:"from"`
+	To        string `json:"to"`
+	Total     int    `json:"total"`
+	User      int    `json:"user"`
+	Assistant int    `json:"assistant"`
+	Sessions  int    `json:"sessions"`
+	Tokens    int64  `json:"tokens"`
+}
+
+// StatsComparison is stats --compare-with output. Change holds the percent
+// change from Previous to Current for each measure, or null when the
+// previous value was zero.
+type StatsComparison struct {
+	Current  PeriodCounts        `json:"current"`
+	Previous PeriodCounts        `json:"previous"`
+	Change   map[string]*float64 `json:"change_percent"`
+}
+
+// comparisonLength parses --compare-with: day, week, month (30 days), or a
+// number of days such as 14d.
+func comparisonLength(period string) (time.Duration, error) {
+	const day = 24 * time.Hour
+	switch period = strings.ToLower(strings.TrimSpace(period)); period {
+	case "day":
+		return day, nil
+	case "week":
+		return 7 * day, nil
+	case "month":
+		return 30 * day, nil
+	}
+	if days, err := strconv.Atoi(strings.TrimSuffix(period, "d")); err == nil && strings.HasSuffix(period, "d") && days > 0 {
+		return time.Duration(days) * day, nil
+	}
+	return 0, fmt.Errorf("unsupported --compare-with %q (use day, week, month, or a number of days like 14d)", period)
+}
+
+// comparisonAccumulator splits records between the window ending at end and
+// the window of the same length just before it.
+type comparisonAccumulator struct {
+	end, start, previousStart time.Time
+	current, previous         PeriodCounts
+	currentSessions           map[string]struct{}
+	previousSessions          map[string]struct{}
+}
+
+func newComparisonAccumulator(end time.Time, length time.Duration) *comparisonAccumulator {
+	start := end.Add(-length)
+	previousStart := start.Add(-length)
+	return &comparisonAccumulator{
+		end:              end,
+		start:            start,
+		previousStart:    previousStart,
+		current:          PeriodCounts{From: start.Format(time.RFC3339), To: end.Format(time.RFC3339)},
+		previous:         PeriodCounts{From: previousStart.Format(time.RFC3339), To: start.Format(time.RFC3339)},
+		currentSessions:  make(map[string]struct{}),
+		previousSessions: make(map[string]struct{}),
+	}
+}
+
+func (a *comparisonAccumulator) add(record Record) {
+	t, ok := parseRecordTime(record.Timestamp)
+	if !ok || t.After(a.end) || t.Before(a.previousStart) {
+		return
+	}
+	counts, sessions := &a.current, a.currentSessions
+	if t.Before(a.start) {
+		counts, sessions = &a.previous, a.previousSessions
+	}
+	counts.Total++
+	switch strings.ToLower(strings.TrimSpace(record.Role)) {
+	case "user":
+		counts.User++
+	case "assistant":
+		counts.Assistant++
+	}
+	sessions[record.SessionID] = struct{}{}
+}
+
+// result completes the comparison. Tokens are known per session only, so
+// a session active in both windows counts its whole usage in each.
+func (a *comparisonAccumulator) result(info map[string]SessionInfo) StatsComparison {
+	a.current.Sessions = len(a.currentSessions)
+	a.previous.Sessions = len(a.previousSessions)
+	if usage := sumUsage(info, a.currentSessions); usage != nil {
+		a.current.Tokens = usage.TotalTokens
+	}
+	if usage := sumUsage(info, a.previousSessions); usage != nil {
+		a.previous.Tokens = usage.TotalTokens
+	}
+	change := func(current, previous int64) *float64 {
+		if previous == 0 {
+			return nil
+		}
+		pct := float64(current-previous) / float64(previous) * 100
+		return &pct
+	}
+	return StatsComparison{
+		Current:  a.current,
+		Previous: a.previous,
+		Change: map[string]*float64{
+			"total":     change(int64(a.current.Total), int64(a.previous.Total)),
+			"user":      change(int64(a.current.User), int64(a.previous.User)),
+			"assistant": change(int64(a.current.Assistant), int64(a.previous.Assistant)),
+			"sessions":  change(int64(a.current.Sessions), int64(a.previous.Sessions)),
+			"tokens":    change(a.current.Tokens, a.previous.Tokens),
+		},
+	}
+}
+
+// print writes one line per measure with both values and the change.
+func (c StatsComparison) print(w io.Writer) {
+	fmt.Fprintf(w, "current=%s..%s previous=%s..%s\n", c.Current.From, c.Current.To, c.Previous.From, c.Previous.To)
+	rows := []struct {
+		name              string
+		current, previous int64
+	}{
+		{"total", int64(c.Current.Total), int64(c.Previous.Total)},
+		{"user", int64(c.Current.User), int64(c.Previous.User)},
+		{"assistant", int64(c.Current.Assistant), int64(c.Previous.Assistant)},
+		{"sessions", int64(c.Current.Sessions), int64(c.Previous.Sessions)},
+		{"tokens", c.Current.Tokens, c.Previous.Tokens},
+	}
+	for _, row := range rows {
+		change := "n/a"
+		if pct := c.Change[row.name]; pct != nil {
+			change = fmt.Sprintf("%+.1f%%", *pct)
+		}
+		fmt.Fprintf(w, "%s current=%d previous=%d change=%s\n", row.name, row.current, row.previous, change)
+	}
+}
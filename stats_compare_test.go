@@ -0,0 +1,57 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestComparison(t *testing.T) {
+	length, err := comparisonLength("week")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if length14, err := comparisonLength("14d"); err != nil || length14 != 14*24*time.Hour {
+		t.Fatalf("unexpected 14d: %v %v", length14, err)
+	}
+	if _, err := comparisonLength("fortnight"); err == nil {
+		t.Fatal("expected an error for an unknown period")
+	}
+
+	end := time.Date(2026, 2, 17, 12, 0, 0, 0, time.UTC)
+	acc := newComparisonAccumulator(end, length)
+	records := []Record{
+		{SessionID: "s1", Timestamp: "2026-02-16T10:00:00Z", Role: "user"},
+		{SessionID: "s1", Timestamp: "2026-02-16T10:01:00Z", Role: "assistant"},
+		{SessionID: "s2", Timestamp: "2026-02-12T10:00:00Z", Role: "user"},
+		{SessionID: "s3", Timestamp: "2026-02-05T10:00:00Z", Role: "user"},
+		{SessionID: "s4", Timestamp: "2026-01-01T10:00:00Z", Role: "user"},
+		{SessionID: "s5", Timestamp: "2026-02-18T10:00:00Z", Role: "user"},
+	}
+	for _, record := range records {
+		acc.add(record)
+	}
+	info := map[string]SessionInfo{
+		"s1": {Usage: &TokenUsage{TotalTokens: 300}},
+		"s3": {Usage: &TokenUsage{TotalTokens: 100}},
+	}
+	comparison := acc.result(info)
+	if comparison.Current.Total != 3 || comparison.Current.Sessions != 2 || comparison.Current.Tokens != 300 {
+		t.Fatalf("unexpected current: %+v", comparison.Current)
+	}
+	if comparison.Previous.Total != 1 || comparison.Previous.Sessions != 1 || comparison.Previous.Assistant != 0 {
+		t.Fatalf("unexpected previous: %+v", comparison.Previous)
+	}
+	if pct := comparison.Change["total"]; pct == nil || *pct != 200 {
+		t.Fatalf("unexpected total change: %v", pct)
+	}
+	if comparison.Change["assistant"] != nil {
+		t.Fatal("expected no change for a zero previous value")
+	}
+
+	var out strings.Builder
+	comparison.print(&out)
+	if !strings.Contains(out.String(), "tokens current=300 previous=100 change=+200.0%\n") || !strings.Contains(out.String(), "assistant current=1 previous=0 change=n/a\n") {
+		t.Fatalf("unexpected output:\n%s", out.String())
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// heatmapWeekdays are the heatmap's rows, Monday first.
+var heatmapWeekdays = []string{"Mon", "Tue", "Wed", "Thu", "Fri", "Sat", "Sun"}
+
+// heatmapShades are the cell characters from no activity to the busiest
+// hour.
+var heatmapShades = []rune{'·', '░', '▒', '▓', '█'}
+
+// ActivityHeatmap counts records per weekday and hour of day. Counts is
+// indexed [weekday][hour] with Monday as 0.
+type ActivityHeatmap struct {
+	Weekdays []string   `json:"weekdays"`
+	Counts   [7][24]int `json:"counts"`
+	Max      int        `json:"max"`
+}
+
+func newActivityHeatmap() *ActivityHeatmap {
+	return &ActivityHeatmap{Weekdays: heatmapWeekdays}
+}
+
+// add counts a record at its time in loc; records without a parseable
+// timestamp are skipped.
+func (h *ActivityHeatmap) add(record Record, loc *time.Location) {
+	t, ok := parseRecordTime(record.Timestamp)
+	if !ok {
+		return
+	}
+	t = t.In(loc)
+	day := (int(t.Weekday()) + 6) % 7
+	h.Counts[day][t.Hour()]++
+	h.Max = max(h.Max, h.Counts[day][t.Hour()])
+}
+
+// render draws the grid with one shade character per hour, scaled to the
+// busiest hour, and each weekday's total at the end of its row.
+func (h *ActivityHeatmap) render(w io.Writer) {
+	fmt.Fprintf(w, "    %s\n", "0     6     12    18")
+	for day, hours := range h.Counts {
+		var row strings.Builder
+		total := 0
+		for _, count := range hours {
+			total += count
+			row.WriteRune(heatmapShade(count, h.Max))
+		}
+		fmt.Fprintf(w, "%s %s %d\n", h.Weekdays[day], row.String(), total)
+	}
+}
+
+// heatmapShade picks a shade for count: the lightest non-empty shade for
+// any activity, up to the darkest for the maximum.
+func heatmapShade(count, maxCount int) rune {
+	if count == 0 || maxCount == 0 {
+		return heatmapShades[0]
+	}
+	levels := len(heatmapShades) - 1
+	level := (count*levels + maxCount - 1) / maxCount
+	return heatmapShades[min(max(level, 1), levels)]
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestActivityHeatmap(t *testing.T) {
+	heatmap := newActivityHeatmap()
+	// 2026-02-16 is a Monday.
+	for i := 0; i < 4; i++ {
+		heatmap.add(Record{Timestamp: "2026-02-16T09:15:00Z"}, time.UTC)
+	}
+	heatmap.add(Record{Timestamp: "2026-02-22T23:59:00Z"}, time.UTC)
+	heatmap.add(Record{Timestamp: "bad"}, time.UTC)
+	if heatmap.Counts[0][9] != 4 || heatmap.Counts[6][23] != 1 || heatmap.Max != 4 {
+		t.Fatalf("unexpected counts: %+v", heatmap.Counts)
+	}
+
+	// Sunday 23:59 UTC is Monday 00:59 an hour east.
+	shifted := newActivityHeatmap()
+	shifted.add(Record{Timestamp: "2026-02-22T23:59:00Z"}, time.FixedZone("UTC+1", 3600))
+	if shifted.Counts[0][0] != 1 {
+		t.Fatalf("expected Monday 00h, got %+v", shifted.Counts)
+	}
+
+	var out strings.Builder
+	heatmap.render(&out)
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != 8 {
+		t.Fatalf("expected header and 7 rows, got:\n%s", out.String())
+	}
+	if want := "Mon " + strings.Repeat("·", 9) + "█" + strings.Repeat("·", 14) + " 4"; lines[1] != want {
+		t.Fatalf("unexpected Monday row %q, want %q", lines[1], want)
+	}
+	if !strings.HasPrefix(lines[7], "Sun "+strings.Repeat("·", 23)+"░") {
+		t.Fatalf("unexpected Sunday row %q", lines[7])
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// unknownModel groups records synced before model tagging existed.
+const unknownModel = "unknown"
+
+// ModelStats is one line of stats --by model output.
+type ModelStats struct {
+	Model     string      `json:"model"`
+	Total     int         `json:"total"`
+	User      int         `json:"user"`
+	Assistant int         `json:"assistant"`
+	Other     int         `json:"other"`
+	Sessions  int         `json:"sessions"`
+	Usage     *TokenUsage `json:"usage,omitempty"`
+}
+
+// modelAccumulator counts records per meta.model.
+type modelAccumulator struct {
+	models   map[string]*ModelStats
+	sessions map[string]map[string]struct{}
+}
+
+func newModelAccumulator() *modelAccumulator {
+	return &modelAccumulator{
+		models:   make(map[string]*ModelStats),
+		sessions: make(map[string]map[string]struct{}),
+	}
+}
+
+func (a *modelAccumulator) add(record Record) {
+	model := strings.TrimSpace(record.Meta["model"])
+	if model == "" {
+		model = unknownModel
+	}
+	stats, ok := a.models[model]
+	if !ok {
+		stats = &ModelStats{Model: model}
+		a.models[model] = stats
+		a.sessions[model] = make(map[string]struct{})
+	}
+	stats.Total++
+	switch strings.ToLower(strings.TrimSpace(record.Role)) {
+	case "user":
+		stats.User++
+	case "assistant":
+		stats.Assistant++
+	default:
+		stats.Other++
+	}
+	a.sessions[model][record.SessionID] = struct{}{}
+}
+
+// result returns the models busiest first. Token usage is only kept per
+// session, so each session's usage counts toward the model the session
+// sidecar names (the last one it used), for sessions the filters matched.
+func (a *modelAccumulator) result(info map[string]SessionInfo) []ModelStats {
+	matched := make(map[string]struct{})
+	for _, sessions := range a.sessions {
+		for sessionID := range sessions {
+			matched[sessionID] = struct{}{}
+		}
+	}
+	for sessionID := range matched {
+		session, ok := info[sessionID]
+		if !ok || session.Usage == nil {
+			continue
+		}
+		model := strings.TrimSpace(session.Model)
+		if model == "" {
+			model = unknownModel
+		}
+		stats, ok := a.models[model]
+		if !ok {
+			// The session's records matched under other models only.
+			stats = &ModelStats{Model: model}
+			a.models[model] = stats
+		}
+		if stats.Usage == nil {
+			stats.Usage = &TokenUsage{}
+		}
+		stats.Usage.add(*session.Usage)
+	}
+
+	result := make([]ModelStats, 0, len(a.models))
+	for model, stats := range a.models {
+		stats.Sessions = len(a.sessions[model])
+		result = append(result, *stats)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Total != result[j].Total {
+			return result[i].Total > result[j].Total
+		}
+		return result[i].Model < result[j].Model
+	})
+	return result
+}
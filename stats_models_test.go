@@ -0,0 +1,36 @@
+package main
+
+import "testing"
+
+func TestModelAccumulator(t *testing.T) {
+	acc := newModelAccumulator()
+	records := []Record{
+		{SessionID: "s1", Role: "user", Meta: map[string]string{"model": "gpt-5-codex"}},
+		{SessionID: "s1", Role: "assistant", Meta: map[string]string{"model": "gpt-5-codex"}},
+		{SessionID: "s2", Role: "assistant", Meta: map[string]string{"model": "o4-mini"}},
+		{SessionID: "s3", Role: "user"},
+	}
+	for _, record := range records {
+		acc.add(record)
+	}
+	info := map[string]SessionInfo{
+		"s1": {Model: "gpt-5-codex", Usage: &TokenUsage{TotalTokens: 100}},
+		"s2": {Model: "o4-mini", Usage: &TokenUsage{TotalTokens: 40}},
+		"s9": {Model: "o4-mini", Usage: &TokenUsage{TotalTokens: 1000}},
+	}
+	result := acc.result(info)
+	if len(result) != 3 {
+		t.Fatalf("expected 3 models, got %+v", result)
+	}
+	first := result[0]
+	if first.Model != "gpt-5-codex" || first.Total != 2 || first.User != 1 || first.Assistant != 1 || first.Sessions != 1 || first.Usage.TotalTokens != 100 {
+		t.Fatalf("unexpected first model: %+v", first)
+	}
+	// s9 matched no records, so its usage is left out.
+	if result[1].Model != "o4-mini" || result[1].Usage.TotalTokens != 40 {
+		t.Fatalf("unexpected o4-mini stats: %+v", result[1])
+	}
+	if result[2].Model != unknownModel || result[2].Usage != nil {
+		t.Fatalf("unexpected unknown stats: %+v", result[2])
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// sparkBars are the sparkline levels from the quietest to the busiest day.
+// Days without records are left blank.
+var sparkBars = []rune("▁▂▃▄▅▆▇█")
+
+// dailyCounts returns the record count of each of the days days ending on
+// end's day in loc, oldest first, with the first day.
+func dailyCounts(buckets []ActivityBucket, end time.Time, days int, loc *time.Location) ([]int, time.Time) {
+	byDay := make(map[string]int, len(buckets))
+	for _, bucket := range buckets {
+		byDay[bucket.Period] = bucket.Total
+	}
+	end = end.In(loc)
+	last := time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, loc)
+	first := last.AddDate(0, 0, -(days - 1))
+	counts := make([]int, days)
+	for i := range counts {
+		counts[i] = byDay[periodKey(first.AddDate(0, 0, i), "day")]
+	}
+	return counts, first
+}
+
+// sparkline draws counts with one bar per value, scaled to the largest.
+func sparkline(counts []int) string {
+	peak := 0
+	for _, count := range counts {
+		peak = max(peak, count)
+	}
+	var b strings.Builder
+	for _, count := range counts {
+		if count == 0 {
+			b.WriteByte(' ')
+			continue
+		}
+		level := (count*len(sparkBars) - 1) / peak
+		b.WriteRune(sparkBars[min(level, len(sparkBars)-1)])
+	}
+	return b.String()
+}
+
+// sparkSummary is the line stats --spark prints.
+func sparkSummary(counts []int, first time.Time) string {
+	total := 0
+	for _, count := range counts {
+		total += count
+	}
+	last := first.AddDate(0, 0, len(counts)-1)
+	return fmt.Sprintf("%s %d records over %d days (%s..%s)", sparkline(counts), total, len(counts), first.Format("2006-01-02"), last.Format("2006-01-02"))
+}
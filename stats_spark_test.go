@@ -0,0 +1,30 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSparkline(t *testing.T) {
+	if got := sparkline([]int{0, 1, 4, 8, 2}); got != " ▁▄█▂" {
+		t.Fatalf("unexpected sparkline %q", got)
+	}
+	if got := sparkline([]int{0, 0}); got != "  " {
+		t.Fatalf("unexpected empty sparkline %q", got)
+	}
+
+	buckets := []ActivityBucket{
+		{Period: "2026-02-10", Total: 9},
+		{Period: "2026-02-15", Total: 3},
+		{Period: "2026-02-17", Total: 6},
+	}
+	end := time.Date(2026, 2, 17, 18, 0, 0, 0, time.UTC)
+	counts, first := dailyCounts(buckets, end, 4, time.UTC)
+	if !reflect.DeepEqual(counts, []int{0, 3, 0, 6}) || first.Format("2006-01-02") != "2026-02-14" {
+		t.Fatalf("unexpected counts %v from %s", counts, first)
+	}
+	if got := sparkSummary(counts, first); got != " ▄ █ 9 records over 4 days (2026-02-14..2026-02-17)" {
+		t.Fatalf("unexpected summary %q", got)
+	}
+}
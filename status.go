@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultStatusURL matches the --metrics-addr the README suggests for watch.
+const defaultStatusURL = "http://127.0.0.1:9464"
+
+func runStatus(args []string) error {
+	fs := flag.NewFlagSet("status", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+
+	baseURL := fs.String("url", defaultStatusURL, "Base URL of a watch --metrics-addr listener or of serve")
+	timeout := fs.Duration("timeout", 5*time.Second, "Give up after this long")
+	jsonOut := fs.Bool("json", false, "Print the readiness report as JSON")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected argument %q", fs.Arg(0))
+	}
+
+	client := &http.Client{Timeout: *timeout}
+	report, err := fetchHealth(client, strings.TrimRight(*baseURL, "/")+"/readyz")
+	if err != nil {
+		return err
+	}
+	if *jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(report); err != nil {
+			return err
+		}
+	} else {
+		printHealthReport(os.Stdout, report)
+	}
+	if report.Status != "ok" {
+		return fmt.Errorf("%s is not ready", report.Component)
+	}
+	return nil
+}
+
+// fetchHealth reads a health report. A 503 still carries a report, which
+// is returned as is.
+func fetchHealth(client *http.Client, url string) (HealthReport, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return HealthReport{}, fmt.Errorf("nothing answered at %s (is watch running with --metrics-addr, or serve?): %w", url, err)
+	}
+	defer resp.Body.Close()
+	var report HealthReport
+	if err := json.NewDecoder(resp.Body).Decode(&report); err != nil || report.Status == "" {
+		return HealthReport{}, fmt.Errorf("%s: unexpected response (%s)", url, resp.Status)
+	}
+	return report, nil
+}
+
+func printHealthReport(w io.Writer, report HealthReport) {
+	line := fmt.Sprintf("status=%s component=%s started_at=%s", report.Status, report.Component, report.StartedAt)
+	if report.LastSync != "" {
+		line += " last_sync=" + report.LastSync
+	}
+	if report.Backlog != nil {
+		line += " backlog=" + strconv.Itoa(*report.Backlog)
+	}
+	if report.OutputWritable != nil {
+		line += " output_writable=" + strconv.FormatBool(*report.OutputWritable)
+	}
+	if report.OutputPath != "" {
+		line += " output=" + report.OutputPath
+	}
+	fmt.Fprintln(w, line)
+	if report.LastError != "" {
+		fmt.Fprintf(w, "last_error: %s\n", report.LastError)
+	}
+	for _, problem := range report.Problems {
+		fmt.Fprintf(w, "problem: %s\n", problem)
+	}
+}
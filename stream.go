@@ -0,0 +1,362 @@
+package main
+
+import (
+	"bufio"
+	"container/heap"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// errStopIteration can be returned from a forEachRecord callback to end the
+// scan early without reporting an error.
+var errStopIteration = errors.New("stop iteration")
+
+// forEachRecord streams the history file one record at a time so callers can
+// aggregate multi-GB histories in constant memory. Unparseable lines are
+// skipped, matching loadRecords.
+func forEachRecord(path string, fn func(Record) error) error {
+	file, err := openHistory(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), scannerMaxTokenSize)
+
+	for scanner.Scan() {
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			continue
+		}
+		if err := fn(record); err != nil {
+			if errors.Is(err, errStopIteration) {
+				return nil
+			}
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	// Closing reports decompression errors, which otherwise look like a
+	// short file.
+	return file.Close()
+}
+
+// compileMatch compiles --match once per command; an empty expression means
+// no regex filter.
+func compileMatch(expr, matchRole string) (*regexp.Regexp, error) {
+	if expr == "" {
+		if strings.TrimSpace(matchRole) != "" {
+			return nil, errors.New("--match-role requires --match")
+		}
+		return nil, nil
+	}
+	pattern, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("--match: %w", err)
+	}
+	return pattern, nil
+}
+
+// textPattern compiles a literal text filter, case-insensitive unless
+// caseSensitive is set. With word set it only matches whole words: the
+// text cannot continue a word at either end, where word characters are
+// ASCII letters, digits, and underscore, as for \b.
+func textPattern(text string, caseSensitive, word bool) *regexp.Regexp {
+	expr := regexp.QuoteMeta(text)
+	if word && text != "" {
+		if isWordByte(text[0]) {
+			expr = `\b` + expr
+		}
+		if isWordByte(text[len(text)-1]) {
+			expr += `\b`
+		}
+	}
+	if !caseSensitive {
+		expr = "(?i)" + expr
+	}
+	return regexp.MustCompile(expr)
+}
+
+func isWordByte(b byte) bool {
+	return b == '_' || 'a' <= b && b <= 'z' || 'A' <= b && b <= 'Z' || '0' <= b && b <= '9'
+}
+
+// newRecordMatcher normalizes filter once and returns a predicate for it.
+func newRecordMatcher(filter RecordFilter) func(Record) bool {
+	sessionID := strings.TrimSpace(filter.SessionID)
+	role := strings.ToLower(strings.TrimSpace(filter.Role))
+	contains := strings.ToLower(strings.TrimSpace(filter.Contains))
+	// The lowercase substring test is the common case; anything stricter
+	// goes through a pattern.
+	var containsPattern *regexp.Regexp
+	if contains != "" && (filter.CaseSensitive || filter.Word) {
+		containsPattern = textPattern(strings.TrimSpace(filter.Contains), filter.CaseSensitive, filter.Word)
+	}
+	model := strings.ToLower(strings.TrimSpace(filter.Model))
+	matchRole := strings.ToLower(strings.TrimSpace(filter.MatchRole))
+	project := strings.TrimSpace(filter.Project)
+
+	return func(record Record) bool {
+		if sessionID != "" && record.SessionID != sessionID {
+			return false
+		}
+		if role != "" && strings.ToLower(strings.TrimSpace(record.Role)) != role {
+			return false
+		}
+		if containsPattern != nil {
+			if !containsPattern.MatchString(record.Text) {
+				return false
+			}
+		} else if contains != "" && !strings.Contains(strings.ToLower(record.Text), contains) {
+			return false
+		}
+		if model != "" && strings.ToLower(record.Meta["model"]) != model {
+			return false
+		}
+		if project != "" && !projectMatches(record, project) {
+			return false
+		}
+		if filter.Match != nil {
+			if matchRole != "" && strings.ToLower(strings.TrimSpace(record.Role)) != matchRole {
+				return false
+			}
+			if !filter.Match.MatchString(record.Text) {
+				return false
+			}
+		}
+		if !filter.From.IsZero() || !filter.To.IsZero() {
+			ts, ok := parseRecordTime(record.Timestamp)
+			if !ok {
+				return false
+			}
+			if !filter.From.IsZero() && ts.Before(filter.From) {
+				return false
+			}
+			if !filter.To.IsZero() && ts.After(filter.To) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// statsAccumulator builds HistoryStats one record at a time. Message
+// lengths go into fixed-size histograms, but the latency tracker keeps the
+// time of every user and assistant message, so memory still grows with the
+// history, by about 40 bytes per message.
+type statsAccumulator struct {
+	stats       HistoryStats
+	sessions    map[string]struct{}
+	latency     *latencyTracker
+	charsByRole map[string]*lengthHistogram
+	wordsByRole map[string]*lengthHistogram
+}
+
+func newStatsAccumulator() *statsAccumulator {
+	return &statsAccumulator{
+		sessions:    make(map[string]struct{}),
+		latency:     newLatencyTracker(),
+		charsByRole: make(map[string]*lengthHistogram),
+		wordsByRole: make(map[string]*lengthHistogram),
+	}
+}
+
+func (a *statsAccumulator) add(record Record) {
+	a.stats.Total++
+	a.sessions[record.SessionID] = struct{}{}
+	a.latency.add(record)
+	role := strings.ToLower(strings.TrimSpace(record.Role))
+	if a.charsByRole[role] == nil {
+		a.charsByRole[role] = new(lengthHistogram)
+		a.wordsByRole[role] = new(lengthHistogram)
+	}
+	a.charsByRole[role].add(utf8.RuneCountInString(record.Text))
+	a.wordsByRole[role].add(len(strings.Fields(record.Text)))
+
+	switch role {
+	case "user":
+		a.stats.User++
+	case "assistant":
+		a.stats.Assistant++
+	default:
+		a.stats.Other++
+	}
+	if role == "error" {
+		a.stats.Errors++
+	}
+
+	a.stats.FirstTimestamp = earlierTimestamp(a.stats.FirstTimestamp, record.Timestamp)
+	a.stats.LastTimestamp = laterTimestamp(a.stats.LastTimestamp, record.Timestamp)
+}
+
+func (a *statsAccumulator) result() HistoryStats {
+	stats := a.stats
+	stats.SessionCount = len(a.sessions)
+	stats.Latency = summarizeLatency(a.latency.all())
+	if len(a.charsByRole) > 0 {
+		stats.Lengths = make(map[string]LengthStats, len(a.charsByRole))
+		for role, chars := range a.charsByRole {
+			stats.Lengths[role] = LengthStats{
+				Chars: chars.percentiles(),
+				Words: a.wordsByRole[role].percentiles(),
+			}
+		}
+	}
+	return stats
+}
+
+// sessionAccumulator builds per-session summaries one record at a time and
+// tracks per-role counts for activity filters.
+type sessionAccumulator struct {
+	bySession  map[string]*SessionSummary
+	roleCounts map[string]map[string]int
+	latency    *latencyTracker
+	// firstUser is the timestamp of each session's earliest user message,
+	// whose text is the summary's preview.
+	firstUser map[string]string
+}
+
+func newSessionAccumulator() *sessionAccumulator {
+	return &sessionAccumulator{
+		bySession:  make(map[string]*SessionSummary),
+		roleCounts: make(map[string]map[string]int),
+		latency:    newLatencyTracker(),
+		firstUser:  make(map[string]string),
+	}
+}
+
+func (a *sessionAccumulator) add(record Record) {
+	sessionID := record.SessionID
+	if strings.TrimSpace(sessionID) == "" {
+		sessionID = "unknown"
+	}
+
+	summary, exists := a.bySession[sessionID]
+	if !exists {
+		summary = &SessionSummary{SessionID: sessionID}
+		a.bySession[sessionID] = summary
+		a.roleCounts[sessionID] = make(map[string]int)
+	}
+
+	role := strings.ToLower(strings.TrimSpace(record.Role))
+	a.roleCounts[sessionID][role]++
+	a.latency.add(Record{SessionID: sessionID, Timestamp: record.Timestamp, Role: role})
+
+	summary.Total++
+	summary.Chars += utf8.RuneCountInString(record.Text)
+	switch role {
+	case "user":
+		summary.User++
+	case "assistant":
+		summary.Assistant++
+	default:
+		summary.Other++
+	}
+	if role == "error" {
+		summary.Errors++
+	}
+
+	if role == "user" && strings.TrimSpace(record.Text) != "" {
+		if first, ok := a.firstUser[sessionID]; !ok || compareTimestamp(record.Timestamp, first) < 0 {
+			a.firstUser[sessionID] = record.Timestamp
+			summary.Preview = sessionPreview(record.Text)
+		}
+	}
+
+	if cwd := record.Meta["cwd"]; cwd != "" && laterTimestamp(summary.LastTimestamp, record.Timestamp) == record.Timestamp {
+		summary.Cwd = cwd
+	}
+
+	summary.FirstTimestamp = earlierTimestamp(summary.FirstTimestamp, record.Timestamp)
+	summary.LastTimestamp = laterTimestamp(summary.LastTimestamp, record.Timestamp)
+}
+
+// summaries returns sessions most recently active first.
+func (a *sessionAccumulator) summaries() []SessionSummary {
+	summaries := make([]SessionSummary, 0, len(a.bySession))
+	for sessionID, summary := range a.bySession {
+		summary.Latency = summarizeLatency(a.latency.session(sessionID))
+		summary.DurationSeconds = int64(sessionDuration(*summary) / time.Second)
+		summaries = append(summaries, *summary)
+	}
+	sortSessionSummaries(summaries)
+	return summaries
+}
+
+// newestRecords keeps only the limit most recent records seen, so show can
+// stream a large history while printing its tail.
+type newestRecords struct {
+	limit int
+	seq   int
+	items recordHeap
+}
+
+type seqRecord struct {
+	record Record
+	seq    int
+}
+
+// recordHeap is a min-heap on (timestamp, arrival order): the root is the
+// oldest record kept and the first to be evicted.
+type recordHeap []seqRecord
+
+func (h recordHeap) Len() int { return len(h) }
+func (h recordHeap) Less(i, j int) bool {
+	if cmp := compareTimestamp(h[i].record.Timestamp, h[j].record.Timestamp); cmp != 0 {
+		return cmp < 0
+	}
+	return h[i].seq < h[j].seq
+}
+func (h recordHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *recordHeap) Push(x any)   { *h = append(*h, x.(seqRecord)) }
+func (h *recordHeap) Pop() any {
+	old := *h
+	item := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return item
+}
+
+func (n *newestRecords) add(record Record) {
+	item := seqRecord{record: record, seq: n.seq}
+	n.seq++
+	if n.items.Len() < n.limit {
+		heap.Push(&n.items, item)
+		return
+	}
+	if recordHeap([]seqRecord{n.items[0], item}).Less(0, 1) {
+		n.items[0] = item
+		heap.Fix(&n.items, 0)
+	}
+}
+
+// records returns the kept records in chronological order.
+func (n *newestRecords) records() []Record {
+	items := append(recordHeap(nil), n.items...)
+	out := make([]Record, len(items))
+	for i := range out {
+		out[i] = heap.Pop(&items).(seqRecord).record
+	}
+	return out
+}
+
+// projectMatches reports whether a record ran in project, which is either
+// an absolute directory (matching it and anything under it) or a
+// case-insensitive substring of the working directory or project name.
+func projectMatches(record Record, project string) bool {
+	cwd := record.Meta["cwd"]
+	if filepath.IsAbs(project) {
+		project = filepath.Clean(project)
+		return cwd != "" && (cwd == project || strings.HasPrefix(cwd, strings.TrimSuffix(project, string(filepath.Separator))+string(filepath.Separator)))
+	}
+	project = strings.ToLower(project)
+	return strings.Contains(strings.ToLower(cwd), project) || strings.Contains(strings.ToLower(record.Meta["project"]), project)
+}
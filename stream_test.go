@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewestRecordsKeepsChronologicalTail(t *testing.T) {
+	newest := &newestRecords{limit: 3}
+	for _, record := range []Record{
+		{ID: "c", Timestamp: "2026-02-17T10:03:00Z"},
+		{ID: "a", Timestamp: "2026-02-17T10:01:00Z"},
+		{ID: "e", Timestamp: "2026-02-17T10:05:00Z"},
+		{ID: "d1", Timestamp: "2026-02-17T10:04:00Z"},
+		{ID: "b", Timestamp: "2026-02-17T10:02:00Z"},
+		{ID: "d2", Timestamp: "2026-02-17T10:04:00Z"},
+	} {
+		newest.add(record)
+	}
+
+	got := newest.records()
+	if len(got) != 3 || got[0].ID != "d1" || got[1].ID != "d2" || got[2].ID != "e" {
+		t.Fatalf("unexpected newest records: %#v", got)
+	}
+}
+
+func TestForEachRecordStopsEarly(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	if err := appendRecords(path, []Record{{ID: "1"}, {ID: "2"}, {ID: "3"}}, false); err != nil {
+		t.Fatal(err)
+	}
+
+	seen := 0
+	err := forEachRecord(path, func(record Record) error {
+		seen++
+		if record.ID == "2" {
+			return errStopIteration
+		}
+		return nil
+	})
+	if err != nil || seen != 2 {
+		t.Fatalf("expected to stop after 2 records, saw %d (err=%v)", seen, err)
+	}
+}
+
+func TestRecordMatcherRegex(t *testing.T) {
+	pattern, err := compileMatch(`panic:.*nil pointer`, "assistant")
+	if err != nil {
+		t.Fatal(err)
+	}
+	match := newRecordMatcher(RecordFilter{Match: pattern, MatchRole: "assistant"})
+	cases := []struct {
+		record Record
+		want   bool
+	}{
+		{Record{Role: "assistant", Text: "panic: runtime error: invalid memory address or nil pointer dereference"}, true},
+		{Record{Role: "user", Text: "panic: runtime error: nil pointer dereference"}, false},
+		{Record{Role: "assistant", Text: "nil pointer, then panic:"}, false},
+	}
+	for _, c := range cases {
+		if got := match(c.record); got != c.want {
+			t.Fatalf("match(%q, %q) = %v, want %v", c.record.Role, c.record.Text, got, c.want)
+		}
+	}
+
+	if _, err := compileMatch("(", ""); err == nil {
+		t.Fatal("expected an error for an invalid expression")
+	}
+	if _, err := compileMatch("", "user"); err == nil {
+		t.Fatal("expected an error for --match-role without --match")
+	}
+}
+
+func TestRecordMatcherCaseAndWord(t *testing.T) {
+	records := []Record{
+		{Text: "register the Handler"},
+		{Text: "the handler is nil"},
+		{Text: "use http.HandlerFunc"},
+		{Text: "Handler_v2 and Handler."},
+	}
+	cases := []struct {
+		filter RecordFilter
+		want   []int
+	}{
+		{RecordFilter{Contains: "handler"}, []int{0, 1, 2, 3}},
+		{RecordFilter{Contains: "Handler", CaseSensitive: true}, []int{0, 2, 3}},
+		{RecordFilter{Contains: "handler", Word: true}, []int{0, 1, 3}},
+		{RecordFilter{Contains: "Handler", CaseSensitive: true, Word: true}, []int{0, 3}},
+		{RecordFilter{Contains: "Handler.", CaseSensitive: true, Word: true}, []int{3}},
+	}
+	for _, c := range cases {
+		match := newRecordMatcher(c.filter)
+		var got []int
+		for i, record := range records {
+			if match(record) {
+				got = append(got, i)
+			}
+		}
+		if fmt.Sprint(got) != fmt.Sprint(c.want) {
+			t.Fatalf("%+v matched %v, want %v", c.filter, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+// Command readers checks the golden exports in testdata with Apache Arrow's
+// Go implementation, an independent Parquet and Arrow IPC reader. It prints
+// the schema and every row of each file; the output must match
+// expected.txt:
+//
+//	cd testdata/readers && go run . | diff expected.txt -
+//
+// export.parquet and export.arrow hold renderExport's output for
+// goldenExportRecords in parquet_test.go, and TestRenderParquetGolden and
+// TestRenderArrowGolden fail when the exporters stop producing exactly
+// those bytes. To change a format on purpose, write the new output over the
+// file, run this check, and review the diff to expected.txt. This is a
+// separate module so the main one does not depend on Arrow.
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/apache/arrow-go/v18/parquet/file"
+	"github.com/apache/arrow-go/v18/parquet/pqarrow"
+)
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	parquetFile, err := file.OpenParquetFile("../export.parquet", false)
+	if err != nil {
+		return err
+	}
+	defer parquetFile.Close()
+	reader, err := pqarrow.NewFileReader(parquetFile, pqarrow.ArrowReadProperties{}, memory.DefaultAllocator)
+	if err != nil {
+		return err
+	}
+	table, err := reader.ReadTable(context.Background())
+	if err != nil {
+		return err
+	}
+	defer table.Release()
+	fmt.Println("== export.parquet")
+	batches := array.NewTableReader(table, -1)
+	defer batches.Release()
+	for batches.Next() {
+		dump(batches.Record())
+	}
+
+	arrowFile, err := os.Open("../export.arrow")
+	if err != nil {
+		return err
+	}
+	defer arrowFile.Close()
+	stream, err := ipc.NewReader(arrowFile)
+	if err != nil {
+		return err
+	}
+	defer stream.Release()
+	fmt.Println("== export.arrow")
+	for stream.Next() {
+		dump(stream.Record())
+	}
+	return stream.Err()
+}
+
+// dump prints the schema, then one line per row with each column's value,
+// or null.
+func dump(record arrow.Record) {
+	for _, field := range record.Schema().Fields() {
+		fmt.Printf("%s: %s nullable=%t\n", field.Name, field.Type, field.Nullable)
+	}
+	for row := 0; row < int(record.NumRows()); row++ {
+		values := make([]string, record.NumCols())
+		for col, column := range record.Columns() {
+			if column.IsNull(row) {
+				values[col] = "null"
+			} else {
+				values[col] = fmt.Sprintf("%q", column.ValueStr(row))
+			}
+		}
+		fmt.Printf("row %d: %s\n", row, strings.Join(values, " "))
+	}
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"codex-history-cli/internal/history"
+)
+
+// The full-text index is a SQLite FTS5 table next to the history, built by
+// the index command with the sqlite3 tool. Its trigram tokenizer matches
+// case-insensitive substrings, the semantics search already has, so search
+// uses it to find candidates and still checks each one itself. It records
+// the history size it covers and a hash of the bytes just before that
+// point. While sync only appends, search adds the records past the indexed
+// size to the candidates; once the history is rewritten, search falls back
+// to scanning until the index is rebuilt.
+const textIndexSchema = `CREATE VIRTUAL TABLE records USING fts5(text, record UNINDEXED, tokenize = 'trigram');
+`
+
+// textIndexBatch is how many records go into one sqlite3 invocation while
+// building, bounding the size of each generated script.
+const textIndexBatch = 5000
+
+// textIndexMinQuery is the shortest query the trigram index can answer.
+const textIndexMinQuery = 3
+
+// textIndexTailBytes is how much of the history before the indexed size is
+// hashed to tell an appended-to history from a rewritten one.
+const textIndexTailBytes = 4096
+
+// textIndexMeta is the ID index's metadata plus the tail hash.
+type textIndexMeta struct {
+	idIndexMeta
+	TailSHA256 string `json:"tail_sha256"`
+}
+
+func textIndexPath(historyPath string) string {
+	return strings.TrimSuffix(historyPath, ".jsonl") + ".fts.db"
+}
+
+func textIndexMetaPath(historyPath string) string {
+	return textIndexPath(historyPath) + ".json"
+}
+
+func runIndex(args []string) error {
+	fs := flag.NewFlagSet("index", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+
+	inputPath := fs.String("in", defaultOutputFile(), "History JSONL path to index")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	count, err := buildTextIndex(*inputPath)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("indexed=%d index=%s\n", count, textIndexPath(*inputPath))
+	return nil
+}
+
+// buildTextIndex indexes every record of the history into a fresh database
+// and swaps it into place, returning how many records it indexed. It holds
+// the history lock so no record is appended past the size it records.
+func buildTextIndex(historyPath string) (int, error) {
+	unlock, err := lockHistory(historyPath)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+	info, err := os.Stat(historyPath)
+	if err != nil {
+		return 0, err
+	}
+	tail, err := historyTailHash(historyPath, info.Size())
+	if err != nil {
+		return 0, err
+	}
+	indexPath := textIndexPath(historyPath)
+	tmpPath := indexPath + ".tmp"
+	os.Remove(tmpPath)
+	defer os.Remove(tmpPath)
+
+	db := history.NewSQLiteCLI(tmpPath)
+	if err := db.Exec(textIndexSchema); err != nil {
+		return 0, err
+	}
+
+	count := 0
+	var script strings.Builder
+	flush := func() error {
+		if script.Len() == 0 {
+			return nil
+		}
+		err := db.Exec("BEGIN;\n" + script.String() + "COMMIT;\n")
+		script.Reset()
+		return err
+	}
+	err = forEachRecord(historyPath, func(record Record) error {
+		data, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(&script, "INSERT INTO records VALUES (%s, %s);\n", sqlString(record.Text), sqlString(string(data)))
+		count++
+		if count%textIndexBatch == 0 {
+			return flush()
+		}
+		return nil
+	})
+	if err == nil {
+		err = flush()
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if err := os.Rename(tmpPath, indexPath); err != nil {
+		return 0, err
+	}
+	data, err := json.Marshal(textIndexMeta{
+		idIndexMeta: idIndexMeta{
+			HistorySize:    info.Size(),
+			HistoryModTime: info.ModTime().UnixNano(),
+			Entries:        int64(count),
+		},
+		TailSHA256: tail,
+	})
+	if err != nil {
+		return 0, err
+	}
+	return count, writeFileAtomic(textIndexMetaPath(historyPath), append(data, '\n'))
+}
+
+// errTextIndexStale reports an index built from an older history.
+var errTextIndexStale = errors.New("full-text index is out of date; run codex-history index to rebuild it")
+
+// historyTailHash hashes the textIndexTailBytes of the history that end at
+// size.
+func historyTailHash(historyPath string, size int64) (string, error) {
+	file, err := os.Open(historyPath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	start := size - textIndexTailBytes
+	if start < 0 {
+		start = 0
+	}
+	hash := sha256.New()
+	if _, err := io.Copy(hash, io.NewSectionReader(file, start, size-start)); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// textIndexCandidates returns the records whose text may contain query:
+// those the full-text index matches, then every record appended since it
+// was built. ok is false when there is no usable index: none was built,
+// the history was rewritten since (err is errTextIndexStale), or the query
+// is too short.
+func textIndexCandidates(historyPath, query string) (records []Record, ok bool, err error) {
+	if utf8.RuneCountInString(query) < textIndexMinQuery {
+		return nil, false, nil
+	}
+	metaData, err := os.ReadFile(textIndexMetaPath(historyPath))
+	if err != nil {
+		return nil, false, nil
+	}
+	var meta textIndexMeta
+	if err := json.Unmarshal(metaData, &meta); err != nil {
+		return nil, false, nil
+	}
+	info, err := os.Stat(historyPath)
+	if err != nil {
+		return nil, false, err
+	}
+	appended := meta.HistorySize != info.Size() || meta.HistoryModTime != info.ModTime().UnixNano()
+	if appended {
+		if info.Size() < meta.HistorySize || meta.TailSHA256 == "" {
+			return nil, false, errTextIndexStale
+		}
+		tail, err := historyTailHash(historyPath, meta.HistorySize)
+		if err != nil {
+			return nil, false, err
+		}
+		if tail != meta.TailSHA256 {
+			return nil, false, errTextIndexStale
+		}
+	}
+
+	phrase := `"` + strings.ReplaceAll(query, `"`, `""`) + `"`
+	rows, err := history.NewSQLiteCLI(textIndexPath(historyPath)).QueryJSON(
+		"SELECT record FROM records WHERE records MATCH " + sqlString(phrase) + " ORDER BY rowid;")
+	if err != nil {
+		return nil, false, err
+	}
+	records = make([]Record, 0, len(rows))
+	for _, row := range rows {
+		raw, _ := row["record"].(string)
+		var record Record
+		if err := json.Unmarshal([]byte(raw), &record); err != nil {
+			return nil, false, fmt.Errorf("full-text index: %w", err)
+		}
+		records = append(records, record)
+	}
+	if appended {
+		if records, err = appendRecordsAfter(records, historyPath, meta.HistorySize); err != nil {
+			return nil, false, err
+		}
+	}
+	return records, true, nil
+}
+
+// appendRecordsAfter appends the records that start at or after offset in
+// the history to records.
+func appendRecordsAfter(records []Record, historyPath string, offset int64) ([]Record, error) {
+	file, err := os.Open(historyPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), scannerMaxTokenSize)
+	for scanner.Scan() {
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			continue
+		}
+		records = append(records, record)
+	}
+	return records, scanner.Err()
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"errors"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestTextIndexCandidates(t *testing.T) {
+	if _, err := exec.LookPath("sqlite3"); err != nil {
+		t.Skip("sqlite3 not available")
+	}
+	historyPath := filepath.Join(t.TempDir(), "history.jsonl")
+	records := []Record{
+		{ID: "1", SessionID: "s1", Timestamp: "2026-02-17T10:00:00Z", Role: "user", Text: `read os.Getenv("API_KEY")`},
+		{ID: "2", SessionID: "s1", Timestamp: "2026-02-17T10:01:00Z", Role: "assistant", Text: "it's in config.go"},
+	}
+	if err := appendRecords(historyPath, records, false); err != nil {
+		t.Fatal(err)
+	}
+
+	count, err := buildTextIndex(historyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Fatalf("expected 2 indexed records, got %d", count)
+	}
+
+	found, ok, err := textIndexCandidates(historyPath, `api_key"`)
+	if err != nil || !ok {
+		t.Fatalf("index not used: ok=%v err=%v", ok, err)
+	}
+	if len(found) != 1 || found[0].ID != "1" || found[0].Text != records[0].Text {
+		t.Fatalf("unexpected candidates: %#v", found)
+	}
+	if found, _, _ = textIndexCandidates(historyPath, "IT'S"); len(found) != 1 || found[0].ID != "2" {
+		t.Fatalf("unexpected candidates for a quoted query: %#v", found)
+	}
+	if _, ok, _ := textIndexCandidates(historyPath, "go"); ok {
+		t.Fatal("queries shorter than a trigram should scan")
+	}
+
+	if err := appendRecords(historyPath, []Record{{ID: "3", SessionID: "s2", Timestamp: "2026-02-18T10:00:00Z", Role: "user", Text: "API_KEY again"}}, false); err != nil {
+		t.Fatal(err)
+	}
+	found, ok, err = textIndexCandidates(historyPath, "api_key")
+	if err != nil || !ok {
+		t.Fatalf("index not used after an append: ok=%v err=%v", ok, err)
+	}
+	if len(found) != 2 || found[0].ID != "1" || found[1].ID != "3" {
+		t.Fatalf("expected the indexed match and the appended record: %#v", found)
+	}
+
+	if _, err := removeRecords(historyPath, func(record Record) bool { return record.ID == "2" }); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, err := textIndexCandidates(historyPath, "api_key"); ok || !errors.Is(err, errTextIndexStale) {
+		t.Fatalf("expected a stale index after a rewrite, got ok=%v err=%v", ok, err)
+	}
+}
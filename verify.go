@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+)
+
+type VerifyIssue struct {
+	Line    int    `json:"line"`
+	ID      string `json:"id,omitempty"`
+	Message string `json:"message"`
+}
+
+type VerifyReport struct {
+	Path    string        `json:"path"`
+	Lines   int           `json:"lines"`
+	Records int           `json:"records"`
+	Issues  []VerifyIssue `json:"issues"`
+}
+
+// verifyHistory checks every line of the history: it must parse as a record,
+// its ID must be the hash of its session, timestamp, role, text, and raw
+// event, and its timestamp must parse. Blank lines are not reported.
+func verifyHistory(path string) (VerifyReport, error) {
+	unlock, err := lockHistory(path)
+	if err != nil {
+		return VerifyReport{}, err
+	}
+	defer unlock()
+
+	file, err := openHistory(path)
+	if err != nil {
+		return VerifyReport{}, err
+	}
+	defer file.Close()
+
+	report := VerifyReport{Path: path, Issues: []VerifyIssue{}}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), scannerMaxTokenSize)
+	for scanner.Scan() {
+		report.Lines++
+		line := scanner.Bytes()
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+
+		var record Record
+		if err := json.Unmarshal(line, &record); err != nil {
+			report.Issues = append(report.Issues, VerifyIssue{Line: report.Lines, Message: "unparseable: " + err.Error()})
+			continue
+		}
+		report.Records++
+
+		if record.ID == "" {
+			report.Issues = append(report.Issues, VerifyIssue{Line: report.Lines, Message: "missing id"})
+		} else if want := recordContentID(record); record.ID != want && !idHashesOriginalText(record) {
+			report.Issues = append(report.Issues, VerifyIssue{Line: report.Lines, ID: record.ID, Message: fmt.Sprintf("id does not match content (want %s)", want)})
+		}
+		if _, ok := parseRecordTime(record.Timestamp); !ok {
+			report.Issues = append(report.Issues, VerifyIssue{Line: report.Lines, ID: record.ID, Message: fmt.Sprintf("bad timestamp %q", record.Timestamp)})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return VerifyReport{}, err
+	}
+	return report, nil
+}
+
+func runVerify(args []string) error {
+	fs := flag.NewFlagSet("verify", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+
+	inputPath := fs.String("in", defaultOutputFile(), "History JSONL path to check")
+	jsonOut := fs.Bool("json", false, "Print as JSON")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	report, err := verifyHistory(*inputPath)
+	if err != nil {
+		return err
+	}
+
+	if *jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(report); err != nil {
+			return err
+		}
+	} else {
+		for _, issue := range report.Issues {
+			fmt.Printf("line %d: %s\n", issue.Line, issue.Message)
+		}
+		fmt.Printf("lines=%d records=%d issues=%d\n", report.Lines, report.Records, len(report.Issues))
+	}
+
+	if len(report.Issues) > 0 {
+		return errors.New("history failed verification")
+	}
+	return nil
+}
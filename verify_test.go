@@ -0,0 +1,45 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestVerifyHistory(t *testing.T) {
+	good := Record{SessionID: "s", Timestamp: "2026-02-17T09:00:00Z", Role: "user", Text: "hello"}
+	good.ID = makeRecordID(good.SessionID, good.Timestamp, good.Role, good.Text)
+
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	if err := appendRecords(path, []Record{
+		good,
+		{ID: "tampered", SessionID: "s", Timestamp: "2026-02-17T09:00:01Z", Role: "user", Text: "x"},
+		{ID: makeRecordID("s", "yesterday", "user", "y"), SessionID: "s", Timestamp: "yesterday", Role: "user", Text: "y"},
+	}, false); err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString(`{"id":"trunc`)
+	file.Close()
+
+	report, err := verifyHistory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Lines != 4 || report.Records != 3 || len(report.Issues) != 3 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	if report.Issues[0].Line != 2 || !strings.Contains(report.Issues[0].Message, "id does not match") {
+		t.Fatalf("unexpected first issue: %+v", report.Issues[0])
+	}
+	if report.Issues[1].Line != 3 || !strings.Contains(report.Issues[1].Message, "bad timestamp") {
+		t.Fatalf("unexpected second issue: %+v", report.Issues[1])
+	}
+	if report.Issues[2].Line != 4 || !strings.HasPrefix(report.Issues[2].Message, "unparseable") {
+		t.Fatalf("unexpected third issue: %+v", report.Issues[2])
+	}
+}
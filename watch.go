@@ -0,0 +1,449 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"os/signal"
+	"runtime"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// watchRun accumulates what a single watch invocation recorded, for the
+// summary printed when it stops.
+type watchRun struct {
+	started      time.Time
+	lastActivity time.Time
+	cycles       int
+	written      int
+	sessions     map[string]struct{}
+}
+
+func newWatchRun(now time.Time) *watchRun {
+	return &watchRun{started: now, lastActivity: now, sessions: make(map[string]struct{})}
+}
+
+func (w *watchRun) record(now time.Time, result SyncResult) {
+	w.cycles++
+	if result.Written == 0 {
+		return
+	}
+	w.lastActivity = now
+	w.written += result.Written
+	for _, record := range result.NewRecords {
+		w.sessions[record.SessionID] = struct{}{}
+	}
+}
+
+// stopReason returns why the watcher should wind down, or "" to keep going.
+func (w *watchRun) stopReason(now time.Time, maxDuration, exitAfterIdle time.Duration) string {
+	if maxDuration > 0 && now.Sub(w.started) >= maxDuration {
+		return fmt.Sprintf("max duration %s reached", maxDuration)
+	}
+	if exitAfterIdle > 0 && now.Sub(w.lastActivity) >= exitAfterIdle {
+		return fmt.Sprintf("idle for %s", exitAfterIdle)
+	}
+	return ""
+}
+
+// untilStop is how long stopReason can stay "" if nothing is written.
+func (w *watchRun) untilStop(now time.Time, maxDuration, exitAfterIdle time.Duration) time.Duration {
+	left := time.Duration(math.MaxInt64)
+	if maxDuration > 0 {
+		left = min(left, w.started.Add(maxDuration).Sub(now))
+	}
+	if exitAfterIdle > 0 {
+		left = min(left, w.lastActivity.Add(exitAfterIdle).Sub(now))
+	}
+	return max(left, 0)
+}
+
+func (w *watchRun) summary(now time.Time) string {
+	return fmt.Sprintf("ran=%s cycles=%d new=%d sessions=%d",
+		now.Sub(w.started).Round(time.Second), w.cycles, w.written, len(w.sessions))
+}
+
+// watchSettings is what watch runs with: its flags, defaulting to the
+// config file. SIGHUP re-reads the config and parses the same flags again.
+type watchSettings struct {
+	opts          SyncOptions
+	interval      time.Duration
+	maxInterval   time.Duration
+	debounce      time.Duration
+	retention     retentionPolicy
+	maxDuration   time.Duration
+	exitAfterIdle time.Duration
+	heartbeatFile string
+	metricsAddr   string
+	daemon        bool
+	pidFile       string
+	logFile       string
+	logMaxSize    int64
+	logMaxAge     time.Duration
+	logKeep       int
+	webhook       *webhook
+	notifier      *desktopNotifier
+	status        bool
+	stop          bool
+}
+
+func parseWatchSettings(args []string) (watchSettings, error) {
+	fs := flag.NewFlagSet("watch", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+
+	sessionsDir := fs.String("sessions-dir", defaultSessionsDir(), "Codex sessions directory")
+	outPath := fs.String("out", defaultOutputFile(), "Output JSONL path")
+	from := fs.String("from", appConfig.Watch.From, "Only include records at/after this RFC3339 timestamp")
+	interval := fs.Duration("interval", defaultWatchInterval(), "Sync interval")
+	maxInterval := fs.Duration("max-interval", defaultWatchMaxInterval(), "Back off to this interval while session files are unchanged, at or below --interval disables")
+	debounce := fs.Duration("debounce", defaultWatchDebounce(), "Hold a sync until session files have been quiet this long, at most one --interval, 0 disables")
+	heartbeatPath := fs.String("heartbeat", defaultHeartbeatFile(), "Heartbeat JSON path updated every cycle, empty disables")
+	maxDuration := fs.Duration("max-duration", 0, "Stop after running this long, 0 means forever")
+	exitAfterIdle := fs.Duration("exit-after-idle", 0, "Stop after this long without new records, 0 means never")
+	relativeSources := fs.Bool("relative-sources", appConfig.Watch.RelativeSources, "Store source_file relative to --sessions-dir")
+	maxTextBytes := fs.Int("max-text-bytes", 0, "Truncate message text longer than N bytes, 0 means no limit")
+	noSanitize := fs.Bool("no-sanitize", false, "Keep invalid UTF-8 and control characters in message text")
+	workers := fs.Int("workers", 0, "Session files parsed in parallel, 0 means one per CPU")
+	fsync := fs.Bool("fsync", false, "Flush appended records to disk after every cycle")
+	include := fs.String("include", appConfig.Watch.Include, "Extra record kinds to extract, comma-separated: tools,reasoning,patches")
+	assetsDir := fs.String("assets-dir", "", "Save inline image attachments into this directory")
+	includeEvents := fs.String("include-events", "", "Also record these event types verbatim, comma-separated")
+	allEvents := fs.Bool("all-events", false, "Record every event no other record was extracted from verbatim")
+	blockSecrets := fs.Bool("block-secrets", false, "Skip new records that appear to contain credentials")
+	hashPaths := fs.Bool("hash-paths", false, "Replace source_file, cwd, and file paths in text with stable hashes")
+	retainMaxAge := fs.String("retain-max-age", appConfig.Retention.MaxAge, "Remove records older than this, e.g. 90d, every --retention-every")
+	retainMaxSize := fs.String("retain-max-size", appConfig.Retention.MaxSize, "Remove the oldest records while the history is larger than this, e.g. 200M")
+	retentionEvery := fs.String("retention-every", appConfig.Retention.Every, "How often to enforce --retain-max-age/--retain-max-size (default 1h)")
+	metricsAddr := fs.String("metrics-addr", "", "Serve /metrics, /healthz, and /readyz on ADDR, empty disables")
+	daemon := fs.Bool("daemon", false, "Run in the background, detached from the terminal, until watch --stop")
+	pidFile := fs.String("pid-file", defaultPIDFile(), "PID file for --daemon, --status, and --stop")
+	logFile := fs.String("log-file", "", "Write timestamped, leveled entries to this file instead of stdout (--daemon default: "+defaultDaemonLogFile()+")")
+	logMaxSize := fs.String("log-max-size", defaultLogMaxSize, "Rotate --log-file before it grows past this size, e.g. 10M, empty disables")
+	logMaxAge := fs.String("log-max-age", defaultLogMaxAge, "Rotate --log-file once its first entry is this old, e.g. 7d, empty disables")
+	logKeep := fs.Int("log-keep", defaultLogKeep, "Rotated log files to keep, as FILE.1 (newest) to FILE.N")
+	status := fs.Bool("status", false, "Report whether a background watcher is running, and exit")
+	stopDaemonFlag := fs.Bool("stop", false, "Stop the background watcher, and exit")
+	webhookURL := fs.String("webhook", "", "POST each cycle's new records as JSON to this URL")
+	var webhookFilters []string
+	fs.Func("webhook-filter", "Only send records matching KEY=VALUE (session, role, model, project, contains, match); repeat to narrow", func(value string) error {
+		webhookFilters = append(webhookFilters, value)
+		return nil
+	})
+	notify := fs.Bool("notify", false, "Raise a desktop notification for new assistant messages")
+
+	if err := fs.Parse(args); err != nil {
+		return watchSettings{}, err
+	}
+	if (*daemon && (*status || *stopDaemonFlag)) || (*status && *stopDaemonFlag) {
+		return watchSettings{}, errors.New("--daemon, --status, and --stop cannot be combined")
+	}
+	settings := watchSettings{
+		interval:      *interval,
+		maxInterval:   max(*maxInterval, *interval),
+		debounce:      *debounce,
+		maxDuration:   *maxDuration,
+		exitAfterIdle: *exitAfterIdle,
+		heartbeatFile: strings.TrimSpace(*heartbeatPath),
+		metricsAddr:   strings.TrimSpace(*metricsAddr),
+		daemon:        *daemon,
+		pidFile:       *pidFile,
+		logFile:       strings.TrimSpace(*logFile),
+		logKeep:       *logKeep,
+		status:        *status,
+		stop:          *stopDaemonFlag,
+	}
+	if settings.status || settings.stop {
+		return settings, nil
+	}
+	if settings.daemon && settings.logFile == "" {
+		settings.logFile = defaultDaemonLogFile()
+	}
+	if *logKeep < 0 {
+		return watchSettings{}, errors.New("--log-keep must be >= 0")
+	}
+	var err error
+	if strings.TrimSpace(*logMaxSize) != "" {
+		if settings.logMaxSize, err = parseByteSize(*logMaxSize); err != nil {
+			return watchSettings{}, fmt.Errorf("--log-max-size: %w", err)
+		}
+	}
+	if strings.TrimSpace(*logMaxAge) != "" {
+		if settings.logMaxAge, err = parseRetentionAge(*logMaxAge); err != nil {
+			return watchSettings{}, fmt.Errorf("invalid --log-max-age %q (use days like 7d or a duration like 12h)", *logMaxAge)
+		}
+	}
+
+	if *interval <= 0 {
+		return watchSettings{}, errors.New("interval must be > 0")
+	}
+	if *maxInterval < 0 || *debounce < 0 {
+		return watchSettings{}, errors.New("--max-interval and --debounce must be >= 0")
+	}
+	if *maxTextBytes < 0 {
+		return watchSettings{}, errors.New("--max-text-bytes must be >= 0")
+	}
+	if *maxDuration < 0 || *exitAfterIdle < 0 {
+		return watchSettings{}, errors.New("--max-duration and --exit-after-idle must be >= 0")
+	}
+
+	since, err := parseBoundTime(*from, "--from")
+	if err != nil {
+		return watchSettings{}, err
+	}
+	includes, err := parseIncludeList(*include)
+	if err != nil {
+		return watchSettings{}, err
+	}
+	events := parseEventList(*includeEvents, *allEvents)
+	settings.retention, err = parseRetentionPolicy(*retainMaxAge, *retainMaxSize, *retentionEvery)
+	if err != nil {
+		return watchSettings{}, err
+	}
+
+	if strings.TrimSpace(*webhookURL) != "" {
+		if settings.webhook, err = newWebhook(strings.TrimSpace(*webhookURL), webhookFilters); err != nil {
+			return watchSettings{}, err
+		}
+	} else if len(webhookFilters) > 0 {
+		return watchSettings{}, errors.New("--webhook-filter needs --webhook")
+	}
+
+	if *notify {
+		if settings.notifier, err = desktopNotifierFor(runtime.GOOS); err != nil {
+			return watchSettings{}, err
+		}
+	}
+
+	settings.opts = SyncOptions{
+		SessionsDir:     *sessionsDir,
+		OutputPath:      *outPath,
+		Since:           since,
+		DryRun:          false,
+		RelativeSources: *relativeSources,
+		MaxTextBytes:    *maxTextBytes,
+		NoSanitize:      *noSanitize,
+		Workers:         *workers,
+		Fsync:           *fsync,
+		Include:         includes,
+		AssetsDir:       strings.TrimSpace(*assetsDir),
+		Events:          events,
+		BlockSecrets:    *blockSecrets,
+		HashPaths:       *hashPaths,
+	}
+	return settings, nil
+}
+
+// reloadWatchSettings re-reads the config file and applies it to the same
+// command line. The sync settings, interval, and retention change; the
+// history, heartbeat, listener, PID file, and log stay as they are, so the
+// checkpoint state kept beside the history carries on. On error the
+// current settings stay in force.
+func reloadWatchSettings(args []string, current watchSettings, log *watchLog) (watchSettings, error) {
+	cfg, err := loadConfig(defaultConfigPath())
+	if err != nil {
+		return current, err
+	}
+	previous := appConfig
+	appConfig = cfg
+	next, err := parseWatchSettings(args)
+	if err != nil {
+		appConfig = previous
+		return current, err
+	}
+	if next.opts.OutputPath != current.opts.OutputPath {
+		log.warn("keeping output %s; restart watch to write to %s", current.opts.OutputPath, next.opts.OutputPath)
+	}
+	next.opts.OutputPath = current.opts.OutputPath
+	next.maxDuration = current.maxDuration
+	next.exitAfterIdle = current.exitAfterIdle
+	next.heartbeatFile = current.heartbeatFile
+	next.metricsAddr = current.metricsAddr
+	next.pidFile = current.pidFile
+	next.logFile = current.logFile
+	next.logMaxSize = current.logMaxSize
+	next.logMaxAge = current.logMaxAge
+	next.logKeep = current.logKeep
+	return next, nil
+}
+
+func runWatch(args []string) error {
+	settings, err := parseWatchSettings(args)
+	if err != nil {
+		return err
+	}
+	switch {
+	case settings.status:
+		return printDaemonStatus(settings.pidFile, settings.heartbeatFile)
+	case settings.stop:
+		return stopDaemon(settings.pidFile)
+	}
+
+	if settings.daemon && !isDaemonChild() {
+		return startDaemon(settings.pidFile, settings.logFile)
+	}
+	if isDaemonChild() {
+		defer removePIDFile(settings.pidFile, os.Getpid())
+	}
+
+	var logFile *rotatingFile
+	if settings.logFile != "" {
+		logFile, err = openRotatingFile(settings.logFile, settings.logMaxSize, settings.logMaxAge, settings.logKeep)
+		if err != nil {
+			return err
+		}
+	}
+	log := newWatchLog(logFile)
+	defer log.Close()
+	if err := watchLoop(args, settings, log); err != nil {
+		log.fail(err)
+		return err
+	}
+	return nil
+}
+
+// watchLoop syncs every interval until it is stopped or a sync fails.
+func watchLoop(args []string, settings watchSettings, log *watchLog) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
+
+	opts := settings.opts
+	log.info("watching %s -> %s (interval=%s)", opts.SessionsDir, opts.OutputPath, settings.interval.String())
+
+	heartbeatFile := settings.heartbeatFile
+	heartbeat, err := loadHeartbeat(heartbeatFile)
+	if heartbeatFile != "" && err != nil {
+		return err
+	}
+	heartbeat.PID = os.Getpid()
+	heartbeat.SessionsDir = opts.SessionsDir
+	heartbeat.OutputPath = opts.OutputPath
+	heartbeat.StartedAt = time.Now().UTC().Format(time.RFC3339)
+
+	syncStats := newSyncMetrics()
+	health := newWatchHealth(opts.SessionsDir, opts.OutputPath, settings.maxInterval, time.Now())
+	if addr := settings.metricsAddr; addr != "" {
+		mux := http.NewServeMux()
+		mux.Handle("GET /metrics", syncStats)
+		mux.Handle("GET /healthz", health.handler(false))
+		mux.Handle("GET /readyz", health.handler(true))
+		listening, shutdown, err := serveInBackground(addr, mux)
+		if err != nil {
+			return err
+		}
+		defer shutdown()
+		log.info("metrics and health at http://%s/", listening)
+	}
+
+	pace := newWatchPace(settings.interval, settings.maxInterval, settings.debounce)
+	// synced is the sessions tree as it was before the last successful
+	// sync; while it still looks the same, there is nothing to sync.
+	var synced sessionSnapshot
+	endCycle := func(now time.Time, written int, err error) {
+		health.recordCycle(now, err)
+		if heartbeatFile != "" {
+			heartbeat.update(now, written, err)
+			if hbErr := writeHeartbeat(heartbeatFile, heartbeat); hbErr != nil {
+				log.warn("failed to write heartbeat: %v", hbErr)
+			}
+		}
+	}
+
+	run := newWatchRun(time.Now())
+	var lastRetention time.Time
+	for {
+		opts := settings.opts
+		var wait time.Duration
+		snapshot, snapErr := snapshotSessions(opts.SessionsDir)
+		start := time.Now()
+		if snapErr == nil && synced != nil && snapshot.equal(synced) {
+			syncStats.recordIdle(start)
+			endCycle(start, 0, nil)
+			run.record(start, SyncResult{})
+			wait = pace.idle()
+		} else if hold, ok := pace.hold(start, snapshot.lastWrite()); ok {
+			// Still being written; sync once the burst is over.
+			wait = hold
+		} else {
+			result, err := syncOnce(opts)
+			syncStats.recordSync(start, result, err)
+			now := time.Now()
+			endCycle(now, result.Written, err)
+			if err != nil {
+				return err
+			}
+			synced = snapshot
+			wait = pace.synced()
+			run.record(now, result)
+			printSecretWarnings(log.warnings(), result.Secrets, opts.BlockSecrets, false)
+
+			if result.Written > 0 {
+				log.event(now, "files=%d scanned=%d new=%d", result.Files, result.Scanned, result.Written)
+			}
+			if settings.webhook != nil && len(result.NewRecords) > 0 {
+				sent, err := settings.webhook.notify(result.NewRecords)
+				if sent > 0 || err != nil {
+					syncStats.recordWebhook(err)
+				}
+				if err != nil {
+					log.warn("webhook failed after %d records: %v", sent, err)
+				} else if sent > 0 {
+					log.event(now, "webhook sent=%d", sent)
+				}
+			}
+			if settings.notifier != nil && len(result.NewRecords) > 0 {
+				if notified, err := settings.notifier.notify(result.NewRecords, run.started); err != nil {
+					log.warn("notification failed: %v", err)
+				} else if notified > 0 {
+					log.event(now, "notified messages=%d", notified)
+				}
+			}
+		}
+
+		now := time.Now()
+		if retention := settings.retention; retention.enabled() && now.Sub(lastRetention) >= retention.every {
+			lastRetention = now
+			removed, err := enforceRetention(opts.OutputPath, retention, now)
+			if err != nil {
+				log.warn("retention failed: %v", err)
+			} else if removed > 0 {
+				log.event(now, "retention removed=%d", removed)
+			}
+		}
+
+		if reason := run.stopReason(now, settings.maxDuration, settings.exitAfterIdle); reason != "" {
+			log.info("watch stopped (%s): %s", reason, run.summary(now))
+			return nil
+		}
+
+		timer := time.NewTimer(min(wait, run.untilStop(now, settings.maxDuration, settings.exitAfterIdle)))
+		select {
+		case <-ctx.Done():
+			log.info("watch stopped: %s", run.summary(time.Now()))
+			return nil
+		case <-hangup:
+			// Either way, the next cycle starts now.
+			next, err := reloadWatchSettings(args, settings, log)
+			if err != nil {
+				log.warn("config not reloaded: %v", err)
+				break
+			}
+			settings = next
+			pace = newWatchPace(settings.interval, settings.maxInterval, settings.debounce)
+			synced = nil
+			health.reconfigure(settings.opts.SessionsDir, settings.maxInterval)
+			heartbeat.SessionsDir = settings.opts.SessionsDir
+			log.event(time.Now(), "reloaded config: watching %s (interval=%s)", settings.opts.SessionsDir, settings.interval)
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+}
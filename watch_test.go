@@ -0,0 +1,81 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatchRunStopReason(t *testing.T) {
+	start := time.Date(2026, 2, 17, 9, 0, 0, 0, time.UTC)
+	run := newWatchRun(start)
+
+	run.record(start.Add(10*time.Minute), SyncResult{Written: 2, NewRecords: []Record{{SessionID: "s1"}, {SessionID: "s2"}}})
+	run.record(start.Add(20*time.Minute), SyncResult{})
+
+	if reason := run.stopReason(start.Add(39*time.Minute), 8*time.Hour, 30*time.Minute); reason != "" {
+		t.Fatalf("expected watcher to keep running, got %q", reason)
+	}
+	if reason := run.stopReason(start.Add(40*time.Minute), 8*time.Hour, 30*time.Minute); reason != "idle for 30m0s" {
+		t.Fatalf("expected idle stop, got %q", reason)
+	}
+	if reason := run.stopReason(start.Add(8*time.Hour), 8*time.Hour, 0); reason != "max duration 8h0m0s reached" {
+		t.Fatalf("expected max duration stop, got %q", reason)
+	}
+	if left := run.untilStop(start.Add(25*time.Minute), 8*time.Hour, 30*time.Minute); left != 15*time.Minute {
+		t.Fatalf("untilStop = %s, want 15m (the idle limit comes first)", left)
+	}
+	if got := run.summary(start.Add(time.Hour)); got != "ran=1h0m0s cycles=2 new=2 sessions=2" {
+		t.Fatalf("unexpected summary: %q", got)
+	}
+}
+
+func TestReloadWatchSettings(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.json")
+	t.Setenv("CODEX_HISTORY_CONFIG", configPath)
+	previous := appConfig
+	t.Cleanup(func() { appConfig = previous })
+
+	writeConfig := func(data string) {
+		t.Helper()
+		if err := os.WriteFile(configPath, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeConfig(`{"sessions_dir": "/srv/a", "output": "/srv/h.jsonl", "watch": {"interval": "1m"}}`)
+	cfg, err := loadConfig(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	appConfig = cfg
+	args := []string{"--heartbeat", "", "--max-duration", "8h", "--retention-every", "2h"}
+	settings, err := parseWatchSettings(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeConfig(`{"sessions_dir": "/srv/b", "output": "/srv/other.jsonl",
+		"watch": {"interval": "10s", "max_interval": "2m", "include": "tools"},
+		"retention": {"max_age": "30d", "every": "1h"}}`)
+	next, err := reloadWatchSettings(args, settings, newWatchLog(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next.opts.SessionsDir != "/srv/b" || next.interval != 10*time.Second || next.maxInterval != 2*time.Minute || !next.opts.Include["tools"] {
+		t.Fatalf("config changes not applied: %+v", next)
+	}
+	if next.retention.maxAge != 30*24*time.Hour || next.retention.every != 2*time.Hour {
+		t.Fatalf("retention: %+v (flags should still win)", next.retention)
+	}
+	if next.opts.OutputPath != "/srv/h.jsonl" || next.maxDuration != 8*time.Hour {
+		t.Fatalf("output and run limits should be kept: %+v", next)
+	}
+
+	writeConfig(`{"watch": {"interval": "soon"}}`)
+	kept, err := reloadWatchSettings(args, next, newWatchLog(nil))
+	if err == nil || kept.opts.SessionsDir != "/srv/b" {
+		t.Fatalf("an invalid config should keep the settings: %v, %+v", err, kept)
+	}
+}
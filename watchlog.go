@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Defaults for watch --log-file rotation.
+const (
+	defaultLogMaxSize = "10M"
+	defaultLogMaxAge  = "7d"
+	defaultLogKeep    = 5
+)
+
+// rotatingFile appends lines to path. Before a line would take the file
+// past maxSize, or once its first entry is older than maxAge, the file is
+// renamed to path.1 (path.1 to path.2, and so on, dropping what is past
+// keep) and a new one is started. Zero limits are off.
+type rotatingFile struct {
+	mu      sync.Mutex
+	path    string
+	maxSize int64
+	maxAge  time.Duration
+	keep    int
+	file    *os.File
+	size    int64
+	started time.Time
+}
+
+func openRotatingFile(path string, maxSize int64, maxAge time.Duration, keep int) (*rotatingFile, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	f := &rotatingFile{path: path, maxSize: maxSize, maxAge: maxAge, keep: keep}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// open opens path for appending. The age of a file carried over from an
+// earlier run counts from its first entry's timestamp.
+func (f *rotatingFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.file, f.size, f.started = file, info.Size(), time.Time{}
+	if f.size > 0 {
+		f.started = firstEntryTime(f.path, info.ModTime())
+	}
+	return nil
+}
+
+// firstEntryTime reads the timestamp that starts the file's first line,
+// falling back to fallback for a file in another format.
+func firstEntryTime(path string, fallback time.Time) time.Time {
+	file, err := os.Open(path)
+	if err != nil {
+		return fallback
+	}
+	defer file.Close()
+	line, _ := bufio.NewReader(io.LimitReader(file, 256)).ReadString('\n')
+	field, _, _ := strings.Cut(line, " ")
+	if ts, err := time.Parse(time.RFC3339, field); err == nil {
+		return ts
+	}
+	return fallback
+}
+
+func (f *rotatingFile) writeLine(now time.Time, line []byte) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.size > 0 && ((f.maxSize > 0 && f.size+int64(len(line)) > f.maxSize) ||
+		(f.maxAge > 0 && now.Sub(f.started) >= f.maxAge)) {
+		if err := f.rotate(); err != nil {
+			return err
+		}
+	}
+	if f.size == 0 {
+		f.started = now
+	}
+	n, err := f.file.Write(line)
+	f.size += int64(n)
+	return err
+}
+
+// rotate shifts the old files up by one and reopens path. f.mu must be held.
+func (f *rotatingFile) rotate() error {
+	f.file.Close()
+	if f.keep < 1 {
+		if err := os.Remove(f.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return f.open()
+	}
+	os.Remove(f.path + "." + strconv.Itoa(f.keep))
+	for i := f.keep - 1; i >= 1; i-- {
+		os.Rename(f.path+"."+strconv.Itoa(i), f.path+"."+strconv.Itoa(i+1))
+	}
+	if err := os.Rename(f.path, f.path+".1"); err != nil {
+		return err
+	}
+	return f.open()
+}
+
+func (f *rotatingFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.file.Close()
+}
+
+// watchLog is where watch reports progress. Without a file it prints as it
+// always has: progress to stdout and warnings to stderr. With one, every
+// entry is a line of timestamp, level, and message.
+type watchLog struct {
+	file *rotatingFile
+	now  func() time.Time
+}
+
+func newWatchLog(file *rotatingFile) *watchLog {
+	return &watchLog{file: file, now: time.Now}
+}
+
+func (l *watchLog) entry(level, message string) {
+	now := l.now()
+	line := fmt.Sprintf("%s %s %s\n", now.UTC().Format(time.RFC3339), level, message)
+	if err := l.file.writeLine(now, []byte(line)); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: failed to write log: %v\n", err)
+	}
+}
+
+// info reports a state change, such as starting or stopping.
+func (l *watchLog) info(format string, args ...any) {
+	if l.file == nil {
+		fmt.Printf(format+"\n", args...)
+		return
+	}
+	l.entry("INFO", fmt.Sprintf(format, args...))
+}
+
+// event reports something that happened at now; on stdout it is prefixed
+// with the time.
+func (l *watchLog) event(now time.Time, format string, args ...any) {
+	if l.file == nil {
+		fmt.Printf("%s "+format+"\n", append([]any{now.UTC().Format(time.RFC3339)}, args...)...)
+		return
+	}
+	l.entry("INFO", fmt.Sprintf(format, args...))
+}
+
+func (l *watchLog) warn(format string, args ...any) {
+	if l.file == nil {
+		fmt.Fprintf(os.Stderr, "warning: "+format+"\n", args...)
+		return
+	}
+	l.entry("WARN", fmt.Sprintf(format, args...))
+}
+
+// fail records the error watch is exiting with. On stdout, main prints it.
+func (l *watchLog) fail(err error) {
+	if l.file != nil {
+		l.entry("ERROR", err.Error())
+	}
+}
+
+// warnings is a writer for helpers that print "warning: ..." lines.
+func (l *watchLog) warnings() io.Writer {
+	if l.file == nil {
+		return os.Stderr
+	}
+	return warningWriter{l}
+}
+
+type warningWriter struct{ log *watchLog }
+
+func (w warningWriter) Write(p []byte) (int, error) {
+	for _, line := range bytes.Split(bytes.TrimRight(p, "\n"), []byte("\n")) {
+		w.log.warn("%s", strings.TrimPrefix(strings.TrimSpace(string(line)), "warning: "))
+	}
+	return len(p), nil
+}
+
+func (l *watchLog) Close() error {
+	if l.file == nil {
+		return nil
+	}
+	return l.file.Close()
+}
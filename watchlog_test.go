@@ -0,0 +1,104 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRotatingFileSize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "watch.log")
+	f, err := openRotatingFile(path, 20, 0, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, 2, 17, 9, 0, 0, 0, time.UTC)
+	for _, line := range []string{"first line\n", "second line\n", "third line\n", "fourth line\n"} {
+		if err := f.writeLine(now, []byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	f.Close()
+
+	for name, want := range map[string]string{
+		path:        "fourth line\n",
+		path + ".1": "third line\n",
+		path + ".2": "second line\n",
+	} {
+		data, err := os.ReadFile(name)
+		if err != nil || string(data) != want {
+			t.Errorf("%s = %q, %v; want %q", filepath.Base(name), data, err, want)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !errors.Is(err, os.ErrNotExist) {
+		t.Error("only --log-keep rotated files should be kept")
+	}
+}
+
+func TestRotatingFileAge(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "watch.log")
+	start := time.Date(2026, 2, 17, 9, 0, 0, 0, time.UTC)
+	if err := os.WriteFile(path, []byte(start.Format(time.RFC3339)+" INFO watching\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// A file from an earlier run keeps the age of its first entry.
+	f, err := openRotatingFile(path, 0, 24*time.Hour, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := f.writeLine(start.Add(23*time.Hour), []byte("same day\n")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path + ".1"); err == nil {
+		t.Fatal("rotated too early")
+	}
+	if err := f.writeLine(start.Add(25*time.Hour), []byte("next day\n")); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "next day\n" {
+		t.Fatalf("after rotation: %q", data)
+	}
+	if data, _ := os.ReadFile(path + ".1"); !strings.HasSuffix(string(data), "same day\n") {
+		t.Fatalf("rotated file: %q", data)
+	}
+}
+
+func TestWatchLogEntries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "watch.log")
+	f, err := openRotatingFile(path, 0, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	log := newWatchLog(f)
+	log.now = func() time.Time { return time.Date(2026, 2, 17, 9, 0, 0, 0, time.UTC) }
+	log.info("watching %s", "/sessions")
+	log.event(log.now(), "files=%d new=%d", 3, 2)
+	printSecretWarnings(log.warnings(), []SecretFinding{{RecordID: "r1", SessionID: "s1", Kinds: []string{"aws"}}}, false, false)
+	log.fail(errors.New("history is locked"))
+	log.Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	want := []string{
+		"2026-02-17T09:00:00Z INFO watching /sessions",
+		"2026-02-17T09:00:00Z INFO files=3 new=2",
+		"2026-02-17T09:00:00Z WARN 1 new records appear to contain credentials",
+		"2026-02-17T09:00:00Z WARN r1 session=s1 kinds=aws",
+		"2026-02-17T09:00:00Z ERROR history is locked",
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("got %d lines:\n%s", len(lines), data)
+	}
+	for i := range want {
+		if !strings.HasPrefix(lines[i], want[i]) {
+			t.Errorf("line %d = %q, want prefix %q", i, lines[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"time"
+)
+
+// sessionStamp is a session file's size and modification time.
+type sessionStamp struct {
+	size    int64
+	modTime time.Time
+}
+
+// sessionSnapshot is the stamp of every session file. Comparing the one
+// taken before the last sync with a new one tells watch, for the price of a
+// stat per file, whether syncing again could find anything.
+type sessionSnapshot map[string]sessionStamp
+
+func snapshotSessions(dir string) (sessionSnapshot, error) {
+	files, err := listSessionFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := make(sessionSnapshot, len(files))
+	for _, path := range files {
+		info, err := os.Stat(path)
+		if err != nil {
+			// Removed since the listing; the next snapshot will not have it.
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+		snapshot[path] = sessionStamp{size: info.Size(), modTime: info.ModTime()}
+	}
+	return snapshot, nil
+}
+
+func (s sessionSnapshot) equal(other sessionSnapshot) bool {
+	if len(s) != len(other) {
+		return false
+	}
+	for path, stamp := range s {
+		if prev, ok := other[path]; !ok || prev.size != stamp.size || !prev.modTime.Equal(stamp.modTime) {
+			return false
+		}
+	}
+	return true
+}
+
+// lastWrite is the newest modification time among the files.
+func (s sessionSnapshot) lastWrite() time.Time {
+	var latest time.Time
+	for _, stamp := range s {
+		if stamp.modTime.After(latest) {
+			latest = stamp.modTime
+		}
+	}
+	return latest
+}
+
+// watchPace decides how long watch waits between checks. Each check that
+// finds nothing changed doubles the wait, up to maxInterval; a sync brings
+// it back to interval. A change written less than debounce ago is held
+// until the files go quiet, so a burst of writes costs one sync, but never
+// for more than interval after it was first seen.
+type watchPace struct {
+	interval    time.Duration
+	maxInterval time.Duration
+	debounce    time.Duration
+	wait        time.Duration
+	pending     time.Time
+}
+
+func newWatchPace(interval, maxInterval, debounce time.Duration) *watchPace {
+	return &watchPace{interval: interval, maxInterval: max(maxInterval, interval), debounce: debounce, wait: interval}
+}
+
+// idle follows a check that found nothing to sync and returns the next wait.
+func (p *watchPace) idle() time.Duration {
+	p.wait = min(2*p.wait, p.maxInterval)
+	return p.wait
+}
+
+// hold reports whether to put off a sync because a file was written at
+// lastWrite, less than debounce before now, and if so how long to wait.
+func (p *watchPace) hold(now, lastWrite time.Time) (time.Duration, bool) {
+	quiet := now.Sub(lastWrite)
+	if p.debounce <= 0 || quiet >= p.debounce {
+		return 0, false
+	}
+	if p.pending.IsZero() {
+		p.pending = now
+	}
+	left := p.interval - now.Sub(p.pending)
+	if left <= 0 {
+		return 0, false
+	}
+	return min(p.debounce-max(quiet, 0), left), true
+}
+
+// synced follows a sync and returns the next wait.
+func (p *watchPace) synced() time.Duration {
+	p.wait = p.interval
+	p.pending = time.Time{}
+	return p.wait
+}
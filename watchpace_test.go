@@ -0,0 +1,95 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSessionSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "2026", "02", "17", "rollout-a.jsonl")
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("{}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	before, err := snapshotSessions(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := snapshotSessions(dir); !again.equal(before) {
+		t.Fatal("an untouched tree should look the same")
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString("{}\n")
+	file.Close()
+	appended, _ := snapshotSessions(dir)
+	if appended.equal(before) {
+		t.Fatal("an append should be a change")
+	}
+	if err := os.WriteFile(filepath.Join(dir, "rollout-b.jsonl"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if added, _ := snapshotSessions(dir); added.equal(appended) {
+		t.Fatal("a new file should be a change")
+	}
+	if info, _ := os.Stat(path); !appended.lastWrite().Equal(info.ModTime()) {
+		t.Fatalf("lastWrite = %s, want %s", appended.lastWrite(), info.ModTime())
+	}
+}
+
+func TestWatchPaceBackoff(t *testing.T) {
+	pace := newWatchPace(5*time.Second, time.Minute, 0)
+	var waits []time.Duration
+	for i := 0; i < 5; i++ {
+		waits = append(waits, pace.idle())
+	}
+	want := []time.Duration{10 * time.Second, 20 * time.Second, 40 * time.Second, time.Minute, time.Minute}
+	for i := range want {
+		if waits[i] != want[i] {
+			t.Fatalf("idle waits = %v, want %v", waits, want)
+		}
+	}
+	if wait := pace.synced(); wait != 5*time.Second {
+		t.Fatalf("after a sync the wait should reset, got %s", wait)
+	}
+
+	// A max interval at or below --interval turns the backoff off.
+	if wait := newWatchPace(5*time.Second, 0, 0).idle(); wait != 5*time.Second {
+		t.Fatalf("backoff disabled: wait = %s", wait)
+	}
+}
+
+func TestWatchPaceHold(t *testing.T) {
+	start := time.Date(2026, 2, 17, 9, 0, 0, 0, time.UTC)
+	pace := newWatchPace(5*time.Second, time.Minute, time.Second)
+
+	if _, ok := pace.hold(start, start.Add(-2*time.Second)); ok {
+		t.Fatal("files quiet for longer than the debounce should sync at once")
+	}
+	if wait, ok := pace.hold(start, start.Add(-300*time.Millisecond)); !ok || wait != 700*time.Millisecond {
+		t.Fatalf("hold = %s, %v; want 700ms", wait, ok)
+	}
+	// Writes that never pause are held for at most one interval.
+	if wait, ok := pace.hold(start.Add(4800*time.Millisecond), start.Add(4700*time.Millisecond)); !ok || wait != 200*time.Millisecond {
+		t.Fatalf("hold near the limit = %s, %v; want 200ms", wait, ok)
+	}
+	if _, ok := pace.hold(start.Add(5*time.Second), start.Add(5*time.Second)); ok {
+		t.Fatal("a change held for a whole interval should sync")
+	}
+	pace.synced()
+	if _, ok := pace.hold(start.Add(6*time.Second), start.Add(6*time.Second)); !ok {
+		t.Fatal("a new burst after a sync should be held again")
+	}
+
+	if _, ok := newWatchPace(5*time.Second, time.Minute, 0).hold(start, start); ok {
+		t.Fatal("--debounce 0 should never hold")
+	}
+}
@@ -0,0 +1,163 @@
+// Codex History web UI. Routes live in the URL hash, so the back button and
+// bookmarks work: #/session/ID[/RECORD_ID] and #/search/QUERY. When serve
+// runs with --token, the token is asked for once and kept in localStorage.
+// Under serve --source the page lives at /sources/NAME/ and names its source.
+(function () {
+  "use strict";
+
+  var sessionsNav = document.getElementById("sessions");
+  var view = document.getElementById("view");
+  var search = document.getElementById("search");
+  var sessions = [];
+  var tokenKey = "codex-history-token";
+
+  function el(tag, className, text) {
+    var node = document.createElement(tag);
+    if (className) node.className = className;
+    if (text !== undefined) node.textContent = text;
+    return node;
+  }
+
+  function getJSON(url) {
+    var headers = {};
+    var token = localStorage.getItem(tokenKey);
+    if (token) headers.Authorization = "Bearer " + token;
+    return fetch(url, { headers: headers }).then(function (response) {
+      return response.json().then(function (body) {
+        if (response.status === 401) {
+          askToken(token ? "That token was not accepted." : "This server needs a token.");
+          throw new Error(body.error);
+        }
+        if (!response.ok) throw new Error(body.error || response.statusText);
+        return body;
+      });
+    });
+  }
+
+  var asking = false;
+  function askToken(reason) {
+    if (asking) return;
+    asking = true;
+    var form = el("form", "message");
+    var input = el("input");
+    input.type = "password";
+    input.placeholder = "Token";
+    input.autocomplete = "current-password";
+    form.append(el("p", null, reason), input, " ", el("button", null, "Sign in"));
+    form.addEventListener("submit", function (event) {
+      event.preventDefault();
+      localStorage.setItem(tokenKey, input.value.trim());
+      asking = false;
+      start();
+    });
+    sessionsNav.replaceChildren();
+    view.replaceChildren(form);
+    input.focus();
+  }
+
+  function showError(err) {
+    if (asking) return;
+    view.replaceChildren(el("p", "message error", err.message));
+  }
+
+  function shortID(id) {
+    return id.length > 8 ? id.slice(0, 8) : id;
+  }
+
+  function sessionHref(id, recordID) {
+    return "#/session/" + encodeURIComponent(id) + (recordID ? "/" + encodeURIComponent(recordID) : "");
+  }
+
+  function renderSessions(activeID) {
+    if (sessions.length === 0) {
+      sessionsNav.replaceChildren(el("p", "meta", "No sessions yet."));
+      return;
+    }
+    sessionsNav.replaceChildren.apply(sessionsNav, sessions.map(function (s) {
+      var link = el("a", "session-link" + (s.session_id === activeID ? " active" : ""));
+      link.href = sessionHref(s.session_id);
+      link.append(el("span", "title", s.preview || s.session_id));
+      var meta = (s.last_timestamp || "").replace("T", " ").replace("Z", "") + " · " + s.total + " messages";
+      if (s.cwd) meta += " · " + s.cwd.split(/[\\/]/).pop();
+      link.append(el("span", "meta", meta));
+      return link;
+    }));
+  }
+
+  function showSession(id, recordID) {
+    renderSessions(id);
+    getJSON("api/sessions/" + encodeURIComponent(id)).then(function (messages) {
+      var heading = el("h2", null, id);
+      var nodes = [heading];
+      messages.forEach(function (m) {
+        var message = el("div", "message " + m.role);
+        message.id = m.id;
+        var meta = m.role + " · " + m.timestamp;
+        if (m.meta && m.meta.model) meta += " · " + m.meta.model;
+        message.append(el("div", "meta", meta));
+        var body = el("div", "body");
+        body.innerHTML = m.html; // rendered and escaped by the server
+        message.append(body);
+        nodes.push(message);
+      });
+      view.replaceChildren.apply(view, nodes);
+      var target = recordID && document.getElementById(recordID);
+      if (target) target.scrollIntoView({ block: "center" });
+      else window.scrollTo(0, 0);
+    }).catch(showError);
+  }
+
+  function showSearch(query) {
+    renderSessions(null);
+    if (search.value !== query) search.value = query;
+    getJSON("api/search?q=" + encodeURIComponent(query)).then(function (hits) {
+      var nodes = [el("h2", null, hits.length + (hits.length === 1 ? " match" : " matches") + " for “" + query + "”")];
+      hits.forEach(function (hit) {
+        var link = el("a", "hit");
+        link.href = sessionHref(hit.session_id, hit.id);
+        link.append(el("div", "meta", hit.role + " · " + hit.timestamp + " · session " + shortID(hit.session_id)));
+        link.append(el("div", "text", hit.snippet));
+        nodes.push(link);
+      });
+      view.replaceChildren.apply(view, nodes);
+    }).catch(showError);
+  }
+
+  function route() {
+    var parts = location.hash.replace(/^#\/?/, "").split("/").map(decodeURIComponent);
+    if (parts[0] === "session" && parts[1]) return showSession(parts[1], parts[2]);
+    if (parts[0] === "search" && parts[1]) return showSearch(parts.slice(1).join("/"));
+    renderSessions(null);
+    view.replaceChildren(el("p", "meta", "Pick a session, or search above."));
+  }
+
+  var timer = null;
+  search.addEventListener("input", function () {
+    clearTimeout(timer);
+    timer = setTimeout(function () {
+      var query = search.value.trim();
+      location.hash = query ? "#/search/" + encodeURIComponent(query) : "#";
+    }, 250);
+  });
+  window.addEventListener("hashchange", route);
+
+  var source = location.pathname.match(/\/sources\/([^/]+)\/$/);
+  if (source) {
+    var name = decodeURIComponent(source[1]);
+    var all = el("a", "source", name);
+    all.href = "../../";
+    all.title = "All histories";
+    document.querySelector("header h1").after(all);
+    document.title = name + " · Codex History";
+  }
+
+  function start() {
+    getJSON("api/sessions").then(function (list) {
+      sessions = list;
+      route();
+    }).catch(function (err) {
+      if (!asking) sessionsNav.replaceChildren(el("p", "message error", err.message));
+    });
+  }
+  start();
+})();
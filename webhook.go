@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	webhookTimeout = 10 * time.Second
+	// webhookBatchSize caps the records in one POST, so a first sync of a
+	// large history does not send one huge request.
+	webhookBatchSize = 100
+)
+
+// webhook POSTs newly written records to a URL after each watch cycle.
+type webhook struct {
+	url    string
+	match  func(Record) bool
+	client *http.Client
+}
+
+// webhookPayload is the JSON body of a webhook POST. Text is a one-line
+// summary, which is what Slack and similar incoming webhooks display.
+type webhookPayload struct {
+	Text     string   `json:"text"`
+	Count    int      `json:"count"`
+	Sessions []string `json:"sessions"`
+	Records  []Record `json:"records"`
+}
+
+// newWebhook checks rawURL and builds the record filter from --webhook-filter
+// KEY=VALUE values; a record must match every one.
+func newWebhook(rawURL string, filters []string) (*webhook, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, fmt.Errorf("invalid --webhook %q: expected an http or https URL", rawURL)
+	}
+	filter, err := parseWebhookFilters(filters)
+	if err != nil {
+		return nil, err
+	}
+	return &webhook{url: rawURL, match: newRecordMatcher(filter), client: &http.Client{Timeout: webhookTimeout}}, nil
+}
+
+// parseWebhookFilters reads KEY=VALUE filters. The keys are the show filter
+// flags: session, role, model, project, contains, and match (a regular
+// expression).
+func parseWebhookFilters(values []string) (RecordFilter, error) {
+	var filter RecordFilter
+	seen := make(map[string]bool)
+	for _, value := range values {
+		key, arg, ok := strings.Cut(value, "=")
+		key = strings.ToLower(strings.TrimSpace(key))
+		if !ok || strings.TrimSpace(arg) == "" {
+			return RecordFilter{}, fmt.Errorf("invalid --webhook-filter %q: expected KEY=VALUE", value)
+		}
+		if seen[key] {
+			return RecordFilter{}, fmt.Errorf("--webhook-filter %s given twice", key)
+		}
+		seen[key] = true
+		switch key {
+		case "session":
+			filter.SessionID = arg
+		case "role":
+			filter.Role = arg
+		case "model":
+			filter.Model = arg
+		case "project":
+			filter.Project = arg
+		case "contains":
+			filter.Contains = arg
+		case "match":
+			pattern, err := regexp.Compile(arg)
+			if err != nil {
+				return RecordFilter{}, fmt.Errorf("invalid --webhook-filter match: %w", err)
+			}
+			filter.Match = pattern
+		default:
+			return RecordFilter{}, fmt.Errorf("unknown --webhook-filter key %q (want session, role, model, project, contains, or match)", key)
+		}
+	}
+	return filter, nil
+}
+
+// notify sends the records that pass the filter, in batches, and returns how
+// many were delivered. It stops at the first failed POST.
+func (h *webhook) notify(records []Record) (int, error) {
+	var matched []Record
+	for _, record := range records {
+		if h.match(record) {
+			matched = append(matched, record)
+		}
+	}
+	sent := 0
+	for start := 0; start < len(matched); start += webhookBatchSize {
+		batch := matched[start:min(start+webhookBatchSize, len(matched))]
+		if err := h.post(newWebhookPayload(batch)); err != nil {
+			return sent, err
+		}
+		sent += len(batch)
+	}
+	return sent, nil
+}
+
+func newWebhookPayload(records []Record) webhookPayload {
+	var sessions []string
+	seen := make(map[string]bool)
+	for _, record := range records {
+		if !seen[record.SessionID] {
+			seen[record.SessionID] = true
+			sessions = append(sessions, record.SessionID)
+		}
+	}
+	text := fmt.Sprintf("%d new Codex records in %d sessions", len(records), len(sessions))
+	if len(records) == 1 {
+		text = fmt.Sprintf("New Codex %s record in session %s: %s", records[0].Role, shortSessionID(records[0].SessionID), sessionPreview(records[0].Text))
+	}
+	return webhookPayload{Text: text, Count: len(records), Sessions: sessions, Records: records}
+}
+
+func (h *webhook) post(payload webhookPayload) error {
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(payload); err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, h.url, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "codex-history")
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s answered %s", h.url, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWebhookNotify(t *testing.T) {
+	var payloads []webhookPayload
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("got %s with Content-Type %q", r.Method, r.Header.Get("Content-Type"))
+		}
+		var payload webhookPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Error(err)
+		}
+		payloads = append(payloads, payload)
+	}))
+	defer srv.Close()
+
+	hook, err := newWebhook(srv.URL, []string{"role=user"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var records []Record
+	for i := 0; i < webhookBatchSize+1; i++ {
+		records = append(records, Record{ID: fmt.Sprint(i), SessionID: fmt.Sprintf("session-%d", i%2), Role: "user", Text: "hello"})
+	}
+	records = append(records, Record{ID: "a", SessionID: "session-0", Role: "assistant", Text: "hi"})
+
+	sent, err := hook.notify(records)
+	if err != nil || sent != webhookBatchSize+1 {
+		t.Fatalf("notify = %d, %v", sent, err)
+	}
+	if len(payloads) != 2 || payloads[0].Count != webhookBatchSize || payloads[1].Count != 1 {
+		t.Fatalf("payloads: %+v", payloads)
+	}
+	if got := payloads[0].Text; got != "100 new Codex records in 2 sessions" {
+		t.Errorf("batch text = %q", got)
+	}
+	if got := payloads[1].Text; got != "New Codex user record in session session-: hello" {
+		t.Errorf("single text = %q", got)
+	}
+	if len(payloads[1].Sessions) != 1 || payloads[1].Records[0].ID != "100" {
+		t.Errorf("last payload: %+v", payloads[1])
+	}
+}
+
+func TestWebhookNotifyFailure(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "no", http.StatusForbidden)
+	}))
+	defer srv.Close()
+
+	hook, err := newWebhook(srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sent, err := hook.notify([]Record{{ID: "1", SessionID: "s", Role: "user", Text: "x"}})
+	if sent != 0 || err == nil || !strings.Contains(err.Error(), "403") {
+		t.Fatalf("notify = %d, %v; want a 403 error", sent, err)
+	}
+	// Nothing matching means nothing to send.
+	hook, _ = newWebhook(srv.URL, []string{"contains=absent"})
+	if sent, err := hook.notify([]Record{{ID: "1", Text: "x"}}); sent != 0 || err != nil {
+		t.Fatalf("filtered notify = %d, %v", sent, err)
+	}
+}
+
+func TestNewWebhookErrors(t *testing.T) {
+	for _, tc := range []struct {
+		url     string
+		filters []string
+		want    string
+	}{
+		{"ftp://example.com", nil, "http or https"},
+		{"example.com/hook", nil, "http or https"},
+		{"https://example.com", []string{"role"}, "KEY=VALUE"},
+		{"https://example.com", []string{"color=red"}, "unknown --webhook-filter key"},
+		{"https://example.com", []string{"role=user", "role=assistant"}, "given twice"},
+		{"https://example.com", []string{"match=("}, "invalid --webhook-filter match"},
+	} {
+		if _, err := newWebhook(tc.url, tc.filters); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("newWebhook(%q, %q) = %v, want %q", tc.url, tc.filters, err, tc.want)
+		}
+	}
+}